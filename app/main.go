@@ -7,13 +7,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"short_url/internal/cache"
+	"strings"
 	"short_url/internal/config"
+	"short_url/internal/events"
 	"short_url/internal/handlers"
 	"short_url/internal/handlers/middlewares"
-	"short_url/internal/repositories"
+	"short_url/internal/leader"
+	"short_url/internal/mailer"
+	"short_url/internal/manage"
+	"short_url/internal/notify"
+	"short_url/internal/oidc"
+	"short_url/internal/plans"
+	"short_url/internal/ratelimit"
 	"short_url/internal/services"
-	"short_url/pkg/client"
 	logg "short_url/pkg/logger"
 	"syscall"
 	"time"
@@ -21,7 +27,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/robfig/cron/v3"
 )
 
 func main() {
@@ -43,71 +48,227 @@ func main() {
 	// Запускаем gin
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	// Значения из контекста запроса (trace_id) доступны через gin.Context
+	router.ContextWithFallback = true
+	// IP клиента (для ограничения попыток входа) берется из X-Forwarded-For только от доверенных прокси
+	if len(conf.HTTP.TrustedProxies) > 0 {
+		if err = router.SetTrustedProxies(conf.HTTP.TrustedProxies); err != nil {
+			l.Fatalf("invalid trusted proxies. Error: %s", err)
+		}
+	}
 
-	// Инициализация клиента PostgreSQL
-	db, err := client.NewPgxClient(ctx, conf.DB)
+	// Инициализация слоя repositories (Postgres и Redis или память процесса, см. STORAGE)
+	store, err := newStorage(ctx, conf, l)
 	if err != nil {
-		l.Fatalf("unable connect to database. Error: %e", err)
+		l.Fatalf("unable to init storage. Error: %s", err)
 	}
+	userRepo := store.users
+	linkRepo := store.links
+	subRepo := store.subs
+	reportRepo := store.reports
+	tokenRepo := store.tokens
+	recoveryRepo := store.recovery
+	identityRepo := store.identities
+	auditRepo := store.audit
+	billRepo := store.bills
+	qiwiEventRepo := store.qiwiEvents
+	promoRepo := store.promos
+	webhookRepo := store.webhooks
+	attemptRepo := store.attempts
+	revocationRepo := store.revocations
+	oidcStateRepo := store.oidcStates
+	jobRepo := store.jobs
 
-	// Инициализация клиент Redis
-	redis, err := client.NewRedisClient(ctx, conf.RDB)
+	// Инициализация отправителя писем
+	mail, err := mailer.NewMailer(conf.Mail)
 	if err != nil {
-		l.Fatalf("unable connect to Redis. Error: %e", err)
+		l.Fatalf("unable to init mailer. Error: %s", err)
 	}
-	p := redis.Pipeline()
 
-	// Инициализация слоя repositories
-	userRepo := repositories.NewPostgresqlUserRepository(&repositories.PostgresqlUserRepositoryConfig{
-		Table: "user",
-		DB: db,
+	// Загрузка каталога тарифных планов
+	catalog, err := plans.Load(conf.Plans, conf.Price)
+	if err != nil {
+		l.Fatalf("unable to load plans. Error: %s", err)
+	}
+
+	// Инициализация канала уведомлений об окончании подписки
+	notifier, err := notify.NewNotifier(&notify.Config{
+		Conf: conf.Notify,
+		Mailer: mail,
+		UserRepo: userRepo,
+		Logger: l,
 	})
-	linkRepo := repositories.NewRedisLinkRepository(&repositories.RedisLinkRepositoryConfig{
-		DB: redis,
-		Pipe: p,
+	if err != nil {
+		l.Fatalf("unable to init notifier. Error: %s", err)
+	}
+
+	// Выбор ведущего экземпляра: опрос QIWI, доставку вебхуков и чистку индексов ссылок выполняет только он
+	elector := leader.NewElector(&leader.Config{
+		DB: store.redis,
+		Key: conf.Leader.Key,
+		ID: conf.Leader.ID,
+		TTL: conf.Leader.TTL,
+		Renew: conf.Leader.Renew,
+		Logger: l,
 	})
-	subRepo := repositories.NewRedisSubRepository(&repositories.RedisSubRepositoryConfig{
-		DB: redis,
-		Pipe: p,
+
+	// Инициализация шины событий
+	bus := events.NewBus()
+	eventMetrics := events.NewMetrics()
+
+	// Инициализация очереди событий для вебхуков пользователей
+	webhookService := services.NewWebhookService(&services.WebhookServiceConfig{
+		WebhookRepo: webhookRepo,
+		Conf: conf.Webhook,
+		Leader: elector,
+		Logger: l,
 	})
 
 	// Инициализация планировщика
 	manager := manager.NewManager(&manager.ManagerConfig{
 		LinkRepo: linkRepo,
-		Scheduler: cron.New(),
+		SubRepo: subRepo,
+		Plans: catalog,
+		Events: bus,
+		WarnBefore: conf.Notify.WarnBefore,
+		Grace: conf.Notify.Grace,
+		JobRepo: jobRepo,
+		Leader: elector,
 		Logger: l,
 	})
 
 	// Инициализация слоя services
+	loginGuard := services.NewLoginGuard(&services.LoginGuardConfig{
+		AttemptRepo: attemptRepo,
+		Logger: l,
+	})
 	tokenService := services.NewTokenService(&services.TSConfig{
 		PrivateKey: conf.JWT.PrivateKey,
 		PublicKey: conf.JWT.PublicKey,
 		TokenExpirationSec: conf.JWT.AccessTokenExpiration,
+		RevocationRepo: revocationRepo,
 		Logger: l,
 	})
-	userService := services.NewAuthService(&services.AuthServiceConfig{
-		AuthRepo: userRepo,
+	linkService := services.NewLinkService(&services.LinkServiceConfig{
+		LinkRepo: linkRepo,
+		Plans: catalog,
+		Events: bus,
+		Logger: l,
+	})
+	promoService := services.NewPromoService(&services.PromoServiceConfig{
+		PromoRepo: promoRepo,
+		AuditRepo: auditRepo,
+		Plans: catalog,
+		Logger: l,
+	})
+	qiwiService := services.NewQiwiService(&services.QiwiServiceConfig{
+		Key: conf.App.SecretKey,
 		SubRepo: subRepo,
+		AuthRepo: userRepo,
+		AuditRepo: auditRepo,
+		BillRepo: billRepo,
+		EventRepo: qiwiEventRepo,
+		Promos: promoService,
+		Plans: catalog,
+		Events: bus,
+		Leader: elector,
 		Logger: l,
 	})
-	linkService := services.NewLinkService(&services.LinkServiceConfig{
+	subscriptionService := services.NewSubscriptionService(&services.SubscriptionServiceConfig{
+		SubRepo: subRepo,
+		BillRepo: billRepo,
 		LinkRepo: linkRepo,
 		Manager: manager,
+		Plans: catalog,
+		Grace: conf.Notify.Grace,
+		Logger: l,
 	})
-	qiwiService := services.NewQiwiService(&services.QiwiServiceConfig{
-		Key: conf.App.SecretKey,
+	userService := services.NewAuthService(&services.AuthServiceConfig{
+		AuthRepo: userRepo,
 		SubRepo: subRepo,
+		LinkRepo: linkRepo,
+		TokenRepo: tokenRepo,
+		RecoveryRepo: recoveryRepo,
+		IdentityRepo: identityRepo,
+		WebhookRepo: webhookRepo,
+		AuditRepo: auditRepo,
+		BillRepo: billRepo,
+		PromoRepo: promoRepo,
+		ReportRepo: reportRepo,
+		Tokens: tokenService,
+		Subscriber: qiwiService,
+		Manager: manager,
+		Mailer: mail,
+		Guard: loginGuard,
+		LinkURL: conf.Mail.LinkURL,
+		Logger: l,
+	})
+
+	adminService := services.NewAdminService(&services.AdminServiceConfig{
 		AuthRepo: userRepo,
+		LinkRepo: linkRepo,
+		SubRepo: subRepo,
+		AuditRepo: auditRepo,
+		Subscriber: qiwiService,
 		Manager: manager,
-		Prices: *conf.Price,
 		Logger: l,
 	})
 
+	reportService := services.NewReportService(&services.ReportServiceConfig{
+		ReportRepo: reportRepo,
+		LinkRepo: linkRepo,
+		AuditRepo: auditRepo,
+		Logger: l,
+	})
+
+	mfaService := services.NewMFAService(&services.MFAServiceConfig{
+		AuthRepo: userRepo,
+		RecoveryRepo: recoveryRepo,
+		AuditRepo: auditRepo,
+		Guard: loginGuard,
+		Issuer: "short_url",
+		Logger: l,
+	})
+
+	auditService := services.NewAuditService(&services.AuditServiceConfig{
+		AuditRepo: auditRepo,
+		Logger: l,
+	})
+
+	// Подписчики шины событий: планировщик, журнал аудита, уведомления, вебхуки и метрики
+	manager.Subscribe(bus)
+	auditService.Subscribe(bus)
+	notify.Subscribe(bus, notifier)
+	webhookService.Subscribe(bus)
+	eventMetrics.Subscribe(bus)
+
+	// Инициализация ограничителя частоты запросов (nil - запросы не ограничиваются)
+	var limiter *ratelimit.Limiter
+	if conf.Rate.Enabled {
+		rules, err := ratelimit.NewRules(conf.Rate)
+		if err != nil {
+			l.Fatalf("invalid rate limit rules. Error: %s", err)
+		}
+
+		limiter = ratelimit.NewLimiter(&ratelimit.Config{
+			Rules: rules,
+			DB: store.redis,
+			Logger: l,
+		})
+	}
+
 	// Регистрация middleware
-	middleware := middlewares.NewMiddlewares(l, tokenService)
+	middleware := middlewares.NewMiddlewares(l, tokenService, limiter, subscriptionService)
 
 	// Регистрация счетчика Prometheus
 	prometheus.MustRegister(middleware.Counter)
+	prometheus.MustRegister(loginGuard.Lockouts)
+	prometheus.MustRegister(eventMetrics.Collectors()...)
+	prometheus.MustRegister(elector.Collectors()...)
+	prometheus.MustRegister(store.Collectors()...)
+
+	// Сквозной идентификатор запроса для логов и журнала аудита
+	router.Use(middleware.Tracer)
 
 	// Инициализация слоя handlers
 	handlers.RegisterAuthHandler(&handlers.AuthHandlerConfig{
@@ -127,17 +288,105 @@ func main() {
 		Router: router,
 		QiwiService: qiwiService,
 		Key: conf.App.SecretKey,
-		Prices: *conf.Price,
 		Middleware: middleware,
 		Logger: l,
 	})
 
-	// Запуск фонового процесса планировщика
+	handlers.RegisterSubscriptionHandler(&handlers.SubscriptionHandlerConfig{
+		Router: router,
+		SubscriptionService: subscriptionService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	handlers.RegisterWebhookHandler(&handlers.WebhookHandlerConfig{
+		Router: router,
+		WebhookService: webhookService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	handlers.RegisterAdminHandler(&handlers.AdminHandlerConfig{
+		Router: router,
+		AdminService: adminService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	handlers.RegisterPromoHandler(&handlers.PromoHandlerConfig{
+		Router: router,
+		PromoService: promoService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	handlers.RegisterReportHandler(&handlers.ReportHandlerConfig{
+		Router: router,
+		ReportService: reportService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	handlers.RegisterMFAHandler(&handlers.MFAHandlerConfig{
+		Router: router,
+		MFAService: mfaService,
+		TokenService: tokenService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	// Вход через провайдера OpenID Connect включается, если он указан в конфигурации
+	if conf.OIDC.Issuer != "" {
+		oidcService := services.NewOIDCService(&services.OIDCServiceConfig{
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer: conf.OIDC.Issuer,
+				ClientID: conf.OIDC.ClientID,
+				ClientSecret: conf.OIDC.ClientSecret,
+				RedirectURL: conf.OIDC.RedirectURL,
+				Scopes: strings.Fields(conf.OIDC.Scopes),
+			}),
+			AuthRepo: userRepo,
+			IdentityRepo: identityRepo,
+			StateRepo: oidcStateRepo,
+			SubRepo: subRepo,
+			AuditRepo: auditRepo,
+			Logger: l,
+		})
+
+		handlers.RegisterOIDCHandler(&handlers.OIDCHandlerConfig{
+			Router: router,
+			OIDCService: oidcService,
+			TokenService: tokenService,
+			Middleware: middleware,
+			Logger: l,
+		})
+	}
+
+	handlers.RegisterAuditHandler(&handlers.AuditHandlerConfig{
+		Router: router,
+		AuditService: auditService,
+		Middleware: middleware,
+		Logger: l,
+	})
+
+	// Запуск выборов ведущего экземпляра
+	leaderChan := elector.Run(ctx)
+
+	// Запуск фонового процесса планировщика (задачи из общего хранилища выполняет ведущий экземпляр)
 	schedChan := manager.SchedChecker(ctx)
 
+	// Запуск сброса кэша ссылок по изменениям на других экземплярах
+	cacheChan := store.Listen(ctx)
+
+	// Запуск фонового процесса чистки истекших ссылок
+	sweepChan := manager.SweepCycle(ctx)
+
 	// Запуск фонового процесса системы проверки платежей Qiwi
 	qiwiChan := qiwiService.QiwiCheckCycle(ctx)
 
+	// Запуск фонового процесса доставки событий на вебхуки
+	webhookChan := webhookService.DeliveryCycle(ctx)
+
 	// Инициализация основного сервера
 	server := &http.Server{
 		Addr: fmt.Sprintf("%s:%s", conf.HTTP.Host, conf.HTTP.Port),
@@ -169,7 +418,7 @@ func main() {
 	l.Infof("prometheus listening on port %v", conf.HTTP.MetricPort)
 
 	// Graceful shutdown
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
@@ -179,9 +428,21 @@ func main() {
 	l.Info("shutting down qiwi-pay system...")
 	qiwiChan <- struct{}{}
 
+	l.Info("shutting down webhook delivery...")
+	webhookChan <- struct{}{}
+
 	l.Info("shutting down scheduler system...")
 	schedChan <- struct{}{}
 
+	l.Info("shutting down expired links sweep...")
+	sweepChan <- struct{}{}
+
+	l.Info("shutting down link cache listener...")
+	cacheChan <- struct{}{}
+
+	l.Info("shutting down leader election...")
+	leaderChan <- struct{}{}
+
 	l.Info("shutting down server...")
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("server forced to shutdown: %v\n", err)
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"short_url/internal/models"
+	"short_url/internal/repositories"
+	"short_url/pkg/client"
+	"strings"
+
+	"github.com/caarlos0/env"
+)
+
+// Разовая миграция ключей Redis в схему v1. Подключение берется из тех же переменных REDIS_*, что и у сервиса.
+// После миграции на всех экземплярах можно выставить REDIS_LEGACY_KEYS=false
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would be migrated")
+	flag.Parse()
+
+	conf := &models.ConfigRedis{}
+	if err := env.Parse(conf); err != nil {
+		log.Fatalf("unable to load configuration. Error: %s", err)
+	}
+
+	ctx := context.Background()
+	db, err := client.NewRedisClient(ctx, conf)
+	if err != nil {
+		log.Fatalf("unable connect to Redis. Error: %s", err)
+	}
+	defer db.Close()
+
+	report, err := repositories.MigrateKeys(ctx, db, *dryRun)
+	if err != nil {
+		log.Fatalf("migration failed. Error: %s", err)
+	}
+
+	mode := "migrated"
+	if *dryRun {
+		mode = "would migrate"
+	}
+	fmt.Printf("%s to %s: links %d, subscriptions %d, subscription starts %d, keep lists %d\n",
+		mode, repositories.KeyVersion, report.Links, report.Subs, report.Starts, report.Keep)
+	fmt.Printf("expired links removed %d, already in %s %d, legacy indexes removed %d\n",
+		report.Expired, repositories.KeyVersion, report.Skipped, report.Indexes)
+	if len(report.Orphans) > 0 {
+		fmt.Printf("links without owner (%d): %s\n", len(report.Orphans), strings.Join(report.Orphans, ", "))
+	}
+}
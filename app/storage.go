@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/config"
+	"short_url/internal/models"
+	"short_url/internal/repositories"
+	"short_url/pkg/client"
+	logg "short_url/pkg/logger"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Наборы методов репозиториев, общие для реализаций на Postgres и Redis и в памяти процесса
+
+type userRepository interface {
+	FindByUsername(ctx context.Context, username string) (models.UserDB, error)
+	FindByEmail(ctx context.Context, email string) (models.UserDB, error)
+	SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error)
+	UpdateUser(ctx context.Context, user models.UserDB) error
+	VerifyEmail(ctx context.Context, username, email string) error
+	SetTOTP(ctx context.Context, username, secret string, enabled bool) error
+	UseTOTPStep(ctx context.Context, username string, step int64) error
+	UpdatePassword(ctx context.Context, username, password string) error
+	DeleteUser(ctx context.Context, username string) error
+	CreateUser(ctx context.Context, user models.UserDB) error
+}
+
+type linkRepository interface {
+	CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error)
+	DeleteLink(ctx context.Context, link, username string) error
+	FindLink(ctx context.Context, link string) (models.LinkDataDB, error)
+	DisableLink(ctx context.Context, link, reason string) error
+	EnableLink(ctx context.Context, link string) error
+	CountLinks(ctx context.Context, username string) (models.LinksAmount, error)
+	GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error)
+	TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error)
+	KeepLinks(ctx context.Context, username string) ([]string, error)
+	SetKeepLinks(ctx context.Context, username string, links []string) error
+}
+
+type subRepository interface {
+	FindSubscribe(ctx context.Context, username string) (time.Duration, bool)
+	FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool)
+	SubState(ctx context.Context, username string) (string, bool, error)
+	AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error
+	FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool)
+	RemoveSubscribe(ctx context.Context, username string) error
+}
+
+type reportRepository interface {
+	CreateReport(ctx context.Context, report models.ReportDB) (int64, error)
+	FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error)
+	FindReport(ctx context.Context, id int64) (models.ReportDB, error)
+	ResolveReports(ctx context.Context, link, status, admin string) error
+	RedactResolver(ctx context.Context, username, pseudonym string) error
+}
+
+type tokenRepository interface {
+	CreateToken(ctx context.Context, token models.UserTokenDB) error
+	UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error)
+	RevokeTokens(ctx context.Context, username, kind string) error
+}
+
+type recoveryRepository interface {
+	ReplaceCodes(ctx context.Context, username string, hashes []string) error
+	UseCode(ctx context.Context, username, hash string) error
+	DeleteCodes(ctx context.Context, username string) error
+}
+
+type identityRepository interface {
+	FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error)
+	CreateIdentity(ctx context.Context, identity models.IdentityDB) error
+	DeleteIdentities(ctx context.Context, username string) error
+}
+
+type auditRepository interface {
+	AppendEntry(ctx context.Context, entry models.AuditEntry) error
+	RedactUser(ctx context.Context, username, pseudonym string) error
+	FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type billRepository interface {
+	CreateBill(ctx context.Context, bill models.BillDB) error
+	UpdateBillStatus(ctx context.Context, id, status string) error
+	FindBill(ctx context.Context, id string) (models.BillDB, error)
+	FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error)
+	FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error)
+	RedactUser(ctx context.Context, username, pseudonym string) error
+}
+
+type qiwiEventRepository interface {
+	MarkProcessed(ctx context.Context, billID, status string) (bool, error)
+	UnmarkProcessed(ctx context.Context, billID string) error
+}
+
+type promoRepository interface {
+	CreatePromo(ctx context.Context, p models.PromoDB) error
+	FindPromo(ctx context.Context, code string) (models.PromoDB, error)
+	FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error)
+	DeletePromo(ctx context.Context, code string) error
+	Redeemed(ctx context.Context, code, username string) (bool, error)
+	UsePromo(ctx context.Context, code, username string) (models.PromoDB, error)
+	ReleasePromo(ctx context.Context, code, username string) error
+	RedactUser(ctx context.Context, username, pseudonym string) error
+}
+
+type webhookRepository interface {
+	CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error)
+	FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error)
+	FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error)
+	DeleteWebhook(ctx context.Context, id int64, username string) error
+	DeleteWebhooks(ctx context.Context, username string) error
+	CreateDelivery(ctx context.Context, d models.DeliveryDB) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error)
+	UpdateDelivery(ctx context.Context, d models.DeliveryDB) error
+	FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error)
+	Redeliver(ctx context.Context, id int64, username string) error
+}
+
+type attemptRepository interface {
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	AddFail(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, d time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+type oidcStateRepository interface {
+	SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error
+	TakeState(ctx context.Context, state string) (models.OIDCState, error)
+}
+
+type jobRepository interface {
+	AddJobs(ctx context.Context, jobs []models.SchedJob) error
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error)
+	FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error)
+	RemoveJob(ctx context.Context, job models.SchedJob) error
+	RemoveUserJobs(ctx context.Context, username string) error
+}
+
+type revocationRepository interface {
+	Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error
+	RevokedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+// storage Репозитории сервиса
+type storage struct {
+	users		userRepository
+	links		linkRepository
+	subs		subRepository
+	reports		reportRepository
+	tokens		tokenRepository
+	recovery	recoveryRepository
+	identities	identityRepository
+	audit		auditRepository
+	bills		billRepository
+	qiwiEvents	qiwiEventRepository
+	promos		promoRepository
+	webhooks	webhookRepository
+	attempts	attemptRepository
+	revocations	revocationRepository
+	oidcStates	oidcStateRepository
+	jobs		jobRepository
+
+	redis		*redis.Client						// nil в режиме memory
+	linkCache	*repositories.CachedLinkRepository	// nil в режиме memory
+}
+
+// newStorage Подключается к хранилищу, выбранному в конфигурации (STORAGE)
+func newStorage(ctx context.Context, conf *models.Config, l *logg.Log) (*storage, error) {
+	if conf.App.Storage == config.StorageMemory {
+		l.Warn("storage mode is memory: data is kept in process memory and lost on restart")
+
+		return newMemoryStorage(), nil
+	}
+
+	// Инициализация клиента PostgreSQL
+	db, err := client.NewPgxClient(ctx, conf.DB)
+	if err != nil {
+		return nil, fmt.Errorf("unable connect to database. Error: %s", err)
+	}
+
+	// Инициализация клиент Redis
+	rdb, err := client.NewRedisClient(ctx, conf.RDB)
+	if err != nil {
+		return nil, fmt.Errorf("unable connect to Redis. Error: %s", err)
+	}
+
+	linkCache := repositories.NewCachedLinkRepository(&repositories.CachedLinkRepositoryConfig{
+		Repo: repositories.NewRedisLinkRepository(&repositories.RedisLinkRepositoryConfig{
+			DB: rdb,
+			Legacy: conf.RDB.LegacyKeys,
+		}),
+		Size: conf.Cache.Size,
+		TTL: conf.Cache.TTL,
+		NegativeTTL: conf.Cache.NegativeTTL,
+		Channel: conf.Cache.Channel,
+		Logger: l,
+	})
+
+	return &storage{
+		users: repositories.NewPostgresqlUserRepository(&repositories.PostgresqlUserRepositoryConfig{
+			Table: "cpuser",
+			DB: db,
+		}),
+		links: linkCache,
+		subs: repositories.NewCachedSubRepository(&repositories.CachedSubRepositoryConfig{
+			Repo: repositories.NewRedisSubRepository(&repositories.RedisSubRepositoryConfig{
+				DB: rdb,
+				Legacy: conf.RDB.LegacyKeys,
+			}),
+			TTL: conf.Plans.SubCacheTTL,
+		}),
+		reports: repositories.NewPostgresqlReportRepository(&repositories.PostgresqlReportRepositoryConfig{
+			Table: "link_report",
+			DB: db,
+		}),
+		tokens: repositories.NewPostgresqlTokenRepository(&repositories.PostgresqlTokenRepositoryConfig{
+			Table: "user_token",
+			DB: db,
+		}),
+		recovery: repositories.NewPostgresqlRecoveryRepository(&repositories.PostgresqlRecoveryRepositoryConfig{
+			Table: "recovery_code",
+			DB: db,
+		}),
+		identities: repositories.NewPostgresqlIdentityRepository(&repositories.PostgresqlIdentityRepositoryConfig{
+			Table: "user_identity",
+			DB: db,
+		}),
+		audit: repositories.NewPostgresqlAuditRepository(&repositories.PostgresqlAuditRepositoryConfig{
+			Table: "audit_log",
+			DB: db,
+		}),
+		bills: repositories.NewPostgresqlBillRepository(&repositories.PostgresqlBillRepositoryConfig{
+			Table: "bill",
+			DB: db,
+		}),
+		qiwiEvents: repositories.NewPostgresqlQiwiEventRepository(&repositories.PostgresqlQiwiEventRepositoryConfig{
+			Table: "qiwi_event",
+			DB: db,
+		}),
+		promos: repositories.NewPostgresqlPromoRepository(&repositories.PostgresqlPromoRepositoryConfig{
+			Table: "promo_code",
+			UsesTable: "promo_redemption",
+			DB: db,
+		}),
+		webhooks: repositories.NewPostgresqlWebhookRepository(&repositories.PostgresqlWebhookRepositoryConfig{
+			Table: "webhook",
+			DeliveryTable: "webhook_delivery",
+			DB: db,
+		}),
+		attempts: repositories.NewRedisAttemptRepository(&repositories.RedisAttemptRepositoryConfig{
+			DB: rdb,
+		}),
+		revocations: repositories.NewRedisRevocationRepository(&repositories.RedisRevocationRepositoryConfig{
+			DB: rdb,
+		}),
+		oidcStates: repositories.NewRedisOIDCStateRepository(&repositories.RedisOIDCStateRepositoryConfig{
+			DB: rdb,
+		}),
+		jobs: repositories.NewRedisJobRepository(&repositories.RedisJobRepositoryConfig{
+			DB: rdb,
+		}),
+		redis: rdb,
+		linkCache: linkCache,
+	}, nil
+}
+
+// newMemoryStorage Создает хранилище в памяти процесса (кэш ссылок ему не нужен)
+func newMemoryStorage() *storage {
+	return &storage{
+		users: repositories.NewMemoryUserRepository(),
+		links: repositories.NewMemoryLinkRepository(&repositories.MemoryLinkRepositoryConfig{}),
+		subs: repositories.NewMemorySubRepository(&repositories.MemorySubRepositoryConfig{}),
+		reports: repositories.NewMemoryReportRepository(&repositories.MemoryReportRepositoryConfig{}),
+		tokens: repositories.NewMemoryTokenRepository(&repositories.MemoryTokenRepositoryConfig{}),
+		recovery: repositories.NewMemoryRecoveryRepository(),
+		identities: repositories.NewMemoryIdentityRepository(),
+		audit: repositories.NewMemoryAuditRepository(&repositories.MemoryAuditRepositoryConfig{}),
+		bills: repositories.NewMemoryBillRepository(&repositories.MemoryBillRepositoryConfig{}),
+		qiwiEvents: repositories.NewMemoryQiwiEventRepository(),
+		promos: repositories.NewMemoryPromoRepository(&repositories.MemoryPromoRepositoryConfig{}),
+		webhooks: repositories.NewMemoryWebhookRepository(&repositories.MemoryWebhookRepositoryConfig{}),
+		attempts: repositories.NewMemoryAttemptRepository(&repositories.MemoryAttemptRepositoryConfig{}),
+		revocations: repositories.NewMemoryRevocationRepository(&repositories.MemoryRevocationRepositoryConfig{}),
+		oidcStates: repositories.NewMemoryOIDCStateRepository(&repositories.MemoryOIDCStateRepositoryConfig{}),
+		jobs: repositories.NewMemoryJobRepository(&repositories.MemoryJobRepositoryConfig{}),
+	}
+}
+
+// Collectors Возвращает метрики хранилища для регистрации
+func (s *storage) Collectors() []prometheus.Collector {
+	if s.linkCache == nil {
+		return nil
+	}
+
+	return s.linkCache.Collectors()
+}
+
+// Listen Запускает сброс кэша ссылок по изменениям на других экземплярах.
+// Без кэша возвращает канал, который только принимает сигнал остановки
+func (s *storage) Listen(ctx context.Context) chan struct{} {
+	if s.linkCache == nil {
+		return make(chan struct{}, 1)
+	}
+
+	return s.linkCache.Listen(ctx)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Нагрузочный генератор: воспроизводит записанную смесь запросов (переадресация, создание, список ссылок)
+// против запущенного сервиса и печатает перцентили задержек по видам запросов.
+//
+//	go run ./cmd/loadgen -addr http://localhost:8080 -mix configs/loadgen.jsonl -token $TOKEN -c 20 -d 30s
+
+// Виды запросов
+const (
+	opRedirect	= "redirect"
+	opCreate	= "create"
+	opList		= "list"
+)
+
+// request Запрос из записанной смеси (одна строка JSON)
+type request struct {
+	Op		string	`json:"op"`
+	Link	string	`json:"link"`	// redirect: короткая ссылка
+	Full	string	`json:"full"`	// create: исходный адрес
+	Time	int64	`json:"time"`	// create: срок ссылки (нс)
+	Custom	string	`json:"custom"`	// create: кастомная ссылка
+}
+
+// stats Результаты запросов одного вида
+type stats struct {
+	latencies	[]time.Duration
+	codes		map[int]int
+	errors		int
+}
+
+// recorder Собирает результаты запросов всех воркеров
+type recorder struct {
+	mux		sync.Mutex
+	ops		map[string]*stats
+}
+
+// add Записывает результат запроса (code 0 - ошибка соединения)
+func (r *recorder) add(op string, d time.Duration, code int) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	s, ok := r.ops[op]
+	if !ok {
+		s = &stats{codes: make(map[int]int)}
+		r.ops[op] = s
+	}
+	if code == 0 || code >= http.StatusInternalServerError {
+		s.errors++
+	}
+	if code != 0 {
+		s.codes[code]++
+	}
+	s.latencies = append(s.latencies, d)
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "base URL of the running instance")
+	mix := flag.String("mix", "configs/loadgen.jsonl", "recorded traffic mix, one JSON request per line")
+	token := flag.String("token", "", "access token for create and list requests")
+	workers := flag.Int("c", 10, "concurrent workers")
+	duration := flag.Duration("d", 30*time.Second, "test duration")
+	total := flag.Int64("n", 0, "stop after this many requests (0 - run for the whole duration)")
+	flag.Parse()
+
+	requests, err := readMix(*mix)
+	if err != nil {
+		log.Fatalf("unable to read traffic mix. Error: %s", err)
+	}
+
+	// Переадресацию проверяем сами, не переходя по адресу ссылки
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	base := strings.TrimSuffix(*addr, "/")
+
+	rec := &recorder{ops: make(map[string]*stats)}
+	deadline := time.Now().Add(*duration)
+	var next int64
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				i := atomic.AddInt64(&next, 1) - 1
+				if *total > 0 && i >= *total {
+					return
+				}
+
+				req := requests[int(i%int64(len(requests)))]
+				began := time.Now()
+				code := send(client, base, *token, req)
+				rec.add(req.Op, time.Since(began), code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	report(rec, time.Since(start))
+}
+
+// readMix Читает записанную смесь запросов
+func readMix(path string) ([]request, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	requests := make([]request, 0)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var req request
+		if err = json.Unmarshal([]byte(text), &req); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		switch req.Op {
+		case opRedirect, opCreate, opList:
+		default:
+			return nil, fmt.Errorf("line %d: unknown op %q", line, req.Op)
+		}
+		requests = append(requests, req)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests in %s", path)
+	}
+
+	return requests, nil
+}
+
+// send Выполняет запрос и возвращает код ответа (0 - ошибка соединения)
+func send(client *http.Client, base, token string, req request) int {
+	var (
+		httpReq	*http.Request
+		err		error
+	)
+	switch req.Op {
+	case opRedirect:
+		httpReq, err = http.NewRequest(http.MethodGet, base+"/v1/"+req.Link, nil)
+	case opCreate:
+		body, _ := json.Marshal(map[string]any{"full": req.Full, "time": req.Time, "custom": req.Custom})
+		httpReq, err = http.NewRequest(http.MethodPost, base+"/v1/newlink", bytes.NewReader(body))
+		if err == nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+	case opList:
+		httpReq, err = http.NewRequest(http.MethodGet, base+"/v1/links", nil)
+	}
+	if err != nil {
+		return 0
+	}
+	if token != "" && req.Op != opRedirect {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+// percentile Возвращает перцентиль отсортированных задержек
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	return sorted[i]
+}
+
+// report Печатает итоги по видам запросов
+func report(rec *recorder, elapsed time.Duration) {
+	ops := make([]string, 0, len(rec.ops))
+	for op := range rec.ops {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	fmt.Printf("%-9s %8s %8s %9s %10s %10s %10s %10s  %s\n", "op", "count", "errors", "rps", "p50", "p90", "p99", "max", "codes")
+	for _, op := range ops {
+		s := rec.ops[op]
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+
+		codes := make([]string, 0, len(s.codes))
+		for code, n := range s.codes {
+			codes = append(codes, fmt.Sprintf("%d:%d", code, n))
+		}
+		sort.Strings(codes)
+
+		fmt.Printf("%-9s %8d %8d %9.1f %10s %10s %10s %10s  %s\n",
+			op, len(s.latencies), s.errors, float64(len(s.latencies))/elapsed.Seconds(),
+			percentile(s.latencies, 0.50), percentile(s.latencies, 0.90), percentile(s.latencies, 0.99),
+			s.latencies[len(s.latencies)-1], strings.Join(codes, " "))
+	}
+}
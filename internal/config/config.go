@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"short_url/internal/models"
@@ -10,27 +14,63 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
+// Режимы хранилища (STORAGE)
+const (
+	StorageExternal	= "external"	// Postgres и Redis
+	StorageMemory	= "memory"		// Память процесса: без внешних зависимостей, данные теряются при перезапуске
+)
+
 // LoadConfig загружает конфигурацию из env
 func LoadConfig() (*models.Config, error) {
 	// Инициализация конфигурации,
 	// если был добавлен новый конфигу в models.Config, то
 	// необходимо проинициализировать его тут, иначе будет nil pointer
 	config := &models.Config{
-		App:	&models.ConfigApp{},
-		Price:	&models.ConfigPrice{},
-		Log:	&models.ConfigLog{},
-		HTTP:	&models.ConfigHTTP{},
+		App:		&models.ConfigApp{},
+		Price:		&models.ConfigPrice{},
+		Plans:		&models.ConfigPlans{},
+		Log:		&models.ConfigLog{},
+		HTTP:		&models.ConfigHTTP{},
 		DB:		&models.ConfigDB{},
-		RDB:	&models.ConfigRedis{},
-		JWT:	&models.ConfigJWT{},
+		RDB:		&models.ConfigRedis{},
+		JWT:		&models.ConfigJWT{},
+		Mail:		&models.ConfigMail{},
+		OIDC:		&models.ConfigOIDC{},
+		Rate:		&models.ConfigRateLimit{},
+		Notify:		&models.ConfigNotify{},
+		Webhook:	&models.ConfigWebhook{},
+		Leader:		&models.ConfigLeader{},
+		Cache:		&models.ConfigLinkCache{},
 	}
 
 	if err := env.Parse(config); err != nil {
 		return nil, fmt.Errorf("unable to load configuration. Error: %s", err)
 	}
 
+	switch config.App.Storage {
+	case StorageExternal, StorageMemory:
+	default:
+		return nil, fmt.Errorf("unknown storage %q", config.App.Storage)
+	}
+
 	privateFile, err := os.ReadFile("rsa_private.pem")
 
+	// Без внешнего хранилища сервис можно запустить и без ключей: токены подписываются временным ключом
+	// и перестают действовать после перезапуска, как и остальные данные
+	if errors.Is(err, fs.ErrNotExist) && config.App.Storage == StorageMemory {
+		log.Print("rsa_private.pem not found, JWT is signed with an ephemeral key")
+
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate ephemeral key. Error: %s", err)
+		}
+
+		config.JWT.PrivateKey = privateKey
+		config.JWT.PublicKey = &privateKey.PublicKey
+
+		return config, nil
+	}
+
 	if err != nil {
 		log.Fatalf("could not read private key pem file: %e", err)
 	}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Event Событие предметной области
+type Event interface {
+	Name() string
+}
+
+// Handler Обработчик события
+type Handler func(ctx context.Context, ev Event) error
+
+// Bus Шина событий внутри процесса. Обработчики вызываются синхронно в порядке подписки,
+// поэтому ошибка побочного действия (например, планирования задачи) доходит до издателя
+type Bus struct {
+	mu			sync.RWMutex
+	handlers	map[string][]Handler
+}
+
+// NewBus Конструктор для Bus
+func NewBus() *Bus {
+	return &Bus{
+		handlers:	make(map[string][]Handler),
+	}
+}
+
+// Subscribe Подписывает обработчик на события с указанным именем
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	b.handlers[name] = append(b.handlers[name], h)
+	b.mu.Unlock()
+}
+
+// On Подписывает типизированный обработчик на события типа T
+func On[T Event](b *Bus, h func(ctx context.Context, ev T) error) {
+	var zero T
+
+	b.Subscribe(zero.Name(), func(ctx context.Context, ev Event) error {
+		e, ok := ev.(T)
+		if !ok {
+			return nil
+		}
+
+		return h(ctx, e)
+	})
+}
+
+// Publish Передает событие всем подписчикам. Ошибка одного обработчика не мешает остальным,
+// издателю возвращается первая из ошибок
+func (b *Bus) Publish(ctx context.Context, ev Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[ev.Name()]
+	b.mu.RUnlock()
+
+	var first error
+	for _, h := range handlers {
+		if err := h(ctx, ev); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBusPublish(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+
+	var (
+		created	[]string
+		deleted	int
+	)
+	On(b, func(ctx context.Context, ev LinkCreated) error {
+		created = append(created, ev.Link)
+		return errors.New("first")
+	})
+	On(b, func(ctx context.Context, ev LinkCreated) error {
+		created = append(created, ev.Link)
+		return errors.New("second")
+	})
+	On(b, func(ctx context.Context, ev LinkDeleted) error {
+		deleted++
+		return nil
+	})
+
+	// Ошибка обработчика не останавливает остальных, издателю возвращается первая
+	err := b.Publish(ctx, LinkCreated{Link: "abc"})
+	if err == nil || err.Error() != "first" {
+		t.Fatalf("expected first error, got %v", err)
+	}
+	if len(created) != 2 || deleted != 0 {
+		t.Fatalf("unexpected handlers calls: created %v, deleted %d", created, deleted)
+	}
+
+	if err = b.Publish(ctx, LinkDeleted{Link: "abc"}); err != nil || deleted != 1 {
+		t.Fatalf("expected link.deleted to be handled, got %v, %d", err, deleted)
+	}
+
+	// Событие без подписчиков не считается ошибкой
+	if err = b.Publish(ctx, BillPaid{BillID: "b1"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
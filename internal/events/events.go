@@ -0,0 +1,72 @@
+package events
+
+import "time"
+
+// Причины удаления ссылки
+const (
+	ReasonUser		= "user"		// Удалена владельцем
+	ReasonExpired	= "expired"		// Истек срок действия
+	ReasonCleanup	= "cleanup"		// Удалена сверх лимитов после окончания подписки
+)
+
+// LinkCreated Пользователь создал ссылку
+type LinkCreated struct {
+	Link	string
+	Owner	string
+	FullURL	string
+	Exp		time.Duration	// 0 - бессрочная
+	Custom	bool
+}
+
+func (LinkCreated) Name() string { return "link.created" }
+
+// LinkDeleted Ссылка удалена
+type LinkDeleted struct {
+	Link	string
+	Owner	string
+	Actor	string
+	Reason	string
+}
+
+func (LinkDeleted) Name() string { return "link.deleted" }
+
+// SubscriptionActivated Подписка оформлена или продлена
+type SubscriptionActivated struct {
+	Username	string
+	Plan		string
+	Added		time.Duration	// На сколько продлена подписка
+	Exp			time.Duration	// Сколько осталось до окончания
+	Renewal		bool			// Продление действующей подписки
+}
+
+func (SubscriptionActivated) Name() string { return "subscription.activated" }
+
+// SubscriptionExpiring Подписка скоро закончится
+type SubscriptionExpiring struct {
+	Username	string
+	ExpiresAt	time.Time
+	CleanupAt	time.Time
+	DeleteLinks	[]string	// Ссылки, которые будут удалены после льготного периода
+}
+
+func (SubscriptionExpiring) Name() string { return "subscription.expiring" }
+
+// SubscriptionEnded Подписка закончилась, начался льготный период
+type SubscriptionEnded struct {
+	Username	string
+	ExpiresAt	time.Time
+	CleanupAt	time.Time
+	DeleteLinks	[]string
+}
+
+func (SubscriptionEnded) Name() string { return "subscription.ended" }
+
+// BillPaid Счет оплачен и подписка по нему оформлена
+type BillPaid struct {
+	BillID		string
+	Username	string
+	Plan		string
+	Promo		string
+}
+
+func (BillPaid) Name() string { return "bill.paid" }
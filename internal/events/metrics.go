@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics Счетчики событий для Prometheus
+type Metrics struct {
+	Links			*prometheus.CounterVec
+	Subscriptions	*prometheus.CounterVec
+	Bills			*prometheus.CounterVec
+}
+
+// NewMetrics Конструктор для Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Links: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "link_events_total",
+				Help: "Кол-во созданных и удаленных ссылок",
+			},
+			[]string{"event", "reason"},
+		),
+		Subscriptions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "subscription_events_total",
+				Help: "Кол-во оформленных, продленных и закончившихся подписок",
+			},
+			[]string{"event", "plan"},
+		),
+		Bills: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bill_paid_total",
+				Help: "Кол-во оплаченных счетов",
+			},
+			[]string{"plan"},
+		),
+	}
+}
+
+// Collectors Возвращает счетчики для регистрации
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.Links, m.Subscriptions, m.Bills}
+}
+
+// Subscribe Подписывает счетчики на события шины
+func (m *Metrics) Subscribe(b *Bus) {
+	On(b, func(ctx context.Context, ev LinkCreated) error {
+		m.Links.WithLabelValues("created", "").Inc()
+		return nil
+	})
+	On(b, func(ctx context.Context, ev LinkDeleted) error {
+		m.Links.WithLabelValues("deleted", ev.Reason).Inc()
+		return nil
+	})
+	On(b, func(ctx context.Context, ev SubscriptionActivated) error {
+		event := "activated"
+		if ev.Renewal {
+			event = "renewed"
+		}
+		m.Subscriptions.WithLabelValues(event, ev.Plan).Inc()
+		return nil
+	})
+	On(b, func(ctx context.Context, ev SubscriptionEnded) error {
+		m.Subscriptions.WithLabelValues("ended", "").Inc()
+		return nil
+	})
+	On(b, func(ctx context.Context, ev BillPaid) error {
+		m.Bills.WithLabelValues(ev.Plan).Inc()
+		return nil
+	})
+}
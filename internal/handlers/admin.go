@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminService Интерфейс к сервису операций администратора
+type adminService interface {
+	SearchUsers(ctx context.Context, search string) ([]models.UserDB, error)
+	GetUserLinks(ctx context.Context, username string) ([]models.LinkDataDTO, error)
+	DisableLink(ctx context.Context, admin models.JWTUserInfo, link, reason string) error
+	EnableLink(ctx context.Context, admin models.JWTUserInfo, link string) error
+	GrantSubscribe(ctx context.Context, admin models.JWTUserInfo, username string, days int, note string) error
+	RevokeSubscribe(ctx context.Context, admin models.JWTUserInfo, username, note string) error
+	PendingBills(ctx context.Context) []models.BillInfo
+	Jobs(ctx context.Context) []models.SchedJob
+}
+
+// AdminHandlerConfig Конфигурация для AdminHandler
+type AdminHandlerConfig struct {
+	Router			*gin.Engine
+	AdminService	adminService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// AdminHandler Для регистрации "ручек" администратора
+type AdminHandler struct {
+	adminService	adminService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// getUsernameFromParam Получает имя пользователя из path
+func getUsernameFromParam(ctx *gin.Context) string {
+	return ctx.Param("username")
+}
+
+// RegisterAdminHandler Фабрика для AdminHandler
+func RegisterAdminHandler(c *AdminHandlerConfig) {
+	adminHandler := AdminHandler{
+		adminService:	c.AdminService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1/admin", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.AdminOnly)
+	g.GET("/users", adminHandler.SearchUsers)
+	g.GET("/users/:username/links", adminHandler.GetUserLinks)
+	g.POST("/links/:link/disable", adminHandler.DisableLink)
+	g.POST("/links/:link/enable", adminHandler.EnableLink)
+	g.POST("/subscriptions/:username", adminHandler.GrantSubscribe)
+	g.POST("/subscriptions/:username/revoke", adminHandler.RevokeSubscribe)
+	g.GET("/bills", adminHandler.PendingBills)
+	g.GET("/jobs", adminHandler.Jobs)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// disableLinkRequest Структура запроса
+type disableLinkRequest struct {
+	Reason	string	`json:"reason" binding:"required"`
+}
+
+// DisableLink Блокирует ссылку за нарушения
+func (h *AdminHandler) DisableLink(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminDisableLinkHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminDisableLinkHandler() started")
+	defer l.Debug("AdminDisableLinkHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req disableLinkRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricAdminDisableLink); !ok {
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminDisableLink)
+
+		return
+	}
+
+	// Блокируем ссылку
+	err = h.adminService.DisableLink(ctx, admin, getLinkFromParam(ctx), req.Reason)
+	if err != nil {
+		LinkErrResp(ctx, l, err, "POST", MetricAdminDisableLink)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricAdminDisableLink)
+
+	return
+}
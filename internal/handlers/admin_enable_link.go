@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnableLink Снимает блокировку со ссылки
+func (h *AdminHandler) EnableLink(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminEnableLinkHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminEnableLinkHandler() started")
+	defer l.Debug("AdminEnableLinkHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminEnableLink)
+
+		return
+	}
+
+	// Снимаем блокировку
+	err = h.adminService.EnableLink(ctx, admin, getLinkFromParam(ctx))
+	if err != nil {
+		if err.Error() == "link not disabled" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "link not disabled",
+			})
+
+			Bridge(ctx, http.StatusConflict, "POST", MetricAdminEnableLink)
+
+			return
+		}
+
+		LinkErrResp(ctx, l, err, "POST", MetricAdminEnableLink)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricAdminEnableLink)
+
+	return
+}
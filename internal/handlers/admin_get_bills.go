@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BillData Структура данных о счете
+type BillData struct {
+	BillID		string	`json:"bill_id"`
+	Username	string	`json:"username"`
+	Exp			string	`json:"time"`
+}
+
+// pendingBillsResponse Ответ на запрос
+type pendingBillsResponse struct {
+	Data []BillData	`json:"data"`
+}
+
+// PendingBills Отдает счета, ожидающие оплаты
+func (h *AdminHandler) PendingBills(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminBillsHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminBillsHandler() started")
+	defer l.Debug("AdminBillsHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	bills := h.adminService.PendingBills(ctx)
+
+	// Маппим данные в ответ
+	resp := pendingBillsResponse{
+		Data: make([]BillData, len(bills)),
+	}
+	for k, b := range bills {
+		resp.Data[k] = BillData{
+			BillID:		b.BillID,
+			Username:	b.Username,
+			Exp:		fmt.Sprint(b.Exp),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminBills)
+
+	return
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobData Структура данных о задаче очистки
+type JobData struct {
+	ID			int		`json:"id"`
+	Kind		string	`json:"kind"`
+	Username	string	`json:"username"`
+	Link		string	`json:"link"`
+	Next		string	`json:"next"`
+}
+
+// jobsResponse Ответ на запрос
+type jobsResponse struct {
+	Data []JobData	`json:"data"`
+}
+
+// Jobs Отдает запланированные задачи очистки
+func (h *AdminHandler) Jobs(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminJobsHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminJobsHandler() started")
+	defer l.Debug("AdminJobsHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	jobs := h.adminService.Jobs(ctx)
+
+	// Маппим данные в ответ
+	resp := jobsResponse{
+		Data: make([]JobData, len(jobs)),
+	}
+	for k, j := range jobs {
+		resp.Data[k] = JobData{
+			ID:			int(j.ID),
+			Kind:		j.Kind,
+			Username:	j.Username,
+			Link:		j.Link,
+			Next:		j.Next.Format(time.RFC3339),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminJobs)
+
+	return
+}
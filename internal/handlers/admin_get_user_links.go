@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminLinkData Структура данных о ссылке для администратора
+type AdminLinkData struct {
+	Short			string	`json:"short"`
+	Full			string	`json:"full"`
+	ExpTime			string	`json:"time"`
+	Disabled		bool	`json:"disabled"`
+	DisabledReason	string	`json:"disabled_reason,omitempty"`
+}
+
+// adminUserLinksResponse Ответ на запрос
+type adminUserLinksResponse struct {
+	Data []AdminLinkData	`json:"data"`
+}
+
+// GetUserLinks Отдает все ссылки любого пользователя
+func (h *AdminHandler) GetUserLinks(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminUserLinksHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminUserLinksHandler() started")
+	defer l.Debug("AdminUserLinksHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем все ссылки пользователя
+	data, err := h.adminService.GetUserLinks(ctx, getUsernameFromParam(ctx))
+	if err != nil {
+		if err.Error() == "user not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "user not found",
+			})
+
+			Bridge(ctx, http.StatusNotFound, "GET", MetricAdminUserLinks)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAdminUserLinks)
+
+		return
+	}
+
+	// Маппим данные в ответ
+	resp := adminUserLinksResponse{
+		Data: make([]AdminLinkData, len(data)),
+	}
+	for k, d := range data {
+		resp.Data[k] = AdminLinkData{
+			Short:			d.Link,
+			Full:			d.FullURL,
+			ExpTime:		fmt.Sprint(d.ExpTime),
+			Disabled:		d.Disabled,
+			DisabledReason:	d.DisabledReason,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminUserLinks)
+
+	return
+}
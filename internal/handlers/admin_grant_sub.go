@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// grantSubRequest Структура запроса
+type grantSubRequest struct {
+	Days	int		`json:"days" binding:"required,gte=1,lte=3650"`
+	Note	string	`json:"note" binding:"required"`
+}
+
+// GrantSubscribe Выдает пользователю подписку вручную, минуя QIWI
+func (h *AdminHandler) GrantSubscribe(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminGrantSubHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminGrantSubHandler() started")
+	defer l.Debug("AdminGrantSubHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req grantSubRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricAdminGrantSub); !ok {
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminGrantSub)
+
+		return
+	}
+
+	// Выдаем подписку
+	err = h.adminService.GrantSubscribe(ctx, admin, getUsernameFromParam(ctx), req.Days, req.Note)
+	if err != nil {
+		if err.Error() == "user not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "user not found",
+			})
+
+			Bridge(ctx, http.StatusNotFound, "POST", MetricAdminGrantSub)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminGrantSub)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricAdminGrantSub)
+
+	return
+}
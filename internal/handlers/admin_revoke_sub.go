@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revokeSubRequest Структура запроса
+type revokeSubRequest struct {
+	Note	string	`json:"note" binding:"required"`
+}
+
+// RevokeSubscribe Отзывает подписку пользователя
+func (h *AdminHandler) RevokeSubscribe(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminRevokeSubHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminRevokeSubHandler() started")
+	defer l.Debug("AdminRevokeSubHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req revokeSubRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricAdminRevokeSub); !ok {
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminRevokeSub)
+
+		return
+	}
+
+	// Отзываем подписку
+	err = h.adminService.RevokeSubscribe(ctx, admin, getUsernameFromParam(ctx), req.Note)
+	if err != nil {
+		if err.Error() == "subscribe not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "subscribe not found",
+			})
+
+			Bridge(ctx, http.StatusNotFound, "POST", MetricAdminRevokeSub)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminRevokeSub)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricAdminRevokeSub)
+
+	return
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserData Структура данных о пользователе
+type UserData struct {
+	Username	string	`json:"username"`
+	FirstName	string	`json:"first_name"`
+	LastName	string	`json:"last_name"`
+	Role		string	`json:"role"`
+}
+
+// searchUsersResponse Ответ на запрос
+type searchUsersResponse struct {
+	Data []UserData	`json:"data"`
+}
+
+// SearchUsers Ищет пользователей по логину, имени или фамилии (?search=)
+func (h *AdminHandler) SearchUsers(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminSearchUsersHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminSearchUsersHandler() started")
+	defer l.Debug("AdminSearchUsersHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Ищем пользователей
+	users, err := h.adminService.SearchUsers(ctx, ctx.Query("search"))
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAdminSearchUsers)
+
+		return
+	}
+
+	// Маппим данные в ответ
+	resp := searchUsersResponse{
+		Data: make([]UserData, len(users)),
+	}
+	for k, u := range users {
+		resp.Data[k] = UserData{
+			Username:	u.Username,
+			FirstName:	u.FirstName,
+			LastName:	u.LastName,
+			Role:		u.Role.ChoiceString(),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminSearchUsers)
+
+	return
+}
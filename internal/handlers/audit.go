@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditService Интерфейс к сервису журнала аудита
+type auditService interface {
+	History(ctx context.Context, username string) ([]models.AuditEntry, error)
+	Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// AuditHandlerConfig Конфигурация для AuditHandler
+type AuditHandlerConfig struct {
+	Router			*gin.Engine
+	AuditService	auditService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// AuditHandler Для регистрации "ручек" журнала аудита
+type AuditHandler struct {
+	auditService	auditService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// AuditData Структура записи журнала аудита
+type AuditData struct {
+	Actor		string	`json:"actor"`
+	Action		string	`json:"action"`
+	Target		string	`json:"target"`
+	Owner		string	`json:"owner"`
+	Details		string	`json:"details,omitempty"`
+	TraceID		string	`json:"trace_id"`
+	CreatedAt	string	`json:"created_at"`
+}
+
+// auditResponse Ответ на запрос
+type auditResponse struct {
+	Data []AuditData	`json:"data"`
+}
+
+// newAuditResponse Маппит записи журнала в ответ
+func newAuditResponse(entries []models.AuditEntry) auditResponse {
+	resp := auditResponse{
+		Data: make([]AuditData, len(entries)),
+	}
+	for k, e := range entries {
+		resp.Data[k] = AuditData{
+			Actor:		e.Actor,
+			Action:		e.Action,
+			Target:		e.Target,
+			Owner:		e.Owner,
+			Details:	e.Details,
+			TraceID:	e.TraceID,
+			CreatedAt:	e.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return resp
+}
+
+// RegisterAuditHandler Фабрика для AuditHandler
+func RegisterAuditHandler(c *AuditHandlerConfig) {
+	auditHandler := AuditHandler{
+		auditService:	c.AuditService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1")
+	g.GET("/audit", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), auditHandler.History)
+
+	a := c.Router.Group("v1/admin", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.AdminOnly)
+	a.GET("/audit", auditHandler.Search)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// History Отдает историю изменений аккаунта пользователя
+func (h *AuditHandler) History(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AuditHistoryHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AuditHistoryHandler() started")
+	defer l.Debug("AuditHistoryHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAuditHistory)
+
+		return
+	}
+
+	entries, err := h.auditService.History(ctx, user.Username)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAuditHistory)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAuditResponse(entries))
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAuditHistory)
+
+	return
+}
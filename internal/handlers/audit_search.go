@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search Отдает записи журнала аудита по фильтру (?actor=&owner=&action=&limit=)
+func (h *AuditHandler) Search(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminAuditHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminAuditHandler() started")
+	defer l.Debug("AdminAuditHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Собираем фильтр из query-параметров
+	filter := models.AuditFilter{
+		Actor:	ctx.Query("actor"),
+		Owner:	ctx.Query("owner"),
+		Action:	ctx.Query("action"),
+	}
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid limit",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "GET", MetricAdminAudit)
+
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := h.auditService.Search(ctx, filter)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAdminAudit)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAuditResponse(entries))
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminAudit)
+
+	return
+}
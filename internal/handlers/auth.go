@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"short_url/internal/handlers/middlewares"
 	"short_url/internal/models"
+	"short_url/internal/ratelimit"
 	myLog "short_url/pkg/logger"
 )
 
@@ -12,6 +13,14 @@ import (
 type authService interface {
 	SignInUserByName(ctx context.Context, dto models.SignInUserDTO) (models.SignInUserDTO, error)
 	SignUpUser(ctx context.Context, dto models.SignUpUserDTO) error
+	GetProfile(ctx context.Context, username string) (models.UserDB, error)
+	UpdateProfile(ctx context.Context, username string, dto models.UpdateUserDTO) (models.UserDB, error)
+	ChangePassword(ctx context.Context, username, oldPassword, newPassword string) error
+	DeleteAccount(ctx context.Context, username, password, code string) error
+	ResendVerification(ctx context.Context, username string) error
+	VerifyEmail(ctx context.Context, secret string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, secret, newPassword string) error
 }
 
 // Интерфейс для сервиса, который управляет токенами доступа
@@ -47,6 +56,15 @@ func RegisterAuthHandler(c *AuthHandlerConfig) {
 	}
 
 	g := c.Router.Group("v1") // Версия API
-	g.POST("/signin", c.Middleware.Recorder, authHandler.SignIn)
-	g.POST("/signup", c.Middleware.Recorder, authHandler.SignUp)
+	g.POST("/signin", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.SignIn)
+	g.POST("/signup", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.SignUp)
+	g.GET("/me", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), authHandler.GetMe)
+	g.PATCH("/me", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), authHandler.UpdateMe)
+	g.POST("/me/password", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), authHandler.ChangePassword)
+	g.DELETE("/me", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), authHandler.DeleteMe)
+	g.POST("/me/email/verify", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), authHandler.ResendVerification)
+	g.GET("/email/verify", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.VerifyEmail)
+	g.POST("/email/verify", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.VerifyEmail)
+	g.POST("/password/forgot", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.ForgotPassword)
+	g.POST("/password/reset", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), authHandler.ResetPassword)
 }
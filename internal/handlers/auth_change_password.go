@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// changePasswordRequest Структура запроса
+type changePasswordRequest struct {
+	OldPassword	string	`json:"old_password" binding:"required"`
+	NewPassword	string	`json:"new_password" binding:"required,gte=6,lte=30"`
+}
+
+// ChangePassword Меняет пароль текущего пользователя
+func (h *AuthHandler) ChangePassword(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ChangePasswordHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ChangePasswordHandler() started")
+	defer l.Debug("ChangePasswordHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req changePasswordRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricChangePass); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricChangePass)
+
+		return
+	}
+
+	err = h.authService.ChangePassword(ctx, user.Username, req.OldPassword, req.NewPassword)
+	if err != nil {
+		if LockoutResp(ctx, err, "POST", MetricChangePass) {
+			return
+		}
+
+		if err.Error() == "invalid password" {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid password",
+			})
+
+			Bridge(ctx, http.StatusForbidden, "POST", MetricChangePass)
+
+			return
+		}
+
+		userErrResp(ctx, l, err, "POST", MetricChangePass)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricChangePass)
+
+	return
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deleteMeRequest Структура запроса. Нужен пароль или, если включен второй фактор, код из приложения
+type deleteMeRequest struct {
+	Password	string	`json:"password"`
+	Code		string	`json:"code"`
+}
+
+// DeleteMe Удаляет аккаунт текущего пользователя вместе со ссылками и подпиской
+func (h *AuthHandler) DeleteMe(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "DeleteMeHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("DeleteMeHandler() started")
+	defer l.Debug("DeleteMeHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req deleteMeRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "DELETE", MetricDeleteMe); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "DELETE", MetricDeleteMe)
+
+		return
+	}
+
+	if err = h.authService.DeleteAccount(ctx, user.Username, req.Password, req.Code); err != nil {
+		if LockoutResp(ctx, err, "DELETE", MetricDeleteMe) {
+			return
+		}
+
+		switch err.Error() {
+		case "reauthentication required":
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "Password or code required",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "DELETE", MetricDeleteMe)
+		case "invalid password", "invalid code":
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid password or code",
+			})
+
+			Bridge(ctx, http.StatusForbidden, "DELETE", MetricDeleteMe)
+		default:
+			userErrResp(ctx, l, err, "DELETE", MetricDeleteMe)
+		}
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "DELETE", MetricDeleteMe)
+
+	return
+}
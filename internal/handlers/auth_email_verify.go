@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyEmailRequest Структура запроса
+type verifyEmailRequest struct {
+	Token	string	`json:"token" binding:"required"`
+}
+
+// ResendVerification Повторно отправляет письмо для подтверждения почты текущего пользователя
+func (h *AuthHandler) ResendVerification(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ResendVerificationHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ResendVerificationHandler() started")
+	defer l.Debug("ResendVerificationHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricResendVerify)
+
+		return
+	}
+
+	err = h.authService.ResendVerification(ctx, user.Username)
+	if err != nil {
+		switch err.Error() {
+		case "email not set":
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "email not set",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "POST", MetricResendVerify)
+
+			return
+		case "email already verified":
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "email already verified",
+			})
+
+			Bridge(ctx, http.StatusConflict, "POST", MetricResendVerify)
+
+			return
+		}
+
+		userErrResp(ctx, l, err, "POST", MetricResendVerify)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricResendVerify)
+
+	return
+}
+
+// VerifyEmail Подтверждает почту по токену из письма (?token= при переходе по ссылке или JSON-тело)
+func (h *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "VerifyEmailHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("VerifyEmailHandler() started")
+	defer l.Debug("VerifyEmailHandler() done")
+
+	method := ctx.Request.Method
+
+	// По ссылке из письма токен приходит в query, из клиента - в теле запроса
+	token := ctx.Query("token")
+	if method == http.MethodPost {
+		var req verifyEmailRequest
+
+		// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+		// через ctx.JSON...
+		if ok := bindData(ctx, l, &req, method, MetricVerifyEmail); !ok {
+			return
+		}
+		token = req.Token
+	}
+
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "token required",
+		})
+
+		Bridge(ctx, http.StatusBadRequest, method, MetricVerifyEmail)
+
+		return
+	}
+
+	if err := h.authService.VerifyEmail(ctx, token); err != nil {
+		if err.Error() == "invalid token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid or expired token",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, method, MetricVerifyEmail)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, method, MetricVerifyEmail)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, method, MetricVerifyEmail)
+
+	return
+}
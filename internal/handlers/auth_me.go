@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// updateMeRequest Структура запроса (пустые поля не меняются)
+type updateMeRequest struct {
+	FirstName	string	`json:"first_name" binding:"omitempty,lte=64"`
+	LastName	string	`json:"last_name" binding:"omitempty,lte=64"`
+	Email		string	`json:"email" binding:"omitempty,email"`
+}
+
+// profileResponse Структура ответа
+type profileResponse struct {
+	Username	string	`json:"username"`
+	FirstName	string	`json:"first_name"`
+	LastName	string	`json:"last_name"`
+	Email		string	`json:"email"`
+	Verified	bool	`json:"email_verified"`
+	TOTP		bool	`json:"totp_enabled"`
+	Role		string	`json:"role"`
+	Subscribe	string	`json:"sub"`
+}
+
+// newProfileResponse Маппит профиль пользователя в ответ
+func newProfileResponse(u models.UserDB, user models.JWTUserInfo) profileResponse {
+	return profileResponse{
+		Username:	u.Username,
+		FirstName:	u.FirstName,
+		LastName:	u.LastName,
+		Email:		u.Email,
+		Verified:	u.EmailVerified,
+		TOTP:		u.TOTPEnabled,
+		Role:		u.Role.ChoiceString(),
+		Subscribe:	user.Subscribe.ChoiceString(),
+	}
+}
+
+// userErrResp Отвечает на ошибку поиска пользователя
+func userErrResp(ctx *gin.Context, l *log.Log, err error, method, handler string) {
+	if err.Error() == "user not found" {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": "user not found",
+		})
+
+		Bridge(ctx, http.StatusNotFound, method, handler)
+
+		return
+	}
+
+	InternalErrResp(ctx, l, err)
+
+	Bridge(ctx, http.StatusInternalServerError, method, handler)
+}
+
+// GetMe Отдает профиль текущего пользователя
+func (h *AuthHandler) GetMe(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "GetMeHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("GetMeHandler() started")
+	defer l.Debug("GetMeHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricGetMe)
+
+		return
+	}
+
+	u, err := h.authService.GetProfile(ctx, user.Username)
+	if err != nil {
+		userErrResp(ctx, l, err, "GET", MetricGetMe)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProfileResponse(u, user))
+
+	Bridge(ctx, http.StatusOK, "GET", MetricGetMe)
+
+	return
+}
+
+// UpdateMe Меняет имя и фамилию текущего пользователя
+func (h *AuthHandler) UpdateMe(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "UpdateMeHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("UpdateMeHandler() started")
+	defer l.Debug("UpdateMeHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req updateMeRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "PATCH", MetricUpdateMe); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "PATCH", MetricUpdateMe)
+
+		return
+	}
+
+	u, err := h.authService.UpdateProfile(ctx, user.Username, models.UpdateUserDTO{
+		FirstName:	req.FirstName,
+		LastName:	req.LastName,
+		Email:		req.Email,
+	})
+	if err != nil {
+		if err.Error() == "email exist" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "email is already in use",
+			})
+
+			Bridge(ctx, http.StatusConflict, "PATCH", MetricUpdateMe)
+
+			return
+		}
+
+		userErrResp(ctx, l, err, "PATCH", MetricUpdateMe)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProfileResponse(u, user))
+
+	Bridge(ctx, http.StatusOK, "PATCH", MetricUpdateMe)
+
+	return
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forgotPasswordRequest Структура запроса
+type forgotPasswordRequest struct {
+	Email	string	`json:"email" binding:"required,email"`
+}
+
+// resetPasswordRequest Структура запроса
+type resetPasswordRequest struct {
+	Token		string	`json:"token" binding:"required"`
+	NewPassword	string	`json:"new_password" binding:"required,gte=6,lte=30"`
+}
+
+// ForgotPassword Отправляет на почту код для сброса пароля.
+// Ответ не зависит от того, зарегистрирована ли почта
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ForgotPasswordHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ForgotPasswordHandler() started")
+	defer l.Debug("ForgotPasswordHandler() done")
+
+	var req forgotPasswordRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricForgotPass); !ok {
+		return
+	}
+
+	if err := h.authService.ForgotPassword(ctx, req.Email); err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricForgotPass)
+
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, "OK")
+
+	Bridge(ctx, http.StatusAccepted, "POST", MetricForgotPass)
+
+	return
+}
+
+// ResetPassword Устанавливает новый пароль по коду из письма
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ResetPasswordHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ResetPasswordHandler() started")
+	defer l.Debug("ResetPasswordHandler() done")
+
+	var req resetPasswordRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricResetPass); !ok {
+		return
+	}
+
+	if err := h.authService.ResetPassword(ctx, req.Token, req.NewPassword); err != nil {
+		if err.Error() == "invalid token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid or expired token",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "POST", MetricResetPass)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricResetPass)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricResetPass)
+
+	return
+}
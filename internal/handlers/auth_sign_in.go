@@ -1,10 +1,7 @@
 package handlers
 
 import (
-	"errors"
-	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 	"net/http"
 	"short_url/internal/models"
 	log "short_url/pkg/logger"
@@ -22,6 +19,13 @@ type signInResponse struct {
 	Username    string `json:"username"`
 }
 
+// Структура ответа, если у пользователя включен второй фактор
+type signInMFAResponse struct {
+	MFARequired	bool	`json:"mfa_required"`
+	MFAToken	string	`json:"mfa_token"`
+	Username	string	`json:"username"`
+}
+
 // SignIn метод AuthService для выполнения входа
 func (h *AuthHandler) SignIn(ctx *gin.Context) {
 	ctxLog := log.ContextWithSpan(ctx, "SignInHandler")
@@ -42,23 +46,18 @@ func (h *AuthHandler) SignIn(ctx *gin.Context) {
 	u, err := h.authService.SignInUserByName(ctxLog, models.SignInUserDTO{
 		Username: req.Username,
 		Password: req.Password,
+		IP: ctx.ClientIP(),
 	})
 
-	// Обрабатываем ошибки
+	// Обрабатываем ошибки (неизвестное имя и неверный пароль неразличимы для клиента)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, gin.H{
-				"error": fmt.Sprintf("user with username=%s not found", req.Username),
-			})
-
-			Bridge(ctx, http.StatusNotFound, "POST", MetricSignIn)
-
+		if LockoutResp(ctx, err, "POST", MetricSignIn) {
 			return
 		}
 
-		if err.Error() == "invalid password" {
+		if err.Error() == "invalid credentials" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid password",
+				"error": "invalid credentials",
 			})
 
 			Bridge(ctx, http.StatusUnauthorized, "POST", MetricSignIn)
@@ -73,10 +72,13 @@ func (h *AuthHandler) SignIn(ctx *gin.Context) {
 		return
 	}
 
-	// Создаем токен доступа
+	// Создаем токен доступа (если включен второй фактор - короткоживущий токен для /v1/signin/mfa)
 	token, err := h.tokenService.CreateToken(ctxLog, models.CreateTokenDTO{
 		Username:	u.Username,
 		Subscribe:	u.Subscribe,
+		Role:		u.Role,
+		Plan:		u.Plan,
+		MFAPending:	u.MFA,
 	})
 	if err != nil {
 		InternalErrResp(ctx, l, err)
@@ -86,6 +88,18 @@ func (h *AuthHandler) SignIn(ctx *gin.Context) {
 		return
 	}
 
+	if u.MFA {
+		ctx.JSON(http.StatusOK, signInMFAResponse{
+			MFARequired:	true,
+			MFAToken:		token,
+			Username:		u.Username,
+		})
+
+		Bridge(ctx, http.StatusOK, "POST", MetricSignIn)
+
+		return
+	}
+
 	// Маппим данные в ответ
 	ctx.JSON(http.StatusOK, signInResponse{
 		AccessToken: token,
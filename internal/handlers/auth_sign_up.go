@@ -13,6 +13,7 @@ type signUpRequest struct {
 	Username	string				`json:"username" binding:"required"`
 	FirstName	string				`json:"first_name" binding:"required"`
 	LastName	string				`json:"last_name" binding:"required"`
+	Email		string				`json:"email" binding:"omitempty,email"`
 	Password	string				`json:"password" binding:"required,gte=6,lte=30"`
 }
 
@@ -38,6 +39,7 @@ func (h *AuthHandler) SignUp(ctx *gin.Context) {
 		Password:	req.Password,
 		LastName:	req.LastName,
 		FirstName:	req.FirstName,
+		Email:		req.Email,
 	})
 
 	// Обрабатываем ошибки
@@ -52,6 +54,26 @@ func (h *AuthHandler) SignUp(ctx *gin.Context) {
 			return
 		}
 
+		if err.Error() == "invalid username" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"Error": "username may contain only letters, digits, '_', '.' and '-'",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "POST", MetricSignUp)
+
+			return
+		}
+
+		if err.Error() == "email exist" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"Error": "email is already in use",
+			})
+
+			Bridge(ctx, http.StatusConflict, "POST", MetricSignUp)
+
+			return
+		}
+
 		InternalErrResp(ctx, l, err)
 
 		Bridge(ctx, http.StatusInternalServerError, "POST", MetricSignUp)
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaService Интерфейс к сервису двухфакторной аутентификации
+type mfaService interface {
+	EnrollTOTP(ctx context.Context, username string) (models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, username, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, username, code string) error
+	VerifyCode(ctx context.Context, username, code string) error
+}
+
+// MFAHandlerConfig Конфигурация для MFAHandler
+type MFAHandlerConfig struct {
+	Router			*gin.Engine
+	MFAService		mfaService
+	TokenService	tokenService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// MFAHandler Для регистрации "ручек" двухфакторной аутентификации
+type MFAHandler struct {
+	mfaService		mfaService
+	tokenService	tokenService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// mfaCodeRequest Структура запроса с кодом из приложения или резервным кодом
+type mfaCodeRequest struct {
+	Code	string	`json:"code" binding:"required,lte=32"`
+}
+
+// RegisterMFAHandler Фабрика для MFAHandler
+func RegisterMFAHandler(c *MFAHandlerConfig) {
+	mfaHandler := MFAHandler{
+		mfaService:		c.MFAService,
+		tokenService:	c.TokenService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1")
+	g.POST("/signin/mfa", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), mfaHandler.SignInMFA)
+	g.POST("/me/mfa/totp", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), mfaHandler.EnrollTOTP)
+	g.POST("/me/mfa/totp/confirm", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), mfaHandler.ConfirmTOTP)
+	g.POST("/me/mfa/totp/disable", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), mfaHandler.DisableTOTP)
+}
+
+// mfaErrResp Отвечает на ошибки сервиса двухфакторной аутентификации
+func mfaErrResp(ctx *gin.Context, l *myLog.Log, err error, method, handler string) {
+	if LockoutResp(ctx, err, method, handler) {
+		return
+	}
+
+	var code int
+	switch err.Error() {
+	case "invalid code":
+		code = http.StatusUnauthorized
+	case "mfa already enabled", "mfa not enrolled", "mfa not enabled":
+		code = http.StatusConflict
+	case "user not found":
+		code = http.StatusNotFound
+	default:
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, method, handler)
+
+		return
+	}
+
+	ctx.JSON(code, gin.H{
+		"error": err.Error(),
+	})
+
+	Bridge(ctx, code, method, handler)
+}
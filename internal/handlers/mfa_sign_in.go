@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signInMFARequest Структура запроса
+type signInMFARequest struct {
+	MFAToken	string	`json:"mfa_token" binding:"required"`
+	Code		string	`json:"code" binding:"required,lte=32"`
+}
+
+// SignInMFA Второй шаг входа: обменивает короткоживущий токен и код второго фактора на токен доступа
+func (h *MFAHandler) SignInMFA(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "SignInMFAHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("SignInMFAHandler() started")
+	defer l.Debug("SignInMFAHandler() done")
+
+	var req signInMFARequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricSignInMFA); !ok {
+		return
+	}
+
+	// Принимаем только токен, выданный на первом шаге входа
+	pending, err := h.tokenService.ValidateToken(ctxLog, req.MFAToken)
+	if err != nil || !pending.MFAPending {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid or expired mfa token",
+		})
+
+		Bridge(ctx, http.StatusUnauthorized, "POST", MetricSignInMFA)
+
+		return
+	}
+
+	// Проверяем код второго фактора
+	if err = h.mfaService.VerifyCode(ctx, pending.Username, req.Code); err != nil {
+		mfaErrResp(ctx, l, err, "POST", MetricSignInMFA)
+
+		return
+	}
+
+	// Создаем полноценный токен доступа
+	token, err := h.tokenService.CreateToken(ctxLog, models.CreateTokenDTO{
+		Username:	pending.Username,
+		Subscribe:	pending.Subscribe,
+		Role:		pending.Role,
+		Plan:		pending.Plan,
+	})
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricSignInMFA)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, signInResponse{
+		AccessToken:	token,
+		Username:		pending.Username,
+	})
+
+	Bridge(ctx, http.StatusOK, "POST", MetricSignInMFA)
+
+	return
+}
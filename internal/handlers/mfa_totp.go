@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// enrollTOTPResponse Структура ответа
+type enrollTOTPResponse struct {
+	Secret	string	`json:"secret"`
+	URI		string	`json:"uri"`
+	QR		string	`json:"qr"`	// PNG в base64
+}
+
+// recoveryCodesResponse Структура ответа
+type recoveryCodesResponse struct {
+	RecoveryCodes	[]string	`json:"recovery_codes"`
+}
+
+// EnrollTOTP Выдает секрет и QR-код для подключения приложения-аутентификатора
+func (h *MFAHandler) EnrollTOTP(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "EnrollTOTPHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("EnrollTOTPHandler() started")
+	defer l.Debug("EnrollTOTPHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricEnrollTOTP)
+
+		return
+	}
+
+	enrollment, err := h.mfaService.EnrollTOTP(ctx, user.Username)
+	if err != nil {
+		mfaErrResp(ctx, l, err, "POST", MetricEnrollTOTP)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:	enrollment.Secret,
+		URI:	enrollment.URI,
+		QR:		base64.StdEncoding.EncodeToString(enrollment.QR),
+	})
+
+	Bridge(ctx, http.StatusOK, "POST", MetricEnrollTOTP)
+
+	return
+}
+
+// ConfirmTOTP Включает второй фактор по первому коду из приложения и отдает резервные коды
+func (h *MFAHandler) ConfirmTOTP(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ConfirmTOTPHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ConfirmTOTPHandler() started")
+	defer l.Debug("ConfirmTOTPHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req mfaCodeRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricConfirmTOTP); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricConfirmTOTP)
+
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(ctx, user.Username, req.Code)
+	if err != nil {
+		mfaErrResp(ctx, l, err, "POST", MetricConfirmTOTP)
+
+		return
+	}
+
+	// Резервные коды показываются только один раз
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+
+	Bridge(ctx, http.StatusOK, "POST", MetricConfirmTOTP)
+
+	return
+}
+
+// DisableTOTP Отключает второй фактор по коду из приложения или резервному коду
+func (h *MFAHandler) DisableTOTP(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "DisableTOTPHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("DisableTOTPHandler() started")
+	defer l.Debug("DisableTOTPHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req mfaCodeRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricDisableTOTP); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricDisableTOTP)
+
+		return
+	}
+
+	if err = h.mfaService.DisableTOTP(ctx, user.Username, req.Code); err != nil {
+		mfaErrResp(ctx, l, err, "POST", MetricDisableTOTP)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricDisableTOTP)
+
+	return
+}
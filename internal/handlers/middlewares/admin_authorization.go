@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"short_url/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly пропускает дальше только пользователей с ролью администратора.
+// Должен вызываться после AuthUser
+func (m *Middlewares) AdminOnly(ctx *gin.Context) {
+	// Если авторизация уже была провалена, ничего не делаем
+	if _, ok := ctx.Get(Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	info, ok := ctx.Get(UserInfo)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "unauthorized",
+		})
+
+		metricSet(ctx, http.StatusUnauthorized)
+
+		return
+	}
+
+	user, ok := info.(models.JWTUserInfo)
+	if !ok || user.Role != models.RoleAdmin {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "admin role required",
+		})
+
+		metricSet(ctx, http.StatusForbidden)
+
+		return
+	}
+
+	ctx.Next()
+}
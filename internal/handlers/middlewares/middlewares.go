@@ -17,6 +17,7 @@ const (
 	Handler		= "handler"			// Обработчик
 
 	MidAuth		= "middleware_auth"	// Проверка авторизации
+	MidRate		= "middleware_rate"	// Ограничение частоты запросов
 	Skip		= "skipped"			// Ключ пропуска обработчика (вместо ctx.Abort())
 )
 
@@ -25,15 +26,28 @@ type tokenService interface {
 	ValidateToken(ctx context.Context, token string) (models.JWTUserInfo, error)
 }
 
+// limiter Интерфейс к ограничителю частоты запросов
+type limiter interface {
+	Allow(ctx context.Context, group, tier, key string) models.RateResult
+}
+
+// subscriptionState Интерфейс к сервису актуального состояния подписки
+type subscriptionState interface {
+	State(ctx context.Context, username string) (models.Subscribe, string, error)
+}
+
 // Middlewares класс для работы с middlewares
 type Middlewares struct {
 	tokenService 	tokenService
+	limiter			limiter
+	subs			subscriptionState
 	logger          *log.Log
 	Counter			*prometheus.CounterVec
 }
 
-// NewMiddlewares конструктор для Middlewares
-func NewMiddlewares(log *log.Log, service tokenService) *Middlewares {
+// NewMiddlewares конструктор для Middlewares (limiter может быть nil - запросы не ограничиваются,
+// subs может быть nil - подписка берется из токена)
+func NewMiddlewares(log *log.Log, service tokenService, limiter limiter, subs subscriptionState) *Middlewares {
 	// Создаем метрику
 	requestTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -45,6 +59,8 @@ func NewMiddlewares(log *log.Log, service tokenService) *Middlewares {
 
 	return &Middlewares{
 		tokenService:	service,
+		limiter:		limiter,
+		subs:			subs,
 		logger:			log,
 		Counter:		requestTotal,
 	}
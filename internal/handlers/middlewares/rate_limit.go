@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"short_url/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки ограничения частоты запросов (draft-ietf-httpapi-ratelimit-headers)
+const (
+	RateLimitHeader		= "RateLimit-Limit"
+	RateRemainingHeader	= "RateLimit-Remaining"
+	RateResetHeader		= "RateLimit-Reset"
+)
+
+// seconds Переводит длительность в целые секунды с округлением вверх
+func seconds(d time.Duration) string {
+	return fmt.Sprint(int64((d + time.Second - 1) / time.Second))
+}
+
+// RateLimit ограничивает частоту запросов к группе маршрутов.
+// Авторизованные пользователи ограничиваются по имени и тарифу подписки, остальные - по IP.
+// Должен стоять после AuthUser, если маршрут требует авторизации
+func (m *Middlewares) RateLimit(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// Запрос уже отклонен предыдущим middleware
+		if _, ok := ctx.Get(Skip); ok || m.limiter == nil {
+			ctx.Next()
+
+			return
+		}
+
+		tier, key := models.TierAnon, "ip:"+ctx.ClientIP()
+		if info, ok := ctx.Get(UserInfo); ok {
+			if user, ok := info.(models.JWTUserInfo); ok {
+				tier, key = user.Subscribe.ChoiceString(), "user:"+user.Username
+			}
+		}
+
+		res := m.limiter.Allow(ctx, group, tier, key)
+		if res.Limit == 0 {
+			ctx.Next()
+
+			return
+		}
+
+		ctx.Header(RateLimitHeader, fmt.Sprint(res.Limit))
+		ctx.Header(RateRemainingHeader, fmt.Sprint(res.Remaining))
+		ctx.Header(RateResetHeader, seconds(res.Reset))
+
+		if !res.Allowed {
+			ctx.Header("Retry-After", seconds(res.RetryAfter))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "rate limit exceeded",
+			})
+
+			ctx.Set(Handler, MidRate)
+			ctx.Set(Method, MidRate)
+			ctx.Set(Code, fmt.Sprint(http.StatusTooManyRequests))
+			ctx.Set(Skip, "true")
+
+			// Не все обработчики проверяют Skip (например, переход по ссылке), поэтому прерываем цепочку
+			ctx.Abort()
+
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Заголовок со сквозным идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// Tracer присваивает запросу сквозной идентификатор (trace_id) для логов и журнала аудита.
+// Если клиент передал заголовок X-Request-ID, используется его значение
+func (m *Middlewares) Tracer(ctx *gin.Context) {
+	trace := ctx.GetHeader(RequestIDHeader)
+	if trace == "" || len(trace) > 64 {
+		trace = uuid.NewString()
+	}
+
+	// Кладем идентификатор в контекст запроса, gin отдает его через ContextWithFallback
+	ctx.Request = ctx.Request.WithContext(log.ContextWithTrace(ctx.Request.Context(), trace))
+	ctx.Header(RequestIDHeader, trace)
+
+	ctx.Next()
+}
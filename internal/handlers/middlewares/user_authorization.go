@@ -61,6 +61,28 @@ func (m *Middlewares) AuthUser(ctx *gin.Context) {
 		return
 	}
 
+	// Токен без пройденного второго фактора годится только для /v1/signin/mfa
+	if info.MFAPending {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "second factor required",
+		})
+
+		metricSet(ctx, http.StatusUnauthorized)
+
+		return
+	}
+
+	// Подписка могла измениться после выдачи токена, поэтому берем ее актуальное состояние.
+	// Если хранилище недоступно, действует состояние из токена
+	if m.subs != nil {
+		sub, plan, err := m.subs.State(ctx, info.Username)
+		if err != nil {
+			m.logger.WithContext(ctx).Warnf("Unable to resolve subscription of %s. Error: %s", info.Username, err)
+		} else {
+			info.Subscribe, info.Plan = sub, plan
+		}
+	}
+
 	ctx.Set(UserInfo, info)
 
 	ctx.Next()
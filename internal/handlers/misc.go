@@ -1,14 +1,12 @@
 package handlers
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
 	"errors"
 	"fmt"
-	"hash"
 	"net/http"
 	"short_url/internal/handlers/middlewares"
 	"short_url/internal/models"
+	"short_url/internal/security"
 	log "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -18,10 +16,31 @@ import (
 const (
 	MetricSignIn		= "signIn"
 	MetricSignUp		= "signUp"
+	MetricGetMe			= "getMe"
+	MetricUpdateMe		= "updateMe"
+	MetricChangePass	= "changePassword"
+	MetricDeleteMe		= "deleteMe"
+	MetricResendVerify	= "resendVerify"
+	MetricVerifyEmail	= "verifyEmail"
+	MetricForgotPass	= "forgotPassword"
+	MetricResetPass		= "resetPassword"
+
+	MetricSignInMFA		= "signInMFA"
+	MetricEnrollTOTP	= "enrollTOTP"
+	MetricConfirmTOTP	= "confirmTOTP"
+	MetricDisableTOTP	= "disableTOTP"
+
+	MetricOIDCLogin		= "oidcLogin"
+	MetricOIDCCallback	= "oidcCallback"
 
 	MetricQiwiNotify	= "qiwiNotify"
 	MetricQiwiSubExt	= "qiwiSubExt"
 	MetricQiwiSub		= "qiwiSub"
+	MetricPlans			= "plans"
+	MetricSubscription	= "subscription"
+	MetricBilling		= "billing"
+	MetricKeepLinks		= "keepLinks"
+	MetricRedeem		= "redeem"
 
 	MetricCreateLink	= "createLink"
 	MetricCreateQR		= "createQR"
@@ -29,12 +48,32 @@ const (
 	MetricGetAllLinks	= "getAllLinks"
 	MetricGetLink		= "getLink"
 	MetricRedirectLink	= "redirectLink"
-)
 
-// hash256 Создает новый хеш
-func hash256() hash.Hash {
-	return sha256.New()
-}
+	MetricAdminSearchUsers	= "adminSearchUsers"
+	MetricAdminUserLinks	= "adminUserLinks"
+	MetricAdminDisableLink	= "adminDisableLink"
+	MetricAdminEnableLink	= "adminEnableLink"
+	MetricAdminGrantSub		= "adminGrantSub"
+	MetricAdminRevokeSub	= "adminRevokeSub"
+	MetricAdminBills		= "adminBills"
+	MetricAdminJobs			= "adminJobs"
+	MetricAdminPromoCreate	= "adminPromoCreate"
+	MetricAdminPromos		= "adminPromos"
+	MetricAdminPromoDelete	= "adminPromoDelete"
+
+	MetricReportLink		= "reportLink"
+	MetricAdminReports		= "adminReports"
+	MetricAdminResolve		= "adminResolveReport"
+
+	MetricCreateWebhook		= "createWebhook"
+	MetricWebhooks			= "webhooks"
+	MetricDeleteWebhook		= "deleteWebhook"
+	MetricDeliveries		= "webhookDeliveries"
+	MetricRedeliver			= "webhookRedeliver"
+
+	MetricAuditHistory		= "auditHistory"
+	MetricAdminAudit		= "adminAudit"
+)
 
 // Bridge Мост к middleware, передающие значения по лейблам метрики в контекст
 func Bridge(ctx *gin.Context, code int, method string, handler string) {
@@ -56,6 +95,93 @@ func InternalErrResp(ctx *gin.Context, l *log.Log, err error) {
 	return
 }
 
+// LockoutResp Вспомогательная функция (отвечает 429 с Retry-After, если вход временно заблокирован)
+func LockoutResp(ctx *gin.Context, err error, method, handler string) bool {
+	var lock *models.LockoutError
+	if !errors.As(err, &lock) {
+		return false
+	}
+
+	ctx.Header("Retry-After", lock.RetryAfterSec())
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error": lock.Error(),
+	})
+
+	Bridge(ctx, http.StatusTooManyRequests, method, handler)
+
+	return true
+}
+
+// LinkErrResp Вспомогательная функция (отдает ответ по ошибке операции над ссылкой пользователя)
+func LinkErrResp(ctx *gin.Context, l *log.Log, err error, method, handler string) {
+	switch err.Error() {
+	case "link not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": "link not found",
+		})
+
+		Bridge(ctx, http.StatusNotFound, method, handler)
+	case "access denied":
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "access denied",
+		})
+
+		Bridge(ctx, http.StatusForbidden, method, handler)
+	default:
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, method, handler)
+	}
+
+	return
+}
+
+// WebhookErrResp Вспомогательная функция (отвечает на ошибки сервиса вебхуков)
+func WebhookErrResp(ctx *gin.Context, l *log.Log, err error, method, handler string) {
+	switch err.Error() {
+	case "webhook not found", "delivery not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+
+		Bridge(ctx, http.StatusNotFound, method, handler)
+	case "invalid url", "invalid event", "limit exceeded":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+
+		Bridge(ctx, http.StatusBadRequest, method, handler)
+	default:
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, method, handler)
+	}
+
+	return
+}
+
+// PromoErrResp Отвечает на ошибку применения промокода. Возвращает false, если ошибка не связана с промокодом
+func PromoErrResp(ctx *gin.Context, err error, method, handler string) bool {
+	code := http.StatusBadRequest
+	switch err.Error() {
+	case "promo not found":
+		code = http.StatusNotFound
+	case "promo already used", "promo already exists":
+		code = http.StatusConflict
+	case "promo expired", "promo exhausted", "promo not applicable", "invalid promo":
+	default:
+		return false
+	}
+
+	ctx.JSON(code, gin.H{
+		"error": err.Error(),
+	})
+
+	Bridge(ctx, code, method, handler)
+
+	return true
+}
+
 // GetUserInfo Возвращает информацию о пользователе и его подписке из контекста
 func GetUserInfo(ctx *gin.Context) (models.JWTUserInfo, error) {
 	// Получаем данные из контекста
@@ -76,23 +202,11 @@ func GetUserInfo(ctx *gin.Context) (models.JWTUserInfo, error) {
 }
 
 // QiwiAuthorizeHash Авторизирует входящее уведомление о счете
-func QiwiAuthorization(ctx *gin.Context, l *log.Log, key string, params, sign string) bool {
-	// Создаем хеш с применением ключа
-	hash := hmac.New(hash256, []byte(key))
-
-	// Хешируем параметры
-	_, err := hash.Write([]byte(params))
-	if err != nil {
-		InternalErrResp(ctx, l, err)
-
-		Bridge(ctx, http.StatusInternalServerError, "POST", MetricQiwiNotify)
-
-		return false
-	}
+func QiwiAuthorization(ctx *gin.Context, l *log.Log, key, sign string, fields ...string) bool {
+	// Сравниваем подпись из заголовка с подписью полей уведомления
+	if !security.VerifyQiwiSignature(key, sign, fields...) {
+		l.Warnf("QIWI notification signature mismatch")
 
-	// Сравниваем полученную строку с заголовком
-	str := string(hash.Sum(nil))
-	if sign != str {
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"error": "authorization failed",
 		})
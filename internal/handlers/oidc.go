@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcService Интерфейс к сервису входа через провайдера OpenID Connect
+type oidcService interface {
+	BeginLogin(ctx context.Context) (string, error)
+	CompleteLogin(ctx context.Context, state, code string) (models.SignInUserDTO, error)
+}
+
+// OIDCHandlerConfig Конфигурация для OIDCHandler
+type OIDCHandlerConfig struct {
+	Router			*gin.Engine
+	OIDCService		oidcService
+	TokenService	tokenService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// OIDCHandler Для регистрации "ручек" входа через провайдера OpenID Connect
+type OIDCHandler struct {
+	oidcService		oidcService
+	tokenService	tokenService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// RegisterOIDCHandler Фабрика для OIDCHandler
+func RegisterOIDCHandler(c *OIDCHandlerConfig) {
+	oidcHandler := OIDCHandler{
+		oidcService:	c.OIDCService,
+		tokenService:	c.TokenService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1/oidc")
+	g.GET("/login", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), oidcHandler.Login)
+	g.GET("/callback", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAuth), oidcHandler.Callback)
+}
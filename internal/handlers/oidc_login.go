@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Login Переадресует пользователя на страницу входа провайдера
+func (h *OIDCHandler) Login(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "OIDCLoginHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("OIDCLoginHandler() started")
+	defer l.Debug("OIDCLoginHandler() done")
+
+	url, err := h.oidcService.BeginLogin(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricOIDCLogin)
+
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, url)
+
+	Bridge(ctx, http.StatusFound, "GET", MetricOIDCLogin)
+
+	return
+}
+
+// Callback Принимает пользователя от провайдера и выдает токен доступа
+func (h *OIDCHandler) Callback(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "OIDCCallbackHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("OIDCCallbackHandler() started")
+	defer l.Debug("OIDCCallbackHandler() done")
+
+	// Провайдер мог вернуть ошибку (например, пользователь отказался от входа).
+	// Значение параметра приходит из адресной строки, поэтому в ответ его не возвращаем
+	if e := ctx.Query("error"); e != "" {
+		l.Infof("provider returned error: %.64q", e)
+
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "provider error",
+		})
+
+		Bridge(ctx, http.StatusUnauthorized, "GET", MetricOIDCCallback)
+
+		return
+	}
+
+	u, err := h.oidcService.CompleteLogin(ctx, ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid state", "provider error":
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+
+			Bridge(ctx, http.StatusUnauthorized, "GET", MetricOIDCCallback)
+
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricOIDCCallback)
+
+		return
+	}
+
+	// Создаем токен доступа так же, как при входе по паролю
+	token, err := h.tokenService.CreateToken(ctxLog, models.CreateTokenDTO{
+		Username:	u.Username,
+		Subscribe:	u.Subscribe,
+		Role:		u.Role,
+		Plan:		u.Plan,
+		MFAPending:	u.MFA,
+	})
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricOIDCCallback)
+
+		return
+	}
+
+	if u.MFA {
+		ctx.JSON(http.StatusOK, signInMFAResponse{
+			MFARequired:	true,
+			MFAToken:		token,
+			Username:		u.Username,
+		})
+
+		Bridge(ctx, http.StatusOK, "GET", MetricOIDCCallback)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, signInResponse{
+		AccessToken:	token,
+		Username:		u.Username,
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricOIDCCallback)
+
+	return
+}
@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"context"
-	"errors"
+	"net/http"
 	"short_url/internal/handlers/middlewares"
 	"short_url/internal/models"
+	"short_url/internal/ratelimit"
 	myLog "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +14,9 @@ import (
 // qiwiService Интерфейс к сервису оплаты подписок через QIWI
 type qiwiService interface {
 	NotifyFromQiwi(ctx context.Context, status, bill string) error
-	BillRequest(ctx context.Context, amo float64, username string) (string, error)
+	BillRequest(ctx context.Context, plan, username, promo string) (string, error)
+	Redeem(ctx context.Context, username, code string) (models.PromoDB, error)
+	Plans(ctx context.Context) []models.Plan
 }
 
 // PayHandlerConfig Конфигурация к PayHandler
@@ -22,7 +25,6 @@ type PayHandlerConfig struct {
 	Logger		*myLog.Log
 	QiwiService	qiwiService
 	Middleware	*middlewares.Middlewares
-	Prices		models.ConfigPrice
 	Key			string
 }
 
@@ -31,28 +33,28 @@ type PayHandler struct {
 	logger		*myLog.Log
 	qiwiService	qiwiService
 	middleware	*middlewares.Middlewares
-	prices		models.ConfigPrice
 	key			string
 }
 
-// getSubFromParam Получает вариант подписки из path и вычисляет на какую сумму выставить счет
-func (h *PayHandler) getSubFromParam(ctx *gin.Context) (float64, error) {
-	sub := ctx.Param("subTime")
+// billErrResp Отвечает на ошибку выставления счета
+func billErrResp(ctx *gin.Context, l *myLog.Log, err error, handler string) {
+	if PromoErrResp(ctx, err, "GET", handler) {
+		return
+	}
+
+	if err.Error() == "plan not found" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid subscribe params",
+		})
 
-	var amo float64
-	var err error
+		Bridge(ctx, http.StatusBadRequest, "GET", handler)
 
-	switch sub {
-	case "weekly":
-		amo = h.prices.Weekly
-	case "monthly":
-		amo = h.prices.Monthly
-	case "yearly":
-		amo = h.prices.Yearly
-	default:
-		err = errors.New("param error")
+		return
 	}
-	return amo, err
+
+	InternalErrResp(ctx, l, err)
+
+	Bridge(ctx, http.StatusInternalServerError, "GET", handler)
 }
 
 // RegisterPayHandler Фабрика для PayHandler
@@ -61,12 +63,13 @@ func RegisterPayHandler(c *PayHandlerConfig) {
 		logger:			c.Logger,
 		qiwiService:	c.QiwiService,
 		middleware:		c.Middleware,
-		prices:			c.Prices,
 		key:			c.Key,
 	}
 
 	g := c.Router.Group("v1") // Версия API
-	g.GET("/qiwi/:subTime", c.Middleware.Recorder, c.Middleware.AuthUser, payHandler.QiwiSub)
+	g.GET("/plans", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAPI), payHandler.Plans)
+	g.GET("/qiwi/:subTime", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupPay), payHandler.QiwiSub)
 	g.POST("/qiwistatus", c.Middleware.Recorder, payHandler.QiwiNotify)
-	g.GET("/qiwi/extend/:subTime", c.Middleware.Recorder, c.Middleware.AuthUser, payHandler.QiwiSubExtend)
+	g.GET("/qiwi/extend/:subTime", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupPay), payHandler.QiwiSubExtend)
+	g.POST("/subscription/redeem", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupPay), payHandler.Redeem)
 }
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plans Отдает каталог тарифных планов с ценами и лимитами
+func (h *PayHandler) Plans(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "PlansHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("PlansHandler() started")
+	defer l.Debug("PlansHandler() done")
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"plans": h.qiwiService.Plans(ctxLog),
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricPlans)
+
+	return
+}
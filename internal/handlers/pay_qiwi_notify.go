@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"fmt"
-	"strings"
+	"encoding/json"
 	"net/http"
+	"short_url/internal/security"
 	log "short_url/pkg/logger"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,9 +26,9 @@ type status struct {
 	ChangedDatetime	string	`json:"datetime"`
 }
 
-// amount Данные о сумме счета
+// amount Данные о сумме счета (QIWI присылает сумму строкой или числом)
 type amount struct {
-	Value		float64	`json:"value"`
+	Value		json.Number	`json:"value"`
 	Currency	string	`json:"currency"`
 }
 
@@ -70,12 +71,22 @@ func (h *PayHandler) QiwiNotify(ctx *gin.Context) {
 		return
 	}
 
-	// Парсим параметры из тела и заголовка уведомления
-	params := strings.Join([]string{req.Bill.Amount.Currency, fmt.Sprint(req.Bill.Amount.Value), req.Bill.BillID, req.Bill.SiteId, req.Bill.Status.Value}, "|")
+	// QIWI подписывает сумму ровно с двумя знаками после точки
+	value, err := strconv.ParseFloat(req.Bill.Amount.Value.String(), 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid amount",
+		})
+
+		Bridge(ctx, http.StatusBadRequest, "POST", MetricQiwiNotify)
+
+		return
+	}
 	api_signature := getApiSignatureFromCtx(ctx)
 
 	// Авторизируем уведомление
-	if QiwiAuthorization(ctx, l, h.key, params, api_signature) {
+	if QiwiAuthorization(ctx, l, h.key, api_signature, req.Bill.Amount.Currency, security.QiwiAmount(value),
+		req.Bill.BillID, req.Bill.SiteId, req.Bill.Status.Value) {
 		// Если счет уже не в статусе ожидания, обрабатываем результат счета
 		if req.Bill.Status.Value != "WAITING" {
 			if err := h.qiwiService.NotifyFromQiwi(ctx, req.Bill.Status.Value, req.Bill.BillID); err != nil {
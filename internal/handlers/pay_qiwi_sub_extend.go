@@ -21,6 +21,8 @@ func (h *PayHandler) QiwiSubExtend(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
+
+		return
 	}
 
 	// Получаем информацию о пользователе
@@ -44,24 +46,10 @@ func (h *PayHandler) QiwiSubExtend(ctx *gin.Context) {
 		return
 	}
 
-	// Получаем желаемый срок подписки из path
-	sub, err := h.getSubFromParam(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid subscribe params",
-		})
-
-		Bridge(ctx, http.StatusBadRequest, "GET", MetricQiwiSubExt)
-
-		return
-	}
-
-	// Создаем счет на оплату и получаем ссылку на него
-	result, err := h.qiwiService.BillRequest(ctx, sub, user.Username)
+	// Создаем счет на оплату плана из path (со скидкой по промокоду из query) и получаем ссылку на него
+	result, err := h.qiwiService.BillRequest(ctx, ctx.Param("subTime"), user.Username, ctx.Query("promo"))
 	if err != nil {
-		InternalErrResp(ctx, l, err)
-
-		Bridge(ctx, http.StatusInternalServerError, "GET", MetricQiwiSubExt)
+		billErrResp(ctx, l, err, MetricQiwiSubExt)
 
 		return
 	}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redeemRequest Структура запроса
+type redeemRequest struct {
+	Code	string	`json:"code" binding:"required,lte=64"`
+}
+
+// Redeem Оформляет пробную подписку по промокоду
+func (h *PayHandler) Redeem(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "RedeemHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("RedeemHandler() started")
+	defer l.Debug("RedeemHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req redeemRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricRedeem); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricRedeem)
+
+		return
+	}
+
+	promo, err := h.qiwiService.Redeem(ctx, user.Username, req.Code)
+	if err != nil {
+		if PromoErrResp(ctx, err, "POST", MetricRedeem) {
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricRedeem)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"days": promo.Days,
+	})
+
+	Bridge(ctx, http.StatusOK, "POST", MetricRedeem)
+
+	return
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promoService Интерфейс к сервису промокодов
+type promoService interface {
+	CreatePromo(ctx context.Context, admin models.JWTUserInfo, p models.PromoDB) (models.PromoDB, error)
+	Promos(ctx context.Context) ([]models.PromoDB, error)
+	DeletePromo(ctx context.Context, admin models.JWTUserInfo, code string) error
+}
+
+// PromoHandlerConfig Конфигурация для PromoHandler
+type PromoHandlerConfig struct {
+	Router			*gin.Engine
+	PromoService	promoService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// PromoHandler Для регистрации "ручек" управления промокодами
+type PromoHandler struct {
+	promoService	promoService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// RegisterPromoHandler Фабрика для PromoHandler
+func RegisterPromoHandler(c *PromoHandlerConfig) {
+	promoHandler := PromoHandler{
+		promoService:	c.PromoService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1/admin", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.AdminOnly)
+	g.POST("/promo", promoHandler.CreatePromo)
+	g.GET("/promo", promoHandler.Promos)
+	g.DELETE("/promo/:code", promoHandler.DeletePromo)
+}
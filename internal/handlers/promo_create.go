@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createPromoRequest Структура запроса
+type createPromoRequest struct {
+	Code		string		`json:"code" binding:"required,alphanum,gte=3,lte=32"`
+	Kind		string		`json:"kind" binding:"required,oneof=percent fixed trial"`
+	Value		float64		`json:"value" binding:"gte=0"`
+	Days		int			`json:"days" binding:"gte=0,lte=3650"`
+	Plan		string		`json:"plan"`
+	MaxUses		int			`json:"max_uses" binding:"gte=0"`
+	ExpiresAt	*time.Time	`json:"expires_at"`
+}
+
+// CreatePromo Создает промокод
+func (h *PromoHandler) CreatePromo(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminPromoCreateHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminPromoCreateHandler() started")
+	defer l.Debug("AdminPromoCreateHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req createPromoRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricAdminPromoCreate); !ok {
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminPromoCreate)
+
+		return
+	}
+
+	promo, err := h.promoService.CreatePromo(ctx, admin, models.PromoDB{
+		Code:		req.Code,
+		Kind:		req.Kind,
+		Value:		req.Value,
+		Days:		req.Days,
+		Plan:		req.Plan,
+		MaxUses:	req.MaxUses,
+		ExpiresAt:	req.ExpiresAt,
+	})
+	if err != nil {
+		if PromoErrResp(ctx, err, "POST", MetricAdminPromoCreate) {
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminPromoCreate)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, promo)
+
+	Bridge(ctx, http.StatusCreated, "POST", MetricAdminPromoCreate)
+
+	return
+}
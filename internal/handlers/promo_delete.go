@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeletePromo Удаляет промокод
+func (h *PromoHandler) DeletePromo(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminPromoDeleteHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminPromoDeleteHandler() started")
+	defer l.Debug("AdminPromoDeleteHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "DELETE", MetricAdminPromoDelete)
+
+		return
+	}
+
+	err = h.promoService.DeletePromo(ctx, admin, ctx.Param("code"))
+	if err != nil {
+		if PromoErrResp(ctx, err, "DELETE", MetricAdminPromoDelete) {
+			return
+		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "DELETE", MetricAdminPromoDelete)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "DELETE", MetricAdminPromoDelete)
+
+	return
+}
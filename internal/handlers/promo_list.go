@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Promos Отдает список промокодов
+func (h *PromoHandler) Promos(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminPromosHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminPromosHandler() started")
+	defer l.Debug("AdminPromosHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	promos, err := h.promoService.Promos(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAdminPromos)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": promos,
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminPromos)
+
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"short_url/internal/handlers/middlewares"
 	"short_url/internal/models"
+	"short_url/internal/ratelimit"
 	myLog "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -13,10 +14,11 @@ import (
 // linkService Интерфейс к сервису, осуществляющему управление пользователя ссылками
 type linkService interface {
 	FindLink(ctx context.Context, link string) (models.LinkDataDTO, error)
+	GetLink(ctx context.Context, user models.JWTUserInfo, link string) (models.LinkDataDTO, error)
 	GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDTO, error)
-	DeleteLink(ctx context.Context, username, link string) error
+	DeleteLink(ctx context.Context, user models.JWTUserInfo, link string) error
 	CreateLink(ctx context.Context, fullUrl, custom string, exp int, user models.JWTUserInfo) (models.LinkDataDTO, error)
-	CreateQR(ctx context.Context, url, link string) (*bytes.Buffer, error)
+	CreateQR(ctx context.Context, user models.JWTUserInfo, url, link string) (*bytes.Buffer, error)
 }
 
 // LinkHandlerConfig Конфигурация для LinkHandler
@@ -50,10 +52,10 @@ func RegisterLinkHandler(c *LinkHandlerConfig) {
 	}
 
 	g := c.Router.Group("v1")
-	g.POST("/newlink", c.Middleware.Recorder, c.Middleware.AuthUser, linkHandler.CreateLink)
-	g.DELETE("/links/:link", c.Middleware.Recorder, c.Middleware.AuthUser, linkHandler.DeleteLink)
-	g.GET("/links", c.Middleware.Recorder, c.Middleware.AuthUser, linkHandler.GetAllLinks)
-	g.GET("/:link", c.Middleware.Recorder, linkHandler.LinkRedirect)
-	g.GET("/links/qr/:link", c.Middleware.Recorder, c.Middleware.AuthUser, linkHandler.CreateCode)
-	g.GET("/links/:link", c.Middleware.Recorder, c.Middleware.AuthUser, linkHandler.GetLink)
+	g.POST("/newlink", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupLink), linkHandler.CreateLink)
+	g.DELETE("/links/:link", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), linkHandler.DeleteLink)
+	g.GET("/links", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), linkHandler.GetAllLinks)
+	g.GET("/:link", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupRedirect), linkHandler.LinkRedirect)
+	g.GET("/links/qr/:link", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), linkHandler.CreateCode)
+	g.GET("/links/:link", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI), linkHandler.GetLink)
 }
@@ -35,6 +35,8 @@ func (h *LinkHandler) CreateLink(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
+
+		return
 	}
 
 	var req createLinkRequest
@@ -68,7 +70,17 @@ func (h *LinkHandler) CreateLink(ctx *gin.Context) {
 			return
 		}
 
-		if err.Error() != "limit exceeded" {
+		if err.Error() == "link already exists" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "link already exists",
+			})
+
+			Bridge(ctx, http.StatusConflict, "POST", MetricCreateLink)
+
+			return
+		}
+
+		if err.Error() == "limit exceeded" {
 			ctx.JSON(http.StatusForbidden, gin.H{
 				"error": "limit exceeded: maximum links",
 			})
@@ -6,7 +6,6 @@ import (
 	log "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v9"
 )
 
 // CreateCode Создает QR-код из ссылки
@@ -21,6 +20,18 @@ func (h *LinkHandler) CreateCode(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricCreateQR)
+
+		return
 	}
 
 	// Получаем короткую ссылку
@@ -29,24 +40,12 @@ func (h *LinkHandler) CreateCode(ctx *gin.Context) {
 	// Конструируем веб-ссылку
 	url := ctx.Request.URL.Scheme + "//" + ctx.Request.Host + "/" + link
 
-	// Создаем QR-код
-	byteQrCode, err := h.linkService.CreateQR(ctx, url, link)
+	// Создаем QR-код, проверяя права пользователя на ссылку
+	byteQrCode, err := h.linkService.CreateQR(ctx, user, url, link)
 	if err != nil {
-		if err != redis.Nil {
-			InternalErrResp(ctx, l, err)
-
-			Bridge(ctx, http.StatusInternalServerError, "GET", MetricCreateQR)
-
-			return
-		} else {
-			ctx.JSON(http.StatusNotFound, gin.H{
-				"error": "link not found",
-			})
-
-			Bridge(ctx, http.StatusNotFound, "GET", MetricCreateQR)
+		LinkErrResp(ctx, l, err, "GET", MetricCreateQR)
 
-			return
-		}
+		return
 	}
 
 	result := byteQrCode.Bytes()
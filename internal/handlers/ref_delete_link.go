@@ -6,7 +6,6 @@ import (
 	log "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v9"
 )
 
 // DeleteLink Удаляет короткую ссылку
@@ -21,6 +20,8 @@ func (h *LinkHandler) DeleteLink(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
+
+		return
 	}
 
 	// Получаем информацию о пользователе
@@ -37,23 +38,11 @@ func (h *LinkHandler) DeleteLink(ctx *gin.Context) {
 	link := getLinkFromParam(ctx)
 
 	// Удаляем ссылку
-	err = h.linkService.DeleteLink(ctx, user.Username, link)
+	err = h.linkService.DeleteLink(ctx, user, link)
 	if err != nil {
-		if err != redis.Nil {
-			InternalErrResp(ctx, l ,err)
-
-			Bridge(ctx, http.StatusInternalServerError, "DELETE", MetricDeleteLink)
+		LinkErrResp(ctx, l, err, "DELETE", MetricDeleteLink)
 
-			return
-		} else {
-			ctx.JSON(http.StatusNotFound, gin.H{
-				"error": "link not found",
-			})
-
-			Bridge(ctx, http.StatusNotFound, "DELETE", MetricDeleteLink)
-
-			return
-		}
+		return
 	}
 
 	ctx.JSON(http.StatusOK, "OK")
@@ -12,9 +12,11 @@ import (
 
 // LinkData Структура данных для одной ссылки
 type LinkData struct {
-	Short   string	`json:"short"`
-	Full    string	`json:"full"`
-	ExpTime string	`json:"time"`
+	Short			string	`json:"short"`
+	Full			string	`json:"full"`
+	ExpTime			string	`json:"time"`
+	Disabled		bool	`json:"disabled"`
+	DisabledReason	string	`json:"disabled_reason,omitempty"`
 }
 
 // getAllLinksResponse Ответ на запрос
@@ -34,6 +36,8 @@ func (h *LinkHandler) GetAllLinks(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
+
+		return
 	}
 
 	// Получаем информацию о пользователе
@@ -75,6 +79,8 @@ func (h *LinkHandler) GetAllLinks(ctx *gin.Context) {
 			Short:   l.Link,
 			Full:    l.FullURL,
 			ExpTime: fmt.Sprint(l.ExpTime),
+			Disabled: l.Disabled,
+			DisabledReason: l.DisabledReason,
 		}
 		resp.Data[k] = linkData
 	}
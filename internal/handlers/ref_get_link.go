@@ -7,14 +7,15 @@ import (
 	log "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v9"
 )
 
 // getLinkResponse Ответ на запрос
 type getLinkResponse struct {
-	Short   string	`json:"short"`
-	Full    string	`json:"full"`
-	ExpTime string	`json:"time"`
+	Short			string	`json:"short"`
+	Full			string	`json:"full"`
+	ExpTime			string	`json:"time"`
+	Disabled		bool	`json:"disabled"`
+	DisabledReason	string	`json:"disabled_reason,omitempty"`
 }
 
 // GetLink Отдает ссылку и информацию о ней
@@ -29,29 +30,29 @@ func (h *LinkHandler) GetLink(ctx *gin.Context) {
 	_, ok := ctx.Get(middlewares.Skip) 
 	if ok {
 		ctx.Next()
-	}
 
-	// Получаем короткую ссылку из path
-	link := getLinkFromParam(ctx)
+		return
+	}
 
-	// Ищем данные связанные с этой ссылкой, проверяем валидность
-	data, err := h.linkService.FindLink(ctx, link)
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
 	if err != nil {
-		if err != redis.Nil {
-			InternalErrResp(ctx, l, err)
+		InternalErrResp(ctx, l, err)
 
-			Bridge(ctx, http.StatusInternalServerError, "GET", MetricGetLink)
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricGetLink)
 
-			return
-		} else {
-			ctx.JSON(http.StatusNotFound, gin.H{
-				"error": "link not found",
-			})
+		return
+	}
 
-			Bridge(ctx, http.StatusNotFound, "GET", MetricGetLink)
+	// Получаем короткую ссылку из path
+	link := getLinkFromParam(ctx)
+
+	// Ищем данные связанные с этой ссылкой, проверяем права пользователя
+	data, err := h.linkService.GetLink(ctx, user, link)
+	if err != nil {
+		LinkErrResp(ctx, l, err, "GET", MetricGetLink)
 
-			return
-		}
+		return
 	}
 
 	// Маппим данные в ответ
@@ -59,6 +60,8 @@ func (h *LinkHandler) GetLink(ctx *gin.Context) {
 		Short:   data.Link,
 		Full:    data.FullURL,
 		ExpTime: fmt.Sprint(data.ExpTime),
+		Disabled: data.Disabled,
+		DisabledReason: data.DisabledReason,
 	}
 
 	ctx.JSON(http.StatusOK, resp)
@@ -1,12 +1,31 @@
 package handlers
 
 import (
+	"bytes"
+	"html/template"
 	"net/http"
 	log "short_url/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// disabledPage Страница-предупреждение для заблокированных ссылок
+var disabledPage = template.Must(template.New("disabled").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Ссылка заблокирована</title></head>
+<body>
+<h1>Ссылка заблокирована</h1>
+<p>Короткая ссылка <b>{{.Link}}</b> заблокирована администрацией сервиса и не ведет на исходный адрес.</p>
+{{if .Reason}}<p>Причина: {{.Reason}}</p>{{end}}
+</body>
+</html>`))
+
+// disabledPageData Данные для страницы-предупреждения
+type disabledPageData struct {
+	Link	string
+	Reason	string
+}
+
 // LinkRedirect Выполняет переадресацию на источник при переходе на короткую ссылку
 func (h *LinkHandler) LinkRedirect(ctx *gin.Context) {
 	ctxLog := log.ContextWithSpan(ctx, "LinkRedirectHandler")
@@ -21,13 +40,7 @@ func (h *LinkHandler) LinkRedirect(ctx *gin.Context) {
 	// Ищем данные связанные с этой ссылкой, проверяем валидность
 	data, err := h.linkService.FindLink(ctx, link)
 	if err != nil {
-		if err.Error() != "not found" {
-			InternalErrResp(ctx, l, err)
-
-			Bridge(ctx, http.StatusInternalServerError, "GET", MetricRedirectLink)
-
-			return
-		} else {
+		if err.Error() == "link not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{
 				"error": "link not found",
 			})
@@ -36,12 +49,37 @@ func (h *LinkHandler) LinkRedirect(ctx *gin.Context) {
 
 			return
 		}
+
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricRedirectLink)
+
+		return
+	}
+
+	// Вместо переадресации по заблокированной ссылке показываем предупреждение
+	if data.Disabled {
+		page := bytes.NewBuffer([]byte{})
+		err = disabledPage.Execute(page, disabledPageData{Link: data.Link, Reason: data.DisabledReason})
+		if err != nil {
+			InternalErrResp(ctx, l, err)
+
+			Bridge(ctx, http.StatusInternalServerError, "GET", MetricRedirectLink)
+
+			return
+		}
+
+		ctx.Data(http.StatusForbidden, "text/html; charset=utf-8", page.Bytes())
+
+		Bridge(ctx, http.StatusForbidden, "GET", MetricRedirectLink)
+
+		return
 	}
 
 	// Переадресовываем пользователя на источник
-	ctx.Redirect(http.StatusOK, data.FullURL)
+	ctx.Redirect(http.StatusFound, data.FullURL)
 
-	Bridge(ctx, http.StatusOK, "GET", MetricRedirectLink)
+	Bridge(ctx, http.StatusFound, "GET", MetricRedirectLink)
 
 	return
 }
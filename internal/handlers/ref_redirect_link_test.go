@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/services"
+	log "short_url/pkg/logger"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v9"
+	"go.uber.org/zap"
+)
+
+// benchLinkRepo Хранилище ссылок в памяти (только чтение) для бенчмарков переадресации
+type benchLinkRepo struct {
+	links	map[string]models.LinkDataDB
+}
+
+func (r *benchLinkRepo) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error) {
+	return models.LinkDataDB{}, errors.New("read only")
+}
+
+func (r *benchLinkRepo) DeleteLink(ctx context.Context, link, username string) error {
+	return errors.New("read only")
+}
+
+func (r *benchLinkRepo) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	data, ok := r.links[link]
+	if !ok {
+		return models.LinkDataDB{}, redis.Nil
+	}
+	return data, nil
+}
+
+func (r *benchLinkRepo) CountLinks(ctx context.Context, username string) (models.LinksAmount, error) {
+	return models.LinksAmount{}, nil
+}
+
+func (r *benchLinkRepo) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+	return nil, nil
+}
+
+func (r *benchLinkRepo) DisableLink(ctx context.Context, link, reason string) error {
+	return errors.New("read only")
+}
+
+func (r *benchLinkRepo) EnableLink(ctx context.Context, link string) error {
+	return errors.New("read only")
+}
+
+func (r *benchLinkRepo) KeepLinks(ctx context.Context, username string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *benchLinkRepo) SetKeepLinks(ctx context.Context, username string, links []string) error {
+	return errors.New("read only")
+}
+
+// newBenchRouter Роутер с обработчиками ссылок, сервисом ссылок и хранилищем в памяти
+func newBenchRouter(n int) (*gin.Engine, []string) {
+	gin.SetMode(gin.ReleaseMode)
+	logger := &log.Log{Logger: zap.NewNop()}
+
+	repo := &benchLinkRepo{links: make(map[string]models.LinkDataDB, n)}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = "l" + strconv.Itoa(i)
+		repo.links[names[i]] = models.LinkDataDB{Link: names[i], FullURL: "https://example.com/" + names[i], ExpTime: time.Hour}
+	}
+	repo.links["blocked"] = models.LinkDataDB{Link: "blocked", FullURL: "https://example.com", Disabled: true, DisabledReason: "spam"}
+
+	router := gin.New()
+	RegisterLinkHandler(&LinkHandlerConfig{
+		Router:			router,
+		LinkService:	services.NewLinkService(&services.LinkServiceConfig{LinkRepo: repo, Logger: logger}),
+		Middleware:		middlewares.NewMiddlewares(logger, nil, nil, nil),
+		Logger:			logger,
+	})
+
+	return router, names
+}
+
+func TestLinkRedirect(t *testing.T) {
+	router, names := newBenchRouter(1)
+
+	for path, code := range map[string]int{"/v1/" + names[0]: http.StatusFound, "/v1/missing": http.StatusNotFound, "/v1/blocked": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestLinkHandlersUnauthorized(t *testing.T) {
+	router, _ := newBenchRouter(1)
+
+	requests := [][2]string{
+		{http.MethodPost, "/v1/newlink"},
+		{http.MethodGet, "/v1/links"},
+		{http.MethodGet, "/v1/links/l0"},
+		{http.MethodDelete, "/v1/links/l0"},
+		{http.MethodGet, "/v1/links/qr/l0"},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(req[0], req[1], nil))
+
+		// Обработчик пропускается: в ответе только ошибка авторизации
+		if body := w.Body.String(); strings.Count(body, "error") != 1 {
+			t.Fatalf("%s %s: expected single error body, got %s", req[0], req[1], body)
+		}
+	}
+}
+
+func BenchmarkLinkRedirect(b *testing.B) {
+	router, names := newBenchRouter(1000)
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = "/v1/" + name
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, paths[i%len(paths)], nil))
+			if w.Code != http.StatusFound {
+				b.Fatalf("expected redirect, got %d", w.Code)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkLinkRedirectNotFound(b *testing.B) {
+	router, _ := newBenchRouter(1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/missing", nil))
+		if w.Code != http.StatusNotFound {
+			b.Fatalf("expected not found, got %d", w.Code)
+		}
+	}
+}
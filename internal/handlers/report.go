@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportService Интерфейс к сервису жалоб на ссылки
+type reportService interface {
+	ReportLink(ctx context.Context, link, reason, reporter string) (int64, error)
+	Reports(ctx context.Context) ([]models.ReportDB, error)
+	ResolveReport(ctx context.Context, admin models.JWTUserInfo, id int64, action, reason string) error
+}
+
+// ReportHandlerConfig Конфигурация для ReportHandler
+type ReportHandlerConfig struct {
+	Router			*gin.Engine
+	ReportService	reportService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// ReportHandler Для регистрации "ручек" жалоб и модерации
+type ReportHandler struct {
+	reportService	reportService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// RegisterReportHandler Фабрика для ReportHandler
+func RegisterReportHandler(c *ReportHandlerConfig) {
+	reportHandler := ReportHandler{
+		reportService:	c.ReportService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	// Публичная форма жалобы
+	c.Router.POST("/report/:link", c.Middleware.Recorder, c.Middleware.RateLimit(ratelimit.GroupAPI), reportHandler.ReportLink)
+
+	// Очередь модерации
+	g := c.Router.Group("v1/admin", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.AdminOnly)
+	g.GET("/reports", reportHandler.Reports)
+	g.POST("/reports/:id/resolve", reportHandler.ResolveReport)
+}
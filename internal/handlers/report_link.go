@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportLinkRequest Структура запроса
+type reportLinkRequest struct {
+	Reason	string	`json:"reason" binding:"required,lte=1000"`
+}
+
+// reportLinkResponse Структура ответа
+type reportLinkResponse struct {
+	ID	int64	`json:"id"`
+}
+
+// ReportLink Принимает жалобу на ссылку от любого посетителя
+func (h *ReportHandler) ReportLink(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "ReportLinkHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("ReportLinkHandler() started")
+	defer l.Debug("ReportLinkHandler() done")
+
+	var req reportLinkRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricReportLink); !ok {
+		return
+	}
+
+	// Сохраняем жалобу
+	id, err := h.reportService.ReportLink(ctx, getLinkFromParam(ctx), req.Reason, ctx.ClientIP())
+	if err != nil {
+		LinkErrResp(ctx, l, err, "POST", MetricReportLink)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reportLinkResponse{
+		ID: id,
+	})
+
+	Bridge(ctx, http.StatusCreated, "POST", MetricReportLink)
+
+	return
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportData Структура данных о жалобе
+type ReportData struct {
+	ID			int64	`json:"id"`
+	Link		string	`json:"link"`
+	Reason		string	`json:"reason"`
+	Reporter	string	`json:"reporter"`
+	CreatedAt	string	`json:"created_at"`
+}
+
+// reportsResponse Ответ на запрос
+type reportsResponse struct {
+	Data []ReportData	`json:"data"`
+}
+
+// Reports Отдает очередь модерации
+func (h *ReportHandler) Reports(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminReportsHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminReportsHandler() started")
+	defer l.Debug("AdminReportsHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	reports, err := h.reportService.Reports(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricAdminReports)
+
+		return
+	}
+
+	// Маппим данные в ответ
+	resp := reportsResponse{
+		Data: make([]ReportData, len(reports)),
+	}
+	for k, r := range reports {
+		resp.Data[k] = ReportData{
+			ID:			r.ID,
+			Link:		r.Link,
+			Reason:		r.Reason,
+			Reporter:	r.Reporter,
+			CreatedAt:	r.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricAdminReports)
+
+	return
+}
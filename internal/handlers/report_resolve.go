@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveReportRequest Структура запроса
+type resolveReportRequest struct {
+	Action	string	`json:"action" binding:"required,oneof=disable dismiss"`
+	Reason	string	`json:"reason"`	// Причина блокировки для посетителей и владельца (обязательна для disable)
+}
+
+// ResolveReport Принимает решение по жалобе
+func (h *ReportHandler) ResolveReport(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "AdminResolveReportHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("AdminResolveReportHandler() started")
+	defer l.Debug("AdminResolveReportHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req resolveReportRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricAdminResolve); !ok {
+		return
+	}
+
+	// Получаем номер жалобы из path
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid report id",
+		})
+
+		Bridge(ctx, http.StatusBadRequest, "POST", MetricAdminResolve)
+
+		return
+	}
+
+	// Получаем информацию об администраторе
+	admin, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricAdminResolve)
+
+		return
+	}
+
+	// Выносим решение
+	err = h.reportService.ResolveReport(ctx, admin, id, req.Action, req.Reason)
+	if err != nil {
+		switch err.Error() {
+		case "report not found":
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "report not found",
+			})
+
+			Bridge(ctx, http.StatusNotFound, "POST", MetricAdminResolve)
+		case "reason required":
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "reason required",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "POST", MetricAdminResolve)
+		case "report already resolved":
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "report already resolved",
+			})
+
+			Bridge(ctx, http.StatusConflict, "POST", MetricAdminResolve)
+		default:
+			LinkErrResp(ctx, l, err, "POST", MetricAdminResolve)
+		}
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "OK")
+
+	Bridge(ctx, http.StatusOK, "POST", MetricAdminResolve)
+
+	return
+}
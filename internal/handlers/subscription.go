@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subscriptionService Интерфейс к сервису состояния подписки
+type subscriptionService interface {
+	Subscription(ctx context.Context, username string) (models.SubscriptionDTO, error)
+	Bills(ctx context.Context, username string) ([]models.BillDB, error)
+	SetKeepLinks(ctx context.Context, username string, links []string) error
+}
+
+// SubscriptionHandlerConfig Конфигурация для SubscriptionHandler
+type SubscriptionHandlerConfig struct {
+	Router				*gin.Engine
+	SubscriptionService	subscriptionService
+	Middleware			*middlewares.Middlewares
+	Logger				*myLog.Log
+}
+
+// SubscriptionHandler Для регистрации "ручек" подписки и истории счетов
+type SubscriptionHandler struct {
+	subscriptionService	subscriptionService
+	middleware			*middlewares.Middlewares
+	logger				*myLog.Log
+}
+
+// RegisterSubscriptionHandler Фабрика для SubscriptionHandler
+func RegisterSubscriptionHandler(c *SubscriptionHandlerConfig) {
+	subscriptionHandler := SubscriptionHandler{
+		subscriptionService:	c.SubscriptionService,
+		middleware:				c.Middleware,
+		logger:					c.Logger,
+	}
+
+	g := c.Router.Group("v1", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI))
+	g.GET("/subscription", subscriptionHandler.Subscription)
+	g.GET("/billing", subscriptionHandler.Billing)
+	g.PUT("/subscription/keep", subscriptionHandler.KeepLinks)
+}
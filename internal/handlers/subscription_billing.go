@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Billing Отдает историю счетов пользователя с суммами и статусами
+func (h *SubscriptionHandler) Billing(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "BillingHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("BillingHandler() started")
+	defer l.Debug("BillingHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricBilling)
+
+		return
+	}
+
+	bills, err := h.subscriptionService.Bills(ctx, user.Username)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricBilling)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": bills,
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricBilling)
+
+	return
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keepLinksRequest Структура запроса
+type keepLinksRequest struct {
+	Links	[]string	`json:"links" binding:"lte=1000,dive,required"`
+}
+
+// KeepLinks Сохраняет ссылки, которые останутся у пользователя после окончания подписки
+func (h *SubscriptionHandler) KeepLinks(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "KeepLinksHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("KeepLinksHandler() started")
+	defer l.Debug("KeepLinksHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req keepLinksRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "PUT", MetricKeepLinks); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "PUT", MetricKeepLinks)
+
+		return
+	}
+
+	err = h.subscriptionService.SetKeepLinks(ctx, user.Username, req.Links)
+	if err != nil {
+		if err.Error() == "limit exceeded" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "links exceed free plan limits",
+			})
+
+			Bridge(ctx, http.StatusBadRequest, "PUT", MetricKeepLinks)
+
+			return
+		}
+
+		LinkErrResp(ctx, l, err, "PUT", MetricKeepLinks)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+	Bridge(ctx, http.StatusNoContent, "PUT", MetricKeepLinks)
+
+	return
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Subscription Отдает план и сроки подписки пользователя, а также последствия ее окончания
+func (h *SubscriptionHandler) Subscription(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "SubscriptionHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("SubscriptionHandler() started")
+	defer l.Debug("SubscriptionHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricSubscription)
+
+		return
+	}
+
+	sub, err := h.subscriptionService.Subscription(ctx, user.Username)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricSubscription)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sub)
+
+	Bridge(ctx, http.StatusOK, "GET", MetricSubscription)
+
+	return
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"short_url/internal/handlers/middlewares"
+	"short_url/internal/models"
+	"short_url/internal/ratelimit"
+	myLog "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookService Интерфейс к сервису вебхуков
+type webhookService interface {
+	CreateWebhook(ctx context.Context, username, url string, events []string) (models.WebhookDB, error)
+	Webhooks(ctx context.Context, username string) ([]models.WebhookDB, error)
+	DeleteWebhook(ctx context.Context, username string, id int64) error
+	Deliveries(ctx context.Context, username string) ([]models.DeliveryDB, error)
+	Redeliver(ctx context.Context, username string, id int64) error
+}
+
+// WebhookHandlerConfig Конфигурация для WebhookHandler
+type WebhookHandlerConfig struct {
+	Router			*gin.Engine
+	WebhookService	webhookService
+	Middleware		*middlewares.Middlewares
+	Logger			*myLog.Log
+}
+
+// WebhookHandler Для регистрации "ручек" вебхуков пользователя
+type WebhookHandler struct {
+	webhookService	webhookService
+	middleware		*middlewares.Middlewares
+	logger			*myLog.Log
+}
+
+// RegisterWebhookHandler Фабрика для WebhookHandler
+func RegisterWebhookHandler(c *WebhookHandlerConfig) {
+	webhookHandler := WebhookHandler{
+		webhookService:	c.WebhookService,
+		middleware:		c.Middleware,
+		logger:			c.Logger,
+	}
+
+	g := c.Router.Group("v1", c.Middleware.Recorder, c.Middleware.AuthUser, c.Middleware.RateLimit(ratelimit.GroupAPI))
+	g.POST("/webhooks", webhookHandler.CreateWebhook)
+	g.GET("/webhooks", webhookHandler.Webhooks)
+	g.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	g.GET("/webhooks/deliveries", webhookHandler.Deliveries)
+	g.POST("/webhooks/deliveries/:id/redeliver", webhookHandler.Redeliver)
+}
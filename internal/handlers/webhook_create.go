@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createWebhookRequest Структура запроса
+type createWebhookRequest struct {
+	URL		string		`json:"url" binding:"required,url,max=2048"`
+	Events	[]string	`json:"events" binding:"lte=20"`
+}
+
+// CreateWebhook Регистрирует адрес для событий пользователя и отдает ключ подписи (показывается один раз)
+func (h *WebhookHandler) CreateWebhook(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "CreateWebhookHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("CreateWebhookHandler() started")
+	defer l.Debug("CreateWebhookHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	var req createWebhookRequest
+
+	// Если данные не прошли валидацию, то просто выходим из "ручки", т.к. в bindData уже записана ошибка
+	// через ctx.JSON...
+	if ok := bindData(ctx, l, &req, "POST", MetricCreateWebhook); !ok {
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricCreateWebhook)
+
+		return
+	}
+
+	hook, err := h.webhookService.CreateWebhook(ctx, user.Username, req.URL, req.Events)
+	if err != nil {
+		WebhookErrResp(ctx, l, err, "POST", MetricCreateWebhook)
+
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data": hook,
+	})
+
+	Bridge(ctx, http.StatusCreated, "POST", MetricCreateWebhook)
+
+	return
+}
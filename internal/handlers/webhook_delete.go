@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteWebhook Удаляет вебхук пользователя вместе с журналом доставок
+func (h *WebhookHandler) DeleteWebhook(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "DeleteWebhookHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("DeleteWebhookHandler() started")
+	defer l.Debug("DeleteWebhookHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем номер вебхука из path
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid webhook id",
+		})
+
+		Bridge(ctx, http.StatusBadRequest, "DELETE", MetricDeleteWebhook)
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "DELETE", MetricDeleteWebhook)
+
+		return
+	}
+
+	err = h.webhookService.DeleteWebhook(ctx, user.Username, id)
+	if err != nil {
+		WebhookErrResp(ctx, l, err, "DELETE", MetricDeleteWebhook)
+
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+
+	Bridge(ctx, http.StatusNoContent, "DELETE", MetricDeleteWebhook)
+
+	return
+}
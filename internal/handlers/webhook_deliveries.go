@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deliveries Отдает журнал доставок событий на вебхуки пользователя
+func (h *WebhookHandler) Deliveries(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "DeliveriesHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("DeliveriesHandler() started")
+	defer l.Debug("DeliveriesHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricDeliveries)
+
+		return
+	}
+
+	deliveries, err := h.webhookService.Deliveries(ctx, user.Username)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricDeliveries)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricDeliveries)
+
+	return
+}
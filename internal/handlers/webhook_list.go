@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhooks Отдает вебхуки пользователя
+func (h *WebhookHandler) Webhooks(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "WebhooksHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("WebhooksHandler() started")
+	defer l.Debug("WebhooksHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricWebhooks)
+
+		return
+	}
+
+	hooks, err := h.webhookService.Webhooks(ctx, user.Username)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "GET", MetricWebhooks)
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data": hooks,
+	})
+
+	Bridge(ctx, http.StatusOK, "GET", MetricWebhooks)
+
+	return
+}
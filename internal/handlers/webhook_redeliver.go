@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"short_url/internal/handlers/middlewares"
+	log "short_url/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Redeliver Ставит доставку события в очередь повторно
+func (h *WebhookHandler) Redeliver(ctx *gin.Context) {
+	ctxLog := log.ContextWithSpan(ctx, "RedeliverHandler")
+	l := h.logger.WithContext(ctxLog)
+
+	l.Debug("RedeliverHandler() started")
+	defer l.Debug("RedeliverHandler() done")
+
+	// Если был получен сигнал пропускаем ручку для обработки метрик
+	if _, ok := ctx.Get(middlewares.Skip); ok {
+		ctx.Next()
+
+		return
+	}
+
+	// Получаем номер доставки из path
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid delivery id",
+		})
+
+		Bridge(ctx, http.StatusBadRequest, "POST", MetricRedeliver)
+
+		return
+	}
+
+	// Получаем информацию о пользователе
+	user, err := GetUserInfo(ctx)
+	if err != nil {
+		InternalErrResp(ctx, l, err)
+
+		Bridge(ctx, http.StatusInternalServerError, "POST", MetricRedeliver)
+
+		return
+	}
+
+	err = h.webhookService.Redeliver(ctx, user.Username, id)
+	if err != nil {
+		WebhookErrResp(ctx, l, err, "POST", MetricRedeliver)
+
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, "OK")
+
+	Bridge(ctx, http.StatusAccepted, "POST", MetricRedeliver)
+
+	return
+}
@@ -0,0 +1,224 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	log "short_url/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// renewScript Продлевает аренду, только если она принадлежит экземпляру.
+// KEYS[1] - ключ аренды, ARGV: идентификатор экземпляра, срок аренды (мс)
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript Снимает аренду, только если она принадлежит экземпляру.
+// KEYS[1] - ключ аренды, ARGV: идентификатор экземпляра
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Config Конфиг для Elector
+type Config struct {
+	DB		*redis.Client	// nil - единственный экземпляр без Redis, всегда ведущий
+	Key		string			// Ключ аренды в Redis
+	ID		string			// Идентификатор экземпляра (пусто - имя хоста и pid)
+	TTL		time.Duration	// Срок аренды: столько ждут остальные экземпляры, если ведущий пропал
+	Renew	time.Duration	// Как часто продлевать аренду (должно быть меньше TTL)
+	Logger	*log.Log
+}
+
+// Elector Выбирает ведущий экземпляр сервиса через аренду ключа в Redis.
+// Периодические задачи выполняются только на ведущем, при его остановке или потере связи
+// с Redis аренду по истечении срока забирает другой экземпляр
+type Elector struct {
+	db			*redis.Client
+	key			string
+	id			string
+	ttl			time.Duration
+	renew		time.Duration
+	leader		bool
+	mux			sync.RWMutex
+	Status		*prometheus.GaugeVec
+	Transitions	*prometheus.CounterVec
+	logger		*log.Log
+}
+
+// NewElector Конструктор для Elector
+func NewElector(c *Config) *Elector {
+	id := c.ID
+	if id == "" {
+		host, _ := os.Hostname()
+		id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	renew := c.Renew
+	if renew <= 0 || renew >= c.TTL {
+		renew = c.TTL / 3
+	}
+
+	e := &Elector{
+		db:		c.DB,
+		key:	c.Key,
+		id:		id,
+		ttl:	c.TTL,
+		renew:	renew,
+		Status: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "leader_status",
+				Help: "Является ли экземпляр ведущим (1 - да)",
+			},
+			[]string{"instance"},
+		),
+		Transitions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "leader_transitions_total",
+				Help: "Кол-во получений и потерь аренды ведущего",
+			},
+			[]string{"instance", "state"},
+		),
+		logger:	c.Logger,
+	}
+	e.Status.WithLabelValues(id).Set(0)
+
+	return e
+}
+
+// Collectors Возвращает метрики для регистрации
+func (e *Elector) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{e.Status, e.Transitions}
+}
+
+// ID Возвращает идентификатор экземпляра
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader Является ли экземпляр ведущим
+func (e *Elector) IsLeader() bool {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	return e.leader
+}
+
+// setLeader Меняет состояние экземпляра и метрики
+func (e *Elector) setLeader(ctx context.Context, leader bool) {
+	e.mux.Lock()
+	changed := e.leader != leader
+	e.leader = leader
+	e.mux.Unlock()
+
+	if !changed {
+		return
+	}
+
+	l := e.logger.WithContext(ctx)
+	if leader {
+		l.Infof("Instance %s became leader", e.id)
+		e.Status.WithLabelValues(e.id).Set(1)
+		e.Transitions.WithLabelValues(e.id, "acquired").Inc()
+	} else {
+		l.Warnf("Instance %s lost leadership", e.id)
+		e.Status.WithLabelValues(e.id).Set(0)
+		e.Transitions.WithLabelValues(e.id, "lost").Inc()
+	}
+}
+
+// Campaign Продлевает аренду ведущего или пытается ее получить. При ошибке Redis экземпляр
+// перестает считаться ведущим: продлить аренду он не может, и ее заберет другой
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	var (
+		ok	bool
+		err	error
+	)
+
+	switch {
+	case e.db == nil:
+		ok = true
+	case e.IsLeader():
+		var n int64
+		n, err = renewScript.Run(ctx, e.db, []string{e.key}, e.id, e.ttl.Milliseconds()).Int64()
+		ok = n == 1
+	default:
+		ok, err = e.db.SetNX(ctx, e.key, e.id, e.ttl).Result()
+	}
+	if err != nil {
+		ok = false
+	}
+
+	e.setLeader(ctx, ok)
+
+	return ok, err
+}
+
+// Resign Отдает аренду, чтобы другой экземпляр стал ведущим без ожидания ее истечения
+func (e *Elector) Resign(ctx context.Context) error {
+	if !e.IsLeader() {
+		return nil
+	}
+	if e.db == nil {
+		e.setLeader(ctx, false)
+		return nil
+	}
+
+	_, err := releaseScript.Run(ctx, e.db, []string{e.key}, e.id).Result()
+	e.setLeader(ctx, false)
+
+	return err
+}
+
+// Run Участвует в выборах в цикле, при остановке отдает аренду
+func (e *Elector) Run(ctx context.Context) chan struct{} {
+	ctx = log.ContextWithSpan(ctx, "LeaderElection")
+	l := e.logger.WithContext(ctx)
+
+	// Канал сигнала остановки
+	doneChannel := make(chan struct{}, 1)
+
+	// Тикер (интервал)
+	ticker := time.NewTicker(e.renew)
+
+	go func(doneChannel chan struct{}, ticker *time.Ticker) {
+		l.Infof("start leader election as %s", e.id)
+
+		if _, err := e.Campaign(ctx); err != nil {
+			l.Errorf("Unable to acquire leader lease. Error: %s", err)
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := e.Campaign(ctx); err != nil {
+					l.Errorf("Unable to acquire leader lease. Error: %s", err)
+				}
+
+			case <-doneChannel:
+				// Останавливаем тикер
+				ticker.Stop()
+
+				if err := e.Resign(ctx); err != nil {
+					l.Errorf("Unable to release leader lease. Error: %s", err)
+				}
+
+				l.Info("end leader election")
+
+				return
+			}
+		}
+	}(doneChannel, ticker)
+
+	return doneChannel
+}
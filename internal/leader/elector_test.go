@@ -0,0 +1,110 @@
+package leader
+
+import (
+	"context"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func newTestElector(db *redis.Client, id string) *Elector {
+	return NewElector(&Config{
+		DB:		db,
+		Key:	"leader:test",
+		ID:		id,
+		TTL:	15 * time.Second,
+		Renew:	5 * time.Second,
+		Logger:	&log.Log{Logger: zap.NewNop()},
+	})
+}
+
+func TestElectorCampaign(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	a, b := newTestElector(db, "a"), newTestElector(db, "b")
+
+	// Аренду получает только один экземпляр
+	if ok, err := a.Campaign(ctx); !ok || err != nil {
+		t.Fatalf("expected a to become leader, got %v (%v)", ok, err)
+	}
+	if ok, err := b.Campaign(ctx); ok || err != nil {
+		t.Fatalf("expected b to stay follower, got %v (%v)", ok, err)
+	}
+
+	// Ведущий продлевает аренду
+	mr.FastForward(10 * time.Second)
+	if ok, _ := a.Campaign(ctx); !ok || mr.TTL("leader:test") != 15*time.Second {
+		t.Fatalf("expected renewed lease, got %v, ttl %s", ok, mr.TTL("leader:test"))
+	}
+
+	// Ведущий пропал: после истечения аренды ее забирает другой, а прежний не может продлить чужую
+	mr.FastForward(16 * time.Second)
+	if ok, _ := b.Campaign(ctx); !ok {
+		t.Fatal("expected b to take over after lease expiration")
+	}
+	if ok, _ := a.Campaign(ctx); ok || a.IsLeader() {
+		t.Fatal("a must step down after losing the lease")
+	}
+	if got, _ := mr.Get("leader:test"); got != "b" {
+		t.Fatalf("expected lease owned by b, got %q", got)
+	}
+
+	if v := testutil.ToFloat64(a.Status.WithLabelValues("a")); v != 0 {
+		t.Fatalf("expected a leader status 0, got %v", v)
+	}
+	if v := testutil.ToFloat64(a.Transitions.WithLabelValues("a", "lost")); v != 1 {
+		t.Fatalf("expected single lost transition for a, got %v", v)
+	}
+	if v := testutil.ToFloat64(b.Status.WithLabelValues("b")); v != 1 {
+		t.Fatalf("expected b leader status 1, got %v", v)
+	}
+}
+
+func TestElectorResign(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	a, b := newTestElector(db, "a"), newTestElector(db, "b")
+	a.Campaign(ctx)
+
+	// Ведомый не может снять чужую аренду
+	if err := b.Resign(ctx); err != nil || !mr.Exists("leader:test") {
+		t.Fatalf("follower must not release the lease (%v)", err)
+	}
+
+	// Остановленный ведущий отдает аренду сразу
+	if err := a.Resign(ctx); err != nil || a.IsLeader() {
+		t.Fatalf("expected a to resign, got %v", err)
+	}
+	if ok, _ := b.Campaign(ctx); !ok {
+		t.Fatal("expected b to become leader right after resignation")
+	}
+
+	// Потеря связи с Redis: ведущий перестает выполнять задачи
+	mr.Close()
+	if ok, err := b.Campaign(ctx); ok || err == nil || b.IsLeader() {
+		t.Fatalf("expected b to step down on Redis error, got %v (%v)", ok, err)
+	}
+}
+
+func TestElectorWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	e := newTestElector(nil, "single")
+
+	// Без Redis единственный экземпляр всегда ведущий
+	if ok, err := e.Campaign(ctx); !ok || err != nil || !e.IsLeader() {
+		t.Fatalf("expected single instance to lead, got %v (%v)", ok, err)
+	}
+
+	if err := e.Resign(ctx); err != nil || e.IsLeader() {
+		t.Fatalf("expected resign without error, got %v, leader %v", err, e.IsLeader())
+	}
+}
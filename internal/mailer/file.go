@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"short_url/internal/models"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer Записывает письма в каталог в виде .eml файлов (для разработки)
+type FileMailer struct {
+	dir		string
+	from	string
+	seq		uint64
+}
+
+// NewFileMailer Конструктор для FileMailer
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{
+		dir:	dir,
+		from:	from,
+	}
+}
+
+// Send Записывает письмо в файл
+func (m *FileMailer) Send(ctx context.Context, mail models.Mail) error {
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), atomic.AddUint64(&m.seq, 1))
+
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, mail), 0o640)
+}
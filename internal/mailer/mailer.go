@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+)
+
+// Режимы отправки писем
+const (
+	ModeSMTP	= "smtp"	// Отправка через SMTP-сервер
+	ModeFile	= "file"	// Запись писем в файлы (для разработки)
+	ModeMemory	= "memory"	// Хранение писем в памяти (для тестов)
+)
+
+// Mailer Интерфейс отправки писем пользователям
+type Mailer interface {
+	Send(ctx context.Context, mail models.Mail) error
+}
+
+// NewMailer Создает отправителя писем по конфигурации
+func NewMailer(conf *models.ConfigMail) (Mailer, error) {
+	switch conf.Mode {
+	case ModeSMTP:
+		return NewSMTPMailer(conf), nil
+	case ModeFile, "":
+		return NewFileMailer(conf.Dir, conf.From), nil
+	case ModeMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail mode %q", conf.Mode)
+	}
+}
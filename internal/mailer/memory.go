@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sync"
+)
+
+// MemoryMailer Хранит отправленные письма в памяти (для тестов)
+type MemoryMailer struct {
+	mails	[]models.Mail
+	mux		sync.Mutex
+}
+
+// NewMemoryMailer Конструктор для MemoryMailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{
+		mails:	make([]models.Mail, 0),
+	}
+}
+
+// Send Сохраняет письмо
+func (m *MemoryMailer) Send(ctx context.Context, mail models.Mail) error {
+	m.mux.Lock()
+	m.mails = append(m.mails, mail)
+	m.mux.Unlock()
+
+	return nil
+}
+
+// Mails Возвращает копию отправленных писем
+func (m *MemoryMailer) Mails() []models.Mail {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	result := make([]models.Mail, len(m.mails))
+	copy(result, m.mails)
+
+	return result
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"short_url/internal/models"
+	"time"
+)
+
+// SMTPMailer Отправляет письма через SMTP-сервер
+type SMTPMailer struct {
+	addr	string
+	host	string
+	from	string
+	auth	smtp.Auth
+}
+
+// NewSMTPMailer Конструктор для SMTPMailer
+func NewSMTPMailer(conf *models.ConfigMail) *SMTPMailer {
+	m := &SMTPMailer{
+		addr:	net.JoinHostPort(conf.Host, conf.Port),
+		host:	conf.Host,
+		from:	conf.From,
+	}
+
+	// Авторизуемся, только если указан пользователь
+	if conf.User != "" {
+		m.auth = smtp.PlainAuth("", conf.User, conf.Password, conf.Host)
+	}
+
+	return m
+}
+
+// Send Отправляет письмо
+func (m *SMTPMailer) Send(ctx context.Context, mail models.Mail) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{mail.To}, buildMessage(m.from, mail))
+}
+
+// buildMessage Собирает письмо в формате RFC 5322
+func buildMessage(from string, mail models.Mail) []byte {
+	msg := bytes.NewBuffer([]byte{})
+
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", mail.To)
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(mail.Body)
+
+	return msg.Bytes()
+}
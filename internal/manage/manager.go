@@ -2,176 +2,324 @@ package manager
 
 import (
 	"context"
-	"fmt"
+	"short_url/internal/events"
 	"short_url/internal/models"
 	log "short_url/pkg/logger"
-	"sync"
+	"sort"
 	"time"
-
-	cron "github.com/robfig/cron/v3"
 )
 
+// Виды задач планировщика
 const (
-	All    			= 50				// Лимит кол-ва всех ссылок для обычного пользователя
-	//SubAll 			= 100				// Лимит кол-ва всех ссылок для подписчика
-
-	Custom    		= 15				// Лимит кол-ва кастомных ссылок для обычного пользователя
-	//SubCustom 		= 30				// Лимит кол-ва кастомных ссылок для подписчика
-
-	//SubPerm			= 10				// Лимит кол-ва ссылок с безграничным сроком действия для подписчика
+	JobUnsubscribe	= "unsubscribe"		// Удаление ссылок сверх лимитов после окончания подписки
+	JobNotify		= "notify"			// Уведомление об окончании подписки
 )
 
-// Нулевое время (для сравнения полей задач)
-var TimeNil time.Time
-
 // linkRepository Интерфейс к репозиторию управления ссылками
 type linkRepository interface {
-	DeleteExpLink(ctx context.Context, link, username string) error
 	DeleteLink(ctx context.Context, link, username string) error
-	CountLinks(ctx context.Context, username string) (models.LinksAmount, error)
 	GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error)
+	KeepLinks(ctx context.Context, username string) ([]string, error)
+	TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error)
+}
+
+// subRepository Интерфейс к репозиторию подписок
+type subRepository interface {
+	FindSubscribe(ctx context.Context, username string) (time.Duration, bool)
 }
 
+// planCatalog Интерфейс к каталогу тарифных планов
+type planCatalog interface {
+	Free() models.Plan
+}
+
+// eventBus Интерфейс к шине событий
+type eventBus interface {
+	Publish(ctx context.Context, ev events.Event) error
+}
+
+// leaderElector Интерфейс к выбору ведущего экземпляра
+type leaderElector interface {
+	IsLeader() bool
+}
+
+// jobRepository Интерфейс к общему для всех экземпляров хранилищу задач
+type jobRepository interface {
+	AddJobs(ctx context.Context, jobs []models.SchedJob) error
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error)
+	FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error)
+	RemoveJob(ctx context.Context, job models.SchedJob) error
+	RemoveUserJobs(ctx context.Context, username string) error
+}
+
+// Как часто убирать истекшие ссылки из индексов пользователей
+const SweepInterval = time.Minute
+
+// Как часто проверять наступившие задачи
+const JobInterval = time.Minute
+
+const (
+	jobBatch		= 100			// Сколько наступивших задач выполнять за одну проверку
+	jobListLimit	= 1000			// Сколько ближайших задач показывать администратору
+	jobTolerance	= time.Minute	// Допустимое расхождение окончания подписки и окончания, к которому относится задача
+)
+
 // ManagerConfig Конфиг для Manager
 type ManagerConfig struct {
 	LinkRepo		linkRepository
-	Scheduler		*cron.Cron
+	SubRepo			subRepository
+	Plans			planCatalog
+	Events			eventBus
+	WarnBefore		[]time.Duration		// За сколько до окончания подписки предупреждать пользователя
+	Grace			time.Duration		// Отсрочка чистки ссылок после окончания подписки
+	JobRepo			jobRepository
+	Leader			leaderElector
+	Now				func() time.Time	// Часы (nil - системные)
 	Logger			*log.Log
 }
 
 // Manager Вспомогательный слой между repositories и services для планировки задач
 type Manager struct {
 	linkRepo		linkRepository
-	scheduler		*cron.Cron
-	subs			map[string]models.CurrentSub
-	links			[]cron.EntryID
-	mux				sync.RWMutex
+	subRepo			subRepository
+	plans			planCatalog
+	events			eventBus
+	warnBefore		[]time.Duration
+	grace			time.Duration
+	jobRepo			jobRepository
+	leader			leaderElector
+	now				func() time.Time
 	logger			*log.Log
 }
 
 // NewManager Конструктор для Manager
 func NewManager(conf *ManagerConfig) *Manager {
+	now := conf.Now
+	if now == nil {
+		now = time.Now
+	}
+
 	return &Manager{
 		linkRepo: conf.LinkRepo,
-		scheduler: conf.Scheduler,
-		subs: make(map[string]models.CurrentSub),
+		subRepo: conf.SubRepo,
+		plans: conf.Plans,
+		events: conf.Events,
+		warnBefore: conf.WarnBefore,
+		grace: conf.Grace,
+		jobRepo: conf.JobRepo,
+		leader: conf.Leader,
+		now: now,
 		logger: conf.Logger,
-		links: make([]cron.EntryID, 0),
 	}
 }
 
-// CheckJobs Проверяет задачи и контролирует чтобы они выполнялись один раз, чистит буфер планировщика
-func (c *Manager) CheckJobs(ctx context.Context) {
-	ctx = log.ContextWithSpan(ctx, "CheckJobs")
+// deleteJob Выполняет удаление ссылки по расписанию и сообщает о нем в шину событий
+func (c *Manager) deleteJob(ctx context.Context, reason, link, username string) {
+	l := c.logger.WithContext(ctx)
+
+	err := c.linkRepo.DeleteLink(ctx, link, username)
+	if err != nil {
+		l.Errorf("Unable to delete link %s. Error: %s", link, err)
+		return
+	}
+
+	err = c.publish(ctx, events.LinkDeleted{
+		Link:	link,
+		Owner:	username,
+		Actor:	models.ActorManager,
+		Reason:	reason,
+	})
+	if err != nil {
+		l.Errorf("Unable to handle deletion of link %s. Error: %s", link, err)
+	}
+}
+
+// publish Передает событие в шину, если она подключена
+func (c *Manager) publish(ctx context.Context, ev events.Event) error {
+	if c.events == nil {
+		return nil
+	}
+
+	return c.events.Publish(ctx, ev)
+}
+
+// Subscribe Подписывает планировщик на события шины: перенос чистки на новое окончание подписки
+func (c *Manager) Subscribe(b *events.Bus) {
+	events.On(b, func(ctx context.Context, ev events.SubscriptionActivated) error {
+		// Отменяем назначенные операции по чистке. Задачи прошлой подписки остаются и без продления:
+		// подписку могли оформить заново в льготный период, когда чистка еще не выполнена
+		c.RemoveCleanSchedule(ctx, ev.Username)
+
+		return c.CleanUnsubscribeSchedule(ctx, models.CurrentSub{Exp: ev.Exp}, ev.Username)
+	})
+}
+
+// Jobs Возвращает список ближайших запланированных задач
+func (c *Manager) Jobs(ctx context.Context) []models.SchedJob {
+	jobs, err := c.jobRepo.FindJobs(ctx, jobListLimit)
+	if err != nil {
+		c.logger.WithContext(ctx).Errorf("Unable to get scheduled jobs. Error: %s", err)
+		return []models.SchedJob{}
+	}
+
+	return jobs
+}
+
+// superseded Проверяет, что задача относится к прошлой подписке: у пользователя есть подписка,
+// которая заканчивается позже окончания, к которому относится задача
+func (c *Manager) superseded(ctx context.Context, job models.SchedJob) bool {
+	if c.subRepo == nil {
+		return false
+	}
+
+	exp, ok := c.subRepo.FindSubscribe(ctx, job.Username)
+	return ok && c.now().Add(exp).After(job.ExpiresAt.Add(jobTolerance))
+}
+
+// RunDueJobs Выполняет наступившие задачи и удаляет их из хранилища. Задачи выполняет только ведущий
+// экземпляр; задача, которую не удалось удалить после выполнения, будет выполнена повторно
+func (c *Manager) RunDueJobs(ctx context.Context) error {
+	if c.leader != nil && !c.leader.IsLeader() {
+		return nil
+	}
+
+	ctx = log.ContextWithSpan(ctx, "RunDueJobs")
 	l := c.logger.WithContext(ctx)
 
-	l.Debug("CheckJobs() started")
-	defer l.Debug("CheckJobs() done")
+	l.Debug("RunDueJobs() started")
+	defer l.Debug("RunDueJobs() done")
 
-	// Итерируемся по кэшу ссылок
-	for k := 0; k < len(c.links); k++ {
-		id := c.links[k]
+	jobs, err := c.jobRepo.DueJobs(ctx, c.now(), jobBatch)
+	if err != nil {
+		l.Errorf("Unable to get due jobs. Error: %s", err)
+		return err
+	}
 
-		// Получаем сведения о задаче
-		job := c.scheduler.Entry(id)
+	for _, job := range jobs {
+		switch {
+		case c.superseded(ctx, job):
+			// Пользователь уже оформил новую подписку: ни уведомление, ни чистка не нужны
+			l.Infof("Skip job %d of user %s: subscription is active", job.ID, job.Username)
+		case job.Kind == JobNotify:
+			c.notifyJob(ctx, job.Notice, job.Username, job.ExpiresAt)
+		case job.Kind == JobUnsubscribe:
+			c.cleanupJob(ctx, job.Username)
+		default:
+			l.Errorf("Unknown job kind %s", job.Kind)
+		}
 
-		// Если существует предыдущее время выполнения, удаляем задачу
-		if job.Prev != TimeNil {
-			c.scheduler.Remove(id)
+		if err = c.jobRepo.RemoveJob(ctx, job); err != nil {
+			l.Errorf("Unable to remove job %d. Error: %s", job.ID, err)
 		}
 	}
 
-	// Итерируемся по кэшу подписок
-	c.mux.RLock()
-	for _, sub := range c.subs {
-		// Итерируемся по задачам пользователя
-		for k := 0; k < len(sub.RemId); k++ {
-			id := sub.RemId[k]
-			
-			// Получаем сведения о задаче
-			job := c.scheduler.Entry(id)
-
-			// Если существует предыдущее время выполнения, удаляем задачу
-			if job.Prev != TimeNil {
-				c.scheduler.Remove(id)
+	return nil
+}
+
+// SweepExpired Убирает истекшие ссылки из индексов пользователей и сообщает о них в шину событий
+func (c *Manager) SweepExpired(ctx context.Context) error {
+	ctx = log.ContextWithSpan(ctx, "SweepExpired")
+	l := c.logger.WithContext(ctx)
+
+	l.Debug("SweepExpired() started")
+	defer l.Debug("SweepExpired() done")
+
+	// Ссылки, убранные до ошибки, все равно сообщаются подписчикам
+	expired, err := c.linkRepo.TrimExpired(ctx, time.Now())
+	if err != nil {
+		l.Errorf("Unable to trim expired links. Error: %s", err)
+	}
+
+	for username, links := range expired {
+		for _, link := range links {
+			perr := c.publish(ctx, events.LinkDeleted{
+				Link:	link,
+				Owner:	username,
+				Actor:	models.ActorManager,
+				Reason:	events.ReasonExpired,
+			})
+			if perr != nil {
+				l.Errorf("Unable to handle expiration of link %s. Error: %s", link, perr)
 			}
 		}
 	}
-	c.mux.RUnlock()
 
-	return
+	return err
 }
 
-// SchedChecker Проверяет в цикле задачи на предмет их единовременного выполнения
-func (c *Manager) SchedChecker(ctx context.Context) chan struct{} {
-	ctx = log.ContextWithSpan(ctx, "SchedChecker")
+// SweepCycle Убирает истекшие ссылки в цикле (только на ведущем экземпляре)
+func (c *Manager) SweepCycle(ctx context.Context) chan struct{} {
+	ctx = log.ContextWithSpan(ctx, "SweepCycle")
 	l := c.logger.WithContext(ctx)
 
 	// Канал сигнала остановки
 	doneChannel := make(chan struct{}, 1)
 
 	// Тикер (интервал)
-	ticker := time.NewTicker(time.Hour * 12)
+	ticker := time.NewTicker(SweepInterval)
 
-	// Запускаем пуллинг запросов к серверу qiwi
 	go func(doneChannel chan struct{}, ticker *time.Ticker) {
-		l.Info("start sched check")
+		l.Info("start expired links sweep")
 		for {
 			select {
 			case <-ticker.C:
-				// Итерируемся по Id счетов
-				c.CheckJobs(ctx)
+				if c.leader == nil || c.leader.IsLeader() {
+					c.SweepExpired(ctx)
+				}
+
 			case <-doneChannel:
 				// Останавливаем тикер
 				ticker.Stop()
 
-				l.Info("end sched check")
+				l.Info("end expired links sweep")
 
 				return
 			}
 		}
 	}(doneChannel, ticker)
-	
+
 	return doneChannel
 }
 
-// formatSched Рассчитывает время удаления и переводит его в формат планировщика
-func formatSched(exp time.Duration) string {
-	// Добавляем длительность к настоящему времени
-	date := time.Now().Add(exp)
+// SchedChecker Проверяет в цикле наступившие задачи и выполняет их (только на ведущем экземпляре)
+func (c *Manager) SchedChecker(ctx context.Context) chan struct{} {
+	ctx = log.ContextWithSpan(ctx, "SchedChecker")
+	l := c.logger.WithContext(ctx)
 
-	// Парсим составляющие даты
-	mi := date.Minute()
-	h := date.Hour()
-	d := date.Day()
-	mo := int(date.Month())
+	// Канал сигнала остановки
+	doneChannel := make(chan struct{}, 1)
 
-	// Вставляем значения в шаблон планировщика
-	result := fmt.Sprintf("%v %v %v %v *", mi, h, d, mo)
-	return result
-}
+	// Тикер (интервал)
+	ticker := time.NewTicker(JobInterval)
 
-// CleaningExpLinkSchedule Планирует удаление ссылок из типов Redis, которым нельзя указать срок действия (множество, хеш)
-func (c *Manager) CleaningExpLinkSchedule(ctx context.Context, link, username string, exp time.Duration) error {
-	ctx = log.ContextWithSpan(ctx, "CleaningExpLinkSchedule")
-	l := c.logger.WithContext(ctx)
+	go func(doneChannel chan struct{}, ticker *time.Ticker) {
+		l.Info("start sched check")
+		for {
+			select {
+			case <-ticker.C:
+				c.RunDueJobs(ctx)
 
-	l.Debug("CleaningExpLinkSchedule() started")
-	defer l.Debug("CleaningExpLinkSchedule() done")
+			case <-doneChannel:
+				// Останавливаем тикер
+				ticker.Stop()
 
-	// Задаем задачу планировщику и получаем ее номер
-	id, err := c.scheduler.AddFunc(formatSched(exp), func(){
-		c.linkRepo.DeleteExpLink(ctx, link, username)
-	})
-	if err != nil {
-		l.Errorf("Unable to add scheduler job. Error: %s", err)
-		return err
-	}
+				l.Info("end sched check")
 
-	// Добавляем номер в кэш для будущего удаления
-	c.links = append(c.links, id)
+				return
+			}
+		}
+	}(doneChannel, ticker)
+	
+	return doneChannel
+}
 
-	return nil
+// split Делит ссылки на оставляемые (первые limit) и удаляемые
+func split(links []models.LinkDataDB, limit int) ([]models.LinkDataDB, []models.LinkDataDB) {
+	if len(links) <= limit {
+		return links, nil
+	}
+
+	return links[:limit], links[limit:]
 }
 
 // RemoveCleanSchedule Отменяет процедуры удаления ссылок из Redis (для новой подписки)
@@ -182,121 +330,204 @@ func (c *Manager) RemoveCleanSchedule(ctx context.Context, username string) {
 	l.Debug("RemoveCleanSchedule() started")
 	defer l.Debug("RemoveCleanSchedule() done")
 
-	// Итерируемся по кешу и отменяем операции
-	c.mux.RLock()
-	for k := 0; k < len(c.subs[username].RemId); k++ {
-		c.scheduler.Remove(c.subs[username].RemId[k])
+	// У пользователя бывают только задачи по подписке, поэтому отменяем все
+	if err := c.jobRepo.RemoveUserJobs(ctx, username); err != nil {
+		l.Errorf("Unable to remove jobs of user %s. Error: %s", username, err)
 	}
-	c.mux.RUnlock()
-
-	// Чистим кэш
-	c.mux.Lock()
-	delete(c.subs, username)
-	c.mux.Unlock()
 
 	return
 }
 
-// CleaningUnsubscribeSchedule Планирует удаление ссылок пользователя, не соответствующих лимитам после прекращения подписки
-func (c *Manager) CleanUnsubscribeSchedule(ctx context.Context, sub models.CurrentSub, username string) error {
-	ctx = log.ContextWithSpan(ctx, "CleanUnsubscribeSchedule")
+// RemoveUserJobs Отменяет все задачи пользователя (при удалении аккаунта)
+func (c *Manager) RemoveUserJobs(ctx context.Context, username string) {
+	ctx = log.ContextWithSpan(ctx, "RemoveUserJobs")
 	l := c.logger.WithContext(ctx)
 
-	l.Debug("CleanUnsubscribeSchedule() started")
-	defer l.Debug("CleanUnsubscribeSchedule() done")
-
-	// Считаем дату расписания
-	date := formatSched(sub.Exp)
-
-	// Создаем слайс, содержащий номера операций удаления
-	sub.RemId = make([]cron.EntryID, 0)
+	l.Debug("RemoveUserJobs() started")
+	defer l.Debug("RemoveUserJobs() done")
 
-	// Получаем кол-во ссылок пользователя по категориям
-	amo, err := c.linkRepo.CountLinks(ctx, username)
-	if err != nil {
-		l.Errorf("Unable to count user links. Error: %s", err)
-		return err
+	if err := c.jobRepo.RemoveUserJobs(ctx, username); err != nil {
+		l.Errorf("Unable to remove jobs of user %s. Error: %s", username, err)
 	}
 
-	// Обьявляем счетчики обычных и кастомных ссылок
-	var defaultCounter, customCounter int
+	return
+}
 
-	// Создаем слайсы для дальнейшей фильтрации
-	defaultLinks := make([]models.LinkDataDB, 0)
-	customLinks := make([]models.LinkDataDB, 0)
+// overQuota Отбирает ссылки пользователя сверх лимитов бесплатного плана.
+// Ссылки из списка сохраняемых пользователем удаляются в последнюю очередь
+func (c *Manager) overQuota(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+	// После подписки действуют лимиты бесплатного плана
+	quota := c.plans.Free().Quota
 
 	// Получаем все ссылки пользователя
 	links, err := c.linkRepo.GetAllLinks(ctx, username)
 	if err != nil {
-		l.Errorf("Unable to get all user links. Error: %s", err)
-		return err
+		return nil, err
 	}
 
-	// Итерируемся по ссылкам, планируем удаление перманентных, отфильтровываем остальные
-	for k := 0; k < len(links); k++ {
-		if links[k].Perm {
-			//
-			id, err := c.scheduler.AddFunc(date, func() {
-				c.linkRepo.DeleteLink(ctx, links[k].Link, username)
-			})
-			if err != nil {
-				l.Errorf("Unable to add scheduler job. Error: %s", err)
-				return err
-			}
+	// Получаем ссылки, которые пользователь выбрал для сохранения
+	keepList, err := c.linkRepo.KeepLinks(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(keepList))
+	for _, link := range keepList {
+		keep[link] = true
+	}
+
+	// Сохраняемые ссылки ставим в начало, чтобы лимиты в первую очередь достались им
+	sort.SliceStable(links, func(i, j int) bool {
+		return keep[links[i].Link] && !keep[links[j].Link]
+	})
 
-			sub.RemId = append(sub.RemId, id)
+	// Разбираем ссылки по категориям
+	permLinks := make([]models.LinkDataDB, 0)
+	customLinks := make([]models.LinkDataDB, 0)
+	defaultLinks := make([]models.LinkDataDB, 0)
+	for _, link := range links {
+		switch {
+		case link.Perm:
+			permLinks = append(permLinks, link)
+		case link.Custom:
+			customLinks = append(customLinks, link)
+		default:
+			defaultLinks = append(defaultLinks, link)
+		}
+	}
 
-		} else {
-			if links[k].Custom {
-				customLinks = append(customLinks, links[k])
+	// Отбираем бессрочные и кастомные ссылки сверх лимитов
+	permLinks, removePerm := split(permLinks, quota.Perm)
+	customLinks, removeCustom := split(customLinks, quota.Custom)
+
+	// Общий лимит: сохраняемые ссылки остаются, из прочих удаляем сначала обычные, затем кастомные и бессрочные
+	kept := make([]models.LinkDataDB, 0, len(links))
+	rest := make([]models.LinkDataDB, 0, len(links))
+	for _, group := range [][]models.LinkDataDB{permLinks, customLinks, defaultLinks} {
+		for _, link := range group {
+			if keep[link.Link] {
+				kept = append(kept, link)
 			} else {
-				defaultLinks = append(defaultLinks, links[k])
+				rest = append(rest, link)
 			}
 		}
 	}
+	_, removeRest := split(append(kept, rest...), quota.All)
+
+	remove := make([]models.LinkDataDB, 0, len(removePerm)+len(removeCustom)+len(removeRest))
+	remove = append(remove, removePerm...)
+	remove = append(remove, removeCustom...)
+	remove = append(remove, removeRest...)
 
-	// Проверяем кол-во кастомных ссылок
-	if amo.Custom > Custom {
-		customCounter = Custom - amo.Custom
+	return remove, nil
+}
+
+// OverQuota Возвращает ссылки, которые будут удалены при переходе пользователя на бесплатный план
+func (c *Manager) OverQuota(ctx context.Context, username string) ([]string, error) {
+	remove, err := c.overQuota(ctx, username)
+	if err != nil {
+		return nil, err
 	}
 
-	// Итерируемся по кастомным ссылкам, планируем удаление
-	for k := 0; k < customCounter; k++ {
-			//
-			id, err := c.scheduler.AddFunc(date, func() {
-				c.linkRepo.DeleteLink(ctx, customLinks[k].Link, username)
-			})
-			if err != nil {
-				l.Errorf("Unable to add scheduler job. Error: %s", err)
-				return err
-			}
+	result := make([]string, len(remove))
+	for k, data := range remove {
+		result[k] = data.Link
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
 
-			sub.RemId = append(sub.RemId, id)
+// notifyJob Сообщает в шину о скором или наступившем окончании подписки (уведомления и вебхуки - подписчики шины)
+func (c *Manager) notifyJob(ctx context.Context, kind, username string, expiresAt time.Time) {
+	l := c.logger.WithContext(ctx)
+
+	// Список удаляемых ссылок собираем на момент отправки
+	links, err := c.OverQuota(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to get links over quota of user %s. Error: %s", username, err)
+		return
 	}
 
-	// Проверяем общее кол-во ссылок
-	if len(defaultLinks) + len(customLinks) - customCounter > All {
-		defaultCounter = All - len(defaultLinks) - len(customLinks) + customCounter
+	expiring := events.SubscriptionExpiring{
+		Username:		username,
+		ExpiresAt:		expiresAt,
+		CleanupAt:		expiresAt.Add(c.grace),
+		DeleteLinks:	links,
+	}
+	var ev events.Event = expiring
+	if kind == models.NoticeSubExpired {
+		ev = events.SubscriptionEnded(expiring)
 	}
 
-	// Итерируемся по обычным ссылкам, планируем удаление
-	for k := 0; k < defaultCounter; k++ {
-		//
-		id, err := c.scheduler.AddFunc(date, func() {
-			c.linkRepo.DeleteLink(ctx, defaultLinks[k].Link, username)
-		})
-		if err != nil {
-			l.Errorf("Unable to add scheduler job. Error: %s", err)
-			return err
+	if err = c.publish(ctx, ev); err != nil {
+		l.Errorf("Unable to send notice %s to user %s. Error: %s", kind, username, err)
+	}
+}
+
+// cleanupJob Удаляет ссылки пользователя сверх лимитов бесплатного плана
+func (c *Manager) cleanupJob(ctx context.Context, username string) {
+	l := c.logger.WithContext(ctx)
+
+	remove, err := c.overQuota(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to get links over quota of user %s. Error: %s", username, err)
+		return
+	}
+
+	for _, data := range remove {
+		c.deleteJob(ctx, events.ReasonCleanup, data.Link, username)
+	}
+}
+
+// CleanUnsubscribeSchedule Планирует предупреждения об окончании подписки и удаление ссылок пользователя,
+// не соответствующих лимитам, по истечении льготного периода
+func (c *Manager) CleanUnsubscribeSchedule(ctx context.Context, sub models.CurrentSub, username string) error {
+	ctx = log.ContextWithSpan(ctx, "CleanUnsubscribeSchedule")
+	l := c.logger.WithContext(ctx)
+
+	l.Debug("CleanUnsubscribeSchedule() started")
+	defer l.Debug("CleanUnsubscribeSchedule() done")
+
+	// Задачи хранятся с абсолютным временем выполнения, выполняет их ведущий экземпляр
+	expiresAt := c.now().Add(sub.Exp)
+	jobs := make([]models.SchedJob, 0, len(c.warnBefore)+2)
+
+	// Предупреждения до окончания подписки (если до них осталось больше минуты)
+	for _, before := range c.warnBefore {
+		if sub.Exp-before < time.Minute {
+			continue
 		}
 
-		sub.RemId = append(sub.RemId, id)
+		jobs = append(jobs, models.SchedJob{
+			Kind:		JobNotify,
+			Notice:		models.NoticeSubExpiring,
+			Username:	username,
+			ExpiresAt:	expiresAt,
+			Next:		expiresAt.Add(-before),
+		})
 	}
 
-	// Записываем данные об операциях в кэш
-	c.mux.Lock()
-	c.subs[username] = sub
-	c.mux.Unlock()
+	// Уведомление об окончании подписки и начале льготного периода
+	jobs = append(jobs, models.SchedJob{
+		Kind:		JobNotify,
+		Notice:		models.NoticeSubExpired,
+		Username:	username,
+		ExpiresAt:	expiresAt,
+		Next:		expiresAt,
+	})
+
+	// Чистка ссылок по окончании льготного периода (состав ссылок определяется в момент чистки)
+	jobs = append(jobs, models.SchedJob{
+		Kind:		JobUnsubscribe,
+		Username:	username,
+		ExpiresAt:	expiresAt,
+		Next:		expiresAt.Add(c.grace),
+	})
+
+	if err := c.jobRepo.AddJobs(ctx, jobs); err != nil {
+		l.Errorf("Unable to add scheduler jobs. Error: %s", err)
+		return err
+	}
 
 	return nil
 }
@@ -1 +1,402 @@
 package manager
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/events"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeLinkRepo Хранилище ссылок в памяти для тестов планировщика
+type fakeLinkRepo struct {
+	links	[]models.LinkDataDB
+	keep	[]string
+	expired	map[string][]string
+}
+
+func (r *fakeLinkRepo) DeleteLink(ctx context.Context, link, username string) error {
+	return nil
+}
+
+func (r *fakeLinkRepo) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+	return append([]models.LinkDataDB{}, r.links...), nil
+}
+
+func (r *fakeLinkRepo) KeepLinks(ctx context.Context, username string) ([]string, error) {
+	return r.keep, nil
+}
+
+func (r *fakeLinkRepo) TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error) {
+	expired := r.expired
+	r.expired = nil
+	return expired, nil
+}
+
+// fakeJobRepo Хранилище задач в памяти для тестов планировщика
+type fakeJobRepo struct {
+	seq		int64
+	jobs	[]models.SchedJob
+}
+
+func (r *fakeJobRepo) AddJobs(ctx context.Context, jobs []models.SchedJob) error {
+	for _, job := range jobs {
+		r.seq++
+		job.ID = r.seq
+		r.jobs = append(r.jobs, job)
+	}
+	return nil
+}
+
+func (r *fakeJobRepo) DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error) {
+	due := make([]models.SchedJob, 0)
+	for _, job := range r.jobs {
+		if !job.Next.After(now) && len(due) < limit {
+			due = append(due, job)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeJobRepo) FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error) {
+	return append([]models.SchedJob{}, r.jobs...), nil
+}
+
+func (r *fakeJobRepo) RemoveJob(ctx context.Context, job models.SchedJob) error {
+	for k := range r.jobs {
+		if r.jobs[k].ID == job.ID {
+			r.jobs = append(r.jobs[:k], r.jobs[k+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *fakeJobRepo) RemoveUserJobs(ctx context.Context, username string) error {
+	rest := make([]models.SchedJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		if job.Username != username {
+			rest = append(rest, job)
+		}
+	}
+	r.jobs = rest
+	return nil
+}
+
+// fakeSubRepo Окончания подписок пользователей для тестов планировщика
+type fakeSubRepo struct {
+	now		func() time.Time
+	until	map[string]time.Time
+}
+
+func (r *fakeSubRepo) FindSubscribe(ctx context.Context, username string) (time.Duration, bool) {
+	exp := r.until[username].Sub(r.now())
+	return exp, exp > 0
+}
+
+// fakeLeader Ведущий или ведомый экземпляр
+type fakeLeader bool
+
+func (l *fakeLeader) IsLeader() bool {
+	return bool(*l)
+}
+
+// fakeCatalog Каталог из одного бесплатного плана
+type fakeCatalog struct {
+	quota models.LinkQuota
+}
+
+func (c fakeCatalog) Free() models.Plan {
+	return models.Plan{Name: "free", Quota: c.quota}
+}
+
+func newTestLinks() *fakeLinkRepo {
+	repo := &fakeLinkRepo{}
+	for i := 0; i < 4; i++ {
+		repo.links = append(repo.links, models.LinkDataDB{Link: fmt.Sprintf("d%d", i)})
+	}
+	for i := 0; i < 3; i++ {
+		repo.links = append(repo.links, models.LinkDataDB{Link: fmt.Sprintf("c%d", i), Custom: true})
+	}
+	repo.links = append(repo.links, models.LinkDataDB{Link: "p0", Perm: true})
+
+	return repo
+}
+
+func TestOverQuota(t *testing.T) {
+	repo := newTestLinks()
+	m := NewManager(&ManagerConfig{
+		LinkRepo:	repo,
+		Plans:		fakeCatalog{quota: models.LinkQuota{All: 5, Custom: 2, Perm: 0}},
+		JobRepo:	&fakeJobRepo{},
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	// Бессрочная ссылка, одна кастомная сверх лимита и одна обычная сверх общего лимита
+	removed, err := m.OverQuota(ctx, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fmt.Sprint(removed) != "[c2 d3 p0]" {
+		t.Fatalf("unexpected links to delete: %v", removed)
+	}
+
+	// Выбранные пользователем ссылки сохраняются вместо остальных
+	repo.keep = []string{"c2", "d3"}
+	removed, err = m.OverQuota(ctx, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fmt.Sprint(removed) != "[c1 d2 p0]" {
+		t.Fatalf("unexpected links to delete with keep list: %v", removed)
+	}
+}
+
+func TestCleanUnsubscribeScheduleNotices(t *testing.T) {
+	bus := events.NewBus()
+	var ended []events.SubscriptionEnded
+	events.On(bus, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		ended = append(ended, ev)
+		return nil
+	})
+
+	m := NewManager(&ManagerConfig{
+		LinkRepo:	newTestLinks(),
+		Plans:		fakeCatalog{quota: models.LinkQuota{All: 5, Custom: 2, Perm: 0}},
+		Events:		bus,
+		WarnBefore:	[]time.Duration{7 * 24 * time.Hour, 24 * time.Hour},
+		Grace:		72 * time.Hour,
+		JobRepo:	&fakeJobRepo{},
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	// До окончания подписки меньше недели: предупреждение за 7 дней не планируется
+	if err := m.CleanUnsubscribeSchedule(ctx, models.CurrentSub{Exp: 3 * 24 * time.Hour}, "alice"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	kinds := map[string]int{}
+	for _, job := range m.Jobs(ctx) {
+		kinds[job.Kind]++
+	}
+	if kinds[JobNotify] != 2 || kinds[JobUnsubscribe] != 1 || len(kinds) != 2 {
+		t.Fatalf("unexpected jobs: %v", kinds)
+	}
+
+	// Продление отменяет все задачи
+	m.RemoveCleanSchedule(ctx, "alice")
+	if jobs := m.Jobs(ctx); len(jobs) != 0 {
+		t.Fatalf("expected no jobs after renewal, got %v", jobs)
+	}
+
+	// Событие содержит срок чистки и ссылки сверх лимитов
+	expiresAt := time.Now()
+	m.notifyJob(ctx, models.NoticeSubExpired, "alice", expiresAt)
+	if len(ended) != 1 {
+		t.Fatalf("expected one event, got %d", len(ended))
+	}
+	ev := ended[0]
+	if ev.Username != "alice" || !ev.CleanupAt.Equal(expiresAt.Add(72*time.Hour)) || len(ev.DeleteLinks) != 3 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestManagerEvents(t *testing.T) {
+	bus := events.NewBus()
+	var published []string
+	events.On(bus, func(ctx context.Context, ev events.LinkDeleted) error {
+		published = append(published, ev.Reason)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.SubscriptionExpiring) error {
+		published = append(published, ev.Name())
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		published = append(published, ev.Name())
+		return nil
+	})
+
+	repo := newTestLinks()
+	repo.expired = map[string][]string{"alice": {"d0"}}
+	m := NewManager(&ManagerConfig{
+		LinkRepo:	repo,
+		Plans:		fakeCatalog{quota: models.LinkQuota{All: 3}},
+		Events:		bus,
+		Grace:		time.Hour,
+		JobRepo:	&fakeJobRepo{},
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	m.Subscribe(bus)
+	ctx := context.Background()
+
+	remove, err := m.OverQuota(ctx, "alice")
+	if err != nil || len(remove) == 0 {
+		t.Fatalf("expected links over quota, got %v (%v)", remove, err)
+	}
+
+	m.SweepExpired(ctx)
+	m.SweepExpired(ctx)
+	m.cleanupJob(ctx, "alice")
+	m.notifyJob(ctx, models.NoticeSubExpiring, "alice", time.Now())
+	m.notifyJob(ctx, models.NoticeSubExpired, "alice", time.Now())
+
+	// Просроченная ссылка, по событию на каждую удаленную при чистке, предупреждение и окончание подписки
+	expected := []string{events.ReasonExpired}
+	for range remove {
+		expected = append(expected, events.ReasonCleanup)
+	}
+	expected = append(expected, events.SubscriptionExpiring{}.Name(), events.SubscriptionEnded{}.Name())
+	if fmt.Sprint(published) != fmt.Sprint(expected) {
+		t.Fatalf("expected events %v, got %v", expected, published)
+	}
+
+	// Подписка на шину: оформление подписки планирует задачи, ссылки со сроком истекают сами
+	bus.Publish(ctx, events.LinkCreated{Link: "x", Owner: "bob", Exp: time.Hour})
+	bus.Publish(ctx, events.SubscriptionActivated{Username: "bob", Exp: 48 * time.Hour})
+
+	kinds := map[string]int{}
+	for _, job := range m.Jobs(ctx) {
+		kinds[job.Kind]++
+	}
+	if kinds[JobNotify] != 1 || kinds[JobUnsubscribe] != 1 || len(kinds) != 2 {
+		t.Fatalf("unexpected jobs: %v", kinds)
+	}
+}
+
+func TestRunDueJobsOnLeaderOnly(t *testing.T) {
+	bus := events.NewBus()
+	var published []string
+	events.On(bus, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		published = append(published, ev.Name())
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.LinkDeleted) error {
+		published = append(published, ev.Reason)
+		return nil
+	})
+
+	// Два экземпляра с общим хранилищем задач
+	jobs := &fakeJobRepo{}
+	now := time.Now()
+	clock := func() time.Time { return now }
+	leading, following := fakeLeader(true), fakeLeader(false)
+	newManager := func(leader *fakeLeader) *Manager {
+		return NewManager(&ManagerConfig{
+			LinkRepo:	newTestLinks(),
+			Plans:		fakeCatalog{quota: models.LinkQuota{All: 7}},
+			Events:		bus,
+			Grace:		time.Hour,
+			JobRepo:	jobs,
+			Leader:		leader,
+			Now:		clock,
+			Logger:		&log.Log{Logger: zap.NewNop()},
+		})
+	}
+	leader, follower := newManager(&leading), newManager(&following)
+	ctx := context.Background()
+
+	// Задачи, запланированные ведомым экземпляром, видны всем
+	if err := follower.CleanUnsubscribeSchedule(ctx, models.CurrentSub{Exp: 24 * time.Hour}, "alice"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(leader.Jobs(ctx)) != 2 {
+		t.Fatalf("unexpected jobs: %v", leader.Jobs(ctx))
+	}
+
+	// Подписка закончилась: ведомый задачи не выполняет
+	now = now.Add(24 * time.Hour)
+	follower.RunDueJobs(ctx)
+	if len(published) != 0 || len(jobs.jobs) != 2 {
+		t.Fatalf("follower must not run jobs, got events %v", published)
+	}
+
+	// Ведущий выполняет наступившее уведомление один раз
+	leader.RunDueJobs(ctx)
+	leader.RunDueJobs(ctx)
+	if fmt.Sprint(published) != fmt.Sprint([]string{events.SubscriptionEnded{}.Name()}) || len(jobs.jobs) != 1 {
+		t.Fatalf("unexpected events %v, jobs %v", published, jobs.jobs)
+	}
+
+	// После смены ведущего чистку выполняет новый ведущий
+	leading, following = false, true
+	now = now.Add(time.Hour)
+	leader.RunDueJobs(ctx)
+	if len(jobs.jobs) != 1 {
+		t.Fatalf("former leader must not run jobs")
+	}
+	follower.RunDueJobs(ctx)
+	if len(published) < 2 || published[1] != events.ReasonCleanup || len(jobs.jobs) != 0 {
+		t.Fatalf("unexpected events %v, jobs %v", published, jobs.jobs)
+	}
+}
+
+func TestResubscribeDuringGrace(t *testing.T) {
+	bus := events.NewBus()
+	var published []string
+	events.On(bus, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		published = append(published, ev.Name())
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.LinkDeleted) error {
+		published = append(published, ev.Reason)
+		return nil
+	})
+
+	jobs := &fakeJobRepo{}
+	now := time.Now()
+	clock := func() time.Time { return now }
+	subs := &fakeSubRepo{now: clock, until: make(map[string]time.Time)}
+	m := NewManager(&ManagerConfig{
+		LinkRepo:	newTestLinks(),
+		SubRepo:	subs,
+		Plans:		fakeCatalog{quota: models.LinkQuota{All: 1}},
+		Events:		bus,
+		Grace:		72 * time.Hour,
+		JobRepo:	jobs,
+		Now:		clock,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	m.Subscribe(bus)
+	ctx := context.Background()
+
+	// Подписка закончилась, уведомление отправлено, чистка ждет конца льготного периода
+	expiresAt := now.Add(24 * time.Hour)
+	subs.until["alice"] = expiresAt
+	bus.Publish(ctx, events.SubscriptionActivated{Username: "alice", Exp: 24 * time.Hour})
+	now = expiresAt
+	m.RunDueJobs(ctx)
+	if len(published) != 1 || len(jobs.jobs) != 1 {
+		t.Fatalf("unexpected events %v, jobs %v", published, jobs.jobs)
+	}
+	stale := jobs.jobs[0]
+
+	// Новая подписка в льготный период (не продление) отменяет чистку прошлой подписки
+	now = now.Add(time.Hour)
+	subs.until["alice"] = now.Add(30 * 24 * time.Hour)
+	bus.Publish(ctx, events.SubscriptionActivated{Username: "alice", Exp: 30 * 24 * time.Hour})
+	for _, job := range jobs.jobs {
+		if job.ID == stale.ID {
+			t.Fatalf("cleanup of the previous subscription must be removed, got %v", jobs.jobs)
+		}
+	}
+
+	// Оставшаяся задача прошлой подписки наступает, но ссылки подписчика не удаляются
+	jobs.jobs = append(jobs.jobs, stale)
+	now = expiresAt.Add(72 * time.Hour)
+	m.RunDueJobs(ctx)
+	if len(published) != 1 {
+		t.Fatalf("links of an active subscriber must stay, got events %v", published)
+	}
+	for _, job := range jobs.jobs {
+		if job.ID == stale.ID {
+			t.Fatal("skipped job must be removed")
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockoutError Ошибка при временной блокировке попыток входа после серии неудач
+type LockoutError struct {
+	RetryAfter	time.Duration	// Через сколько можно повторить попытку
+}
+
+// Error Реализует интерфейс error
+func (e *LockoutError) Error() string {
+	return "too many attempts"
+}
+
+// RetryAfterSec Возвращает время до повторной попытки в целых секундах (с округлением вверх)
+func (e *LockoutError) RetryAfterSec() string {
+	sec := int64((e.RetryAfter + time.Second - 1) / time.Second)
+	if sec < 1 {
+		sec = 1
+	}
+
+	return fmt.Sprint(sec)
+}
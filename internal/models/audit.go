@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Действия, которые записываются в журнал аудита
+const (
+	AuditSignUp			= "user.signup"				// Регистрация пользователя
+	AuditUserUpdate		= "user.update"				// Изменение профиля пользователя
+	AuditUserPassword	= "user.password"			// Смена пароля
+	AuditUserDelete		= "user.delete"				// Удаление аккаунта
+	AuditIdentityLink	= "user.identity.link"		// Привязка аккаунта внешнего провайдера
+	AuditMFAEnable		= "user.mfa.enable"			// Включение двухфакторной аутентификации
+	AuditMFADisable		= "user.mfa.disable"		// Отключение двухфакторной аутентификации
+	AuditMFARecovery	= "user.mfa.recovery"		// Вход по резервному коду
+	AuditLinkCreate		= "link.create"				// Создание ссылки
+	AuditLinkDelete		= "link.delete"				// Удаление ссылки пользователем
+	AuditLinkExpire		= "link.expire"				// Удаление просроченной ссылки планировщиком
+	AuditLinkCleanup	= "link.cleanup"			// Удаление ссылки сверх лимитов после окончания подписки
+	AuditLinkDisable	= "link.disable"			// Блокировка ссылки администратором
+	AuditLinkEnable		= "link.enable"				// Снятие блокировки со ссылки
+	AuditSubAdd			= "subscription.add"		// Оформление или продление подписки
+	AuditSubGrant		= "subscription.grant"		// Выдача подписки администратором
+	AuditSubRevoke		= "subscription.revoke"		// Отзыв подписки администратором
+	AuditPromoCreate	= "promo.create"			// Создание промокода
+	AuditPromoDelete	= "promo.delete"			// Удаление промокода
+	AuditPromoRedeem	= "promo.redeem"			// Активация пробной подписки по промокоду
+	AuditReportResolve	= "report.resolve"			// Решение по жалобе на ссылку
+)
+
+// Служебные инициаторы действий
+const (
+	ActorSystem		= "system"		// Действие выполнено сервисом (например, после оплаты счета)
+	ActorManager	= "manager"		// Действие выполнено планировщиком задач
+)
+
+// AuditEntry Запись журнала аудита
+type AuditEntry struct {
+	ID			int64
+	Actor		string	// Кто выполнил действие
+	Action		string	// Что было сделано
+	Target		string	// Над чем было выполнено действие
+	Owner		string	// Чей аккаунт затрагивает действие
+	Details		string	// Дополнительные сведения (например, заметка администратора)
+	TraceID		string
+	CreatedAt	time.Time
+}
+
+// AuditFilter Параметры выборки из журнала аудита (пустые поля не учитываются)
+type AuditFilter struct {
+	Actor	string
+	Owner	string
+	Action	string
+	Limit	int
+}
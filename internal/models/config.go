@@ -2,17 +2,26 @@ package models
 
 import (
 	"crypto/rsa"
+	"time"
 )
 
 // Config основная конфигурация сервиса
 type Config struct {
 	App		*ConfigApp
 	Price	*ConfigPrice
+	Plans	*ConfigPlans
 	Log		*ConfigLog
 	HTTP	*ConfigHTTP
 	DB		*ConfigDB
 	RDB		*ConfigRedis
 	JWT		*ConfigJWT
+	Mail	*ConfigMail
+	OIDC	*ConfigOIDC
+	Rate	*ConfigRateLimit
+	Notify	*ConfigNotify
+	Webhook	*ConfigWebhook
+	Leader	*ConfigLeader
+	Cache	*ConfigLinkCache
 }
 
 // ConfigHTTP конфигурация для HTTP
@@ -20,6 +29,9 @@ type ConfigHTTP struct {
 	Host       string `env:"HTTP_HOST"`         //  HTTP хост
 	Port       string `env:"HTTP_PORT"`         //  HTTP порт
 	MetricPort string `env:"HTTP_METRICS_PORT"` //  Prometheus порт
+
+	// Адреса прокси, которым доверяется X-Forwarded-For (пусто - доверять всем)
+	TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES" envSeparator:","`
 }
 
 // ConfigDB конфигурация для подключения к базе данных
@@ -39,11 +51,22 @@ type ConfigRedis struct {
 	User		string	`env:"REDIS_USER"`
 	Password	string	`env:"REDIS_PASSWORD"`
 	Database	int		`env:"REDIS_DATABASE"`
+	LegacyKeys	bool	`env:"REDIS_LEGACY_KEYS" envDefault:"true"`	// Читать ключи прежней схемы, пока не выполнена миграция
 }
 
 // ConfigApp конфигурация для внутренних модулей приложения
 type ConfigApp struct {
 	SecretKey     string `env:"SECRET_KEY"`
+	Storage       string `env:"STORAGE" envDefault:"external"` // Хранилище: external (Postgres и Redis) или memory (в памяти процесса)
+}
+
+// ConfigPlans конфигурация каталога тарифных планов.
+// Без файла каталог собирается из WEEK_PRICE, MONTH_PRICE и YEAR_PRICE с лимитами по умолчанию
+type ConfigPlans struct {
+	File	string	`env:"PLANS_FILE"`	// JSON-файл со списком планов (см. configs/plans.json)
+
+	// Сколько кэшируется состояние подписки при проверке запросов
+	SubCacheTTL	time.Duration	`env:"SUB_CACHE_TTL" envDefault:"15s"`
 }
 
 // ConfigPrice Стоимость подписок
@@ -63,9 +86,74 @@ type ConfigLog struct {
 	Output	string	`env:"LOG_OUTPUT"`
 }
 
+// ConfigMail конфигурация для отправки писем
+type ConfigMail struct {
+	Mode		string	`env:"MAIL_MODE" envDefault:"file"`	// smtp, file или memory
+	Dir			string	`env:"MAIL_DIR" envDefault:"mail"`	// Каталог для писем в режиме file
+	Host		string	`env:"SMTP_HOST"`
+	Port		string	`env:"SMTP_PORT"`
+	User		string	`env:"SMTP_USER"`
+	Password	string	`env:"SMTP_PASSWORD"`
+	From		string	`env:"MAIL_FROM"`
+	LinkURL		string	`env:"MAIL_LINK_URL"`				// Адрес сервиса для ссылок в письмах
+}
+
+// ConfigOIDC конфигурация входа через внешнего провайдера OpenID Connect (пустой OIDC_ISSUER - вход отключен)
+type ConfigOIDC struct {
+	Issuer			string	`env:"OIDC_ISSUER"`
+	ClientID		string	`env:"OIDC_CLIENT_ID"`
+	ClientSecret	string	`env:"OIDC_CLIENT_SECRET"`
+	RedirectURL		string	`env:"OIDC_REDIRECT_URL"`
+	Scopes			string	`env:"OIDC_SCOPES" envDefault:"openid profile email"`
+}
+
+// ConfigRateLimit конфигурация ограничения частоты запросов.
+// Правила группы задаются по тарифам в формате "anon=60/1m,default=120/1m,sub=600/1m"
+// (тариф без правила не ограничивается)
+type ConfigRateLimit struct {
+	Enabled		bool	`env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	API			string	`env:"RATE_LIMIT_API" envDefault:"anon=60/1m,default=120/1m,sub=600/1m"`	// Остальные запросы к API
+	Auth		string	`env:"RATE_LIMIT_AUTH" envDefault:"anon=20/1m"`						// Вход, регистрация, сброс пароля
+	Link		string	`env:"RATE_LIMIT_LINK" envDefault:"default=10/1m,sub=100/1m"`		// Создание ссылок
+	Redirect	string	`env:"RATE_LIMIT_REDIRECT" envDefault:"anon=300/1m"`				// Переход по короткой ссылке
+	Pay			string	`env:"RATE_LIMIT_PAY" envDefault:"default=5/10m,sub=5/10m"`			// Выставление счетов QIWI
+}
+
 // ConfigJWT конфигурация для создания токенов авторизации
 type ConfigJWT struct {
 	PublicKey             *rsa.PublicKey
 	PrivateKey            *rsa.PrivateKey
 	AccessTokenExpiration int64 `env:"JWT_ACCESS_TOKEN_EXPIRATION"`
 }
+
+// ConfigNotify конфигурация уведомлений об окончании подписки
+type ConfigNotify struct {
+	Mode		string			`env:"NOTIFY_MODE" envDefault:"log"`									// mail, webhook или log
+	WebhookURL	string			`env:"NOTIFY_WEBHOOK_URL"`											// Адрес для режима webhook
+	WarnBefore	[]time.Duration	`env:"NOTIFY_WARN_BEFORE" envSeparator:"," envDefault:"168h,24h"`	// За сколько до окончания предупреждать
+	Grace		time.Duration	`env:"SUB_GRACE_PERIOD" envDefault:"72h"`							// Отсрочка чистки ссылок после окончания подписки
+}
+
+// ConfigWebhook конфигурация доставки событий на вебхуки пользователей
+type ConfigWebhook struct {
+	Interval	time.Duration	`env:"WEBHOOK_INTERVAL" envDefault:"10s"`		// Как часто проверять очередь доставок
+	Timeout		time.Duration	`env:"WEBHOOK_TIMEOUT" envDefault:"10s"`		// Таймаут запроса к адресу вебхука
+	MaxAttempts	int				`env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`		// Сколько попыток до отметки failed
+	Backoff		time.Duration	`env:"WEBHOOK_BACKOFF" envDefault:"30s"`		// Задержка перед первым повтором (далее удваивается)
+}
+
+// ConfigLeader конфигурация выбора ведущего экземпляра для фоновых задач
+type ConfigLeader struct {
+	Key		string			`env:"LEADER_KEY" envDefault:"leader:workers"`	// Ключ аренды в Redis
+	ID		string			`env:"LEADER_ID"`								// Идентификатор экземпляра (пусто - имя хоста и pid)
+	TTL		time.Duration	`env:"LEADER_TTL" envDefault:"15s"`			// Срок аренды ведущего
+	Renew	time.Duration	`env:"LEADER_RENEW" envDefault:"5s"`			// Как часто продлевать аренду
+}
+
+// ConfigLinkCache конфигурация кэша ссылок для переадресации
+type ConfigLinkCache struct {
+	Size		int				`env:"LINK_CACHE_SIZE" envDefault:"10000"`				// Сколько ссылок хранится в памяти (0 - кэш выключен)
+	TTL			time.Duration	`env:"LINK_CACHE_TTL" envDefault:"30s"`					// Сколько хранится найденная ссылка
+	NegativeTTL	time.Duration	`env:"LINK_CACHE_NEGATIVE_TTL" envDefault:"5s"`			// Сколько хранится отметка о несуществующей ссылке
+	Channel		string			`env:"LINK_CACHE_CHANNEL" envDefault:"v1:cache:links"`	// Канал Redis для сброса кэша на других экземплярах
+}
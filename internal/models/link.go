@@ -4,18 +4,23 @@ import "time"
 
 // LinkDataDTO Структура данных о ссылке для слоя service
 type LinkDataDTO struct {
-	Link	string
-	FullURL	string
-	ExpTime	int
+	Link			string
+	FullURL			string
+	ExpTime			int
+	Disabled		bool
+	DisabledReason	string
 }
 
 // LinkData Структура данных о ссылке для слоя repositories
 type LinkDataDB struct {
-	Link	string
-	FullURL	string
-	ExpTime	time.Duration
-	Perm	bool
-	Custom	bool
+	Link			string
+	FullURL			string
+	ExpTime			time.Duration
+	Perm			bool
+	Custom			bool
+	Owner			string
+	Disabled		bool
+	DisabledReason	string
 }
 
 // LinksAmount Структура данных о ссылках пользователя
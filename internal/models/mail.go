@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Виды одноразовых токенов пользователя
+const (
+	TokenVerify	= "verify"	// Подтверждение почты
+	TokenReset	= "reset"	// Сброс пароля
+)
+
+// Mail Письмо пользователю
+type Mail struct {
+	To		string
+	Subject	string
+	Body	string
+}
+
+// UserTokenDB Одноразовый токен пользователя (в базе хранится только хеш)
+type UserTokenDB struct {
+	ID			int64
+	Username	string
+	Kind		string
+	Hash		string
+	Email		string		// Почта, которую подтверждает токен
+	ExpiresAt	time.Time
+}
@@ -0,0 +1,8 @@
+package models
+
+// TOTPEnrollment Данные для подключения приложения-аутентификатора
+type TOTPEnrollment struct {
+	Secret	string	// Секрет в base32 для ручного ввода
+	URI		string	// otpauth-ссылка
+	QR		[]byte	// QR-код с otpauth-ссылкой в формате PNG
+}
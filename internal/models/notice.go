@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Виды уведомлений об окончании подписки
+const (
+	NoticeSubExpiring	= "sub_expiring"	// Подписка скоро закончится
+	NoticeSubExpired	= "sub_expired"		// Подписка закончилась, идет льготный период
+)
+
+// Notice Уведомление пользователя об окончании подписки
+type Notice struct {
+	Kind		string		`json:"kind"`
+	Username	string		`json:"username"`
+	ExpiresAt	time.Time	`json:"expires_at"`
+	CleanupAt	time.Time	`json:"cleanup_at"`		// Когда будут удалены ссылки сверх лимитов бесплатного плана
+	DeleteLinks	[]string	`json:"delete_links"`
+}
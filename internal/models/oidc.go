@@ -0,0 +1,26 @@
+package models
+
+// OIDCClaims Сведения о пользователе из ID token провайдера
+type OIDCClaims struct {
+	Subject				string
+	Email				string
+	EmailVerified		bool
+	PreferredUsername	string
+	GivenName			string
+	FamilyName			string
+}
+
+// IdentityDB Привязка аккаунта к пользователю внешнего провайдера
+type IdentityDB struct {
+	ID			int64
+	Username	string
+	Issuer		string
+	Subject		string
+	Email		string
+}
+
+// OIDCState Параметры начатого входа, которые нужны при возврате от провайдера
+type OIDCState struct {
+	Nonce		string	`json:"nonce"`
+	Verifier	string	`json:"verifier"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LinkQuota Лимиты ссылок пользователя
+type LinkQuota struct {
+	All		int	`json:"all"`		// Лимит кол-ва всех ссылок
+	Custom	int	`json:"custom"`		// Лимит кол-ва кастомных ссылок
+	Perm	int	`json:"perm"`		// Лимит кол-ва ссылок с безграничным сроком действия
+}
+
+// Plan Тарифный план: стоимость, срок подписки и лимиты ссылок
+type Plan struct {
+	Name	string		`json:"name"`		// Идентификатор плана (используется в /v1/qiwi/:subTime)
+	Title	string		`json:"title"`
+	Price	float64		`json:"price"`		// Стоимость в рублях (0 - бесплатный план)
+	Days	int			`json:"days"`		// Срок подписки в днях
+	Quota	LinkQuota	`json:"quota"`
+}
+
+// Duration Возвращает срок подписки по плану
+func (p Plan) Duration() time.Duration {
+	return time.Duration(p.Days) * time.Hour * 24
+}
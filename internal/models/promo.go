@@ -0,0 +1,47 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Виды промокодов
+const (
+	PromoPercent	= "percent"		// Скидка в процентах от цены плана
+	PromoFixed		= "fixed"		// Скидка фиксированной суммой
+	PromoTrial		= "trial"		// Бесплатные дни подписки
+)
+
+// Минимальная сумма счета QIWI (скидка не уменьшает цену ниже нее)
+const MinBillAmount = 1.0
+
+// PromoDB Промокод
+type PromoDB struct {
+	Code		string		`json:"code"`
+	Kind		string		`json:"kind"`
+	Value		float64		`json:"value"`			// Процент или сумма скидки
+	Days		int			`json:"days"`			// Бесплатные дни (для пробной подписки)
+	Plan		string		`json:"plan"`			// План, к которому применяется код (пусто - любой платный)
+	MaxUses		int			`json:"max_uses"`		// 0 - без ограничений
+	Uses		int			`json:"uses"`
+	ExpiresAt	*time.Time	`json:"expires_at"`		// nil - бессрочный
+	CreatedBy	string		`json:"created_by"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// Apply Возвращает цену плана со скидкой по промокоду
+func (p PromoDB) Apply(price float64) float64 {
+	switch p.Kind {
+	case PromoPercent:
+		price -= price * p.Value / 100
+	case PromoFixed:
+		price -= p.Value
+	}
+
+	price = math.Round(price*100) / 100
+	if price < MinBillAmount {
+		return MinBillAmount
+	}
+
+	return price
+}
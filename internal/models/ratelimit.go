@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Тарифы ограничения частоты запросов
+const (
+	TierAnon	= "anon"	// Запрос без авторизации (ключ - IP клиента)
+)
+
+// RateRule Правило ограничения: не более Limit запросов за Period
+type RateRule struct {
+	Limit	int
+	Period	time.Duration
+}
+
+// RateResult Результат проверки запроса ограничителем
+type RateResult struct {
+	Allowed		bool
+	Limit		int
+	Remaining	int
+	Reset		time.Duration	// Через сколько квота восстановится полностью
+	RetryAfter	time.Duration	// Через сколько можно повторить отклоненный запрос
+}
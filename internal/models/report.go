@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Статусы жалоб на ссылки
+const (
+	ReportOpen		= "open"		// Жалоба ожидает рассмотрения
+	ReportDisabled	= "disabled"	// По жалобе ссылка заблокирована
+	ReportDismissed	= "dismissed"	// Жалоба отклонена
+)
+
+// ReportDB Структура жалобы на ссылку для слоя repositories
+type ReportDB struct {
+	ID			int64
+	Link		string
+	Reason		string
+	Reporter	string
+	Status		string
+	CreatedAt	time.Time
+	ResolvedBy	string
+}
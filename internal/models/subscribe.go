@@ -1,19 +1,79 @@
 package models
 
-import (
-	"time"
-
-	"github.com/robfig/cron/v3"
-)
+import "time"
 
 // SubInfo Структура с информацией о приобретенной пользователем подпиской
 type SubInfo struct {
 	Username	string
+	Plan		string			// Тарифный план (пусто - план текущей подписки)
 	Exp			time.Duration
+	Promo		string			// Промокод, примененный к счету
 }
 
 // CurrentSub Структура с информацией о текущей подписке пользователя
 type CurrentSub struct {
 	Exp		time.Duration
-	RemId	[]cron.EntryID
+}
+
+// Статусы счета QIWI
+const (
+	BillWaiting		= "WAITING"		// Ожидает оплаты
+	BillPaid		= "PAID"		// Оплачен
+	BillRejected	= "REJECTED"	// Отклонен
+	BillExpired		= "EXPIRED"		// Просрочен
+)
+
+// BillDB Счет на оплату подписки из истории счетов
+type BillDB struct {
+	ID			string		`json:"bill_id"`
+	Username	string		`json:"-"`
+	Plan		string		`json:"plan"`
+	Amount		float64		`json:"amount"`
+	Promo		string		`json:"promo,omitempty"`
+	Status		string		`json:"status"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+}
+
+// SubscriptionDB Действующая подписка пользователя
+type SubscriptionDB struct {
+	Plan	string
+	Start	time.Time		// Нулевое для подписок, оформленных до учета даты начала
+	Exp		time.Duration	// Сколько осталось до окончания
+}
+
+// DowngradeDTO Что произойдет по окончании подписки
+type DowngradeDTO struct {
+	At			time.Time	`json:"at"`					// Когда будут удалены ссылки (окончание подписки и льготного периода)
+	Plan		string		`json:"plan"`				// План после окончания подписки
+	KeepLinks	[]string	`json:"keep_links"`			// Ссылки, выбранные пользователем для сохранения
+	DeleteLinks	[]string	`json:"delete_links"`		// Ссылки сверх лимитов плана, которые будут удалены
+}
+
+// SubscriptionDTO Состояние подписки пользователя
+type SubscriptionDTO struct {
+	Active		bool			`json:"active"`
+	Plan		Plan			`json:"plan"`
+	Start		*time.Time		`json:"start,omitempty"`
+	ExpiresAt	*time.Time		`json:"expires_at,omitempty"`
+	Downgrade	*DowngradeDTO	`json:"downgrade,omitempty"`
+}
+
+// BillInfo Информация о выставленном счете, ожидающем оплаты
+type BillInfo struct {
+	BillID		string
+	Username	string
+	Plan		string
+	Exp			time.Duration
+}
+
+// SchedJob Запланированная задача (уведомление или чистка ссылок), общая для всех экземпляров
+type SchedJob struct {
+	ID			int64
+	Kind		string
+	Notice		string		// Вид уведомления (для задач уведомлений)
+	Username	string
+	Link		string
+	ExpiresAt	time.Time	// Окончание подписки, к которому относится задача
+	Next		time.Time	// Когда задачу нужно выполнить
 }
@@ -4,4 +4,7 @@ package models
 type CreateTokenDTO struct {
 	Username	string		`json:"username"`
 	Subscribe	Subscribe	`json:"sub"`
+	Role		Role		`json:"role"`
+	Plan		string		`json:"plan"`
+	MFAPending	bool		`json:"mfa_pending"`
 }
@@ -33,6 +33,39 @@ const (
 	Default
 )
 
+// Role определяет роль пользователя в сервисе (1 - RoleUser, 2 - RoleAdmin)
+type Role int
+
+// ChoiceRole Меняет формат роли на цифровой
+func (r *Role) ChoiceRole(role string) Role {
+	switch role {
+	case "admin":
+		return RoleAdmin
+	case "user":
+		return RoleUser
+	default:
+		return RoleUser
+	}
+}
+
+// ChoiceString Меняет формат роли на строчный
+func (r *Role) ChoiceString() string {
+	switch *r {
+	case RoleAdmin:
+		return "admin"
+	case RoleUser:
+		return "user"
+	default:
+		return "user"
+	}
+}
+
+// Роли пользователей
+const (
+	RoleUser	Role = iota + 1
+	RoleAdmin
+)
+
 // User структура пользователя для базы данных
 type UserDB struct {
 	ID			string `json:"id"`
@@ -40,12 +73,21 @@ type UserDB struct {
 	FirstName	string `json:"first_name"`
 	LastName	string `json:"last_name"`
 	Password	string `json:"-"`
+	Role		Role   `json:"role"`
+	Email		string `json:"email"`
+	EmailVerified	bool   `json:"email_verified"`
+	TOTPSecret	string `json:"-"`
+	TOTPEnabled	bool   `json:"totp_enabled"`
+	TOTPStep	int64  `json:"-"`	// Последний принятый шаг TOTP
 }
 
 // JWTUserInfo список информации, которая будет представлена о пользователе в JWT
 type JWTUserInfo struct {
 	Username 	string		`json:"username"`
 	Subscribe	Subscribe	`json:"sub"`
+	Role		Role		`json:"role"`
+	Plan		string		`json:"plan,omitempty"`			// Тарифный план подписки
+	MFAPending	bool		`json:"mfa_pending,omitempty"`	// Пароль проверен, второй фактор - нет
 }
 
 // SignInUserDTO структура пользователя для слоя service
@@ -54,7 +96,18 @@ type SignInUserDTO struct {
 	FirstName	string		`json:"first_name"`
 	LastName	string		`json:"last_name"`
 	Subscribe	Subscribe	`json:"sub"`
+	Role		Role		`json:"role"`
+	Plan		string		`json:"plan"`
+	MFA			bool		`json:"mfa"`
 	Password	string		`json:"-"`
+	IP			string		`json:"-"`
+}
+
+// UpdateUserDTO структура изменений профиля для слоя service (пустые поля не меняются)
+type UpdateUserDTO struct {
+	FirstName	string		`json:"first_name"`
+	LastName	string		`json:"last_name"`
+	Email		string		`json:"email"`
 }
 
 // SignUpUserDTO структура пользователя для слоя service
@@ -62,5 +115,6 @@ type SignUpUserDTO struct {
 	Username	string		`json:"username" bson:"username"`
 	FirstName	string		`json:"first_name" bson:"first_name"`
 	LastName	string		`json:"last_name" bson:"last_name"`
+	Email		string		`json:"email" bson:"email"`
 	Password	string		`json:"-" bson:"password"`
 }
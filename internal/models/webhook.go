@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Виды событий, отправляемых на вебхуки пользователей
+const (
+	EventLinkCreated	= "link.created"			// Пользователь создал ссылку
+	EventLinkDeleted	= "link.deleted"			// Ссылка удалена (пользователем или чисткой после подписки)
+	EventLinkExpired	= "link.expired"			// Срок действия ссылки истек
+	EventSubStarted		= "subscription.started"	// Оформлена новая подписка
+	EventSubEnded		= "subscription.ended"		// Подписка закончилась
+)
+
+// EventKinds Все виды событий (вебхук без списка событий получает все)
+var EventKinds = []string{EventLinkCreated, EventLinkDeleted, EventLinkExpired, EventSubStarted, EventSubEnded}
+
+// Статусы доставки события
+const (
+	DeliveryPending	= "pending"		// Ожидает отправки или повтора
+	DeliverySuccess	= "success"		// Адрес ответил кодом 2xx
+	DeliveryFailed	= "failed"		// Попытки исчерпаны
+)
+
+// Event Событие, отправляемое на вебхуки пользователя (тело запроса)
+type Event struct {
+	ID			string			`json:"id"`
+	Kind		string			`json:"kind"`
+	Username	string			`json:"username"`
+	Data		map[string]any	`json:"data,omitempty"`
+	CreatedAt	time.Time		`json:"created_at"`
+}
+
+// WebhookDB Адрес, на который отправляются события пользователя
+type WebhookDB struct {
+	ID			int64		`json:"id"`
+	Username	string		`json:"username"`
+	URL			string		`json:"url"`
+	Secret		string		`json:"secret,omitempty"`	// Ключ подписи (показывается только при создании)
+	Events		[]string	`json:"events"`				// Пусто - все события
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// DeliveryDB Доставка события на вебхук
+type DeliveryDB struct {
+	ID				int64		`json:"id"`
+	WebhookID		int64		`json:"webhook_id"`
+	Username		string		`json:"username"`
+	EventID			string		`json:"event_id"`
+	Kind			string		`json:"kind"`
+	Payload			string		`json:"payload"`
+	Status			string		`json:"status"`
+	Attempts		int			`json:"attempts"`
+	NextAttempt		time.Time	`json:"next_attempt"`
+	ResponseCode	int			`json:"response_code"`
+	LastError		string		`json:"last_error"`
+	CreatedAt		time.Time	`json:"created_at"`
+	UpdatedAt		time.Time	`json:"updated_at"`
+	URL				string		`json:"-"`		// Адрес и ключ вебхука (для отправки)
+	Secret			string		`json:"-"`
+}
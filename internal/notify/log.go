@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strings"
+)
+
+// LogNotifier Записывает уведомления в лог
+type LogNotifier struct {
+	logger	*log.Log
+}
+
+// NewLogNotifier Конструктор для LogNotifier
+func NewLogNotifier(logger *log.Log) *LogNotifier {
+	return &LogNotifier{
+		logger:	logger,
+	}
+}
+
+// Notify Записывает уведомление в лог
+func (n *LogNotifier) Notify(ctx context.Context, notice models.Notice) error {
+	n.logger.WithContext(ctx).Infof("notice %s for user %s: expires at %s, cleanup at %s, links to delete: [%s]",
+		notice.Kind, notice.Username, notice.ExpiresAt.Format(timeFormat), notice.CleanupAt.Format(timeFormat),
+		strings.Join(notice.DeleteLinks, ", "))
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_url/internal/models"
+	"strings"
+)
+
+// Формат дат в уведомлениях
+const timeFormat = "02.01.2006 15:04 MST"
+
+// MailNotifier Отправляет уведомления письмом на подтвержденную почту пользователя
+type MailNotifier struct {
+	mailer		mailSender
+	userRepo	userRepository
+}
+
+// NewMailNotifier Конструктор для MailNotifier
+func NewMailNotifier(mailer mailSender, userRepo userRepository) *MailNotifier {
+	return &MailNotifier{
+		mailer:		mailer,
+		userRepo:	userRepo,
+	}
+}
+
+// Notify Отправляет уведомление письмом
+func (n *MailNotifier) Notify(ctx context.Context, notice models.Notice) error {
+	u, err := n.userRepo.FindByUsername(ctx, notice.Username)
+	if err != nil {
+		return err
+	}
+
+	// Письма отправляются только на подтвержденную почту
+	if u.Email == "" || !u.EmailVerified {
+		return errors.New("email not verified")
+	}
+
+	return n.mailer.Send(ctx, noticeMail(u.Email, notice))
+}
+
+// noticeMail Составляет письмо с уведомлением
+func noticeMail(to string, notice models.Notice) models.Mail {
+	var b strings.Builder
+
+	subject := "Подписка скоро закончится"
+	if notice.Kind == models.NoticeSubExpired {
+		subject = "Подписка закончилась"
+		fmt.Fprintf(&b, "Подписка аккаунта %s закончилась %s.\n", notice.Username, notice.ExpiresAt.Format(timeFormat))
+	} else {
+		fmt.Fprintf(&b, "Подписка аккаунта %s закончится %s.\n", notice.Username, notice.ExpiresAt.Format(timeFormat))
+	}
+
+	if len(notice.DeleteLinks) == 0 {
+		b.WriteString("Все ваши ссылки укладываются в лимиты бесплатного плана и будут сохранены.\n")
+	} else {
+		fmt.Fprintf(&b, "\n%s ссылки сверх лимитов бесплатного плана будут удалены:\n", notice.CleanupAt.Format(timeFormat))
+		for _, link := range notice.DeleteLinks {
+			fmt.Fprintf(&b, "- %s\n", link)
+		}
+		b.WriteString("\nПродлите подписку или выберите ссылки, которые нужно сохранить, в настройках подписки.\n")
+	}
+
+	return models.Mail{
+		To:			to,
+		Subject:	subject,
+		Body:		b.String(),
+	}
+}
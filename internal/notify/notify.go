@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/events"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+)
+
+// Каналы уведомлений
+const (
+	ModeMail	= "mail"		// Письмо на подтвержденную почту пользователя
+	ModeWebhook	= "webhook"		// POST-запрос с уведомлением в формате JSON
+	ModeLog		= "log"			// Запись в лог (для разработки и тестов)
+)
+
+// Notifier Интерфейс отправки уведомлений пользователям
+type Notifier interface {
+	Notify(ctx context.Context, notice models.Notice) error
+}
+
+// mailSender Интерфейс к отправителю писем
+type mailSender interface {
+	Send(ctx context.Context, mail models.Mail) error
+}
+
+// userRepository Интерфейс к репозиторию пользователей (для поиска почты)
+type userRepository interface {
+	FindByUsername(ctx context.Context, username string) (models.UserDB, error)
+}
+
+// Config Зависимости для создания Notifier
+type Config struct {
+	Conf		*models.ConfigNotify
+	Mailer		mailSender
+	UserRepo	userRepository
+	Logger		*log.Log
+}
+
+// NewNotifier Создает канал уведомлений по конфигурации
+func NewNotifier(c *Config) (Notifier, error) {
+	switch c.Conf.Mode {
+	case ModeMail:
+		return NewMailNotifier(c.Mailer, c.UserRepo), nil
+	case ModeWebhook:
+		if c.Conf.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook url is required for notify mode %q", ModeWebhook)
+		}
+		return NewWebhookNotifier(c.Conf.WebhookURL), nil
+	case ModeLog, "":
+		return NewLogNotifier(c.Logger), nil
+	default:
+		return nil, fmt.Errorf("unknown notify mode %q", c.Conf.Mode)
+	}
+}
+
+// Subscribe Подписывает канал уведомлений на события окончания подписки
+func Subscribe(b *events.Bus, n Notifier) {
+	events.On(b, func(ctx context.Context, ev events.SubscriptionExpiring) error {
+		return n.Notify(ctx, models.Notice{
+			Kind:			models.NoticeSubExpiring,
+			Username:		ev.Username,
+			ExpiresAt:		ev.ExpiresAt,
+			CleanupAt:		ev.CleanupAt,
+			DeleteLinks:	ev.DeleteLinks,
+		})
+	})
+	events.On(b, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		return n.Notify(ctx, models.Notice{
+			Kind:			models.NoticeSubExpired,
+			Username:		ev.Username,
+			ExpiresAt:		ev.ExpiresAt,
+			CleanupAt:		ev.CleanupAt,
+			DeleteLinks:	ev.DeleteLinks,
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"short_url/internal/models"
+	"time"
+)
+
+// Таймаут запроса к адресу уведомлений
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier Отправляет уведомления POST-запросом в формате JSON
+type WebhookNotifier struct {
+	url		string
+	client	*http.Client
+}
+
+// NewWebhookNotifier Конструктор для WebhookNotifier
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:	url,
+		client:	&http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify Отправляет уведомление на адрес из конфигурации
+func (n *WebhookNotifier) Notify(ctx context.Context, notice models.Notice) error {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"short_url/internal/models"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var got models.Notice
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL)
+	notice := models.Notice{Kind: models.NoticeSubExpiring, Username: "alice", DeleteLinks: []string{"abc"}}
+
+	if err := n.Notify(context.Background(), notice); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Kind != notice.Kind || got.Username != "alice" || len(got.DeleteLinks) != 1 {
+		t.Fatalf("unexpected notice received: %+v", got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := n.Notify(context.Background(), notice); err == nil {
+		t.Fatal("expected error on failed delivery")
+	}
+}
@@ -0,0 +1,336 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"short_url/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Config Параметры клиента OIDC
+type Config struct {
+	Issuer			string
+	ClientID		string
+	ClientSecret	string
+	RedirectURL		string
+	Scopes			[]string
+	Client			*http.Client
+}
+
+// discovery Документ /.well-known/openid-configuration (используемые поля)
+type discovery struct {
+	Issuer			string	`json:"issuer"`
+	AuthEndpoint	string	`json:"authorization_endpoint"`
+	TokenEndpoint	string	`json:"token_endpoint"`
+	JWKSURI			string	`json:"jwks_uri"`
+}
+
+// jwk Открытый ключ провайдера (поддерживаются только RSA)
+type jwk struct {
+	Kid	string	`json:"kid"`
+	Kty	string	`json:"kty"`
+	N	string	`json:"n"`
+	E	string	`json:"e"`
+}
+
+// Как часто можно перечитывать JWKS из-за неизвестного kid. Токен с чужим kid может прислать кто угодно,
+// поэтому без ограничения каждый такой запрос превращался бы в запрос к провайдеру
+const KeysRefreshInterval = time.Minute
+
+// tokenResponse Ответ token endpoint
+type tokenResponse struct {
+	IDToken		string	`json:"id_token"`
+	Error		string	`json:"error"`
+	ErrorDesc	string	`json:"error_description"`
+}
+
+// Provider Клиент OIDC: authorization code flow с PKCE (RFC 7636) и проверкой ID token
+type Provider struct {
+	conf	Config
+	client	*http.Client
+	meta	*discovery
+	keys	map[string]*rsa.PublicKey
+	mux		sync.Mutex
+
+	refreshedAt	time.Time			// Когда JWKS перечитывался последний раз
+	refreshMux	sync.Mutex			// Перечитывает JWKS один запрос за раз
+	now			func() time.Time
+}
+
+// NewProvider Конструктор для Provider. Документ discovery загружается при первом обращении
+func NewProvider(conf Config) *Provider {
+	client := conf.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &Provider{
+		conf:	conf,
+		client:	client,
+		keys:	make(map[string]*rsa.PublicKey),
+		now:	time.Now,
+	}
+}
+
+// Issuer Возвращает идентификатор провайдера
+func (p *Provider) Issuer() string {
+	return p.conf.Issuer
+}
+
+// getJSON Выполняет GET-запрос и разбирает JSON-ответ
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// discover Загружает и кэширует документ discovery
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mux.Lock()
+	meta := p.meta
+	p.mux.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	meta = &discovery{}
+	err := p.getJSON(ctx, strings.TrimRight(p.conf.Issuer, "/")+"/.well-known/openid-configuration", meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Issuer != p.conf.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: expected %s, got %s", p.conf.Issuer, meta.Issuer)
+	}
+
+	p.mux.Lock()
+	p.meta = meta
+	p.mux.Unlock()
+
+	return meta, nil
+}
+
+// CodeChallenge Вычисляет code_challenge по code_verifier (метод S256)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL Формирует адрес страницы входа провайдера
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.conf.ClientID)
+	v.Set("redirect_uri", p.conf.RedirectURL)
+	v.Set("scope", strings.Join(p.conf.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthEndpoint, "?") {
+		sep = "&"
+	}
+
+	return meta.AuthEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange Обменивает код авторизации на ID token и проверяет его
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (models.OIDCClaims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return models.OIDCClaims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.conf.RedirectURL)
+	form.Set("client_id", p.conf.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.conf.ClientSecret != "" {
+		form.Set("client_secret", p.conf.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return models.OIDCClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return models.OIDCClaims{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return models.OIDCClaims{}, err
+	}
+
+	tokens := tokenResponse{}
+	if err = json.Unmarshal(body, &tokens); err != nil {
+		return models.OIDCClaims{}, fmt.Errorf("unable to parse token response: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return models.OIDCClaims{}, fmt.Errorf("token endpoint error %d: %s %s", resp.StatusCode, tokens.Error, tokens.ErrorDesc)
+	}
+	if tokens.IDToken == "" {
+		return models.OIDCClaims{}, errors.New("token response without id_token")
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// findKey Ищет ключ среди загруженных
+func (p *Provider) findKey(kid string) (*rsa.PublicKey, bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// publicKey Возвращает ключ провайдера по kid, при неизвестном kid перечитывает JWKS (ротация ключей),
+// но не чаще раза в KeysRefreshInterval: в промежутке неизвестный kid сразу отклоняется
+func (p *Provider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key, ok := p.findKey(kid); ok {
+		return key, nil
+	}
+
+	p.refreshMux.Lock()
+	defer p.refreshMux.Unlock()
+
+	// Пока ждали, ключи мог перечитать параллельный запрос
+	if key, ok := p.findKey(kid); ok {
+		return key, nil
+	}
+	now := p.now()
+	if !p.refreshedAt.IsZero() && now.Sub(p.refreshedAt) < KeysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	// Неудачная попытка тоже считается, иначе недоступный провайдер опрашивался бы на каждый запрос
+	p.refreshedAt = now
+
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err = p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N:	new(big.Int).SetBytes(n),
+			E:	int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mux.Lock()
+	p.keys = keys
+	p.mux.Unlock()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+// VerifyIDToken Проверяет подпись и утверждения ID token (iss, aud, exp, nonce)
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (models.OIDCClaims, error) {
+	result := models.OIDCClaims{}
+	claims := jwt.MapClaims{}
+
+	parser := jwt.Parser{ValidMethods: []string{"RS256"}}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return result, fmt.Errorf("invalid id token: %s", err)
+	}
+
+	if !claims.VerifyIssuer(p.conf.Issuer, true) {
+		return result, errors.New("invalid id token issuer")
+	}
+	if !claims.VerifyAudience(p.conf.ClientID, true) {
+		return result, errors.New("invalid id token audience")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return result, errors.New("id token expired")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return result, errors.New("invalid id token nonce")
+	}
+
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+	if result.Subject == "" {
+		return result, errors.New("id token without subject")
+	}
+
+	return result, nil
+}
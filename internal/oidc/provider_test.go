@@ -0,0 +1,196 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// mockIssuer Локальный провайдер OIDC для тестов: discovery, JWKS и token endpoint
+type mockIssuer struct {
+	server		*httptest.Server
+	key			*rsa.PrivateKey
+	challenge	string	// code_challenge из адреса входа
+	nonce		string
+	audience	string
+	jwksHits	int		// Сколько раз запрашивали JWKS
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, audience: "client"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":					m.server.URL,
+			"authorization_endpoint":	m.server.URL + "/authorize",
+			"token_endpoint":			m.server.URL + "/token",
+			"jwks_uri":					m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.jwksHits++
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kid":	"k1",
+				"kty":	"RSA",
+				"n":	base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":	base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || CodeChallenge(r.Form.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":					m.server.URL,
+			"aud":					[]string{m.audience},
+			"sub":					"42",
+			"exp":					time.Now().Add(time.Minute).Unix(),
+			"iat":					time.Now().Unix(),
+			"nonce":				m.nonce,
+			"email":				"alice@example.com",
+			"email_verified":		true,
+			"preferred_username":	"alice",
+		})
+		token.Header["kid"] = "k1"
+		raw, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": raw, "token_type": "Bearer"})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize Имитирует страницу входа: запоминает challenge и nonce из адреса
+func (m *mockIssuer) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client" {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+}
+
+func newTestProvider(m *mockIssuer) *Provider {
+	return NewProvider(Config{
+		Issuer:			m.server.URL,
+		ClientID:		"client",
+		RedirectURL:	"http://short/v1/oidc/callback",
+	})
+}
+
+func TestProviderExchange(t *testing.T) {
+	m := newMockIssuer(t)
+	p := newTestProvider(m)
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m.authorize(t, authURL)
+
+	// Код без правильного code_verifier не обменивается
+	if _, err = p.Exchange(ctx, "good-code", "other-verifier", "nonce-1"); err == nil {
+		t.Fatal("expected error for wrong code verifier")
+	}
+
+	claims, err := p.Exchange(ctx, "good-code", "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if claims.Subject != "42" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.PreferredUsername != "alice" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestProviderRejectsForeignToken(t *testing.T) {
+	m := newMockIssuer(t)
+	p := newTestProvider(m)
+	ctx := context.Background()
+
+	authURL, _ := p.AuthCodeURL(ctx, "state", "nonce-1", "verifier-1")
+	m.authorize(t, authURL)
+
+	// Подмененный nonce
+	if _, err := p.Exchange(ctx, "good-code", "verifier-1", "nonce-2"); err == nil {
+		t.Fatal("expected error for nonce mismatch")
+	}
+
+	// Токен, выданный другому клиенту
+	m.audience = "other-client"
+	if _, err := p.Exchange(ctx, "good-code", "verifier-1", "nonce-1"); err == nil {
+		t.Fatal("expected error for foreign audience")
+	}
+}
+
+func TestProviderLimitsKeyRefresh(t *testing.T) {
+	m := newMockIssuer(t)
+	p := newTestProvider(m)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Поддельный токен с неизвестным kid
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": m.server.URL, "aud": "client", "sub": "42"})
+	forged.Header["kid"] = "k2"
+	raw, _ := forged.SignedString(other)
+
+	// JWKS перечитывается не чаще раза в интервал, сколько бы поддельных токенов ни пришло
+	for i := 0; i < 5; i++ {
+		if _, err = p.VerifyIDToken(ctx, raw, ""); err == nil {
+			t.Fatal("expected error for unknown key id")
+		}
+	}
+	if m.jwksHits != 1 {
+		t.Fatalf("expected single JWKS request, got %d", m.jwksHits)
+	}
+
+	now = now.Add(KeysRefreshInterval)
+	p.VerifyIDToken(ctx, raw, "")
+	p.VerifyIDToken(ctx, raw, "")
+	if m.jwksHits != 2 {
+		t.Fatalf("expected JWKS refresh after interval, got %d requests", m.jwksHits)
+	}
+
+	// Известный ключ продолжает приниматься без запросов к провайдеру
+	authURL, _ := p.AuthCodeURL(ctx, "state", "nonce-1", "verifier-1")
+	m.authorize(t, authURL)
+	if _, err = p.Exchange(ctx, "good-code", "verifier-1", "nonce-1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.jwksHits != 2 {
+		t.Fatalf("known key must not trigger refresh, got %d requests", m.jwksHits)
+	}
+}
@@ -0,0 +1,127 @@
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"short_url/internal/models"
+)
+
+// Имя бесплатного плана (обязателен в каталоге, его лимиты действуют без подписки)
+const Free = "free"
+
+// Лимиты по умолчанию (для каталога, собранного из цен)
+var (
+	FreeQuota	= models.LinkQuota{All: 50, Custom: 15, Perm: 0}
+	SubQuota	= models.LinkQuota{All: 100, Custom: 30, Perm: 10}
+)
+
+// Catalog Каталог тарифных планов
+type Catalog struct {
+	plans	[]models.Plan
+	byName	map[string]models.Plan
+}
+
+// NewCatalog Проверяет планы и создает каталог
+func NewCatalog(list []models.Plan) (*Catalog, error) {
+	c := &Catalog{
+		plans:	make([]models.Plan, 0, len(list)),
+		byName:	make(map[string]models.Plan, len(list)),
+	}
+
+	for _, p := range list {
+		if p.Name == "" {
+			return nil, fmt.Errorf("plan without name")
+		}
+		if _, ok := c.byName[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate plan %q", p.Name)
+		}
+		if p.Quota.All < 0 || p.Quota.Custom < 0 || p.Quota.Perm < 0 {
+			return nil, fmt.Errorf("plan %q: negative quota", p.Name)
+		}
+
+		if p.Name == Free {
+			if p.Price != 0 || p.Days != 0 {
+				return nil, fmt.Errorf("plan %q must have zero price and days", Free)
+			}
+		} else if p.Price <= 0 || p.Days <= 0 {
+			return nil, fmt.Errorf("plan %q: price and days must be positive", p.Name)
+		}
+
+		c.plans = append(c.plans, p)
+		c.byName[p.Name] = p
+	}
+
+	if _, ok := c.byName[Free]; !ok {
+		return nil, fmt.Errorf("plan %q is required", Free)
+	}
+	if len(c.plans) < 2 {
+		return nil, fmt.Errorf("at least one paid plan is required")
+	}
+
+	return c, nil
+}
+
+// Load Загружает каталог из файла конфигурации или собирает его из цен подписок
+func Load(conf *models.ConfigPlans, prices *models.ConfigPrice) (*Catalog, error) {
+	if conf.File == "" {
+		return NewCatalog(FromPrices(*prices))
+	}
+
+	data, err := os.ReadFile(conf.File)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []models.Plan
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", conf.File, err)
+	}
+
+	return NewCatalog(list)
+}
+
+// FromPrices Собирает прежний набор планов (неделя, месяц, год) из цен подписок
+func FromPrices(prices models.ConfigPrice) []models.Plan {
+	return []models.Plan{
+		{Name: Free, Title: "Бесплатный", Quota: FreeQuota},
+		{Name: "weekly", Title: "Неделя", Price: prices.Weekly, Days: 7, Quota: SubQuota},
+		{Name: "monthly", Title: "Месяц", Price: prices.Monthly, Days: 31, Quota: SubQuota},
+		{Name: "yearly", Title: "Год", Price: prices.Yearly, Days: 365, Quota: SubQuota},
+	}
+}
+
+// All Возвращает все планы в порядке объявления
+func (c *Catalog) All() []models.Plan {
+	result := make([]models.Plan, len(c.plans))
+	copy(result, c.plans)
+
+	return result
+}
+
+// Find Находит план по имени
+func (c *Catalog) Find(name string) (models.Plan, bool) {
+	p, ok := c.byName[name]
+	return p, ok
+}
+
+// Free Возвращает бесплатный план
+func (c *Catalog) Free() models.Plan {
+	return c.byName[Free]
+}
+
+// Subscribed Возвращает план действующей подписки. Подписки, оформленные до появления каталога
+// (или по плану, удаленному из каталога), получают первый платный план
+func (c *Catalog) Subscribed(name string) models.Plan {
+	if p, ok := c.byName[name]; ok && p.Name != Free {
+		return p
+	}
+
+	for _, p := range c.plans {
+		if p.Name != Free {
+			return p
+		}
+	}
+
+	return c.Free()
+}
@@ -0,0 +1,84 @@
+package plans
+
+import (
+	"os"
+	"path/filepath"
+	"short_url/internal/models"
+	"testing"
+)
+
+func TestNewCatalogValidation(t *testing.T) {
+	free := models.Plan{Name: Free}
+	paid := models.Plan{Name: "monthly", Price: 200, Days: 31}
+
+	cases := map[string][]models.Plan{
+		"no free plan":		{paid},
+		"no paid plan":		{free},
+		"duplicate":		{free, paid, paid},
+		"paid free plan":	{{Name: Free, Price: 1}, paid},
+		"zero days":		{free, {Name: "weekly", Price: 50}},
+		"negative quota":	{free, {Name: "weekly", Price: 50, Days: 7, Quota: models.LinkQuota{All: -1}}},
+	}
+	for name, list := range cases {
+		if _, err := NewCatalog(list); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := NewCatalog([]models.Plan{free, paid}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestCatalogSubscribed(t *testing.T) {
+	c, err := NewCatalog(FromPrices(models.ConfigPrice{Weekly: 50, Monthly: 200, Yearly: 600}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := c.Subscribed("yearly"); p.Name != "yearly" || p.Quota != SubQuota {
+		t.Fatalf("unexpected plan %+v", p)
+	}
+
+	// Подписка до появления каталога хранит "1" вместо имени плана
+	if p := c.Subscribed("1"); p.Name != "weekly" {
+		t.Fatalf("legacy subscribe must get first paid plan, got %+v", p)
+	}
+	if p := c.Subscribed(Free); p.Name == Free {
+		t.Fatal("active subscribe must not get free plan")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plans.json")
+	data := `[
+		{"name": "free", "quota": {"all": 5, "custom": 1}},
+		{"name": "team", "price": 999, "days": 90, "quota": {"all": 1000, "custom": 500, "perm": 100}}
+	]`
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(&models.ConfigPlans{File: file}, &models.ConfigPrice{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p, ok := c.Find("team")
+	if !ok || p.Duration().Hours() != 90*24 || p.Quota.Perm != 100 {
+		t.Fatalf("unexpected plan %+v", p)
+	}
+	if c.Free().Quota.All != 5 {
+		t.Fatalf("unexpected free plan %+v", c.Free())
+	}
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	c, err := Load(&models.ConfigPlans{File: "../../configs/plans.json"}, &models.ConfigPrice{})
+	if err != nil {
+		t.Fatalf("example catalog must be valid: %s", err)
+	}
+	if c.Free().Quota != FreeQuota {
+		t.Fatalf("example free plan must match defaults, got %+v", c.Free().Quota)
+	}
+}
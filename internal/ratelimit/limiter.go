@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Группы маршрутов с отдельными правилами
+const (
+	GroupAPI		= "api"			// Остальные запросы к API
+	GroupAuth		= "auth"		// Вход, регистрация, сброс пароля
+	GroupLink		= "link"		// Создание ссылок
+	GroupRedirect	= "redirect"	// Переход по короткой ссылке
+	GroupPay		= "pay"			// Выставление счетов QIWI
+)
+
+// Как часто писать в лог о работе без Redis
+const fallbackLogInterval = time.Minute
+
+// Rules Правила ограничения по группам маршрутов и тарифам
+type Rules map[string]map[string]models.RateRule
+
+// Config Конфигурация для Limiter
+type Config struct {
+	Rules	Rules
+	DB		*redis.Client	// nil - только ограничитель в памяти
+	Logger	*log.Log
+}
+
+// Limiter Ограничитель частоты запросов по алгоритму token bucket.
+// Квоты хранятся в Redis и делятся между экземплярами сервиса, при недоступности Redis
+// используется ограничитель в памяти процесса
+type Limiter struct {
+	rules		Rules
+	redis		*RedisStore
+	memory		*MemoryStore
+	logger		*log.Log
+	now			func() time.Time
+	lastLog		int64
+}
+
+// NewLimiter Конструктор для Limiter
+func NewLimiter(c *Config) *Limiter {
+	l := &Limiter{
+		rules:	c.Rules,
+		memory:	NewMemoryStore(),
+		logger:	c.Logger,
+		now:	time.Now,
+	}
+	if c.DB != nil {
+		l.redis = NewRedisStore(c.DB)
+	}
+
+	return l
+}
+
+// NewRules Собирает правила из конфигурации
+func NewRules(conf *models.ConfigRateLimit) (Rules, error) {
+	groups := map[string]string{
+		GroupAPI:		conf.API,
+		GroupAuth:		conf.Auth,
+		GroupLink:		conf.Link,
+		GroupRedirect:	conf.Redirect,
+		GroupPay:		conf.Pay,
+	}
+
+	rules := make(Rules, len(groups))
+	for group, s := range groups {
+		tiers, err := ParseRules(s)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %s", group, err)
+		}
+		rules[group] = tiers
+	}
+
+	return rules, nil
+}
+
+// ParseRules Разбирает правила тарифов вида "default=120/1m,sub=600/1m"
+func ParseRules(s string) (map[string]models.RateRule, error) {
+	result := make(map[string]models.RateRule)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid rule %q", part)
+		}
+
+		rule, err := ParseRule(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		result[strings.TrimSpace(kv[0])] = rule
+	}
+
+	return result, nil
+}
+
+// ParseRule Разбирает правило вида "120/1m" (120 запросов в минуту)
+func ParseRule(s string) (models.RateRule, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "/", 2)
+	if len(parts) != 2 {
+		return models.RateRule{}, fmt.Errorf("invalid rule %q", s)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return models.RateRule{}, fmt.Errorf("invalid limit in rule %q", s)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return models.RateRule{}, fmt.Errorf("invalid period in rule %q", s)
+	}
+
+	return models.RateRule{Limit: limit, Period: period}, nil
+}
+
+// Allow Расходует одну единицу квоты ключа в группе. Если для тарифа нет правила,
+// запрос разрешается, а Limit результата равен 0
+func (l *Limiter) Allow(ctx context.Context, group, tier, key string) models.RateResult {
+	if l == nil {
+		return models.RateResult{Allowed: true}
+	}
+
+	rule, ok := l.rules[group][tier]
+	if !ok {
+		return models.RateResult{Allowed: true}
+	}
+
+	key = group + ":" + key
+	now := l.now()
+
+	if l.redis != nil {
+		res, err := l.redis.Take(ctx, key, rule, now)
+		if err == nil {
+			return res
+		}
+
+		// Не засоряем лог при длительной недоступности Redis
+		last := atomic.LoadInt64(&l.lastLog)
+		if now.UnixNano()-last >= int64(fallbackLogInterval) && atomic.CompareAndSwapInt64(&l.lastLog, last, now.UnixNano()) {
+			l.logger.WithContext(ctx).Errorf("rate limit: Redis unavailable, using in-memory limiter. Error: %s", err)
+		}
+	}
+
+	return l.memory.Take(key, rule, now)
+}
+
+// refill Возвращает кол-во токенов после пополнения за прошедшее время
+func refill(tokens float64, elapsed time.Duration, rule models.RateRule) float64 {
+	if elapsed > 0 {
+		tokens += float64(elapsed) * float64(rule.Limit) / float64(rule.Period)
+	}
+
+	return math.Min(tokens, float64(rule.Limit))
+}
+
+// result Формирует результат по остатку токенов после проверки запроса
+func result(tokens float64, allowed bool, rule models.RateRule) models.RateResult {
+	perToken := float64(rule.Period) / float64(rule.Limit)
+
+	res := models.RateResult{
+		Allowed:	allowed,
+		Limit:		rule.Limit,
+		Remaining:	int(math.Floor(tokens)),
+		Reset:		time.Duration((float64(rule.Limit) - tokens) * perToken),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+
+	return res
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("anon=60/1m, sub=5/10s")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rules[models.TierAnon] != (models.RateRule{Limit: 60, Period: time.Minute}) || rules["sub"] != (models.RateRule{Limit: 5, Period: 10 * time.Second}) {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+
+	for _, s := range []string{"anon", "anon=60", "anon=0/1m", "anon=10/0s", "=1/1m"} {
+		if _, err = ParseRules(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	m := NewMemoryStore()
+	rule := models.RateRule{Limit: 2, Period: 2 * time.Second}
+	now := time.Unix(1000, 0)
+
+	if res := m.Take("k", rule, now); !res.Allowed || res.Remaining != 1 || res.Reset != time.Second {
+		t.Fatalf("unexpected first result: %+v", res)
+	}
+	m.Take("k", rule, now)
+
+	res := m.Take("k", rule, now)
+	if res.Allowed || res.Remaining != 0 || res.RetryAfter != time.Second {
+		t.Fatalf("expected denial with retry after 1s, got %+v", res)
+	}
+
+	// Через секунду восстанавливается один токен
+	if res = m.Take("k", rule, now.Add(time.Second)); !res.Allowed {
+		t.Fatalf("expected token after refill, got %+v", res)
+	}
+
+	// Ключи независимы
+	if res = m.Take("other", rule, now); !res.Allowed {
+		t.Fatalf("other key must not be limited, got %+v", res)
+	}
+}
+
+func TestLimiterFallback(t *testing.T) {
+	// Redis недоступен: порт закрыт, повторы отключены
+	db := redis.NewClient(&redis.Options{
+		Addr:			"127.0.0.1:1",
+		DialTimeout:	100 * time.Millisecond,
+		MaxRetries:		-1,
+	})
+	defer db.Close()
+
+	l := NewLimiter(&Config{
+		Rules:	Rules{GroupLink: {"default": {Limit: 1, Period: time.Minute}}},
+		DB:		db,
+		Logger:	&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	if res := l.Allow(ctx, GroupLink, "default", "user:alice"); !res.Allowed || res.Limit != 1 {
+		t.Fatalf("expected first request allowed, got %+v", res)
+	}
+	if res := l.Allow(ctx, GroupLink, "default", "user:alice"); res.Allowed {
+		t.Fatalf("in-memory limiter must deny second request, got %+v", res)
+	}
+
+	// Тариф без правила не ограничивается
+	if res := l.Allow(ctx, GroupLink, "sub", "user:bob"); !res.Allowed || res.Limit != 0 {
+		t.Fatalf("expected unlimited tier, got %+v", res)
+	}
+}
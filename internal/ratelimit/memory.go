@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"short_url/internal/models"
+	"sync"
+	"time"
+)
+
+// Сколько ключей хранится в памяти до чистки восстановившихся квот
+const memoryMaxKeys = 100000
+
+// bucket Квота одного ключа
+type bucket struct {
+	tokens	float64
+	ts		time.Time
+	period	time.Duration
+}
+
+// MemoryStore Хранилище квот в памяти процесса (квоты не делятся между экземплярами сервиса)
+type MemoryStore struct {
+	mu		sync.Mutex
+	buckets	map[string]*bucket
+}
+
+// NewMemoryStore Конструктор для MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:	make(map[string]*bucket),
+	}
+}
+
+// Take Расходует токен из квоты ключа
+func (m *MemoryStore) Take(key string, rule models.RateRule, now time.Time) models.RateResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		if len(m.buckets) >= memoryMaxKeys {
+			m.prune(now)
+		}
+
+		b = &bucket{tokens: float64(rule.Limit), ts: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.ts), rule)
+	b.ts = now
+	b.period = rule.Period
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return result(b.tokens, allowed, rule)
+}
+
+// prune Удаляет ключи, квота которых уже полностью восстановилась
+func (m *MemoryStore) prune(now time.Time) {
+	for key, b := range m.buckets {
+		if now.Sub(b.ts) >= b.period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Префикс ключей квот в Redis
+const redisPrefix = "ratelimit:"
+
+// takeScript Атомарно пополняет квоту и расходует токен.
+// KEYS[1] - ключ квоты, ARGV: лимит, период (мс), текущее время (мс)
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = limit
+	ts = now
+end
+
+if now > ts then
+	tokens = math.min(limit, tokens + (now - ts) * limit / period)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(math.max(now, ts)))
+redis.call('PEXPIRE', KEYS[1], period)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore Хранилище квот в Redis, общее для всех экземпляров сервиса
+type RedisStore struct {
+	db	*redis.Client
+}
+
+// NewRedisStore Конструктор для RedisStore
+func NewRedisStore(db *redis.Client) *RedisStore {
+	return &RedisStore{
+		db:	db,
+	}
+}
+
+// Take Расходует токен из квоты ключа
+func (r *RedisStore) Take(ctx context.Context, key string, rule models.RateRule, now time.Time) (models.RateResult, error) {
+	reply, err := takeScript.Run(ctx, r.db, []string{redisPrefix + key},
+		rule.Limit, rule.Period.Milliseconds(), now.UnixMilli()).Slice()
+	if err != nil {
+		return models.RateResult{}, err
+	}
+	if len(reply) != 2 {
+		return models.RateResult{}, fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	s, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return models.RateResult{}, err
+	}
+
+	return result(tokens, allowed == 1, rule), nil
+}
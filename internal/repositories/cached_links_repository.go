@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CachedLinkRepositoryConfig Конфигурация для CachedLinkRepository
+type CachedLinkRepositoryConfig struct {
+	Repo		*RedisLinkRepository
+	Size		int				// Сколько ссылок хранится в кэше (0 - кэш выключен)
+	TTL			time.Duration	// Сколько хранится найденная ссылка
+	NegativeTTL	time.Duration	// Сколько хранится отметка о несуществующей ссылке
+	Channel		string			// Канал Redis для сброса кэша на других экземплярах
+	Logger		*log.Log
+}
+
+// linkEntry Закэшированная ссылка. Пустая data.FullURL - отметка о несуществующей ссылке
+type linkEntry struct {
+	link		string
+	data		models.LinkDataDB
+	expireAt	time.Time	// Когда запись устаревает
+	linkExpAt	time.Time	// Когда истекает сама ссылка (нулевое - бессрочная)
+}
+
+// CachedLinkRepository Репозиторий ссылок с LRU-кэшем для переадресации.
+// Запись живет не дольше TTL и не дольше самой ссылки. Изменения ссылок сбрасывают кэш на этом экземпляре сразу,
+// на остальных - через сообщение в канале Redis (если сообщение потеряно, запись устареет через TTL)
+type CachedLinkRepository struct {
+	*RedisLinkRepository
+	size		int
+	ttl			time.Duration
+	negativeTTL	time.Duration
+	channel		string
+	logger		*log.Log
+
+	entries		map[string]*list.Element
+	order		*list.List	// Недавно использованные - в начале
+	gen			uint64		// Счетчик сбросов: чтение из Redis, начатое до сброса, в кэш не попадает
+	mux			sync.Mutex
+
+	Requests		*prometheus.CounterVec
+	Invalidations	*prometheus.CounterVec
+}
+
+// NewCachedLinkRepository Конструктор для CachedLinkRepository
+func NewCachedLinkRepository(c *CachedLinkRepositoryConfig) *CachedLinkRepository {
+	return &CachedLinkRepository{
+		RedisLinkRepository:	c.Repo,
+		size:					c.Size,
+		ttl:					c.TTL,
+		negativeTTL:			c.NegativeTTL,
+		channel:				c.Channel,
+		logger:					c.Logger,
+		entries:				make(map[string]*list.Element),
+		order:					list.New(),
+		Requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "link_cache_requests_total",
+				Help: "Кол-во обращений к кэшу ссылок: hit - найдена ссылка, negative - найдена отметка об отсутствии, miss - запрос в Redis",
+			},
+			[]string{"result"},
+		),
+		Invalidations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "link_cache_invalidations_total",
+				Help: "Кол-во сбросов записей кэша ссылок: local - изменения этого экземпляра, remote - других",
+			},
+			[]string{"source"},
+		),
+	}
+}
+
+// Collectors Возвращает метрики для регистрации
+func (r *CachedLinkRepository) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{r.Requests, r.Invalidations}
+}
+
+// FindLink Находит ссылку, обращаясь к Redis только при промахе кэша. Если ссылки нет, возвращает redis.Nil
+func (r *CachedLinkRepository) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	if r.size <= 0 {
+		return r.RedisLinkRepository.FindLink(ctx, link)
+	}
+
+	now := time.Now()
+	if entry, ok := r.get(link, now); ok {
+		if entry.data.FullURL == "" {
+			r.Requests.WithLabelValues("negative").Inc()
+			return models.LinkDataDB{Link: link}, redis.Nil
+		}
+
+		r.Requests.WithLabelValues("hit").Inc()
+		data := entry.data
+		if !entry.linkExpAt.IsZero() {
+			data.ExpTime = entry.linkExpAt.Sub(now)
+		}
+		return data, nil
+	}
+	r.Requests.WithLabelValues("miss").Inc()
+
+	r.mux.Lock()
+	gen := r.gen
+	r.mux.Unlock()
+
+	data, err := r.RedisLinkRepository.FindLink(ctx, link)
+	switch {
+	case errors.Is(err, redis.Nil):
+		r.put(gen, &linkEntry{link: link, data: models.LinkDataDB{Link: link}, expireAt: now.Add(r.negativeTTL)})
+	case err == nil:
+		entry := &linkEntry{link: link, data: data, expireAt: now.Add(r.ttl)}
+		if data.ExpTime > 0 {
+			entry.linkExpAt = now.Add(data.ExpTime)
+			if entry.linkExpAt.Before(entry.expireAt) {
+				entry.expireAt = entry.linkExpAt
+			}
+		}
+		r.put(gen, entry)
+	}
+
+	return data, err
+}
+
+// CreateLink Создает ссылку и сбрасывает отметку о ее отсутствии
+func (r *CachedLinkRepository) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error) {
+	defer r.invalidate(ctx, link)
+
+	return r.RedisLinkRepository.CreateLink(ctx, link, username, fullUrl, exp, custom, quota)
+}
+
+// DeleteLink Удаляет ссылку и сбрасывает ее в кэше
+func (r *CachedLinkRepository) DeleteLink(ctx context.Context, link, username string) error {
+	defer r.invalidate(ctx, link)
+
+	return r.RedisLinkRepository.DeleteLink(ctx, link, username)
+}
+
+// DisableLink Блокирует ссылку и сбрасывает ее в кэше
+func (r *CachedLinkRepository) DisableLink(ctx context.Context, link, reason string) error {
+	defer r.invalidate(ctx, link)
+
+	return r.RedisLinkRepository.DisableLink(ctx, link, reason)
+}
+
+// EnableLink Снимает блокировку ссылки и сбрасывает ее в кэше
+func (r *CachedLinkRepository) EnableLink(ctx context.Context, link string) error {
+	defer r.invalidate(ctx, link)
+
+	return r.RedisLinkRepository.EnableLink(ctx, link)
+}
+
+// Listen Сбрасывает записи кэша по сообщениям других экземпляров
+func (r *CachedLinkRepository) Listen(ctx context.Context) chan struct{} {
+	ctx = log.ContextWithSpan(ctx, "LinkCacheListen")
+	l := r.logger.WithContext(ctx)
+
+	// Канал сигнала остановки
+	doneChannel := make(chan struct{}, 1)
+
+	pubsub := r.db.Subscribe(ctx, r.channel)
+
+	go func(doneChannel chan struct{}, pubsub *redis.PubSub) {
+		l.Info("start link cache invalidation listener")
+		messages := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				r.forget(msg.Payload)
+				r.Invalidations.WithLabelValues("remote").Inc()
+
+			case <-doneChannel:
+				pubsub.Close()
+
+				l.Info("end link cache invalidation listener")
+
+				return
+			}
+		}
+	}(doneChannel, pubsub)
+
+	return doneChannel
+}
+
+// invalidate Сбрасывает ссылку в кэше этого экземпляра и сообщает об изменении остальным
+func (r *CachedLinkRepository) invalidate(ctx context.Context, link string) {
+	r.forget(link)
+	r.Invalidations.WithLabelValues("local").Inc()
+
+	if r.channel == "" {
+		return
+	}
+	if err := r.db.Publish(ctx, r.channel, link).Err(); err != nil {
+		r.logger.WithContext(ctx).Errorf("Unable to publish link cache invalidation. Error: %s", err)
+	}
+}
+
+// get Возвращает актуальную запись и отмечает ее использованной
+func (r *CachedLinkRepository) get(link string, now time.Time) (*linkEntry, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	el, ok := r.entries[link]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*linkEntry)
+	if !now.Before(entry.expireAt) {
+		r.order.Remove(el)
+		delete(r.entries, link)
+		return nil, false
+	}
+	r.order.MoveToFront(el)
+
+	return entry, true
+}
+
+// put Добавляет запись, вытесняя давно не использованные. Если с начала чтения кэш сбрасывался, запись отбрасывается
+func (r *CachedLinkRepository) put(gen uint64, entry *linkEntry) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if gen != r.gen {
+		return
+	}
+
+	if el, ok := r.entries[entry.link]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[entry.link] = r.order.PushFront(entry)
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*linkEntry).link)
+	}
+}
+
+// forget Удаляет ссылку из кэша
+func (r *CachedLinkRepository) forget(link string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.gen++
+	if el, ok := r.entries[link]; ok {
+		r.order.Remove(el)
+		delete(r.entries, link)
+	}
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func newTestCachedLinkRepository(db *redis.Client, size int) *CachedLinkRepository {
+	return NewCachedLinkRepository(&CachedLinkRepositoryConfig{
+		Repo:			NewRedisLinkRepository(&RedisLinkRepositoryConfig{DB: db}),
+		Size:			size,
+		TTL:			time.Minute,
+		NegativeTTL:	time.Minute,
+		Channel:		"test:cache:links",
+		Logger:			&log.Log{Logger: zap.NewNop()},
+	})
+}
+
+func TestCachedLinkRepositoryFindLink(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	r := newTestCachedLinkRepository(db, 2)
+	ctx := context.Background()
+
+	r.CreateLink(ctx, "abc", "alice", "https://example.com", time.Hour, false, nil)
+
+	// Повторное чтение не идет в Redis
+	r.FindLink(ctx, "abc")
+	mr.HSet(LinkKey("abc"), "url", "https://changed.example")
+	link, err := r.FindLink(ctx, "abc")
+	if err != nil || link.FullURL != "https://example.com" || link.ExpTime <= 0 || link.ExpTime > time.Hour {
+		t.Fatalf("expected cached link, got %+v (%v)", link, err)
+	}
+
+	// Несуществующая ссылка тоже кэшируется, а ее создание сбрасывает отметку
+	if _, err = r.FindLink(ctx, "new"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil, got %v", err)
+	}
+	if _, err = r.FindLink(ctx, "new"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected cached redis.Nil, got %v", err)
+	}
+	r.CreateLink(ctx, "new", "alice", "https://example.org", 0, false, nil)
+	if link, err = r.FindLink(ctx, "new"); err != nil || link.FullURL != "https://example.org" {
+		t.Fatalf("expected created link, got %+v (%v)", link, err)
+	}
+
+	// Давно не использованная ссылка вытесняется
+	r.FindLink(ctx, "other")
+	if link, _ = r.FindLink(ctx, "abc"); link.FullURL != "https://changed.example" {
+		t.Fatalf("expected evicted link to be read from Redis, got %+v", link)
+	}
+
+	for result, want := range map[string]float64{"hit": 1, "negative": 1, "miss": 5} {
+		if got := testutil.ToFloat64(r.Requests.WithLabelValues(result)); got != want {
+			t.Fatalf("expected %v %s requests, got %v", want, result, got)
+		}
+	}
+}
+
+func TestCachedLinkRepositoryInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	a, b := newTestCachedLinkRepository(db, 10), newTestCachedLinkRepository(db, 10)
+	ctx := context.Background()
+
+	done := b.Listen(ctx)
+	defer func() { done <- struct{}{} }()
+
+	a.CreateLink(ctx, "abc", "alice", "https://example.com", 0, false, nil)
+	if link, err := b.FindLink(ctx, "abc"); err != nil || link.Disabled {
+		t.Fatalf("unexpected link %+v (%v)", link, err)
+	}
+
+	// Блокировка на одном экземпляре сбрасывает кэш другого
+	a.DisableLink(ctx, "abc", "spam")
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(b.Invalidations.WithLabelValues("remote")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if link, _ := b.FindLink(ctx, "abc"); !link.Disabled {
+		t.Fatal("expected disabled link after remote invalidation")
+	}
+
+	a.DeleteLink(ctx, "abc", "alice")
+	deadline = time.Now().Add(time.Second)
+	for testutil.ToFloat64(b.Invalidations.WithLabelValues("remote")) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := b.FindLink(ctx, "abc"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected deleted link after remote invalidation, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Сколько записей хранится в кэше до чистки устаревших
+const subCacheMaxKeys = 100000
+
+// CachedSubRepositoryConfig Конфигурация для CachedSubRepository
+type CachedSubRepositoryConfig struct {
+	Repo	*RedisSubRepository
+	TTL		time.Duration
+}
+
+// subState Закэшированное состояние подписки
+type subState struct {
+	plan	string
+	ok		bool
+	at		time.Time
+}
+
+// CachedSubRepository Репозиторий подписок с кратковременным кэшем состояния подписки.
+// Изменения через этот экземпляр сбрасывают кэш сразу, сделанные другими экземплярами сервиса - через TTL
+type CachedSubRepository struct {
+	*RedisSubRepository
+	ttl		time.Duration
+	states	map[string]subState
+	mux		sync.Mutex
+}
+
+// NewCachedSubRepository Конструктор для CachedSubRepository
+func NewCachedSubRepository(c *CachedSubRepositoryConfig) *CachedSubRepository {
+	return &CachedSubRepository{
+		RedisSubRepository:	c.Repo,
+		ttl:				c.TTL,
+		states:				make(map[string]subState),
+	}
+}
+
+// SubState Находит план действующей подписки, обращаясь к Redis не чаще раза в TTL
+func (r *CachedSubRepository) SubState(ctx context.Context, username string) (string, bool, error) {
+	now := time.Now()
+
+	r.mux.Lock()
+	state, found := r.states[username]
+	r.mux.Unlock()
+	if found && now.Sub(state.at) < r.ttl {
+		return state.plan, state.ok, nil
+	}
+
+	plan, ok, err := r.RedisSubRepository.SubState(ctx, username)
+	if err != nil {
+		return "", false, err
+	}
+
+	r.mux.Lock()
+	if len(r.states) >= subCacheMaxKeys {
+		r.prune(now)
+	}
+	r.states[username] = subState{plan: plan, ok: ok, at: now}
+	r.mux.Unlock()
+
+	return plan, ok, nil
+}
+
+// AddSubRedis Добавляет пользователю подписку и сбрасывает кэш
+func (r *CachedSubRepository) AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error {
+	defer r.forget(username)
+
+	return r.RedisSubRepository.AddSubRedis(ctx, username, plan, exp)
+}
+
+// RemoveSubscribe Удаляет подписку пользователя и сбрасывает кэш
+func (r *CachedSubRepository) RemoveSubscribe(ctx context.Context, username string) error {
+	defer r.forget(username)
+
+	return r.RedisSubRepository.RemoveSubscribe(ctx, username)
+}
+
+// forget Удаляет состояние пользователя из кэша
+func (r *CachedSubRepository) forget(username string) {
+	r.mux.Lock()
+	delete(r.states, username)
+	r.mux.Unlock()
+}
+
+// prune Удаляет устаревшие записи
+func (r *CachedSubRepository) prune(now time.Time) {
+	for username, state := range r.states {
+		if now.Sub(state.at) >= r.ttl {
+			delete(r.states, username)
+		}
+	}
+}
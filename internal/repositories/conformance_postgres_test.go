@@ -0,0 +1,714 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"short_url/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Переменная с адресом тестовой базы. Таблицы в ней создаются из migrations/init.sql и очищаются перед каждой проверкой
+const testPostgresEnv = "TEST_POSTGRES_URL"
+
+// newTestPostgres Подключается к тестовой базе. Без TEST_POSTGRES_URL проверка на Postgres пропускается
+func newTestPostgres(t *testing.T) *pgxpool.Pool {
+	url := os.Getenv(testPostgresEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testPostgresEnv)
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("unable connect to database: %s", err)
+	}
+	t.Cleanup(db.Close)
+
+	schema, err := os.ReadFile("../../migrations/init.sql")
+	if err != nil {
+		t.Fatalf("unable to read migrations: %s", err)
+	}
+	if _, err = db.Exec(ctx, string(schema)); err != nil {
+		t.Fatalf("unable to apply migrations: %s", err)
+	}
+
+	_, err = db.Exec(ctx, `TRUNCATE cpuser, recovery_code, user_identity, user_token, link_report, audit_log, bill,
+		promo_code, promo_redemption, qiwi_event, webhook, webhook_delivery RESTART IDENTITY`)
+	if err != nil {
+		t.Fatalf("unable to clean tables: %s", err)
+	}
+
+	return db
+}
+
+// postgresOrMemory Запускает проверку на Postgres и в памяти процесса (с системными часами, как у Postgres)
+func postgresOrMemory[T any](t *testing.T, newPostgres func(db *pgxpool.Pool) T, newMemory func() T, check func(t *testing.T, r T)) {
+	t.Run("postgres", func(t *testing.T) {
+		check(t, newPostgres(newTestPostgres(t)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		check(t, newMemory())
+	})
+}
+
+type conformanceUsers interface {
+	FindByUsername(ctx context.Context, username string) (models.UserDB, error)
+	FindByEmail(ctx context.Context, email string) (models.UserDB, error)
+	SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error)
+	UpdateUser(ctx context.Context, user models.UserDB) error
+	VerifyEmail(ctx context.Context, username, email string) error
+	SetTOTP(ctx context.Context, username, secret string, enabled bool) error
+	UseTOTPStep(ctx context.Context, username string, step int64) error
+	UpdatePassword(ctx context.Context, username, password string) error
+	DeleteUser(ctx context.Context, username string) error
+	CreateUser(ctx context.Context, user models.UserDB) error
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformanceUsers {
+		return NewPostgresqlUserRepository(&PostgresqlUserRepositoryConfig{Table: "cpuser", DB: db})
+	}, func() conformanceUsers {
+		return NewMemoryUserRepository()
+	}, func(t *testing.T, r conformanceUsers) {
+		ctx := context.Background()
+
+		if err := r.CreateUser(ctx, models.UserDB{Username: "alice", FirstName: "Alice", LastName: "Smith", Password: "hash", Email: "Alice@Example.com"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := r.CreateUser(ctx, models.UserDB{Username: "bob", FirstName: "Bob", Password: "hash"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := r.CreateUser(ctx, models.UserDB{Username: "carol", LastName: "Alison", Password: "hash"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Имя и почта (без учета регистра) уникальны, пустая почта - нет
+		if err := r.CreateUser(ctx, models.UserDB{Username: "alice", Password: "hash"}); err == nil {
+			t.Fatal("expected duplicate username error")
+		}
+		if err := r.CreateUser(ctx, models.UserDB{Username: "alice2", Password: "hash", Email: "alice@example.COM"}); err == nil {
+			t.Fatal("expected duplicate email error")
+		}
+
+		user, err := r.FindByUsername(ctx, "alice")
+		if err != nil || user.ID == "" || user.Role != models.RoleUser || user.EmailVerified || user.Password != "hash" {
+			t.Fatalf("unexpected user: %+v (%v)", user, err)
+		}
+		if _, err = r.FindByUsername(ctx, "missing"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+
+		// Поиск без учета регистра по логину, имени и фамилии, по алфавиту
+		found, err := r.SearchUsers(ctx, "ALI", 10)
+		if err != nil || len(found) != 2 || found[0].Username != "alice" || found[1].Username != "carol" || found[0].Password != "" {
+			t.Fatalf("unexpected search result: %+v (%v)", found, err)
+		}
+		if found, _ = r.SearchUsers(ctx, "ali", 1); len(found) != 1 {
+			t.Fatalf("expected limited result, got %+v", found)
+		}
+
+		// Почта подтверждается, только если не менялась
+		if err = r.VerifyEmail(ctx, "alice", "other@example.com"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+		if err = r.VerifyEmail(ctx, "alice", "Alice@Example.com"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err = r.SetTOTP(ctx, "alice", "secret", true); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Поиск по почте не возвращает данные второго фактора
+		user, err = r.FindByEmail(ctx, "alice@example.com")
+		if err != nil || user.Username != "alice" || !user.EmailVerified || user.TOTPSecret != "" || user.TOTPEnabled {
+			t.Fatalf("unexpected user by email: %+v (%v)", user, err)
+		}
+		if _, err = r.FindByEmail(ctx, ""); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows for empty email, got %v", err)
+		}
+		if user, _ = r.FindByUsername(ctx, "alice"); user.TOTPSecret != "secret" || !user.TOTPEnabled {
+			t.Fatalf("expected TOTP settings, got %+v", user)
+		}
+
+		// Шаг TOTP принимается только новее последнего принятого
+		if err = r.UseTOTPStep(ctx, "alice", 100); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, step := range []int64{100, 99} {
+			if err = r.UseTOTPStep(ctx, "alice", step); !errors.Is(err, pgx.ErrNoRows) {
+				t.Fatalf("step %d: expected pgx.ErrNoRows, got %v", step, err)
+			}
+		}
+		if err = r.UseTOTPStep(ctx, "alice", 101); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if err = r.UpdateUser(ctx, models.UserDB{Username: "bob", FirstName: "Robert", Email: "bob@example.com"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err = r.UpdateUser(ctx, models.UserDB{Username: "carol", Email: "BOB@example.com"}); err == nil {
+			t.Fatal("expected duplicate email error")
+		}
+		if err = r.UpdatePassword(ctx, "bob", "new"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if user, _ = r.FindByUsername(ctx, "bob"); user.FirstName != "Robert" || user.Email != "bob@example.com" || user.Password != "new" {
+			t.Fatalf("unexpected updated user: %+v", user)
+		}
+
+		for name, err := range map[string]error{
+			"UpdateUser":		r.UpdateUser(ctx, models.UserDB{Username: "missing"}),
+			"SetTOTP":			r.SetTOTP(ctx, "missing", "", false),
+			"UseTOTPStep":		r.UseTOTPStep(ctx, "missing", 1),
+			"UpdatePassword":	r.UpdatePassword(ctx, "missing", "new"),
+			"DeleteUser":		r.DeleteUser(ctx, "missing"),
+		} {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				t.Fatalf("%s: expected pgx.ErrNoRows, got %v", name, err)
+			}
+		}
+
+		if err = r.DeleteUser(ctx, "bob"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err = r.FindByUsername(ctx, "bob"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected deleted user, got %v", err)
+		}
+	})
+}
+
+type conformanceTokens interface {
+	CreateToken(ctx context.Context, token models.UserTokenDB) error
+	UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error)
+	RevokeTokens(ctx context.Context, username, kind string) error
+}
+
+func TestTokenRepositoryConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformanceTokens {
+		return NewPostgresqlTokenRepository(&PostgresqlTokenRepositoryConfig{Table: "user_token", DB: db})
+	}, func() conformanceTokens {
+		return NewMemoryTokenRepository(&MemoryTokenRepositoryConfig{})
+	}, func(t *testing.T, r conformanceTokens) {
+		ctx := context.Background()
+		exp := time.Now().Add(time.Hour)
+
+		r.CreateToken(ctx, models.UserTokenDB{Username: "alice", Kind: models.TokenVerify, Hash: "h1", Email: "a@example.com", ExpiresAt: exp})
+		r.CreateToken(ctx, models.UserTokenDB{Username: "alice", Kind: models.TokenReset, Hash: "h2", ExpiresAt: exp})
+		r.CreateToken(ctx, models.UserTokenDB{Username: "alice", Kind: models.TokenReset, Hash: "h3", ExpiresAt: time.Now().Add(-time.Minute)})
+		if err := r.CreateToken(ctx, models.UserTokenDB{Username: "bob", Kind: models.TokenReset, Hash: "h1", ExpiresAt: exp}); err == nil {
+			t.Fatal("expected duplicate hash error")
+		}
+
+		// Токен используется один раз и только для своего вида
+		if _, err := r.UseToken(ctx, models.TokenReset, "h1"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows for wrong kind, got %v", err)
+		}
+		token, err := r.UseToken(ctx, models.TokenVerify, "h1")
+		if err != nil || token.ID == 0 || token.Username != "alice" || token.Email != "a@example.com" {
+			t.Fatalf("unexpected token: %+v (%v)", token, err)
+		}
+		if _, err = r.UseToken(ctx, models.TokenVerify, "h1"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected used token, got %v", err)
+		}
+		if _, err = r.UseToken(ctx, models.TokenReset, "h3"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected expired token, got %v", err)
+		}
+
+		if err = r.RevokeTokens(ctx, "alice", models.TokenReset); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err = r.UseToken(ctx, models.TokenReset, "h2"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected revoked token, got %v", err)
+		}
+	})
+}
+
+type conformanceAccounts interface {
+	conformanceRecovery
+	conformanceIdentity
+}
+
+// accountRepos Резервные коды и привязки к провайдерам в одной проверке
+type accountRepos struct {
+	conformanceRecovery
+	conformanceIdentity
+}
+
+type conformanceRecovery interface {
+	ReplaceCodes(ctx context.Context, username string, hashes []string) error
+	UseCode(ctx context.Context, username, hash string) error
+	DeleteCodes(ctx context.Context, username string) error
+}
+
+type conformanceIdentity interface {
+	FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error)
+	CreateIdentity(ctx context.Context, identity models.IdentityDB) error
+	DeleteIdentities(ctx context.Context, username string) error
+}
+
+func TestAccountRepositoriesConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformanceAccounts {
+		return accountRepos{
+			NewPostgresqlRecoveryRepository(&PostgresqlRecoveryRepositoryConfig{Table: "recovery_code", DB: db}),
+			NewPostgresqlIdentityRepository(&PostgresqlIdentityRepositoryConfig{Table: "user_identity", DB: db}),
+		}
+	}, func() conformanceAccounts {
+		return accountRepos{NewMemoryRecoveryRepository(), NewMemoryIdentityRepository()}
+	}, func(t *testing.T, r conformanceAccounts) {
+		ctx := context.Background()
+
+		// Резервный код используется один раз, новый набор заменяет прежний
+		r.ReplaceCodes(ctx, "alice", []string{"c1", "c2"})
+		if err := r.UseCode(ctx, "alice", "c1"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := r.UseCode(ctx, "alice", "c1"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected used code, got %v", err)
+		}
+		if err := r.UseCode(ctx, "bob", "c2"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected other user's code to fail, got %v", err)
+		}
+		r.ReplaceCodes(ctx, "alice", []string{"c3"})
+		if err := r.UseCode(ctx, "alice", "c2"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected replaced code, got %v", err)
+		}
+		r.DeleteCodes(ctx, "alice")
+		if err := r.UseCode(ctx, "alice", "c3"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected deleted code, got %v", err)
+		}
+
+		// Пара провайдер и идентификатор уникальна
+		if err := r.CreateIdentity(ctx, models.IdentityDB{Username: "alice", Issuer: "https://idp", Subject: "1", Email: "a@example.com"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := r.CreateIdentity(ctx, models.IdentityDB{Username: "bob", Issuer: "https://idp", Subject: "1"}); err == nil {
+			t.Fatal("expected duplicate identity error")
+		}
+		identity, err := r.FindIdentity(ctx, "https://idp", "1")
+		if err != nil || identity.ID == 0 || identity.Username != "alice" || identity.Email != "a@example.com" {
+			t.Fatalf("unexpected identity: %+v (%v)", identity, err)
+		}
+		r.DeleteIdentities(ctx, "alice")
+		if _, err = r.FindIdentity(ctx, "https://idp", "1"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected deleted identity, got %v", err)
+		}
+	})
+}
+
+type conformanceModeration interface {
+	conformanceReports
+	conformanceAudit
+}
+
+// moderationRepos Жалобы и журнал аудита в одной проверке
+type moderationRepos struct {
+	conformanceReports
+	conformanceAudit
+}
+
+type conformanceReports interface {
+	CreateReport(ctx context.Context, report models.ReportDB) (int64, error)
+	FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error)
+	FindReport(ctx context.Context, id int64) (models.ReportDB, error)
+	ResolveReports(ctx context.Context, link, status, admin string) error
+	RedactResolver(ctx context.Context, username, pseudonym string) error
+}
+
+type conformanceAudit interface {
+	AppendEntry(ctx context.Context, entry models.AuditEntry) error
+	RedactUser(ctx context.Context, username, pseudonym string) error
+	FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+func TestModerationRepositoriesConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformanceModeration {
+		return moderationRepos{
+			NewPostgresqlReportRepository(&PostgresqlReportRepositoryConfig{Table: "link_report", DB: db}),
+			NewPostgresqlAuditRepository(&PostgresqlAuditRepositoryConfig{Table: "audit_log", DB: db}),
+		}
+	}, func() conformanceModeration {
+		return moderationRepos{
+			NewMemoryReportRepository(&MemoryReportRepositoryConfig{}),
+			NewMemoryAuditRepository(&MemoryAuditRepositoryConfig{}),
+		}
+	}, func(t *testing.T, r conformanceModeration) {
+		ctx := context.Background()
+
+		first, _ := r.CreateReport(ctx, models.ReportDB{Link: "a", Reason: "spam", Reporter: "bob"})
+		second, _ := r.CreateReport(ctx, models.ReportDB{Link: "a", Reason: "phishing"})
+		r.CreateReport(ctx, models.ReportDB{Link: "b", Reason: "spam"})
+
+		report, err := r.FindReport(ctx, first)
+		if err != nil || report.Status != models.ReportOpen || report.Reporter != "bob" || report.CreatedAt.IsZero() {
+			t.Fatalf("unexpected report: %+v (%v)", report, err)
+		}
+		if _, err = r.FindReport(ctx, 100); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+
+		// Закрываются только открытые жалобы на ссылку
+		r.ResolveReports(ctx, "a", models.ReportDisabled, "admin")
+		r.ResolveReports(ctx, "a", models.ReportDismissed, "other")
+		if report, _ = r.FindReport(ctx, second); report.Status != models.ReportDisabled || report.ResolvedBy != "admin" {
+			t.Fatalf("unexpected resolved report: %+v", report)
+		}
+		open, _ := r.FindReports(ctx, models.ReportOpen, 10)
+		if len(open) != 1 || open[0].Link != "b" {
+			t.Fatalf("unexpected open reports: %+v", open)
+		}
+		disabled, _ := r.FindReports(ctx, models.ReportDisabled, 1)
+		if len(disabled) != 1 || disabled[0].ID != first {
+			t.Fatalf("expected oldest report first, got %+v", disabled)
+		}
+		if err = r.RedactResolver(ctx, "admin", "deleted:9"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if report, _ = r.FindReport(ctx, second); report.ResolvedBy != "deleted:9" {
+			t.Fatalf("expected redacted resolver, got %+v", report)
+		}
+
+		// Журнал - от новых записей к старым, пустые поля фильтра не учитываются
+		r.AppendEntry(ctx, models.AuditEntry{Actor: "alice", Action: "link.create", Target: "a", Owner: "alice"})
+		r.AppendEntry(ctx, models.AuditEntry{Actor: "admin", Action: "link.disable", Target: "a", Owner: "alice", Details: "spam"})
+		r.AppendEntry(ctx, models.AuditEntry{Actor: "bob", Action: "link.create", Target: "b", Owner: "bob", TraceID: "t1"})
+
+		entries, err := r.FindEntries(ctx, models.AuditFilter{Limit: 10})
+		if err != nil || len(entries) != 3 || entries[0].Actor != "bob" || entries[0].TraceID != "t1" || entries[2].Actor != "alice" {
+			t.Fatalf("unexpected entries: %+v (%v)", entries, err)
+		}
+		entries, _ = r.FindEntries(ctx, models.AuditFilter{Owner: "alice", Limit: 10})
+		if len(entries) != 2 || entries[0].Action != "link.disable" || entries[0].Details != "spam" {
+			t.Fatalf("unexpected entries by owner: %+v", entries)
+		}
+		entries, _ = r.FindEntries(ctx, models.AuditFilter{Action: "link.create", Actor: "alice", Limit: 10})
+		if len(entries) != 1 || entries[0].Target != "a" {
+			t.Fatalf("unexpected filtered entries: %+v", entries)
+		}
+		if entries, _ = r.FindEntries(ctx, models.AuditFilter{Limit: 1}); len(entries) != 1 {
+			t.Fatalf("expected limited entries, got %+v", entries)
+		}
+
+		// Обезличивание: имя заменяется псевдонимом, записи администраторов сохраняют подробности
+		r.AppendEntry(ctx, models.AuditEntry{Actor: "alice", Action: "user.update", Target: "alice", Owner: "alice", Details: "alice@example.com"})
+		r.AppendEntry(ctx, models.AuditEntry{Actor: "bob", Action: "link.create", Target: "alice", Owner: "bob"})
+		if err = r.RedactUser(ctx, "alice", "deleted:1"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if entries, _ = r.FindEntries(ctx, models.AuditFilter{Owner: "alice", Limit: 10}); len(entries) != 0 {
+			t.Fatalf("expected no entries of redacted user, got %+v", entries)
+		}
+		entries, _ = r.FindEntries(ctx, models.AuditFilter{Owner: "deleted:1", Limit: 10})
+		if len(entries) != 3 || entries[0].Target != "deleted:1" || entries[0].Details != "" || entries[1].Details != "spam" || entries[1].Actor != "admin" || entries[2].Target != "a" {
+			t.Fatalf("unexpected redacted entries: %+v", entries)
+		}
+		if entries, _ = r.FindEntries(ctx, models.AuditFilter{Actor: "bob", Limit: 10}); len(entries) != 2 || entries[0].Target != "alice" {
+			t.Fatalf("link named after redacted user must be kept, got %+v", entries)
+		}
+	})
+}
+
+type conformancePayments interface {
+	conformanceBills
+	conformanceQiwiEvents
+}
+
+// paymentRepos Счета и обработанные уведомления QIWI в одной проверке
+type paymentRepos struct {
+	conformanceBills
+	conformanceQiwiEvents
+}
+
+type conformanceBills interface {
+	CreateBill(ctx context.Context, bill models.BillDB) error
+	UpdateBillStatus(ctx context.Context, id, status string) error
+	FindBill(ctx context.Context, id string) (models.BillDB, error)
+	FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error)
+	FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error)
+	RedactUser(ctx context.Context, username, pseudonym string) error
+}
+
+type conformanceQiwiEvents interface {
+	MarkProcessed(ctx context.Context, billID, status string) (bool, error)
+	UnmarkProcessed(ctx context.Context, billID string) error
+}
+
+func TestPaymentRepositoriesConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformancePayments {
+		return paymentRepos{
+			NewPostgresqlBillRepository(&PostgresqlBillRepositoryConfig{Table: "bill", DB: db}),
+			NewPostgresqlQiwiEventRepository(&PostgresqlQiwiEventRepositoryConfig{Table: "qiwi_event", DB: db}),
+		}
+	}, func() conformancePayments {
+		return paymentRepos{
+			NewMemoryBillRepository(&MemoryBillRepositoryConfig{}),
+			NewMemoryQiwiEventRepository(),
+		}
+	}, func(t *testing.T, r conformancePayments) {
+		ctx := context.Background()
+
+		for _, id := range []string{"b1", "b2", "b3"} {
+			err := r.CreateBill(ctx, models.BillDB{ID: id, Username: "alice", Plan: "pro", Amount: 199.999, Promo: "SALE", Status: models.BillWaiting})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		if err := r.CreateBill(ctx, models.BillDB{ID: "b1", Username: "bob", Plan: "pro", Status: models.BillWaiting}); err == nil {
+			t.Fatal("expected duplicate bill error")
+		}
+
+		// Сумма хранится с точностью до копейки
+		bill, err := r.FindBill(ctx, "b1")
+		if err != nil || bill.Amount != 200 || bill.Promo != "SALE" || bill.CreatedAt.IsZero() {
+			t.Fatalf("unexpected bill: %+v (%v)", bill, err)
+		}
+		if _, err = r.FindBill(ctx, "missing"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+
+		if err = r.UpdateBillStatus(ctx, "b1", models.BillPaid); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err = r.UpdateBillStatus(ctx, "missing", models.BillPaid); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+		if bill, _ = r.FindBill(ctx, "b1"); bill.Status != models.BillPaid || bill.UpdatedAt.Before(bill.CreatedAt) {
+			t.Fatalf("unexpected paid bill: %+v", bill)
+		}
+
+		// Ожидающие оплаты - от старых к новым, счета пользователя - от новых к старым
+		waiting, _ := r.FindWaitingBills(ctx, 10)
+		if len(waiting) != 2 || waiting[0].ID != "b2" || waiting[1].ID != "b3" {
+			t.Fatalf("unexpected waiting bills: %+v", waiting)
+		}
+		bills, _ := r.FindBills(ctx, "alice", 2)
+		if len(bills) != 2 || bills[0].ID != "b3" || bills[1].ID != "b2" {
+			t.Fatalf("unexpected user bills: %+v", bills)
+		}
+
+		// Счета удаленного пользователя остаются под псевдонимом
+		if err = r.RedactUser(ctx, "alice", "deleted:1"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bills, _ = r.FindBills(ctx, "alice", 10); len(bills) != 0 {
+			t.Fatalf("expected no bills of redacted user, got %+v", bills)
+		}
+		if bill, _ = r.FindBill(ctx, "b1"); bill.Username != "deleted:1" || bill.Status != models.BillPaid {
+			t.Fatalf("unexpected redacted bill: %+v", bill)
+		}
+
+		// Счет обрабатывается один раз, пока отметку не сняли
+		if ok, err := r.MarkProcessed(ctx, "b1", models.BillPaid); !ok || err != nil {
+			t.Fatalf("expected first mark, got %v (%v)", ok, err)
+		}
+		if ok, _ := r.MarkProcessed(ctx, "b1", models.BillPaid); ok {
+			t.Fatal("expected repeated mark to be rejected")
+		}
+		r.UnmarkProcessed(ctx, "b1")
+		if ok, _ := r.MarkProcessed(ctx, "b1", models.BillPaid); !ok {
+			t.Fatal("expected mark after unmark")
+		}
+	})
+}
+
+type conformancePromos interface {
+	CreatePromo(ctx context.Context, p models.PromoDB) error
+	FindPromo(ctx context.Context, code string) (models.PromoDB, error)
+	FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error)
+	DeletePromo(ctx context.Context, code string) error
+	Redeemed(ctx context.Context, code, username string) (bool, error)
+	UsePromo(ctx context.Context, code, username string) (models.PromoDB, error)
+	ReleasePromo(ctx context.Context, code, username string) error
+	RedactUser(ctx context.Context, username, pseudonym string) error
+}
+
+func TestPromoRepositoryConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformancePromos {
+		return NewPostgresqlPromoRepository(&PostgresqlPromoRepositoryConfig{Table: "promo_code", UsesTable: "promo_redemption", DB: db})
+	}, func() conformancePromos {
+		return NewMemoryPromoRepository(&MemoryPromoRepositoryConfig{})
+	}, func(t *testing.T, r conformancePromos) {
+		ctx := context.Background()
+		past := time.Now().Add(-time.Hour)
+
+		r.CreatePromo(ctx, models.PromoDB{Code: "ONE", Kind: models.PromoPercent, Value: 10.555, MaxUses: 1, CreatedBy: "admin"})
+		r.CreatePromo(ctx, models.PromoDB{Code: "OLD", Kind: models.PromoTrial, Days: 7, ExpiresAt: &past})
+		r.CreatePromo(ctx, models.PromoDB{Code: "ANY", Kind: models.PromoTrial, Days: 3, Plan: "pro"})
+		if err := r.CreatePromo(ctx, models.PromoDB{Code: "ONE", Kind: models.PromoTrial}); err == nil {
+			t.Fatal("expected duplicate promo error")
+		}
+
+		p, err := r.FindPromo(ctx, "ONE")
+		if err != nil || p.Value != 10.56 || p.Uses != 0 || p.ExpiresAt != nil || p.CreatedBy != "admin" {
+			t.Fatalf("unexpected promo: %+v (%v)", p, err)
+		}
+		if promos, _ := r.FindPromos(ctx, 10); len(promos) != 3 || promos[0].Code != "ANY" {
+			t.Fatalf("expected newest promo first, got %+v", promos)
+		}
+
+		// Код списывается один раз на пользователя и не больше лимита
+		if p, err = r.UsePromo(ctx, "ONE", "alice"); err != nil || p.Uses != 1 {
+			t.Fatalf("unexpected used promo: %+v (%v)", p, err)
+		}
+		if _, err = r.UsePromo(ctx, "ONE", "bob"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected exhausted promo, got %v", err)
+		}
+		if _, err = r.UsePromo(ctx, "OLD", "alice"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected expired promo, got %v", err)
+		}
+		if _, err = r.UsePromo(ctx, "missing", "alice"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected missing promo, got %v", err)
+		}
+		r.UsePromo(ctx, "ANY", "alice")
+		if _, err = r.UsePromo(ctx, "ANY", "alice"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected redeemed promo, got %v", err)
+		}
+		if p, _ = r.FindPromo(ctx, "ANY"); p.Uses != 1 {
+			t.Fatalf("rejected redemption must not count, got %d uses", p.Uses)
+		}
+
+		// Возврат использования освобождает лимит, повторный возврат ничего не меняет
+		r.ReleasePromo(ctx, "ONE", "alice")
+		r.ReleasePromo(ctx, "ONE", "alice")
+		if ok, _ := r.Redeemed(ctx, "ONE", "alice"); ok {
+			t.Fatal("expected released redemption")
+		}
+		if p, _ = r.FindPromo(ctx, "ONE"); p.Uses != 0 {
+			t.Fatalf("expected released use, got %d uses", p.Uses)
+		}
+		if _, err = r.UsePromo(ctx, "ONE", "bob"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// После обезличивания использование числится за псевдонимом
+		if err = r.RedactUser(ctx, "alice", "deleted:1"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ok, _ := r.Redeemed(ctx, "ANY", "alice"); ok {
+			t.Fatal("expected redacted redemption")
+		}
+		if ok, _ := r.Redeemed(ctx, "ANY", "deleted:1"); !ok {
+			t.Fatal("expected redemption under pseudonym")
+		}
+
+		// Удаление вместе с историей использований
+		if err = r.DeletePromo(ctx, "ANY"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err = r.DeletePromo(ctx, "ANY"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+		if ok, _ := r.Redeemed(ctx, "ANY", "alice"); ok {
+			t.Fatal("expected redemptions deleted with promo")
+		}
+	})
+}
+
+type conformanceWebhooks interface {
+	CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error)
+	FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error)
+	FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error)
+	DeleteWebhook(ctx context.Context, id int64, username string) error
+	DeleteWebhooks(ctx context.Context, username string) error
+	CreateDelivery(ctx context.Context, d models.DeliveryDB) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error)
+	UpdateDelivery(ctx context.Context, d models.DeliveryDB) error
+	FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error)
+	Redeliver(ctx context.Context, id int64, username string) error
+}
+
+func TestWebhookRepositoryConformance(t *testing.T) {
+	postgresOrMemory(t, func(db *pgxpool.Pool) conformanceWebhooks {
+		return NewPostgresqlWebhookRepository(&PostgresqlWebhookRepositoryConfig{Table: "webhook", DeliveryTable: "webhook_delivery", DB: db})
+	}, func() conformanceWebhooks {
+		return NewMemoryWebhookRepository(&MemoryWebhookRepositoryConfig{})
+	}, func(t *testing.T, r conformanceWebhooks) {
+		ctx := context.Background()
+
+		all, err := r.CreateWebhook(ctx, models.WebhookDB{Username: "alice", URL: "https://example.com/all", Secret: "s1", Events: []string{}})
+		if err != nil || all.ID == 0 || all.CreatedAt.IsZero() {
+			t.Fatalf("unexpected webhook: %+v (%v)", all, err)
+		}
+		created, _ := r.CreateWebhook(ctx, models.WebhookDB{Username: "alice", URL: "https://example.com/created", Secret: "s2", Events: []string{models.EventLinkCreated}})
+		r.CreateWebhook(ctx, models.WebhookDB{Username: "bob", URL: "https://example.com/bob", Secret: "s3", Events: []string{}})
+
+		// Пустой список событий - подписка на все
+		hooks, _ := r.FindSubscribed(ctx, "alice", models.EventLinkDeleted)
+		if len(hooks) != 1 || hooks[0].ID != all.ID {
+			t.Fatalf("unexpected subscribed webhooks: %+v", hooks)
+		}
+		hooks, _ = r.FindSubscribed(ctx, "alice", models.EventLinkCreated)
+		if len(hooks) != 2 || hooks[0].ID != all.ID || hooks[1].Secret != "s2" || len(hooks[1].Events) != 1 {
+			t.Fatalf("unexpected subscribed webhooks: %+v", hooks)
+		}
+
+		// Доставки на несуществующий вебхук нет
+		if err = r.CreateDelivery(ctx, models.DeliveryDB{WebhookID: 100, Username: "alice", EventID: "e0", Kind: models.EventLinkCreated}); err == nil {
+			t.Fatal("expected foreign key error")
+		}
+		for _, d := range []models.DeliveryDB{
+			{WebhookID: all.ID, Username: "alice", EventID: "e1", Kind: models.EventLinkCreated, Payload: "{}"},
+			{WebhookID: created.ID, Username: "alice", EventID: "e1", Kind: models.EventLinkCreated, Payload: "{}"},
+		} {
+			if err = r.CreateDelivery(ctx, d); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+
+		due, _ := r.DueDeliveries(ctx, time.Now().Add(time.Second), 10)
+		if len(due) != 2 || due[0].Status != models.DeliveryPending || due[0].URL != all.URL || due[0].Secret != "s1" || due[0].Attempts != 0 {
+			t.Fatalf("unexpected due deliveries: %+v", due)
+		}
+
+		// Неудачная попытка откладывает доставку
+		failed := due[0]
+		failed.Attempts = 1
+		failed.NextAttempt = time.Now().Add(time.Hour)
+		failed.ResponseCode = 500
+		failed.LastError = "server error"
+		if err = r.UpdateDelivery(ctx, failed); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if due, _ = r.DueDeliveries(ctx, time.Now().Add(time.Second), 10); len(due) != 1 || due[0].WebhookID != created.ID {
+			t.Fatalf("unexpected due deliveries after retry: %+v", due)
+		}
+
+		deliveries, _ := r.FindDeliveries(ctx, "alice", 10)
+		if len(deliveries) != 2 || deliveries[0].WebhookID != created.ID || deliveries[1].LastError != "server error" || deliveries[1].ResponseCode != 500 {
+			t.Fatalf("unexpected deliveries: %+v", deliveries)
+		}
+
+		// Повторная отправка - только своих доставок
+		if err = r.Redeliver(ctx, failed.ID, "bob"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+		if err = r.Redeliver(ctx, failed.ID, "alice"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if due, _ = r.DueDeliveries(ctx, time.Now().Add(time.Second), 10); len(due) != 2 {
+			t.Fatalf("expected redelivered delivery to be due, got %+v", due)
+		}
+
+		// Вебхук удаляется вместе с доставками и только владельцем
+		if err = r.DeleteWebhook(ctx, all.ID, "bob"); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+		if err = r.DeleteWebhook(ctx, all.ID, "alice"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if deliveries, _ = r.FindDeliveries(ctx, "alice", 10); len(deliveries) != 1 {
+			t.Fatalf("expected deliveries deleted with webhook, got %+v", deliveries)
+		}
+		r.DeleteWebhooks(ctx, "alice")
+		if hooks, _ = r.FindWebhooks(ctx, "alice"); len(hooks) != 0 {
+			t.Fatalf("expected no webhooks, got %+v", hooks)
+		}
+		if hooks, _ = r.FindWebhooks(ctx, "bob"); len(hooks) != 1 {
+			t.Fatalf("expected bob's webhook untouched, got %+v", hooks)
+		}
+	})
+}
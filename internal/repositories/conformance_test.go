@@ -0,0 +1,540 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+// Общие проверки реализаций хранилищ: одни и те же сценарии выполняются на Redis (miniredis), Postgres
+// (если задан TEST_POSTGRES_URL, см. conformance_postgres_test.go) и в памяти процесса
+
+// testClock Часы теста. Сдвиг переводит вперед и хранилища в памяти, и miniredis
+type testClock struct {
+	at	time.Time
+	mr	*miniredis.Miniredis
+	mux	sync.Mutex
+}
+
+func (c *testClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.at
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mux.Lock()
+	c.at = c.at.Add(d)
+	c.mux.Unlock()
+
+	if c.mr != nil {
+		c.mr.FastForward(d)
+	}
+}
+
+// redisOrMemory Запускает проверку на Redis и в памяти процесса
+func redisOrMemory[T any](t *testing.T, newRedis func(db *redis.Client) T, newMemory func(now func() time.Time) T, check func(t *testing.T, r T, clock *testClock)) {
+	t.Run("redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		clock := &testClock{at: time.Now(), mr: mr}
+		check(t, newRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), clock)
+	})
+	t.Run("memory", func(t *testing.T) {
+		clock := &testClock{at: time.Now()}
+		check(t, newMemory(clock.Now), clock)
+	})
+}
+
+type conformanceLinks interface {
+	CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error)
+	DeleteLink(ctx context.Context, link, username string) error
+	FindLink(ctx context.Context, link string) (models.LinkDataDB, error)
+	DisableLink(ctx context.Context, link, reason string) error
+	EnableLink(ctx context.Context, link string) error
+	CountLinks(ctx context.Context, username string) (models.LinksAmount, error)
+	GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error)
+	TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error)
+	KeepLinks(ctx context.Context, username string) ([]string, error)
+	SetKeepLinks(ctx context.Context, username string, links []string) error
+}
+
+func linksConformance(t *testing.T, check func(t *testing.T, r conformanceLinks, clock *testClock)) {
+	redisOrMemory(t, func(db *redis.Client) conformanceLinks {
+		return NewRedisLinkRepository(&RedisLinkRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceLinks {
+		return NewMemoryLinkRepository(&MemoryLinkRepositoryConfig{Now: now})
+	}, check)
+}
+
+// linkNames Возвращает имена ссылок по порядку
+func linkNames(links []models.LinkDataDB) []string {
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		result = append(result, link.Link)
+	}
+
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestLinkRepositoryConformance(t *testing.T) {
+	linksConformance(t, func(t *testing.T, r conformanceLinks, clock *testClock) {
+		ctx := context.Background()
+
+		if _, err := r.CreateLink(ctx, "b", "alice", "https://example.com/b", 2*time.Hour, false, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := r.CreateLink(ctx, "a", "alice", "https://example.com/a", time.Hour, true, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := r.CreateLink(ctx, "p", "alice", "https://example.com/p", 0, false, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := r.CreateLink(ctx, "a", "bob", "https://evil.example", time.Hour, false, nil); err == nil || err.Error() != "link already exists" {
+			t.Fatalf("expected link already exists, got %v", err)
+		}
+
+		// Оставшийся срок - в целых секундах, у бессрочной ссылки срока нет
+		clock.Advance(10 * time.Minute)
+		link, err := r.FindLink(ctx, "a")
+		if err != nil || link.ExpTime != 50*time.Minute || link.Owner != "alice" || !link.Custom || link.Perm {
+			t.Fatalf("unexpected link: %+v (%v)", link, err)
+		}
+		if link, _ = r.FindLink(ctx, "p"); !link.Perm || link.ExpTime != -1 {
+			t.Fatalf("unexpected permanent link: %+v", link)
+		}
+		if _, err = r.FindLink(ctx, "missing"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected redis.Nil, got %v", err)
+		}
+
+		// Ссылки пользователя - по времени истечения, бессрочные последними
+		links, _ := r.GetAllLinks(ctx, "alice")
+		if names := linkNames(links); !equalStrings(names, []string{"a", "b", "p"}) {
+			t.Fatalf("unexpected links order: %v", names)
+		}
+		if amount, _ := r.CountLinks(ctx, "alice"); amount.All != 3 || amount.Perm != 1 || amount.Custom != 1 {
+			t.Fatalf("unexpected amount: %+v", amount)
+		}
+
+		// Блокировка
+		if err = r.EnableLink(ctx, "a"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected redis.Nil for enabled link, got %v", err)
+		}
+		if err = r.DisableLink(ctx, "a", "spam"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if link, _ = r.FindLink(ctx, "a"); !link.Disabled || link.DisabledReason != "spam" {
+			t.Fatalf("expected disabled link, got %+v", link)
+		}
+		if err = r.EnableLink(ctx, "a"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if link, _ = r.FindLink(ctx, "a"); link.Disabled || link.DisabledReason != "" {
+			t.Fatalf("expected enabled link, got %+v", link)
+		}
+		if err = r.DisableLink(ctx, "missing", "spam"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected redis.Nil, got %v", err)
+		}
+
+		// Истекшая ссылка пропадает сразу, из индекса ее убирает TrimExpired
+		clock.Advance(time.Hour)
+		if _, err = r.FindLink(ctx, "a"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected expired link, got %v", err)
+		}
+		if err = r.DisableLink(ctx, "a", "spam"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expired link must not be resurrected, got %v", err)
+		}
+		links, _ = r.GetAllLinks(ctx, "alice")
+		if names := linkNames(links); !equalStrings(names, []string{"b", "p"}) {
+			t.Fatalf("unexpected links after expiry: %v", names)
+		}
+		removed, err := r.TrimExpired(ctx, clock.Now())
+		if err != nil || len(removed) != 1 || !equalStrings(removed["alice"], []string{"a"}) {
+			t.Fatalf("unexpected trimmed links: %v (%v)", removed, err)
+		}
+
+		// Истекшую ссылку может занять другой пользователь
+		if _, err = r.CreateLink(ctx, "a", "bob", "https://example.com/bob", time.Hour, false, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if link, _ = r.FindLink(ctx, "a"); link.Owner != "bob" {
+			t.Fatalf("expected link owned by bob, got %+v", link)
+		}
+
+		// Удаление
+		if err = r.DeleteLink(ctx, "b", "alice"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err = r.DeleteLink(ctx, "b", "alice"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected redis.Nil, got %v", err)
+		}
+		links, _ = r.GetAllLinks(ctx, "alice")
+		if names := linkNames(links); !equalStrings(names, []string{"p"}) {
+			t.Fatalf("unexpected links after delete: %v", names)
+		}
+	})
+}
+
+func TestLinkRepositoryConformanceKeepLinks(t *testing.T) {
+	linksConformance(t, func(t *testing.T, r conformanceLinks, clock *testClock) {
+		ctx := context.Background()
+
+		if keep, err := r.KeepLinks(ctx, "alice"); err != nil || len(keep) != 0 {
+			t.Fatalf("expected no links, got %v (%v)", keep, err)
+		}
+
+		// Повторы не сохраняются, новый список заменяет прежний
+		r.SetKeepLinks(ctx, "alice", []string{"b", "a", "b"})
+		keep, _ := r.KeepLinks(ctx, "alice")
+		sort.Strings(keep)
+		if !equalStrings(keep, []string{"a", "b"}) {
+			t.Fatalf("unexpected keep links: %v", keep)
+		}
+
+		r.SetKeepLinks(ctx, "alice", []string{"c"})
+		if keep, _ = r.KeepLinks(ctx, "alice"); !equalStrings(keep, []string{"c"}) {
+			t.Fatalf("unexpected keep links: %v", keep)
+		}
+
+		r.SetKeepLinks(ctx, "alice", nil)
+		if keep, _ = r.KeepLinks(ctx, "alice"); len(keep) != 0 {
+			t.Fatalf("expected no links, got %v", keep)
+		}
+	})
+}
+
+func TestLinkRepositoryConformanceConcurrentCreate(t *testing.T) {
+	linksConformance(t, func(t *testing.T, r conformanceLinks, clock *testClock) {
+		ctx := context.Background()
+
+		// Из одновременных попыток занять ссылку удается только одна
+		var (
+			wg		sync.WaitGroup
+			mux		sync.Mutex
+			created	int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := r.CreateLink(ctx, "race", "alice", "https://example.com", time.Hour, false, nil); err == nil {
+					mux.Lock()
+					created++
+					mux.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if created != 1 {
+			t.Fatalf("expected exactly one created link, got %d", created)
+		}
+	})
+}
+
+func TestLinkRepositoryConformanceConcurrentQuota(t *testing.T) {
+	linksConformance(t, func(t *testing.T, r conformanceLinks, clock *testClock) {
+		ctx := context.Background()
+		quota := &models.LinkQuota{All: 5, Custom: 5, Perm: 1}
+
+		// Одновременные запросы не превышают лимиты бессрочных и всех ссылок
+		create := func(prefix string, exp time.Duration) int {
+			var (
+				wg		sync.WaitGroup
+				mux		sync.Mutex
+				created	int
+			)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := r.CreateLink(ctx, fmt.Sprintf("%s%d", prefix, i), "alice", "https://example.com", exp, false, quota)
+					if err == nil {
+						mux.Lock()
+						created++
+						mux.Unlock()
+					} else if err.Error() != "limit exceeded" {
+						t.Errorf("unexpected error: %s", err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			return created
+		}
+
+		if created := create("perm", 0); created != 1 {
+			t.Fatalf("expected one permanent link, got %d", created)
+		}
+		if created := create("temp", time.Hour); created != 4 {
+			t.Fatalf("expected four temporary links, got %d", created)
+		}
+	})
+}
+
+type conformanceSubs interface {
+	FindSubscribe(ctx context.Context, username string) (time.Duration, bool)
+	FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool)
+	SubState(ctx context.Context, username string) (string, bool, error)
+	AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error
+	FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool)
+	RemoveSubscribe(ctx context.Context, username string) error
+}
+
+func TestSubRepositoryConformance(t *testing.T) {
+	redisOrMemory(t, func(db *redis.Client) conformanceSubs {
+		return NewRedisSubRepository(&RedisSubRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceSubs {
+		return NewMemorySubRepository(&MemorySubRepositoryConfig{Now: now})
+	}, func(t *testing.T, r conformanceSubs, clock *testClock) {
+		ctx := context.Background()
+
+		if _, ok := r.FindSubscribe(ctx, "alice"); ok {
+			t.Fatal("expected no subscription")
+		}
+		if _, ok, err := r.SubState(ctx, "alice"); ok || err != nil {
+			t.Fatalf("expected no subscription, got %v (%v)", ok, err)
+		}
+
+		if err := r.AddSubRedis(ctx, "alice", "pro", 24*time.Hour); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		clock.Advance(time.Hour)
+
+		plan, exp, ok := r.FindSubPlan(ctx, "alice")
+		if !ok || plan != "pro" || exp != 23*time.Hour {
+			t.Fatalf("unexpected subscription: %s %s %v", plan, exp, ok)
+		}
+		sub, ok := r.FindSubscription(ctx, "alice")
+		if !ok || sub.Plan != "pro" || sub.Exp != 23*time.Hour || sub.Start.IsZero() {
+			t.Fatalf("unexpected subscription: %+v", sub)
+		}
+
+		// При продлении сохраняется дата начала
+		if err := r.AddSubRedis(ctx, "alice", "team", 48*time.Hour); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		renewed, _ := r.FindSubscription(ctx, "alice")
+		if renewed.Plan != "team" || renewed.Exp != 48*time.Hour || !renewed.Start.Equal(sub.Start) {
+			t.Fatalf("unexpected renewed subscription: %+v (started %s)", renewed, sub.Start)
+		}
+
+		// Истекшей подписки нет
+		clock.Advance(48 * time.Hour)
+		if _, ok = r.FindSubscription(ctx, "alice"); ok {
+			t.Fatal("expected expired subscription")
+		}
+
+		r.AddSubRedis(ctx, "bob", "pro", time.Hour)
+		if err := r.RemoveSubscribe(ctx, "bob"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if plan, ok, _ := r.SubState(ctx, "bob"); ok || plan != "" {
+			t.Fatalf("expected removed subscription, got %q", plan)
+		}
+	})
+}
+
+type conformanceAttempts interface {
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	AddFail(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, d time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+func TestAttemptRepositoryConformance(t *testing.T) {
+	redisOrMemory(t, func(db *redis.Client) conformanceAttempts {
+		return NewRedisAttemptRepository(&RedisAttemptRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceAttempts {
+		return NewMemoryAttemptRepository(&MemoryAttemptRepositoryConfig{Now: now})
+	}, func(t *testing.T, r conformanceAttempts, clock *testClock) {
+		ctx := context.Background()
+
+		// Окно отсчитывается от первой неудачи
+		for want := int64(1); want <= 3; want++ {
+			if n, err := r.AddFail(ctx, "alice", 10*time.Minute); n != want || err != nil {
+				t.Fatalf("expected %d fails, got %d (%v)", want, n, err)
+			}
+			clock.Advance(3 * time.Minute)
+		}
+		clock.Advance(time.Minute)
+		if n, _ := r.AddFail(ctx, "alice", 10*time.Minute); n != 1 {
+			t.Fatalf("expected new window, got %d fails", n)
+		}
+
+		if left, err := r.LockedFor(ctx, "alice"); left != 0 || err != nil {
+			t.Fatalf("expected no lock, got %s (%v)", left, err)
+		}
+		r.Lock(ctx, "alice", 15*time.Minute)
+		clock.Advance(5 * time.Minute)
+		if left, _ := r.LockedFor(ctx, "alice"); left != 10*time.Minute {
+			t.Fatalf("expected 10m lock, got %s", left)
+		}
+		clock.Advance(10 * time.Minute)
+		if left, _ := r.LockedFor(ctx, "alice"); left != 0 {
+			t.Fatalf("expected expired lock, got %s", left)
+		}
+
+		r.AddFail(ctx, "bob", time.Hour)
+		r.Lock(ctx, "bob", time.Hour)
+		if err := r.Reset(ctx, "bob"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if left, _ := r.LockedFor(ctx, "bob"); left != 0 {
+			t.Fatalf("expected reset lock, got %s", left)
+		}
+		if n, _ := r.AddFail(ctx, "bob", time.Hour); n != 1 {
+			t.Fatalf("expected reset counter, got %d", n)
+		}
+	})
+}
+
+type conformanceRevocations interface {
+	Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error
+	RevokedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+func TestRevocationRepositoryConformance(t *testing.T) {
+	redisOrMemory(t, func(db *redis.Client) conformanceRevocations {
+		return NewRedisRevocationRepository(&RedisRevocationRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceRevocations {
+		return NewMemoryRevocationRepository(&MemoryRevocationRepositoryConfig{Now: now})
+	}, func(t *testing.T, r conformanceRevocations, clock *testClock) {
+		ctx := context.Background()
+
+		if at, err := r.RevokedAt(ctx, "alice"); !at.IsZero() || err != nil {
+			t.Fatalf("expected no revocation, got %s (%v)", at, err)
+		}
+
+		// Момент отзыва хранится с точностью до секунды, как iat в токене
+		at := clock.Now()
+		if err := r.Revoke(ctx, "alice", at, time.Hour); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got, _ := r.RevokedAt(ctx, "alice"); got.Unix() != at.Unix() {
+			t.Fatalf("expected revocation at %s, got %s", at, got)
+		}
+		if got, _ := r.RevokedAt(ctx, "bob"); !got.IsZero() {
+			t.Fatalf("expected no revocation for other user, got %s", got)
+		}
+
+		clock.Advance(time.Hour)
+		if got, _ := r.RevokedAt(ctx, "alice"); !got.IsZero() {
+			t.Fatalf("expected expired revocation, got %s", got)
+		}
+	})
+}
+
+type conformanceOIDCStates interface {
+	SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error
+	TakeState(ctx context.Context, state string) (models.OIDCState, error)
+}
+
+func TestOIDCStateRepositoryConformance(t *testing.T) {
+	redisOrMemory(t, func(db *redis.Client) conformanceOIDCStates {
+		return NewRedisOIDCStateRepository(&RedisOIDCStateRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceOIDCStates {
+		return NewMemoryOIDCStateRepository(&MemoryOIDCStateRepositoryConfig{Now: now})
+	}, func(t *testing.T, r conformanceOIDCStates, clock *testClock) {
+		ctx := context.Background()
+
+		if err := r.SaveState(ctx, "s1", models.OIDCState{Nonce: "n1", Verifier: "v1"}, time.Minute); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		r.SaveState(ctx, "s2", models.OIDCState{Nonce: "n2", Verifier: "v2"}, time.Minute)
+
+		// state используется один раз
+		if st, err := r.TakeState(ctx, "s1"); err != nil || st.Nonce != "n1" || st.Verifier != "v1" {
+			t.Fatalf("unexpected state: %+v (%v)", st, err)
+		}
+		if _, err := r.TakeState(ctx, "s1"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected redis.Nil on reuse, got %v", err)
+		}
+
+		clock.Advance(time.Minute)
+		if _, err := r.TakeState(ctx, "s2"); !errors.Is(err, redis.Nil) {
+			t.Fatalf("expected expired state, got %v", err)
+		}
+	})
+}
+
+type conformanceJobs interface {
+	AddJobs(ctx context.Context, jobs []models.SchedJob) error
+	DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error)
+	FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error)
+	RemoveJob(ctx context.Context, job models.SchedJob) error
+	RemoveUserJobs(ctx context.Context, username string) error
+}
+
+func TestJobRepositoryConformance(t *testing.T) {
+	redisOrMemory(t, func(db *redis.Client) conformanceJobs {
+		return NewRedisJobRepository(&RedisJobRepositoryConfig{DB: db})
+	}, func(now func() time.Time) conformanceJobs {
+		return NewMemoryJobRepository(&MemoryJobRepositoryConfig{})
+	}, func(t *testing.T, r conformanceJobs, clock *testClock) {
+		ctx := context.Background()
+		now := clock.Now()
+		exp := now.Add(time.Hour)
+
+		err := r.AddJobs(ctx, []models.SchedJob{
+			{Kind: "unsubscribe", Username: "alice", ExpiresAt: exp, Next: now.Add(2 * time.Hour)},
+			{Kind: "notify", Notice: "sub_expired", Username: "alice", ExpiresAt: exp, Next: now.Add(time.Hour)},
+			{Kind: "notify", Notice: "sub_expired", Username: "bob", ExpiresAt: exp, Next: now.Add(-time.Minute)},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Наступила только задача bob
+		due, err := r.DueJobs(ctx, now, 10)
+		if err != nil || len(due) != 1 || due[0].Username != "bob" || due[0].Notice != "sub_expired" {
+			t.Fatalf("unexpected due jobs: %+v (%v)", due, err)
+		}
+		if due[0].ExpiresAt.Unix() != exp.Unix() || due[0].ID == 0 {
+			t.Fatalf("unexpected job fields: %+v", due[0])
+		}
+
+		// Список упорядочен по времени выполнения
+		all, err := r.FindJobs(ctx, 10)
+		if err != nil || len(all) != 3 || all[0].Username != "bob" || all[1].Kind != "notify" || all[2].Kind != "unsubscribe" {
+			t.Fatalf("unexpected jobs: %+v (%v)", all, err)
+		}
+
+		if err = r.RemoveJob(ctx, due[0]); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if due, _ = r.DueJobs(ctx, now.Add(time.Hour), 10); len(due) != 1 || due[0].Username != "alice" {
+			t.Fatalf("unexpected due jobs: %+v", due)
+		}
+
+		// Отмена задач пользователя не затрагивает других
+		r.AddJobs(ctx, []models.SchedJob{{Kind: "notify", Username: "bob", ExpiresAt: exp, Next: now}})
+		if err = r.RemoveUserJobs(ctx, "alice"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if all, _ = r.FindJobs(ctx, 10); len(all) != 1 || all[0].Username != "bob" {
+			t.Fatalf("unexpected jobs: %+v", all)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Хранилища в памяти процесса (STORAGE=memory) повторяют поведение реализаций на Postgres и Redis,
+// включая ошибки (pgx.ErrNoRows, redis.Nil) и сроки жизни записей. Данные теряются при перезапуске
+// и не делятся между экземплярами, поэтому режим годится только для тестов и локальной разработки.
+// Время берется из часов Now конфигурации (nil - системные), в тестах их можно подменить
+
+// Ошибки нарушения ограничений, которые в Postgres проверяет сама база
+var (
+	errMemoryDuplicate	= errors.New("duplicate key value violates unique constraint")
+	errMemoryForeignKey	= errors.New("insert violates foreign key constraint")
+	errMemoryLimit		= errors.New("LIMIT must not be negative")
+)
+
+// nowFunc Возвращает часы хранилища (nil - системные)
+func nowFunc(now func() time.Time) func() time.Time {
+	if now == nil {
+		return time.Now
+	}
+
+	return now
+}
+
+// limitRows Ограничивает выборку как LIMIT в Postgres
+func limitRows[T any](rows []T, limit int) ([]T, error) {
+	if limit < 0 {
+		return nil, errMemoryLimit
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	return rows, nil
+}
+
+// roundMoney Округляет сумму как numeric(12, 2)
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// redisTTL Переводит оставшееся время в ответ команды TTL (целые секунды с округлением)
+func redisTTL(left time.Duration) time.Duration {
+	return (left + time.Second/2) / time.Second * time.Second
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Сколько ключей хранится до чистки истекших
+const attemptMemoryMaxKeys = 100000
+
+// MemoryAttemptRepositoryConfig Конфигурация для MemoryAttemptRepository
+type MemoryAttemptRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memoryFails Счетчик неудачных попыток за окно
+type memoryFails struct {
+	n			int64
+	expireAt	time.Time
+}
+
+// MemoryAttemptRepository Учет неудачных попыток входа и временных блокировок в памяти процесса
+type MemoryAttemptRepository struct {
+	now		func() time.Time
+	fails	map[string]memoryFails
+	locks	map[string]time.Time
+	mux		sync.Mutex
+}
+
+// NewMemoryAttemptRepository Конструктор для MemoryAttemptRepository
+func NewMemoryAttemptRepository(c *MemoryAttemptRepositoryConfig) *MemoryAttemptRepository {
+	return &MemoryAttemptRepository{
+		now:	nowFunc(c.Now),
+		fails:	make(map[string]memoryFails),
+		locks:	make(map[string]time.Time),
+	}
+}
+
+// LockedFor Возвращает, сколько еще действует блокировка по ключу (0 - блокировки нет)
+func (r *MemoryAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	until, ok := r.locks[key]
+	if !ok {
+		return 0, nil
+	}
+
+	left := until.Sub(r.now())
+	if left <= 0 {
+		delete(r.locks, key)
+		return 0, nil
+	}
+
+	return left, nil
+}
+
+// AddFail Увеличивает счетчик неудачных попыток. Окно отсчитывается от первой неудачи
+func (r *MemoryAttemptRepository) AddFail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	fails, ok := r.fails[key]
+	if !ok || !now.Before(fails.expireAt) {
+		if len(r.fails) >= attemptMemoryMaxKeys {
+			r.prune(now)
+		}
+		fails = memoryFails{expireAt: now.Add(window)}
+	}
+	fails.n++
+	r.fails[key] = fails
+
+	return fails.n, nil
+}
+
+// Lock Блокирует попытки входа по ключу на время d
+func (r *MemoryAttemptRepository) Lock(ctx context.Context, key string, d time.Duration) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	if len(r.locks) >= attemptMemoryMaxKeys {
+		r.prune(now)
+	}
+	r.locks[key] = now.Add(d)
+
+	return nil
+}
+
+// Reset Сбрасывает счетчик и блокировку по ключу
+func (r *MemoryAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.fails, key)
+	delete(r.locks, key)
+
+	return nil
+}
+
+// prune Удаляет истекшие счетчики и блокировки
+func (r *MemoryAttemptRepository) prune(now time.Time) {
+	for key, fails := range r.fails {
+		if !now.Before(fails.expireAt) {
+			delete(r.fails, key)
+		}
+	}
+	for key, until := range r.locks {
+		if !now.Before(until) {
+			delete(r.locks, key)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryAuditRepositoryConfig Конфигурация для MemoryAuditRepository
+type MemoryAuditRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// MemoryAuditRepository Журнал аудита в памяти процесса с поведением PostgresqlAuditRepository (только добавление записей и обезличивание)
+type MemoryAuditRepository struct {
+	now		func() time.Time
+	entries	[]models.AuditEntry
+	mux		sync.Mutex
+}
+
+// NewMemoryAuditRepository Конструктор для MemoryAuditRepository
+func NewMemoryAuditRepository(c *MemoryAuditRepositoryConfig) *MemoryAuditRepository {
+	return &MemoryAuditRepository{
+		now:	nowFunc(c.Now),
+	}
+}
+
+// AppendEntry Добавляет запись в журнал аудита
+func (r *MemoryAuditRepository) AppendEntry(ctx context.Context, entry models.AuditEntry) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	entry.ID = int64(len(r.entries) + 1)
+	entry.CreatedAt = r.now()
+	r.entries = append(r.entries, entry)
+
+	return nil
+}
+
+// RedactUser Заменяет имя удаленного пользователя в журнале псевдонимом, как PostgresqlAuditRepository
+func (r *MemoryAuditRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for k, entry := range r.entries {
+		if entry.Actor != username && entry.Owner != username {
+			continue
+		}
+		if entry.Target == username && entry.Owner == username {
+			entry.Target = pseudonym
+		}
+		if entry.Actor == username {
+			entry.Actor = pseudonym
+			entry.Details = ""
+		}
+		if entry.Owner == username {
+			entry.Owner = pseudonym
+		}
+		r.entries[k] = entry
+	}
+
+	return nil
+}
+
+// FindEntries Возвращает записи журнала по фильтру, начиная с самых новых
+func (r *MemoryAuditRepository) FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.AuditEntry, 0)
+	for _, entry := range r.entries {
+		if filter.Actor != "" && entry.Actor != filter.Actor ||
+			filter.Owner != "" && entry.Owner != filter.Owner ||
+			filter.Action != "" && entry.Action != filter.Action {
+			continue
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID > result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return limitRows(result, filter.Limit)
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryBillRepositoryConfig Конфигурация для MemoryBillRepository
+type MemoryBillRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// MemoryBillRepository Хранилище истории счетов в памяти процесса с поведением PostgresqlBillRepository
+type MemoryBillRepository struct {
+	now		func() time.Time
+	bills	map[string]models.BillDB
+	mux		sync.Mutex
+}
+
+// NewMemoryBillRepository Конструктор для MemoryBillRepository
+func NewMemoryBillRepository(c *MemoryBillRepositoryConfig) *MemoryBillRepository {
+	return &MemoryBillRepository{
+		now:	nowFunc(c.Now),
+		bills:	make(map[string]models.BillDB),
+	}
+}
+
+// CreateBill Сохраняет выставленный счет
+func (r *MemoryBillRepository) CreateBill(ctx context.Context, bill models.BillDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.bills[bill.ID]; ok {
+		return errMemoryDuplicate
+	}
+
+	now := r.now()
+	bill.Amount = roundMoney(bill.Amount)
+	bill.CreatedAt = now
+	bill.UpdatedAt = now
+	r.bills[bill.ID] = bill
+
+	return nil
+}
+
+// UpdateBillStatus Меняет статус счета
+func (r *MemoryBillRepository) UpdateBillStatus(ctx context.Context, id, status string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	bill, ok := r.bills[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	bill.Status = status
+	bill.UpdatedAt = r.now()
+	r.bills[id] = bill
+
+	return nil
+}
+
+// FindBill Возвращает счет по номеру
+func (r *MemoryBillRepository) FindBill(ctx context.Context, id string) (models.BillDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	bill, ok := r.bills[id]
+	if !ok {
+		return models.BillDB{}, pgx.ErrNoRows
+	}
+
+	return bill, nil
+}
+
+// FindWaitingBills Возвращает счета, ожидающие оплаты, начиная с самых старых
+func (r *MemoryBillRepository) FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error) {
+	return r.findBills(func(bill models.BillDB) bool {
+		return bill.Status == models.BillWaiting
+	}, false, limit)
+}
+
+// FindBills Возвращает счета пользователя, начиная с последних
+func (r *MemoryBillRepository) FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error) {
+	return r.findBills(func(bill models.BillDB) bool {
+		return bill.Username == username
+	}, true, limit)
+}
+
+// findBills Выбирает счета по условию, упорядочивая по дате создания
+func (r *MemoryBillRepository) findBills(match func(models.BillDB) bool, desc bool, limit int) ([]models.BillDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.BillDB, 0)
+	for _, bill := range r.bills {
+		if match(bill) {
+			result = append(result, bill)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if desc {
+			a, b = b, a
+		}
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID < b.ID
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	return limitRows(result, limit)
+}
+
+// RedactUser Заменяет имя удаленного пользователя в истории счетов псевдонимом
+func (r *MemoryBillRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for id, bill := range r.bills {
+		if bill.Username == username {
+			bill.Username = pseudonym
+			r.bills[id] = bill
+		}
+	}
+
+	return nil
+}
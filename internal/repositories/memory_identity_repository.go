@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// memoryIdentityKey Уникальный ключ привязки: провайдер и идентификатор пользователя у провайдера
+type memoryIdentityKey struct {
+	issuer	string
+	subject	string
+}
+
+// MemoryIdentityRepository Хранилище привязок к внешним провайдерам в памяти процесса с поведением PostgresqlIdentityRepository
+type MemoryIdentityRepository struct {
+	identities	map[memoryIdentityKey]models.IdentityDB
+	lastID		int64
+	mux			sync.Mutex
+}
+
+// NewMemoryIdentityRepository Конструктор для MemoryIdentityRepository
+func NewMemoryIdentityRepository() *MemoryIdentityRepository {
+	return &MemoryIdentityRepository{
+		identities:	make(map[memoryIdentityKey]models.IdentityDB),
+	}
+}
+
+// FindIdentity Ищет привязку по провайдеру и идентификатору пользователя у провайдера
+func (r *MemoryIdentityRepository) FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	identity, ok := r.identities[memoryIdentityKey{issuer: issuer, subject: subject}]
+	if !ok {
+		return models.IdentityDB{}, pgx.ErrNoRows
+	}
+
+	return identity, nil
+}
+
+// CreateIdentity Сохраняет привязку аккаунта к пользователю провайдера
+func (r *MemoryIdentityRepository) CreateIdentity(ctx context.Context, identity models.IdentityDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := memoryIdentityKey{issuer: identity.Issuer, subject: identity.Subject}
+	if _, ok := r.identities[key]; ok {
+		return errMemoryDuplicate
+	}
+
+	r.lastID++
+	identity.ID = r.lastID
+	r.identities[key] = identity
+
+	return nil
+}
+
+// DeleteIdentities Удаляет все привязки пользователя
+func (r *MemoryIdentityRepository) DeleteIdentities(ctx context.Context, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for key, identity := range r.identities {
+		if identity.Username == username {
+			delete(r.identities, key)
+		}
+	}
+
+	return nil
+}
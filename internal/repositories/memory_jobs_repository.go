@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryJobRepositoryConfig Конфигурация для MemoryJobRepository
+type MemoryJobRepositoryConfig struct {
+}
+
+// MemoryJobRepository Хранилище задач планировщика в памяти процесса с поведением RedisJobRepository
+type MemoryJobRepository struct {
+	seq		int64
+	jobs	map[int64]models.SchedJob
+	mux		sync.Mutex
+}
+
+// NewMemoryJobRepository Конструктор для MemoryJobRepository
+func NewMemoryJobRepository(c *MemoryJobRepositoryConfig) *MemoryJobRepository {
+	return &MemoryJobRepository{
+		jobs:	make(map[int64]models.SchedJob),
+	}
+}
+
+// AddJobs Сохраняет задачи и ставит их в очередь. Время хранится с точностью до секунды
+func (r *MemoryJobRepository) AddJobs(ctx context.Context, jobs []models.SchedJob) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for _, job := range jobs {
+		r.seq++
+		job.ID = r.seq
+		job.ExpiresAt = time.Unix(job.ExpiresAt.Unix(), 0)
+		job.Next = time.Unix(job.Next.Unix(), 0)
+		r.jobs[job.ID] = job
+	}
+
+	return nil
+}
+
+// sorted Возвращает задачи в порядке выполнения, подходящие под условие
+func (r *MemoryJobRepository) sorted(match func(job models.SchedJob) bool, limit int) []models.SchedJob {
+	result := make([]models.SchedJob, 0)
+	for _, job := range r.jobs {
+		if match(job) {
+			result = append(result, job)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Next.Equal(result[j].Next) {
+			return result[i].Next.Before(result[j].Next)
+		}
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// DueJobs Возвращает задачи, время которых наступило, начиная с самых старых
+func (r *MemoryJobRepository) DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	due := now.Unix()
+	return r.sorted(func(job models.SchedJob) bool {
+		return job.Next.Unix() <= due
+	}, limit), nil
+}
+
+// FindJobs Возвращает ближайшие задачи
+func (r *MemoryJobRepository) FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.sorted(func(job models.SchedJob) bool {
+		return true
+	}, limit), nil
+}
+
+// RemoveJob Удаляет выполненную задачу
+func (r *MemoryJobRepository) RemoveJob(ctx context.Context, job models.SchedJob) error {
+	r.mux.Lock()
+	delete(r.jobs, job.ID)
+	r.mux.Unlock()
+
+	return nil
+}
+
+// RemoveUserJobs Отменяет все задачи пользователя
+func (r *MemoryJobRepository) RemoveUserJobs(ctx context.Context, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for id, job := range r.jobs {
+		if job.Username == username {
+			delete(r.jobs, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// MemoryLinkRepositoryConfig Конфигурация для MemoryLinkRepository
+type MemoryLinkRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memoryLink Ссылка в памяти. Нулевое expireAt - бессрочная
+type memoryLink struct {
+	data		models.LinkDataDB
+	expireAt	time.Time
+}
+
+// MemoryLinkRepository Хранилище ссылок в памяти процесса с поведением RedisLinkRepository:
+// ссылка истекает вместе с данными, индекс пользователя хранит время истечения и чистится TrimExpired
+type MemoryLinkRepository struct {
+	now		func() time.Time
+	links	map[string]*memoryLink
+	index	map[string]map[string]time.Time	// Ссылки пользователя и время их истечения
+	keep	map[string][]string
+	mux		sync.Mutex
+}
+
+// NewMemoryLinkRepository Конструктор для MemoryLinkRepository
+func NewMemoryLinkRepository(c *MemoryLinkRepositoryConfig) *MemoryLinkRepository {
+	return &MemoryLinkRepository{
+		now:	nowFunc(c.Now),
+		links:	make(map[string]*memoryLink),
+		index:	make(map[string]map[string]time.Time),
+		keep:	make(map[string][]string),
+	}
+}
+
+// live Возвращает действующую ссылку, истекшую удаляет
+func (r *MemoryLinkRepository) live(link string, now time.Time) (*memoryLink, bool) {
+	l, ok := r.links[link]
+	if !ok {
+		return nil, false
+	}
+	if !l.expireAt.IsZero() && !now.Before(l.expireAt) {
+		delete(r.links, link)
+		return nil, false
+	}
+
+	return l, true
+}
+
+// linkData Возвращает данные ссылки с оставшимся сроком, как их читает RedisLinkRepository
+func (l *memoryLink) linkData(now time.Time) models.LinkDataDB {
+	data := l.data
+	data.ExpTime = -1
+	if !l.expireAt.IsZero() {
+		data.ExpTime = redisTTL(l.expireAt.Sub(now))
+	}
+
+	return data
+}
+
+// overQuota Проверяет лимиты всех и бессрочных ссылок по индексу пользователя, как createLinkScript
+func (r *MemoryLinkRepository) overQuota(username string, perm bool, quota *models.LinkQuota, now time.Time) bool {
+	if quota == nil {
+		return false
+	}
+
+	var all, perms int
+	for _, expireAt := range r.index[username] {
+		if expireAt.IsZero() {
+			perms++
+		}
+		if expireAt.IsZero() || now.Before(expireAt) {
+			all++
+		}
+	}
+
+	return all >= quota.All || perm && perms >= quota.Perm
+}
+
+// CreateLink Создает ссылку. Занятая ссылка не перезаписывается, лимиты проверяются как в RedisLinkRepository
+func (r *MemoryLinkRepository) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	if r.overQuota(username, exp == 0, quota, now) {
+		return models.LinkDataDB{}, errors.New("limit exceeded")
+	}
+	if _, ok := r.live(link, now); ok {
+		return models.LinkDataDB{}, errors.New("link already exists")
+	}
+
+	data := models.LinkDataDB{
+		Link:		link,
+		FullURL:	fullUrl,
+		ExpTime:	exp,
+		Perm:		exp == 0,
+		Custom:		custom,
+		Owner:		username,
+	}
+
+	var expireAt time.Time
+	if exp > 0 {
+		expireAt = now.Add(exp)
+	}
+	r.links[link] = &memoryLink{data: data, expireAt: expireAt}
+
+	if r.index[username] == nil {
+		r.index[username] = make(map[string]time.Time)
+	}
+	r.index[username][link] = expireAt
+
+	return data, nil
+}
+
+// DeleteLink Удаляет ссылку и убирает ее из индекса пользователя. Если ссылки нет, возвращает redis.Nil
+func (r *MemoryLinkRepository) DeleteLink(ctx context.Context, link, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.index[username], link)
+
+	if _, ok := r.live(link, r.now()); !ok {
+		return redis.Nil
+	}
+	delete(r.links, link)
+
+	return nil
+}
+
+// FindLink Находит ссылку и метаданные о ней. Если ссылки нет, возвращает redis.Nil
+func (r *MemoryLinkRepository) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	l, ok := r.live(link, now)
+	if !ok {
+		return models.LinkDataDB{Link: link}, redis.Nil
+	}
+
+	return l.linkData(now), nil
+}
+
+// DisableLink Блокирует ссылку с указанием причины. Если ссылки нет, возвращает redis.Nil
+func (r *MemoryLinkRepository) DisableLink(ctx context.Context, link, reason string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	l, ok := r.live(link, r.now())
+	if !ok {
+		return redis.Nil
+	}
+	l.data.Disabled = true
+	l.data.DisabledReason = reason
+
+	return nil
+}
+
+// EnableLink Снимает блокировку со ссылки. Если ссылки нет или она не заблокирована, возвращает redis.Nil
+func (r *MemoryLinkRepository) EnableLink(ctx context.Context, link string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	l, ok := r.live(link, r.now())
+	if !ok || !l.data.Disabled {
+		return redis.Nil
+	}
+	l.data.Disabled = false
+	l.data.DisabledReason = ""
+
+	return nil
+}
+
+// CountLinks Считает кол-во ссылок на аккаунте пользователя
+func (r *MemoryLinkRepository) CountLinks(ctx context.Context, username string) (models.LinksAmount, error) {
+	links, _ := r.GetAllLinks(ctx, username)
+
+	result := models.LinksAmount{All: len(links)}
+	for _, link := range links {
+		if link.Perm {
+			result.Perm += 1
+		}
+		if link.Custom {
+			result.Custom += 1
+		}
+	}
+
+	return result, nil
+}
+
+// GetAllLinks Получает действующие ссылки пользователя в порядке истечения (бессрочные - последними)
+func (r *MemoryLinkRepository) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	type scored struct {
+		link		string
+		expireAt	time.Time
+	}
+	active := make([]scored, 0, len(r.index[username]))
+	for link, expireAt := range r.index[username] {
+		if expireAt.IsZero() || expireAt.After(now) {
+			active = append(active, scored{link: link, expireAt: expireAt})
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		a, b := active[i], active[j]
+		if a.expireAt.Equal(b.expireAt) {
+			return a.link < b.link
+		}
+		if a.expireAt.IsZero() || b.expireAt.IsZero() {
+			return b.expireAt.IsZero()
+		}
+		return a.expireAt.Before(b.expireAt)
+	})
+
+	result := make([]models.LinkDataDB, 0, len(active))
+	for _, item := range active {
+		l, ok := r.live(item.link, now)
+		if !ok {
+			continue
+		}
+
+		data := l.linkData(now)
+		data.Owner = username
+		result = append(result, data)
+	}
+
+	return result, nil
+}
+
+// TrimExpired Убирает из индексов ссылки, истекшие к моменту now, если ссылка не занята заново тем же пользователем.
+// Возвращает убранные ссылки по владельцам
+func (r *MemoryLinkRepository) TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make(map[string][]string)
+	for username, links := range r.index {
+		for link, expireAt := range links {
+			if expireAt.IsZero() || expireAt.After(now) {
+				continue
+			}
+			if l, ok := r.live(link, r.now()); ok && l.data.Owner == username {
+				continue
+			}
+
+			delete(links, link)
+			result[username] = append(result[username], link)
+		}
+		if len(links) == 0 {
+			delete(r.index, username)
+		}
+	}
+
+	return result, nil
+}
+
+// KeepLinks Получает ссылки, которые пользователь выбрал для сохранения после окончания подписки
+func (r *MemoryLinkRepository) KeepLinks(ctx context.Context, username string) ([]string, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return append([]string{}, r.keep[username]...), nil
+}
+
+// SetKeepLinks Заменяет список ссылок, сохраняемых после окончания подписки
+func (r *MemoryLinkRepository) SetKeepLinks(ctx context.Context, username string, links []string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	// Как и множество в Redis, список хранится без повторов
+	seen := make(map[string]bool, len(links))
+	keep := make([]string, 0, len(links))
+	for _, link := range links {
+		if !seen[link] {
+			seen[link] = true
+			keep = append(keep, link)
+		}
+	}
+
+	if len(keep) == 0 {
+		delete(r.keep, username)
+		return nil
+	}
+	r.keep[username] = keep
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// MemoryOIDCStateRepositoryConfig Конфигурация для MemoryOIDCStateRepository
+type MemoryOIDCStateRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memoryOIDCState Параметры входа и срок их хранения
+type memoryOIDCState struct {
+	state		models.OIDCState
+	expireAt	time.Time
+}
+
+// MemoryOIDCStateRepository Хранилище начатых входов в памяти процесса с поведением RedisOIDCStateRepository
+type MemoryOIDCStateRepository struct {
+	now		func() time.Time
+	states	map[string]memoryOIDCState
+	mux		sync.Mutex
+}
+
+// NewMemoryOIDCStateRepository Конструктор для MemoryOIDCStateRepository
+func NewMemoryOIDCStateRepository(c *MemoryOIDCStateRepositoryConfig) *MemoryOIDCStateRepository {
+	return &MemoryOIDCStateRepository{
+		now:	nowFunc(c.Now),
+		states:	make(map[string]memoryOIDCState),
+	}
+}
+
+// SaveState Сохраняет параметры входа по state на время ttl
+func (r *MemoryOIDCStateRepository) SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	// Чистим просроченные попытки входа
+	now := r.now()
+	for k, v := range r.states {
+		if !now.Before(v.expireAt) {
+			delete(r.states, k)
+		}
+	}
+	r.states[state] = memoryOIDCState{state: st, expireAt: now.Add(ttl)}
+
+	return nil
+}
+
+// TakeState Достает и сразу удаляет параметры входа. Если state не найден или истек, возвращает redis.Nil
+func (r *MemoryOIDCStateRepository) TakeState(ctx context.Context, state string) (models.OIDCState, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	v, ok := r.states[state]
+	delete(r.states, state)
+	if !ok || !r.now().Before(v.expireAt) {
+		return models.OIDCState{}, redis.Nil
+	}
+
+	return v.state, nil
+}
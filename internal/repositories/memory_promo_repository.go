@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryPromoRepositoryConfig Конфигурация для MemoryPromoRepository
+type MemoryPromoRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// MemoryPromoRepository Хранилище промокодов в памяти процесса с поведением PostgresqlPromoRepository
+type MemoryPromoRepository struct {
+	now		func() time.Time
+	promos	map[string]models.PromoDB
+	uses	map[string]map[string]bool	// Пользователи, использовавшие промокод
+	mux		sync.Mutex
+}
+
+// NewMemoryPromoRepository Конструктор для MemoryPromoRepository
+func NewMemoryPromoRepository(c *MemoryPromoRepositoryConfig) *MemoryPromoRepository {
+	return &MemoryPromoRepository{
+		now:	nowFunc(c.Now),
+		promos:	make(map[string]models.PromoDB),
+		uses:	make(map[string]map[string]bool),
+	}
+}
+
+// CreatePromo Создает промокод
+func (r *MemoryPromoRepository) CreatePromo(ctx context.Context, p models.PromoDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.promos[p.Code]; ok {
+		return errMemoryDuplicate
+	}
+
+	p.Value = roundMoney(p.Value)
+	p.Uses = 0
+	p.CreatedAt = r.now()
+	if p.ExpiresAt != nil {
+		exp := *p.ExpiresAt
+		p.ExpiresAt = &exp
+	}
+	r.promos[p.Code] = p
+
+	return nil
+}
+
+// FindPromo Находит промокод
+func (r *MemoryPromoRepository) FindPromo(ctx context.Context, code string) (models.PromoDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	p, ok := r.promos[code]
+	if !ok {
+		return models.PromoDB{}, pgx.ErrNoRows
+	}
+
+	return p, nil
+}
+
+// FindPromos Возвращает промокоды, начиная с последних созданных
+func (r *MemoryPromoRepository) FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.PromoDB, 0, len(r.promos))
+	for _, p := range r.promos {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].Code < result[j].Code
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return limitRows(result, limit)
+}
+
+// DeletePromo Удаляет промокод вместе с историей использований. Если промокода нет, возвращает pgx.ErrNoRows
+func (r *MemoryPromoRepository) DeletePromo(ctx context.Context, code string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.promos[code]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.promos, code)
+	delete(r.uses, code)
+
+	return nil
+}
+
+// Redeemed Проверяет, использовал ли пользователь промокод
+func (r *MemoryPromoRepository) Redeemed(ctx context.Context, code, username string) (bool, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.uses[code][username], nil
+}
+
+// UsePromo Списывает использование промокода пользователем.
+// Если код не найден, просрочен, исчерпан или уже использован пользователем, возвращает pgx.ErrNoRows
+func (r *MemoryPromoRepository) UsePromo(ctx context.Context, code, username string) (models.PromoDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	p, ok := r.promos[code]
+	if !ok ||
+		p.MaxUses != 0 && p.Uses >= p.MaxUses ||
+		p.ExpiresAt != nil && !p.ExpiresAt.After(r.now()) ||
+		r.uses[code][username] {
+		return models.PromoDB{}, pgx.ErrNoRows
+	}
+
+	p.Uses++
+	r.promos[code] = p
+	if r.uses[code] == nil {
+		r.uses[code] = make(map[string]bool)
+	}
+	r.uses[code][username] = true
+
+	return p, nil
+}
+
+// ReleasePromo Возвращает использование промокода (счет со скидкой не оплачен)
+func (r *MemoryPromoRepository) ReleasePromo(ctx context.Context, code, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if !r.uses[code][username] {
+		return nil
+	}
+	delete(r.uses[code], username)
+
+	if p, ok := r.promos[code]; ok && p.Uses > 0 {
+		p.Uses--
+		r.promos[code] = p
+	}
+
+	return nil
+}
+
+// RedactUser Заменяет имя удаленного пользователя в использованиях промокодов псевдонимом
+func (r *MemoryPromoRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for _, uses := range r.uses {
+		if uses[username] {
+			delete(uses, username)
+			uses[pseudonym] = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryQiwiEventRepository Учет обработанных счетов QIWI в памяти процесса с поведением PostgresqlQiwiEventRepository
+type MemoryQiwiEventRepository struct {
+	events	map[string]string	// Статус, с которым обработан счет
+	mux		sync.Mutex
+}
+
+// NewMemoryQiwiEventRepository Конструктор для MemoryQiwiEventRepository
+func NewMemoryQiwiEventRepository() *MemoryQiwiEventRepository {
+	return &MemoryQiwiEventRepository{
+		events:	make(map[string]string),
+	}
+}
+
+// MarkProcessed Отмечает счет обработанным. Возвращает false, если счет уже был обработан
+func (r *MemoryQiwiEventRepository) MarkProcessed(ctx context.Context, billID, status string) (bool, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.events[billID]; ok {
+		return false, nil
+	}
+	r.events[billID] = status
+
+	return true, nil
+}
+
+// UnmarkProcessed Снимает отметку, если обработать счет не удалось
+func (r *MemoryQiwiEventRepository) UnmarkProcessed(ctx context.Context, billID string) error {
+	r.mux.Lock()
+	delete(r.events, billID)
+	r.mux.Unlock()
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryRecoveryRepository Хранилище резервных кодов в памяти процесса с поведением PostgresqlRecoveryRepository
+type MemoryRecoveryRepository struct {
+	codes	map[string]map[string]bool	// Коды пользователя и признак использования
+	mux		sync.Mutex
+}
+
+// NewMemoryRecoveryRepository Конструктор для MemoryRecoveryRepository
+func NewMemoryRecoveryRepository() *MemoryRecoveryRepository {
+	return &MemoryRecoveryRepository{
+		codes:	make(map[string]map[string]bool),
+	}
+}
+
+// ReplaceCodes Заменяет все резервные коды пользователя новыми
+func (r *MemoryRecoveryRepository) ReplaceCodes(ctx context.Context, username string, hashes []string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = false
+	}
+	r.codes[username] = codes
+
+	return nil
+}
+
+// UseCode Помечает резервный код использованным. Если кода нет или он уже использован, возвращает pgx.ErrNoRows
+func (r *MemoryRecoveryRepository) UseCode(ctx context.Context, username, hash string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	used, ok := r.codes[username][hash]
+	if !ok || used {
+		return pgx.ErrNoRows
+	}
+	r.codes[username][hash] = true
+
+	return nil
+}
+
+// DeleteCodes Удаляет все резервные коды пользователя
+func (r *MemoryRecoveryRepository) DeleteCodes(ctx context.Context, username string) error {
+	r.mux.Lock()
+	delete(r.codes, username)
+	r.mux.Unlock()
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryReportRepositoryConfig Конфигурация для MemoryReportRepository
+type MemoryReportRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// MemoryReportRepository Хранилище жалоб на ссылки в памяти процесса с поведением PostgresqlReportRepository
+type MemoryReportRepository struct {
+	now		func() time.Time
+	reports	[]models.ReportDB	// В порядке добавления
+	mux		sync.Mutex
+}
+
+// NewMemoryReportRepository Конструктор для MemoryReportRepository
+func NewMemoryReportRepository(c *MemoryReportRepositoryConfig) *MemoryReportRepository {
+	return &MemoryReportRepository{
+		now:	nowFunc(c.Now),
+	}
+}
+
+// CreateReport Сохраняет жалобу на ссылку
+func (r *MemoryReportRepository) CreateReport(ctx context.Context, report models.ReportDB) (int64, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	id := int64(len(r.reports) + 1)
+	r.reports = append(r.reports, models.ReportDB{
+		ID:			id,
+		Link:		report.Link,
+		Reason:		report.Reason,
+		Reporter:	report.Reporter,
+		Status:		models.ReportOpen,
+		CreatedAt:	r.now(),
+	})
+
+	return id, nil
+}
+
+// FindReports Возвращает жалобы с указанным статусом, начиная с самых старых
+func (r *MemoryReportRepository) FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.ReportDB, 0)
+	for _, report := range r.reports {
+		if report.Status == status {
+			result = append(result, report)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return limitRows(result, limit)
+}
+
+// FindReport Ищет жалобу по номеру
+func (r *MemoryReportRepository) FindReport(ctx context.Context, id int64) (models.ReportDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if id < 1 || id > int64(len(r.reports)) {
+		return models.ReportDB{}, pgx.ErrNoRows
+	}
+
+	return r.reports[id-1], nil
+}
+
+// ResolveReports Закрывает все открытые жалобы на ссылку с указанным статусом
+func (r *MemoryReportRepository) ResolveReports(ctx context.Context, link, status, admin string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for i := range r.reports {
+		if r.reports[i].Link == link && r.reports[i].Status == models.ReportOpen {
+			r.reports[i].Status = status
+			r.reports[i].ResolvedBy = admin
+		}
+	}
+
+	return nil
+}
+
+// RedactResolver Заменяет имя удаленного администратора в решенных им жалобах псевдонимом
+func (r *MemoryReportRepository) RedactResolver(ctx context.Context, username, pseudonym string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for i := range r.reports {
+		if r.reports[i].ResolvedBy == username {
+			r.reports[i].ResolvedBy = pseudonym
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRevocationRepositoryConfig Конфигурация для MemoryRevocationRepository
+type MemoryRevocationRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memoryRevocation Момент отзыва токенов и срок хранения записи
+type memoryRevocation struct {
+	at			time.Time
+	expireAt	time.Time
+}
+
+// MemoryRevocationRepository Хранилище моментов отзыва токенов в памяти процесса с поведением RedisRevocationRepository
+type MemoryRevocationRepository struct {
+	now		func() time.Time
+	revoked	map[string]memoryRevocation
+	mux		sync.Mutex
+}
+
+// NewMemoryRevocationRepository Конструктор для MemoryRevocationRepository
+func NewMemoryRevocationRepository(c *MemoryRevocationRepositoryConfig) *MemoryRevocationRepository {
+	return &MemoryRevocationRepository{
+		now:		nowFunc(c.Now),
+		revoked:	make(map[string]memoryRevocation),
+	}
+}
+
+// Revoke Запоминает момент отзыва токенов пользователя на время ttl
+func (r *MemoryRevocationRepository) Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	for name, rev := range r.revoked {
+		if !now.Before(rev.expireAt) {
+			delete(r.revoked, name)
+		}
+	}
+	r.revoked[username] = memoryRevocation{at: time.Unix(at.Unix(), 0), expireAt: now.Add(ttl)}
+
+	return nil
+}
+
+// RevokedAt Возвращает момент отзыва токенов пользователя (нулевое время - токены не отзывались)
+func (r *MemoryRevocationRepository) RevokedAt(ctx context.Context, username string) (time.Time, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rev, ok := r.revoked[username]
+	if !ok || !r.now().Before(rev.expireAt) {
+		return time.Time{}, nil
+	}
+
+	return rev.at, nil
+}
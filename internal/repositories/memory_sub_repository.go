@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sync"
+	"time"
+)
+
+// MemorySubRepositoryConfig Конфигурация для MemorySubRepository
+type MemorySubRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memorySub Подписка в памяти. Нулевое expireAt - бессрочная
+type memorySub struct {
+	plan		string
+	start		int64
+	expireAt	time.Time
+}
+
+// MemorySubRepository Хранилище подписок в памяти процесса с поведением RedisSubRepository
+type MemorySubRepository struct {
+	now		func() time.Time
+	subs	map[string]memorySub
+	mux		sync.Mutex
+}
+
+// NewMemorySubRepository Конструктор для MemorySubRepository
+func NewMemorySubRepository(c *MemorySubRepositoryConfig) *MemorySubRepository {
+	return &MemorySubRepository{
+		now:	nowFunc(c.Now),
+		subs:	make(map[string]memorySub),
+	}
+}
+
+// findSub Находит действующую подписку и ее срок, истекшую удаляет
+func (r *MemorySubRepository) findSub(username string) (memorySub, time.Duration, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	sub, ok := r.subs[username]
+	if !ok {
+		return memorySub{}, -1, false
+	}
+	if sub.expireAt.IsZero() {
+		return sub, -1, true
+	}
+
+	now := r.now()
+	if !now.Before(sub.expireAt) {
+		delete(r.subs, username)
+		return memorySub{}, -1, false
+	}
+
+	return sub, redisTTL(sub.expireAt.Sub(now)), true
+}
+
+// FindSubscribe Находит подписку и ее срок по имени пользователя
+func (r *MemorySubRepository) FindSubscribe(ctx context.Context, username string) (time.Duration, bool) {
+	_, exp, ok := r.findSub(username)
+
+	return exp, ok
+}
+
+// FindSubPlan Находит подписку, ее тарифный план и срок по имени пользователя
+func (r *MemorySubRepository) FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool) {
+	sub, exp, ok := r.findSub(username)
+	if !ok {
+		return "", -1, false
+	}
+
+	return sub.plan, exp, true
+}
+
+// SubState Находит план действующей подписки
+func (r *MemorySubRepository) SubState(ctx context.Context, username string) (string, bool, error) {
+	sub, _, ok := r.findSub(username)
+
+	return sub.plan, ok, nil
+}
+
+// AddSubRedis Добавляет пользователю подписку по тарифному плану на ограниченное время.
+// При продлении действующей подписки дата начала сохраняется
+func (r *MemorySubRepository) AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error {
+	current, _, ok := r.findSub(username)
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	sub := memorySub{plan: plan, start: now.Unix()}
+	if ok && current.start > 0 {
+		sub.start = current.start
+	}
+	if exp > 0 {
+		sub.expireAt = now.Add(exp)
+	}
+	r.subs[username] = sub
+
+	return nil
+}
+
+// FindSubscription Находит действующую подписку пользователя с планом и датой начала
+func (r *MemorySubRepository) FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool) {
+	sub, exp, ok := r.findSub(username)
+	if !ok {
+		return models.SubscriptionDB{}, false
+	}
+
+	return models.SubscriptionDB{Plan: sub.plan, Exp: exp, Start: time.Unix(sub.start, 0)}, true
+}
+
+// RemoveSubscribe Удаляет подписку пользователя
+func (r *MemorySubRepository) RemoveSubscribe(ctx context.Context, username string) error {
+	r.mux.Lock()
+	delete(r.subs, username)
+	r.mux.Unlock()
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryTokenRepositoryConfig Конфигурация для MemoryTokenRepository
+type MemoryTokenRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// memoryToken Токен в памяти. used - токен использован или отозван
+type memoryToken struct {
+	token	models.UserTokenDB
+	used	bool
+}
+
+// MemoryTokenRepository Хранилище одноразовых токенов в памяти процесса с поведением PostgresqlTokenRepository
+type MemoryTokenRepository struct {
+	now		func() time.Time
+	tokens	map[string]*memoryToken	// По хешу токена
+	lastID	int64
+	mux		sync.Mutex
+}
+
+// NewMemoryTokenRepository Конструктор для MemoryTokenRepository
+func NewMemoryTokenRepository(c *MemoryTokenRepositoryConfig) *MemoryTokenRepository {
+	return &MemoryTokenRepository{
+		now:	nowFunc(c.Now),
+		tokens:	make(map[string]*memoryToken),
+	}
+}
+
+// CreateToken Сохраняет хеш токена
+func (r *MemoryTokenRepository) CreateToken(ctx context.Context, token models.UserTokenDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.tokens[token.Hash]; ok {
+		return errMemoryDuplicate
+	}
+
+	r.lastID++
+	token.ID = r.lastID
+	r.tokens[token.Hash] = &memoryToken{token: token}
+
+	return nil
+}
+
+// UseToken Помечает токен использованным и возвращает его. Если токена нет, он использован или истек, возвращает pgx.ErrNoRows
+func (r *MemoryTokenRepository) UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	t, ok := r.tokens[hash]
+	if !ok || t.used || t.token.Kind != kind || !t.token.ExpiresAt.After(r.now()) {
+		return models.UserTokenDB{}, pgx.ErrNoRows
+	}
+	t.used = true
+
+	return t.token, nil
+}
+
+// RevokeTokens Помечает использованными все действующие токены пользователя указанного вида
+func (r *MemoryTokenRepository) RevokeTokens(ctx context.Context, username, kind string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for _, t := range r.tokens {
+		if t.token.Username == username && t.token.Kind == kind {
+			t.used = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryUserRepository Хранилище пользователей в памяти процесса с поведением PostgresqlUserRepository
+type MemoryUserRepository struct {
+	users	map[string]models.UserDB
+	lastID	int64
+	mux		sync.Mutex
+}
+
+// NewMemoryUserRepository Конструктор для MemoryUserRepository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:	make(map[string]models.UserDB),
+	}
+}
+
+// FindByUsername Ищет пользователя по его имени
+func (r *MemoryUserRepository) FindByUsername(ctx context.Context, username string) (models.UserDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok {
+		return models.UserDB{}, pgx.ErrNoRows
+	}
+
+	return user, nil
+}
+
+// findEmail Ищет пользователя по почте без учета регистра (пустая почта не ищется)
+func (r *MemoryUserRepository) findEmail(email string) (models.UserDB, bool) {
+	if email == "" {
+		return models.UserDB{}, false
+	}
+
+	for _, user := range r.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			return user, true
+		}
+	}
+
+	return models.UserDB{}, false
+}
+
+// FindByEmail Ищет пользователя по почте (без учета регистра)
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (models.UserDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.findEmail(email)
+	if !ok {
+		return models.UserDB{}, pgx.ErrNoRows
+	}
+
+	// Как и запрос в Postgres, не возвращает данные второго фактора
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPStep = 0
+
+	return user, nil
+}
+
+// SearchUsers Ищет пользователей, имя, фамилия или логин которых содержат строку поиска
+func (r *MemoryUserRepository) SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	search = strings.ToLower(search)
+	result := make([]models.UserDB, 0)
+	for _, user := range r.users {
+		if !strings.Contains(strings.ToLower(user.Username), search) &&
+			!strings.Contains(strings.ToLower(user.FirstName), search) &&
+			!strings.Contains(strings.ToLower(user.LastName), search) {
+			continue
+		}
+
+		result = append(result, models.UserDB{
+			ID:			user.ID,
+			Username:	user.Username,
+			FirstName:	user.FirstName,
+			LastName:	user.LastName,
+			Role:		user.Role,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Username < result[j].Username
+	})
+
+	return limitRows(result, limit)
+}
+
+// UpdateUser Обновляет имя, фамилию и почту пользователя
+func (r *MemoryUserRepository) UpdateUser(ctx context.Context, user models.UserDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	current, ok := r.users[user.Username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	if other, found := r.findEmail(user.Email); found && other.Username != user.Username {
+		return errMemoryDuplicate
+	}
+
+	current.FirstName = user.FirstName
+	current.LastName = user.LastName
+	current.Email = user.Email
+	current.EmailVerified = user.EmailVerified
+	r.users[user.Username] = current
+
+	return nil
+}
+
+// VerifyEmail Отмечает почту пользователя подтвержденной, если она не менялась после выдачи токена
+func (r *MemoryUserRepository) VerifyEmail(ctx context.Context, username, email string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok || user.Email != email {
+		return pgx.ErrNoRows
+	}
+	user.EmailVerified = true
+	r.users[username] = user
+
+	return nil
+}
+
+// SetTOTP Сохраняет секрет TOTP и признак включения второго фактора
+func (r *MemoryUserRepository) SetTOTP(ctx context.Context, username, secret string, enabled bool) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	user.TOTPSecret = secret
+	user.TOTPEnabled = enabled
+	r.users[username] = user
+
+	return nil
+}
+
+// UseTOTPStep Запоминает принятый шаг TOTP. Если шаг не новее последнего принятого, возвращает pgx.ErrNoRows
+func (r *MemoryUserRepository) UseTOTPStep(ctx context.Context, username string, step int64) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok || user.TOTPStep >= step {
+		return pgx.ErrNoRows
+	}
+	user.TOTPStep = step
+	r.users[username] = user
+
+	return nil
+}
+
+// UpdatePassword Заменяет хеш пароля пользователя
+func (r *MemoryUserRepository) UpdatePassword(ctx context.Context, username, password string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	user.Password = password
+	r.users[username] = user
+
+	return nil
+}
+
+// DeleteUser Удаляет пользователя
+func (r *MemoryUserRepository) DeleteUser(ctx context.Context, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.users[username]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.users, username)
+
+	return nil
+}
+
+// CreateUser Создает пользователя с ролью user. Занятые имя или почта - ошибка, как нарушение уникальности в Postgres
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user models.UserDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.users[user.Username]; ok {
+		return errMemoryDuplicate
+	}
+	if _, ok := r.findEmail(user.Email); ok {
+		return errMemoryDuplicate
+	}
+
+	r.lastID++
+	r.users[user.Username] = models.UserDB{
+		ID:			strconv.FormatInt(r.lastID, 10),
+		Username:	user.Username,
+		FirstName:	user.FirstName,
+		LastName:	user.LastName,
+		Password:	user.Password,
+		Role:		models.RoleUser,
+		Email:		user.Email,
+	}
+
+	return nil
+}
+
+// SetRole Назначает пользователю роль. В Postgres администраторы назначаются вручную запросом к базе,
+// в памяти - этим методом
+func (r *MemoryUserRepository) SetRole(username string, role models.Role) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	user, ok := r.users[username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	user.Role = role
+	r.users[username] = user
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MemoryWebhookRepositoryConfig Конфигурация для MemoryWebhookRepository
+type MemoryWebhookRepositoryConfig struct {
+	Now		func() time.Time	// Часы (nil - системные)
+}
+
+// MemoryWebhookRepository Хранилище вебхуков и журнала доставок в памяти процесса с поведением PostgresqlWebhookRepository
+type MemoryWebhookRepository struct {
+	now				func() time.Time
+	webhooks		map[int64]models.WebhookDB
+	deliveries		map[int64]models.DeliveryDB
+	lastWebhook		int64
+	lastDelivery	int64
+	mux				sync.Mutex
+}
+
+// NewMemoryWebhookRepository Конструктор для MemoryWebhookRepository
+func NewMemoryWebhookRepository(c *MemoryWebhookRepositoryConfig) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		now:		nowFunc(c.Now),
+		webhooks:	make(map[int64]models.WebhookDB),
+		deliveries:	make(map[int64]models.DeliveryDB),
+	}
+}
+
+// CreateWebhook Сохраняет вебхук и возвращает его с номером и временем создания
+func (r *MemoryWebhookRepository) CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.lastWebhook++
+	w.ID = r.lastWebhook
+	w.CreatedAt = r.now()
+	w.Events = append([]string{}, w.Events...)
+	r.webhooks[w.ID] = w
+
+	return w, nil
+}
+
+// findWebhooks Выбирает вебхуки по условию в порядке номеров
+func (r *MemoryWebhookRepository) findWebhooks(match func(models.WebhookDB) bool) []models.WebhookDB {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.WebhookDB, 0)
+	for _, w := range r.webhooks {
+		if match(w) {
+			w.Events = append([]string{}, w.Events...)
+			result = append(result, w)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// FindWebhooks Возвращает вебхуки пользователя
+func (r *MemoryWebhookRepository) FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error) {
+	return r.findWebhooks(func(w models.WebhookDB) bool {
+		return w.Username == username
+	}), nil
+}
+
+// FindSubscribed Возвращает вебхуки пользователя, подписанные на вид события
+func (r *MemoryWebhookRepository) FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error) {
+	return r.findWebhooks(func(w models.WebhookDB) bool {
+		if w.Username != username {
+			return false
+		}
+		if len(w.Events) == 0 {
+			return true
+		}
+		for _, e := range w.Events {
+			if e == kind {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// deleteWebhook Удаляет вебхук вместе с журналом доставок
+func (r *MemoryWebhookRepository) deleteWebhook(id int64) {
+	delete(r.webhooks, id)
+	for deliveryID, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+}
+
+// DeleteWebhook Удаляет вебхук пользователя вместе с журналом доставок. Если вебхука нет, возвращает pgx.ErrNoRows
+func (r *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, id int64, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	w, ok := r.webhooks[id]
+	if !ok || w.Username != username {
+		return pgx.ErrNoRows
+	}
+	r.deleteWebhook(id)
+
+	return nil
+}
+
+// DeleteWebhooks Удаляет все вебхуки пользователя
+func (r *MemoryWebhookRepository) DeleteWebhooks(ctx context.Context, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for id, w := range r.webhooks {
+		if w.Username == username {
+			r.deleteWebhook(id)
+		}
+	}
+
+	return nil
+}
+
+// CreateDelivery Ставит событие в очередь доставки. Доставка на несуществующий вебхук - ошибка внешнего ключа
+func (r *MemoryWebhookRepository) CreateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.webhooks[d.WebhookID]; !ok {
+		return errMemoryForeignKey
+	}
+
+	now := r.now()
+	r.lastDelivery++
+	r.deliveries[r.lastDelivery] = models.DeliveryDB{
+		ID:				r.lastDelivery,
+		WebhookID:		d.WebhookID,
+		Username:		d.Username,
+		EventID:		d.EventID,
+		Kind:			d.Kind,
+		Payload:		d.Payload,
+		Status:			models.DeliveryPending,
+		NextAttempt:	now,
+		CreatedAt:		now,
+		UpdatedAt:		now,
+	}
+
+	return nil
+}
+
+// findDeliveries Выбирает доставки по условию с адресом и ключом вебхука
+func (r *MemoryWebhookRepository) findDeliveries(match func(models.DeliveryDB) bool, less func(a, b models.DeliveryDB) bool, limit int) ([]models.DeliveryDB, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	result := make([]models.DeliveryDB, 0)
+	for _, d := range r.deliveries {
+		if !match(d) {
+			continue
+		}
+
+		w := r.webhooks[d.WebhookID]
+		d.URL = w.URL
+		d.Secret = w.Secret
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+
+	return limitRows(result, limit)
+}
+
+// DueDeliveries Возвращает доставки, время отправки которых наступило
+func (r *MemoryWebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error) {
+	return r.findDeliveries(func(d models.DeliveryDB) bool {
+		return d.Status == models.DeliveryPending && !d.NextAttempt.After(now)
+	}, func(a, b models.DeliveryDB) bool {
+		if a.NextAttempt.Equal(b.NextAttempt) {
+			return a.ID < b.ID
+		}
+		return a.NextAttempt.Before(b.NextAttempt)
+	}, limit)
+}
+
+// UpdateDelivery Сохраняет результат попытки доставки
+func (r *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	current, ok := r.deliveries[d.ID]
+	if !ok {
+		return nil
+	}
+	current.Status = d.Status
+	current.Attempts = d.Attempts
+	current.NextAttempt = d.NextAttempt
+	current.ResponseCode = d.ResponseCode
+	current.LastError = d.LastError
+	current.UpdatedAt = r.now()
+	r.deliveries[d.ID] = current
+
+	return nil
+}
+
+// FindDeliveries Возвращает доставки пользователя, начиная с последних
+func (r *MemoryWebhookRepository) FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error) {
+	return r.findDeliveries(func(d models.DeliveryDB) bool {
+		return d.Username == username
+	}, func(a, b models.DeliveryDB) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID > b.ID
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	}, limit)
+}
+
+// Redeliver Ставит доставку пользователя в очередь заново с новым запасом попыток.
+// Если доставки нет, возвращает pgx.ErrNoRows
+func (r *MemoryWebhookRepository) Redeliver(ctx context.Context, id int64, username string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok || d.Username != username {
+		return pgx.ErrNoRows
+	}
+
+	now := r.now()
+	d.Status = models.DeliveryPending
+	d.Attempts = 0
+	d.NextAttempt = now
+	d.UpdatedAt = now
+	r.deliveries[id] = d
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlAuditRepositoryConfig конфигурация для PostgresqlAuditRepository
+type PostgresqlAuditRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlAuditRepository - слой для записи и чтения журнала аудита в Postgresql
+type PostgresqlAuditRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlAuditRepository конструктор для PostgresqlAuditRepository
+func NewPostgresqlAuditRepository(c *PostgresqlAuditRepositoryConfig) *PostgresqlAuditRepository {
+	return &PostgresqlAuditRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// AppendEntry добавляет запись в журнал аудита
+func (r *PostgresqlAuditRepository) AppendEntry(ctx context.Context, entry models.AuditEntry) error {
+	query := fmt.Sprintf("INSERT INTO %s (actor, action, target, owner, details, trace_id) VALUES ($1, $2, $3, $4, $5, $6)", r.table)
+
+	_, err := r.db.Exec(ctx, query, entry.Actor, entry.Action, entry.Target, entry.Owner, entry.Details, entry.TraceID)
+
+	return err
+}
+
+// RedactUser заменяет имя удаленного пользователя в журнале псевдонимом. Сведения, которые пользователь
+// записал сам (например, адреса ссылок), стираются; записи администраторов о нем сохраняются.
+// target меняется только в записях о самом пользователе: имя чужой ссылки может совпадать с его именем
+func (r *PostgresqlAuditRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Без этой настройки триггер audit_log_append_only пропускает любые изменения журнала
+	if _, err = tx.Exec(ctx, "SET LOCAL audit.redact = 'on'"); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET
+		actor = CASE WHEN actor = $1 THEN $2 ELSE actor END,
+		owner = CASE WHEN owner = $1 THEN $2 ELSE owner END,
+		target = CASE WHEN target = $1 AND owner = $1 THEN $2 ELSE target END,
+		details = CASE WHEN actor = $1 THEN '' ELSE details END
+		WHERE actor = $1 OR owner = $1`, r.table)
+	if _, err = tx.Exec(ctx, query, username, pseudonym); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// FindEntries возвращает записи журнала по фильтру, начиная с самых новых
+func (r *PostgresqlAuditRepository) FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	// Собираем условия выборки из заполненных полей фильтра
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	for column, value := range map[string]string{"actor": filter.Actor, "owner": filter.Owner, "action": filter.Action} {
+		if value == "" {
+			continue
+		}
+
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`SELECT audit_id, actor, action, target, owner, details, trace_id, created_at FROM %s
+		%s ORDER BY created_at DESC, audit_id DESC LIMIT $%d`, r.table, where, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+
+		err = rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Target, &entry.Owner, &entry.Details, &entry.TraceID, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlBillRepositoryConfig конфигурация для PostgresqlBillRepository
+type PostgresqlBillRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlBillRepository - слой для хранения истории счетов в Postgresql
+type PostgresqlBillRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlBillRepository конструктор для PostgresqlBillRepository
+func NewPostgresqlBillRepository(c *PostgresqlBillRepositoryConfig) *PostgresqlBillRepository {
+	return &PostgresqlBillRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// CreateBill сохраняет выставленный счет
+func (r *PostgresqlBillRepository) CreateBill(ctx context.Context, bill models.BillDB) error {
+	query := fmt.Sprintf("INSERT INTO %s (bill_id, username, plan, amount, promo, status) VALUES ($1, $2, $3, $4, $5, $6)", r.table)
+
+	_, err := r.db.Exec(ctx, query, bill.ID, bill.Username, bill.Plan, bill.Amount, bill.Promo, bill.Status)
+
+	return err
+}
+
+// UpdateBillStatus меняет статус счета
+func (r *PostgresqlBillRepository) UpdateBillStatus(ctx context.Context, id, status string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $2, updated_at = now() WHERE bill_id = $1", r.table)
+
+	tag, err := r.db.Exec(ctx, query, id, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// FindBill возвращает счет по номеру
+func (r *PostgresqlBillRepository) FindBill(ctx context.Context, id string) (models.BillDB, error) {
+	query := fmt.Sprintf(`SELECT bill_id, username, plan, amount::float8, promo, status, created_at, updated_at FROM %s
+		WHERE bill_id = $1`, r.table)
+
+	var bill models.BillDB
+	err := r.db.QueryRow(ctx, query, id).Scan(&bill.ID, &bill.Username, &bill.Plan, &bill.Amount, &bill.Promo, &bill.Status, &bill.CreatedAt, &bill.UpdatedAt)
+
+	return bill, err
+}
+
+// FindWaitingBills возвращает счета, ожидающие оплаты, начиная с самых старых
+func (r *PostgresqlBillRepository) FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error) {
+	query := fmt.Sprintf(`SELECT bill_id, username, plan, amount::float8, promo, status, created_at, updated_at FROM %s
+		WHERE status = $1 ORDER BY created_at LIMIT $2`, r.table)
+
+	return r.scanBills(ctx, query, models.BillWaiting, limit)
+}
+
+// FindBills возвращает счета пользователя, начиная с последних
+func (r *PostgresqlBillRepository) FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error) {
+	query := fmt.Sprintf(`SELECT bill_id, username, plan, amount::float8, promo, status, created_at, updated_at FROM %s
+		WHERE username = $1 ORDER BY created_at DESC LIMIT $2`, r.table)
+
+	return r.scanBills(ctx, query, username, limit)
+}
+
+// scanBills выполняет запрос и читает счета
+func (r *PostgresqlBillRepository) scanBills(ctx context.Context, query string, args ...any) ([]models.BillDB, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.BillDB, 0)
+	for rows.Next() {
+		var bill models.BillDB
+
+		err = rows.Scan(&bill.ID, &bill.Username, &bill.Plan, &bill.Amount, &bill.Promo, &bill.Status, &bill.CreatedAt, &bill.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, bill)
+	}
+
+	return result, rows.Err()
+}
+
+// RedactUser заменяет имя удаленного пользователя в истории счетов псевдонимом.
+// Сами счета остаются для бухгалтерии
+func (r *PostgresqlBillRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	query := fmt.Sprintf("UPDATE %s SET username = $2 WHERE username = $1", r.table)
+
+	_, err := r.db.Exec(ctx, query, username, pseudonym)
+
+	return err
+}
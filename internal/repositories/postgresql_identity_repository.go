@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlIdentityRepositoryConfig конфигурация для PostgresqlIdentityRepository
+type PostgresqlIdentityRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlIdentityRepository - слой для управления привязками к внешним провайдерам в Postgresql
+type PostgresqlIdentityRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlIdentityRepository конструктор для PostgresqlIdentityRepository
+func NewPostgresqlIdentityRepository(c *PostgresqlIdentityRepositoryConfig) *PostgresqlIdentityRepository {
+	return &PostgresqlIdentityRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// FindIdentity ищет привязку по провайдеру и идентификатору пользователя у провайдера
+func (r *PostgresqlIdentityRepository) FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error) {
+	var identity models.IdentityDB
+
+	query := fmt.Sprintf("SELECT identity_id, username, issuer, subject, email FROM %s WHERE issuer = $1 AND subject = $2", r.table)
+
+	err := r.db.QueryRow(ctx, query, issuer, subject).Scan(&identity.ID, &identity.Username, &identity.Issuer, &identity.Subject, &identity.Email)
+
+	return identity, err
+}
+
+// CreateIdentity сохраняет привязку аккаунта к пользователю провайдера
+func (r *PostgresqlIdentityRepository) CreateIdentity(ctx context.Context, identity models.IdentityDB) error {
+	query := fmt.Sprintf("INSERT INTO %s (username, issuer, subject, email) VALUES ($1, $2, $3, $4)", r.table)
+
+	_, err := r.db.Exec(ctx, query, identity.Username, identity.Issuer, identity.Subject, identity.Email)
+
+	return err
+}
+
+// DeleteIdentities удаляет все привязки пользователя
+func (r *PostgresqlIdentityRepository) DeleteIdentities(ctx context.Context, username string) error {
+	_, err := r.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE username = $1", r.table), username)
+
+	return err
+}
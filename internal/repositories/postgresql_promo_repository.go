@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlPromoRepositoryConfig конфигурация для PostgresqlPromoRepository
+type PostgresqlPromoRepositoryConfig struct {
+	Table			string		// Таблица промокодов
+	UsesTable		string		// Таблица использований промокодов пользователями
+	DB				*pgxpool.Pool
+}
+
+// PostgresqlPromoRepository - слой для управления промокодами в Postgresql
+type PostgresqlPromoRepository struct {
+	table		string
+	usesTable	string
+	db			*pgxpool.Pool
+}
+
+// NewPostgresqlPromoRepository конструктор для PostgresqlPromoRepository
+func NewPostgresqlPromoRepository(c *PostgresqlPromoRepositoryConfig) *PostgresqlPromoRepository {
+	return &PostgresqlPromoRepository{
+		table:		c.Table,
+		usesTable:	c.UsesTable,
+		db:			c.DB,
+	}
+}
+
+// Поля промокода в порядке сканирования
+const promoFields = "code, kind, value::float8, days, plan, max_uses, uses, expires_at, created_by, created_at"
+
+// scanPromo сканирует промокод из строки результата
+func scanPromo(row pgx.Row) (models.PromoDB, error) {
+	var p models.PromoDB
+
+	err := row.Scan(&p.Code, &p.Kind, &p.Value, &p.Days, &p.Plan, &p.MaxUses, &p.Uses, &p.ExpiresAt, &p.CreatedBy, &p.CreatedAt)
+
+	return p, err
+}
+
+// CreatePromo создает промокод
+func (r *PostgresqlPromoRepository) CreatePromo(ctx context.Context, p models.PromoDB) error {
+	query := fmt.Sprintf(`INSERT INTO %s (code, kind, value, days, plan, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, r.table)
+
+	_, err := r.db.Exec(ctx, query, p.Code, p.Kind, p.Value, p.Days, p.Plan, p.MaxUses, p.ExpiresAt, p.CreatedBy)
+
+	return err
+}
+
+// FindPromo находит промокод
+func (r *PostgresqlPromoRepository) FindPromo(ctx context.Context, code string) (models.PromoDB, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE code = $1", promoFields, r.table)
+
+	return scanPromo(r.db.QueryRow(ctx, query, code))
+}
+
+// FindPromos возвращает промокоды, начиная с последних созданных
+func (r *PostgresqlPromoRepository) FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY created_at DESC LIMIT $1", promoFields, r.table)
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.PromoDB, 0)
+	for rows.Next() {
+		p, err := scanPromo(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, p)
+	}
+
+	return result, rows.Err()
+}
+
+// DeletePromo удаляет промокод вместе с историей использований. Если промокода нет, возвращает pgx.ErrNoRows
+func (r *PostgresqlPromoRepository) DeletePromo(ctx context.Context, code string) error {
+	tag, err := r.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE code = $1", r.table), code)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Redeemed проверяет, использовал ли пользователь промокод
+func (r *PostgresqlPromoRepository) Redeemed(ctx context.Context, code, username string) (bool, error) {
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE code = $1 AND username = $2)", r.usesTable)
+
+	var ok bool
+	err := r.db.QueryRow(ctx, query, code, username).Scan(&ok)
+
+	return ok, err
+}
+
+// UsePromo атомарно списывает использование промокода пользователем (в одной транзакции).
+// Если код не найден, просрочен, исчерпан или уже использован пользователем, возвращает pgx.ErrNoRows
+func (r *PostgresqlPromoRepository) UsePromo(ctx context.Context, code, username string) (models.PromoDB, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return models.PromoDB{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf(`UPDATE %s SET uses = uses + 1
+		WHERE code = $1 AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > now())
+		RETURNING %s`, r.table, promoFields)
+	p, err := scanPromo(tx.QueryRow(ctx, query, code))
+	if err != nil {
+		return models.PromoDB{}, err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (code, username) VALUES ($1, $2) ON CONFLICT DO NOTHING", r.usesTable)
+	tag, err := tx.Exec(ctx, query, code, username)
+	if err != nil {
+		return models.PromoDB{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.PromoDB{}, pgx.ErrNoRows
+	}
+
+	return p, tx.Commit(ctx)
+}
+
+// ReleasePromo возвращает использование промокода (счет со скидкой не оплачен)
+func (r *PostgresqlPromoRepository) ReleasePromo(ctx context.Context, code, username string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE code = $1 AND username = $2", r.usesTable)
+	tag, err := tx.Exec(ctx, query, code, username)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET uses = uses - 1 WHERE code = $1 AND uses > 0", r.table), code)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RedactUser заменяет имя удаленного пользователя в использованиях промокодов псевдонимом
+func (r *PostgresqlPromoRepository) RedactUser(ctx context.Context, username, pseudonym string) error {
+	query := fmt.Sprintf("UPDATE %s SET username = $2 WHERE username = $1", r.usesTable)
+
+	_, err := r.db.Exec(ctx, query, username, pseudonym)
+
+	return err
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlQiwiEventRepositoryConfig конфигурация для PostgresqlQiwiEventRepository
+type PostgresqlQiwiEventRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlQiwiEventRepository - слой для учета обработанных счетов QIWI в Postgresql
+type PostgresqlQiwiEventRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlQiwiEventRepository конструктор для PostgresqlQiwiEventRepository
+func NewPostgresqlQiwiEventRepository(c *PostgresqlQiwiEventRepositoryConfig) *PostgresqlQiwiEventRepository {
+	return &PostgresqlQiwiEventRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// MarkProcessed отмечает счет обработанным. Возвращает false, если счет уже был обработан
+func (r *PostgresqlQiwiEventRepository) MarkProcessed(ctx context.Context, billID, status string) (bool, error) {
+	query := fmt.Sprintf("INSERT INTO %s (bill_id, status) VALUES ($1, $2) ON CONFLICT (bill_id) DO NOTHING", r.table)
+
+	tag, err := r.db.Exec(ctx, query, billID, status)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// UnmarkProcessed снимает отметку, если обработать счет не удалось
+func (r *PostgresqlQiwiEventRepository) UnmarkProcessed(ctx context.Context, billID string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE bill_id = $1", r.table)
+
+	_, err := r.db.Exec(ctx, query, billID)
+
+	return err
+}
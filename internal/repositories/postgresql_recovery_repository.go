@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlRecoveryRepositoryConfig конфигурация для PostgresqlRecoveryRepository
+type PostgresqlRecoveryRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlRecoveryRepository - слой для управления резервными кодами двухфакторной аутентификации в Postgresql
+type PostgresqlRecoveryRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlRecoveryRepository конструктор для PostgresqlRecoveryRepository
+func NewPostgresqlRecoveryRepository(c *PostgresqlRecoveryRepositoryConfig) *PostgresqlRecoveryRepository {
+	return &PostgresqlRecoveryRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// ReplaceCodes заменяет все резервные коды пользователя новыми (в одной транзакции)
+func (r *PostgresqlRecoveryRepository) ReplaceCodes(ctx context.Context, username string, hashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE username = $1", r.table), username)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (username, code_hash) VALUES ($1, $2)", r.table)
+	for _, hash := range hashes {
+		if _, err = tx.Exec(ctx, query, username, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseCode помечает резервный код использованным. Если кода нет или он уже использован, возвращает pgx.ErrNoRows
+func (r *PostgresqlRecoveryRepository) UseCode(ctx context.Context, username, hash string) error {
+	query := fmt.Sprintf("UPDATE %s SET used_at = now() WHERE username = $1 AND code_hash = $2 AND used_at IS NULL", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username, hash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteCodes удаляет все резервные коды пользователя
+func (r *PostgresqlRecoveryRepository) DeleteCodes(ctx context.Context, username string) error {
+	_, err := r.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE username = $1", r.table), username)
+
+	return err
+}
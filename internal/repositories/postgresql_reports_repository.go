@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlReportRepositoryConfig конфигурация для PostgresqlReportRepository
+type PostgresqlReportRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlReportRepository - слой для управления жалобами на ссылки в Postgresql
+type PostgresqlReportRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlReportRepository конструктор для PostgresqlReportRepository
+func NewPostgresqlReportRepository(c *PostgresqlReportRepositoryConfig) *PostgresqlReportRepository {
+	return &PostgresqlReportRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// CreateReport сохраняет жалобу на ссылку
+func (r *PostgresqlReportRepository) CreateReport(ctx context.Context, report models.ReportDB) (int64, error) {
+	var id int64
+
+	query := fmt.Sprintf("INSERT INTO %s (link, reason, reporter) VALUES ($1, $2, $3) RETURNING report_id", r.table)
+
+	err := r.db.QueryRow(ctx, query, report.Link, report.Reason, report.Reporter).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// FindReports возвращает жалобы с указанным статусом, начиная с самых старых
+func (r *PostgresqlReportRepository) FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error) {
+	query := fmt.Sprintf(`SELECT report_id, link, reason, reporter, status, created_at, resolved_by FROM %s
+		WHERE status = $1 ORDER BY created_at LIMIT $2`, r.table)
+
+	rows, err := r.db.Query(ctx, query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.ReportDB, 0)
+	for rows.Next() {
+		var report models.ReportDB
+
+		err = rows.Scan(&report.ID, &report.Link, &report.Reason, &report.Reporter, &report.Status, &report.CreatedAt, &report.ResolvedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, report)
+	}
+
+	return result, rows.Err()
+}
+
+// FindReport ищет жалобу по номеру
+func (r *PostgresqlReportRepository) FindReport(ctx context.Context, id int64) (models.ReportDB, error) {
+	var report models.ReportDB
+
+	query := fmt.Sprintf("SELECT report_id, link, reason, reporter, status, created_at, resolved_by FROM %s WHERE report_id = $1", r.table)
+
+	err := r.db.QueryRow(ctx, query, id).Scan(&report.ID, &report.Link, &report.Reason, &report.Reporter, &report.Status, &report.CreatedAt, &report.ResolvedBy)
+
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// ResolveReports закрывает все открытые жалобы на ссылку с указанным статусом
+func (r *PostgresqlReportRepository) ResolveReports(ctx context.Context, link, status, admin string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, resolved_by = $2 WHERE link = $3 AND status = $4", r.table)
+
+	_, err := r.db.Exec(ctx, query, status, admin, link, models.ReportOpen)
+
+	return err
+}
+
+// RedactResolver заменяет имя удаленного администратора в решенных им жалобах псевдонимом
+func (r *PostgresqlReportRepository) RedactResolver(ctx context.Context, username, pseudonym string) error {
+	query := fmt.Sprintf("UPDATE %s SET resolved_by = $2 WHERE resolved_by = $1", r.table)
+
+	_, err := r.db.Exec(ctx, query, username, pseudonym)
+
+	return err
+}
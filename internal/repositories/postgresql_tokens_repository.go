@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlTokenRepositoryConfig конфигурация для PostgresqlTokenRepository
+type PostgresqlTokenRepositoryConfig struct {
+	Table string
+	DB    *pgxpool.Pool
+}
+
+// PostgresqlTokenRepository - слой для управления одноразовыми токенами пользователей в Postgresql
+type PostgresqlTokenRepository struct {
+	table string
+	db    *pgxpool.Pool
+}
+
+// NewPostgresqlTokenRepository конструктор для PostgresqlTokenRepository
+func NewPostgresqlTokenRepository(c *PostgresqlTokenRepositoryConfig) *PostgresqlTokenRepository {
+	return &PostgresqlTokenRepository{
+		table: c.Table,
+		db:    c.DB,
+	}
+}
+
+// CreateToken сохраняет хеш токена
+func (r *PostgresqlTokenRepository) CreateToken(ctx context.Context, token models.UserTokenDB) error {
+	query := fmt.Sprintf("INSERT INTO %s (username, kind, token_hash, email, expires_at) VALUES ($1, $2, $3, $4, $5)", r.table)
+
+	_, err := r.db.Exec(ctx, query, token.Username, token.Kind, token.Hash, token.Email, token.ExpiresAt)
+
+	return err
+}
+
+// UseToken помечает токен использованным и возвращает его.
+// Проверка срока и отметка выполняются одним запросом, поэтому токен нельзя использовать дважды
+func (r *PostgresqlTokenRepository) UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error) {
+	var token models.UserTokenDB
+
+	query := fmt.Sprintf(`UPDATE %s SET used_at = now()
+		WHERE kind = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING token_id, username, kind, token_hash, email, expires_at`, r.table)
+
+	err := r.db.QueryRow(ctx, query, kind, hash).Scan(&token.ID, &token.Username, &token.Kind, &token.Hash, &token.Email, &token.ExpiresAt)
+
+	return token, err
+}
+
+// RevokeTokens помечает использованными все действующие токены пользователя указанного вида
+func (r *PostgresqlTokenRepository) RevokeTokens(ctx context.Context, username, kind string) error {
+	query := fmt.Sprintf("UPDATE %s SET used_at = now() WHERE username = $1 AND kind = $2 AND used_at IS NULL", r.table)
+
+	_, err := r.db.Exec(ctx, query, username, kind)
+
+	return err
+}
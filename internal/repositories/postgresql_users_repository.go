@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"short_url/internal/models"
 )
@@ -30,25 +31,165 @@ func NewPostgresqlUserRepository(c *PostgresqlUserRepositoryConfig) *PostgresqlU
 // FindByUsername ищет пользователя по его имени
 func (r *PostgresqlUserRepository) FindByUsername(ctx context.Context, username string) (models.UserDB, error) {
 	var user models.UserDB
+	var role string
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE username = $1", r.table)
+	query := fmt.Sprintf("SELECT user_id, username, first_name, last_name, password, role, email, email_verified, totp_secret, totp_enabled, totp_last_step FROM %s WHERE username = $1", r.table)
 
-	err := r.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Password)
+	err := r.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Password, &role, &user.Email, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.TOTPStep)
 
 	if err != nil {
 		return user, err
 	}
 
+	user.Role = user.Role.ChoiceRole(role)
+
+	return user, nil
+}
+
+// FindByEmail ищет пользователя по почте (без учета регистра)
+func (r *PostgresqlUserRepository) FindByEmail(ctx context.Context, email string) (models.UserDB, error) {
+	var user models.UserDB
+	var role string
+
+	query := fmt.Sprintf("SELECT user_id, username, first_name, last_name, password, role, email, email_verified FROM %s WHERE email <> '' AND lower(email) = lower($1)", r.table)
+
+	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.Password, &role, &user.Email, &user.EmailVerified)
+
+	if err != nil {
+		return user, err
+	}
+
+	user.Role = user.Role.ChoiceRole(role)
+
 	return user, nil
 }
 
+// SearchUsers ищет пользователей, имя, фамилия или логин которых содержат строку поиска
+func (r *PostgresqlUserRepository) SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error) {
+	query := fmt.Sprintf(`SELECT user_id, username, first_name, last_name, role FROM %s
+		WHERE username ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1
+		ORDER BY username LIMIT $2`, r.table)
+
+	rows, err := r.db.Query(ctx, query, "%"+search+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.UserDB, 0)
+	for rows.Next() {
+		var user models.UserDB
+		var role string
+
+		if err = rows.Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &role); err != nil {
+			return nil, err
+		}
+
+		user.Role = user.Role.ChoiceRole(role)
+		result = append(result, user)
+	}
+
+	return result, rows.Err()
+}
+
+// UpdateUser обновляет имя, фамилию и почту пользователя
+func (r *PostgresqlUserRepository) UpdateUser(ctx context.Context, user models.UserDB) error {
+	query := fmt.Sprintf("UPDATE %s SET first_name = $2, last_name = $3, email = $4, email_verified = $5 WHERE username = $1", r.table)
+
+	tag, err := r.db.Exec(ctx, query, user.Username, user.FirstName, user.LastName, user.Email, user.EmailVerified)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// VerifyEmail отмечает почту пользователя подтвержденной, если она не менялась после выдачи токена
+func (r *PostgresqlUserRepository) VerifyEmail(ctx context.Context, username, email string) error {
+	query := fmt.Sprintf("UPDATE %s SET email_verified = true WHERE username = $1 AND email = $2", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetTOTP сохраняет секрет TOTP и признак включения второго фактора
+func (r *PostgresqlUserRepository) SetTOTP(ctx context.Context, username, secret string, enabled bool) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_secret = $2, totp_enabled = $3 WHERE username = $1", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username, secret, enabled)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// UseTOTPStep запоминает принятый шаг TOTP. Если шаг не новее последнего принятого (код предъявлен повторно),
+// возвращает pgx.ErrNoRows
+func (r *PostgresqlUserRepository) UseTOTPStep(ctx context.Context, username string, step int64) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_last_step = $2 WHERE username = $1 AND totp_last_step < $2", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdatePassword заменяет хеш пароля пользователя
+func (r *PostgresqlUserRepository) UpdatePassword(ctx context.Context, username, password string) error {
+	query := fmt.Sprintf("UPDATE %s SET password = $2 WHERE username = $1", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username, password)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteUser удаляет пользователя из базы данных
+func (r *PostgresqlUserRepository) DeleteUser(ctx context.Context, username string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE username = $1", r.table)
+
+	tag, err := r.db.Exec(ctx, query, username)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
 // CreateUser создает пользователя в базе данных
 func (r *PostgresqlUserRepository) CreateUser(ctx context.Context, user models.UserDB) error {
 	var id int64
 
-	query := fmt.Sprintf("INSERT INTO %s (username, first_name, last_name, password) VALUES ($1, $2, $3, $4) RETURNING user_id", r.table)
+	query := fmt.Sprintf("INSERT INTO %s (username, first_name, last_name, password, email) VALUES ($1, $2, $3, $4, $5) RETURNING user_id", r.table)
 
-	err := r.db.QueryRow(ctx, query, user.Username, user.FirstName, user.LastName, user.Password).Scan(&id)
+	err := r.db.QueryRow(ctx, query, user.Username, user.FirstName, user.LastName, user.Password, user.Email).Scan(&id)
 
 	if err != nil {
 		return err
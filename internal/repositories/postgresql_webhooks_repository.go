@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresqlWebhookRepositoryConfig конфигурация для PostgresqlWebhookRepository
+type PostgresqlWebhookRepositoryConfig struct {
+	Table			string		// Таблица вебхуков
+	DeliveryTable	string		// Таблица доставок событий
+	DB				*pgxpool.Pool
+}
+
+// PostgresqlWebhookRepository - слой для хранения вебхуков и журнала доставок в Postgresql
+type PostgresqlWebhookRepository struct {
+	table			string
+	deliveryTable	string
+	db				*pgxpool.Pool
+}
+
+// NewPostgresqlWebhookRepository конструктор для PostgresqlWebhookRepository
+func NewPostgresqlWebhookRepository(c *PostgresqlWebhookRepositoryConfig) *PostgresqlWebhookRepository {
+	return &PostgresqlWebhookRepository{
+		table:			c.Table,
+		deliveryTable:	c.DeliveryTable,
+		db:				c.DB,
+	}
+}
+
+// Поля вебхука и доставки в порядке сканирования
+const (
+	webhookFields	= "id, username, url, secret, events, created_at"
+	deliveryFields	= "d.id, d.webhook_id, d.username, d.event_id, d.kind, d.payload, d.status, d.attempts, d.next_attempt, d.response_code, d.last_error, d.created_at, d.updated_at, w.url, w.secret"
+)
+
+// scanWebhooks сканирует вебхуки из результата запроса
+func scanWebhooks(rows pgx.Rows) ([]models.WebhookDB, error) {
+	defer rows.Close()
+
+	result := make([]models.WebhookDB, 0)
+	for rows.Next() {
+		var w models.WebhookDB
+
+		err := rows.Scan(&w.ID, &w.Username, &w.URL, &w.Secret, &w.Events, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, w)
+	}
+
+	return result, rows.Err()
+}
+
+// scanDeliveries сканирует доставки из результата запроса
+func scanDeliveries(rows pgx.Rows) ([]models.DeliveryDB, error) {
+	defer rows.Close()
+
+	result := make([]models.DeliveryDB, 0)
+	for rows.Next() {
+		var d models.DeliveryDB
+
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Username, &d.EventID, &d.Kind, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttempt, &d.ResponseCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+// CreateWebhook сохраняет вебхук и возвращает его с номером и временем создания
+func (r *PostgresqlWebhookRepository) CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error) {
+	query := fmt.Sprintf("INSERT INTO %s (username, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id, created_at", r.table)
+
+	err := r.db.QueryRow(ctx, query, w.Username, w.URL, w.Secret, w.Events).Scan(&w.ID, &w.CreatedAt)
+
+	return w, err
+}
+
+// FindWebhooks возвращает вебхуки пользователя
+func (r *PostgresqlWebhookRepository) FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE username = $1 ORDER BY id", webhookFields, r.table)
+
+	rows, err := r.db.Query(ctx, query, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhooks(rows)
+}
+
+// FindSubscribed возвращает вебхуки пользователя, подписанные на вид события
+func (r *PostgresqlWebhookRepository) FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE username = $1 AND (cardinality(events) = 0 OR $2 = ANY(events))
+		ORDER BY id`, webhookFields, r.table)
+
+	rows, err := r.db.Query(ctx, query, username, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhooks(rows)
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с журналом доставок. Если вебхука нет, возвращает pgx.ErrNoRows
+func (r *PostgresqlWebhookRepository) DeleteWebhook(ctx context.Context, id int64, username string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND username = $2", r.table)
+
+	tag, err := r.db.Exec(ctx, query, id, username)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteWebhooks удаляет все вебхуки пользователя
+func (r *PostgresqlWebhookRepository) DeleteWebhooks(ctx context.Context, username string) error {
+	_, err := r.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE username = $1", r.table), username)
+
+	return err
+}
+
+// CreateDelivery ставит событие в очередь доставки
+func (r *PostgresqlWebhookRepository) CreateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	query := fmt.Sprintf(`INSERT INTO %s (webhook_id, username, event_id, kind, payload)
+		VALUES ($1, $2, $3, $4, $5)`, r.deliveryTable)
+
+	_, err := r.db.Exec(ctx, query, d.WebhookID, d.Username, d.EventID, d.Kind, d.Payload)
+
+	return err
+}
+
+// DueDeliveries возвращает доставки, время отправки которых наступило
+func (r *PostgresqlWebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s d JOIN %s w ON w.id = d.webhook_id
+		WHERE d.status = $1 AND d.next_attempt <= $2 ORDER BY d.next_attempt LIMIT $3`, deliveryFields, r.deliveryTable, r.table)
+
+	rows, err := r.db.Query(ctx, query, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// UpdateDelivery сохраняет результат попытки доставки
+func (r *PostgresqlWebhookRepository) UpdateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $2, attempts = $3, next_attempt = $4, response_code = $5, last_error = $6,
+		updated_at = now() WHERE id = $1`, r.deliveryTable)
+
+	_, err := r.db.Exec(ctx, query, d.ID, d.Status, d.Attempts, d.NextAttempt, d.ResponseCode, d.LastError)
+
+	return err
+}
+
+// FindDeliveries возвращает доставки пользователя, начиная с последних
+func (r *PostgresqlWebhookRepository) FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s d JOIN %s w ON w.id = d.webhook_id
+		WHERE d.username = $1 ORDER BY d.created_at DESC, d.id DESC LIMIT $2`, deliveryFields, r.deliveryTable, r.table)
+
+	rows, err := r.db.Query(ctx, query, username, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// Redeliver ставит доставку пользователя в очередь заново с новым запасом попыток.
+// Если доставки нет, возвращает pgx.ErrNoRows
+func (r *PostgresqlWebhookRepository) Redeliver(ctx context.Context, id int64, username string) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $3, attempts = 0, next_attempt = now(), updated_at = now()
+		WHERE id = $1 AND username = $2`, r.deliveryTable)
+
+	tag, err := r.db.Exec(ctx, query, id, username, models.DeliveryPending)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
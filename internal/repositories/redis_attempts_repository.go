@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Префиксы ключей счетчиков неудачных попыток входа
+const (
+	attemptFailPrefix	= "attempt:fail:"	// Кол-во неудачных попыток за окно
+	attemptLockPrefix	= "attempt:lock:"	// Временная блокировка
+)
+
+// addFailScript Увеличивает счетчик и задает окно, если у счетчика нет срока: первая неудача
+// или счетчик, оставшийся без срока. KEYS[1] - счетчик, ARGV: окно (мс)
+var addFailScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return n
+`)
+
+// RedisAttemptRepositoryConfig Конфигурация для RedisAttemptRepository
+type RedisAttemptRepositoryConfig struct {
+	DB	*redis.Client
+}
+
+// RedisAttemptRepository Слой для учета неудачных попыток входа и временных блокировок
+type RedisAttemptRepository struct {
+	db	*redis.Client
+}
+
+// NewRedisAttemptRepository Конструктор для RedisAttemptRepository
+func NewRedisAttemptRepository(c *RedisAttemptRepositoryConfig) *RedisAttemptRepository {
+	return &RedisAttemptRepository{
+		db:	c.DB,
+	}
+}
+
+// LockedFor Возвращает, сколько еще действует блокировка по ключу (0 - блокировки нет)
+func (r *RedisAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.db.PTTL(ctx, attemptLockPrefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Отрицательный TTL - ключа нет (или он без срока, чего мы не допускаем)
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// AddFail Увеличивает счетчик неудачных попыток. Окно отсчитывается от первой неудачи и задается
+// тем же скриптом, поэтому счетчик не остается без срока
+func (r *RedisAttemptRepository) AddFail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	n, err := addFailScript.Run(ctx, r.db, []string{attemptFailPrefix + key}, window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Lock Блокирует попытки входа по ключу на время d
+func (r *RedisAttemptRepository) Lock(ctx context.Context, key string, d time.Duration) error {
+	_, err := r.db.Set(ctx, attemptLockPrefix+key, 1, d).Result()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reset Сбрасывает счетчик и блокировку по ключу
+func (r *RedisAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := r.db.Del(ctx, attemptFailPrefix+key, attemptLockPrefix+key).Result()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
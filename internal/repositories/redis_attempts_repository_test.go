@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+func TestRedisAttemptRepositoryAddFailExpires(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := NewRedisAttemptRepository(&RedisAttemptRepositoryConfig{
+		DB:	redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+	})
+	ctx := context.Background()
+
+	if n, err := r.AddFail(ctx, "user:alice", time.Minute); err != nil || n != 1 {
+		t.Fatalf("expected first fail, got %d, %v", n, err)
+	}
+	if ttl := mr.TTL(attemptFailPrefix + "user:alice"); ttl != time.Minute {
+		t.Fatalf("expected window on first fail, got %s", ttl)
+	}
+
+	// Окно не продлевается следующими неудачами
+	mr.FastForward(30 * time.Second)
+	if n, err := r.AddFail(ctx, "user:alice", time.Minute); err != nil || n != 2 {
+		t.Fatalf("expected second fail, got %d, %v", n, err)
+	}
+	if ttl := mr.TTL(attemptFailPrefix + "user:alice"); ttl != 30*time.Second {
+		t.Fatalf("expected window from first fail, got %s", ttl)
+	}
+
+	// Счетчик, оставшийся без срока, получает окно и не блокирует вход навсегда
+	mr.Set(attemptFailPrefix+"user:bob", "9")
+	if n, err := r.AddFail(ctx, "user:bob", time.Minute); err != nil || n != 10 {
+		t.Fatalf("expected tenth fail, got %d, %v", n, err)
+	}
+	if ttl := mr.TTL(attemptFailPrefix + "user:bob"); ttl != time.Minute {
+		t.Fatalf("expected window on counter without expiry, got %s", ttl)
+	}
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"short_url/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// RedisJobRepositoryConfig Конфигурация для RedisJobRepository
+type RedisJobRepositoryConfig struct {
+	DB	*redis.Client
+}
+
+// RedisJobRepository Слой для хранения задач планировщика. Задача - хеш v1:job:<id>, очередь - сортированное
+// множество v1:jobs по времени выполнения, номера задач пользователя - множество v1:user:<name>:jobs
+type RedisJobRepository struct {
+	db	*redis.Client
+}
+
+// NewRedisJobRepository Конструктор для RedisJobRepository
+func NewRedisJobRepository(c *RedisJobRepositoryConfig) *RedisJobRepository {
+	return &RedisJobRepository{
+		db:	c.DB,
+	}
+}
+
+// AddJobs Сохраняет задачи и ставит их в очередь. Время хранится с точностью до секунды
+func (r *RedisJobRepository) AddJobs(ctx context.Context, jobs []models.SchedJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// Номера выделяем одним запросом
+	last, err := r.db.IncrBy(ctx, JobSeqKey, int64(len(jobs))).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for k, job := range jobs {
+			id := last - int64(len(jobs)) + int64(k) + 1
+
+			pipe.HSet(ctx, JobKey(id),
+				"kind", job.Kind,
+				"notice", job.Notice,
+				"username", job.Username,
+				"link", job.Link,
+				"expires_at", job.ExpiresAt.Unix(),
+			)
+			pipe.SAdd(ctx, UserJobsKey(job.Username), id)
+			pipe.ZAdd(ctx, JobsKey, redis.Z{Score: float64(job.Next.Unix()), Member: id})
+		}
+		return nil
+	})
+
+	return err
+}
+
+// DueJobs Возвращает задачи, время которых наступило, начиная с самых старых
+func (r *RedisJobRepository) DueJobs(ctx context.Context, now time.Time, limit int) ([]models.SchedJob, error) {
+	queue, err := r.db.ZRangeByScoreWithScores(ctx, JobsKey, &redis.ZRangeBy{
+		Min:	"-inf",
+		Max:	strconv.FormatInt(now.Unix(), 10),
+		Count:	int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.loadJobs(ctx, queue)
+}
+
+// FindJobs Возвращает ближайшие задачи
+func (r *RedisJobRepository) FindJobs(ctx context.Context, limit int) ([]models.SchedJob, error) {
+	queue, err := r.db.ZRangeWithScores(ctx, JobsKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.loadJobs(ctx, queue)
+}
+
+// loadJobs Читает хеши задач из очереди. Задачи, удаленные между запросами, пропускаются
+func (r *RedisJobRepository) loadJobs(ctx context.Context, queue []redis.Z) ([]models.SchedJob, error) {
+	cmds := make([]*redis.MapStringStringCmd, len(queue))
+	ids := make([]int64, len(queue))
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for k, z := range queue {
+			ids[k], _ = strconv.ParseInt(z.Member.(string), 10, 64)
+			cmds[k] = pipe.HGetAll(ctx, JobKey(ids[k]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SchedJob, 0, len(queue))
+	for k, cmd := range cmds {
+		data := cmd.Val()
+		if len(data) == 0 {
+			continue
+		}
+
+		exp, _ := strconv.ParseInt(data["expires_at"], 10, 64)
+		result = append(result, models.SchedJob{
+			ID:			ids[k],
+			Kind:		data["kind"],
+			Notice:		data["notice"],
+			Username:	data["username"],
+			Link:		data["link"],
+			ExpiresAt:	time.Unix(exp, 0),
+			Next:		time.Unix(int64(queue[k].Score), 0),
+		})
+	}
+
+	return result, nil
+}
+
+// RemoveJob Удаляет выполненную задачу
+func (r *RedisJobRepository) RemoveJob(ctx context.Context, job models.SchedJob) error {
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, JobsKey, job.ID)
+		pipe.Del(ctx, JobKey(job.ID))
+		pipe.SRem(ctx, UserJobsKey(job.Username), job.ID)
+		return nil
+	})
+
+	return err
+}
+
+// RemoveUserJobs Отменяет все задачи пользователя
+func (r *RedisJobRepository) RemoveUserJobs(ctx context.Context, username string) error {
+	members, err := r.db.SMembers(ctx, UserJobsKey(username)).Result()
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err = r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		ids := make([]any, len(members))
+		keys := make([]string, len(members))
+		for k, member := range members {
+			id, _ := strconv.ParseInt(member, 10, 64)
+			ids[k] = member
+			keys[k] = JobKey(id)
+		}
+
+		pipe.ZRem(ctx, JobsKey, ids...)
+		pipe.Del(ctx, keys...)
+		// Номера удаляем поштучно: задачи, добавленные после чтения множества, остаются
+		pipe.SRem(ctx, UserJobsKey(username), ids...)
+		return nil
+	})
+
+	return err
+}
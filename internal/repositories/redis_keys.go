@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Версия схемы ключей Redis. Ключи разных сущностей разнесены по пространствам имен,
+// поэтому имя пользователя не совпадет с короткой ссылкой или ключом подписки
+const KeyVersion = "v1"
+
+// Пространства имен ключей схемы v1
+const (
+	linkNamespace	= KeyVersion + ":link:"		// v1:link:<alias> - хеш ссылки
+	userNamespace	= KeyVersion + ":user:"		// v1:user:<name>:links, :keep, :revoked, :jobs
+	subNamespace	= KeyVersion + ":sub:"		// v1:sub:<name>, v1:sub:<name>:start
+	oidcNamespace	= KeyVersion + ":oidc:"		// v1:oidc:<state> - начатый вход через провайдера
+	jobNamespace	= KeyVersion + ":job:"		// v1:job:<id> - хеш задачи планировщика
+)
+
+// Ключи очереди задач планировщика
+const (
+	JobsKey		= KeyVersion + ":jobs"			// Сортированное множество номеров задач по времени выполнения
+	JobSeqKey	= KeyVersion + ":jobs:seq"		// Счетчик номеров задач
+)
+
+// Префиксы ключей прежней схемы (до v1), которые читаются на время перехода
+const (
+	legacyLinkPrefix		= "meta-"		// Хеш ссылки; таймер - ключ с именем самой ссылки
+	legacyUserLinksPrefix	= "links-"		// Сортированное множество ссылок пользователя
+	legacyKeepPrefix		= "keep-"		// Сохраняемые ссылки пользователя
+	legacySubStartPrefix	= "sub-start-"	// Дата начала подписки; сама подписка - ключ с именем пользователя
+)
+
+// LinkKey Ключ хеша ссылки
+func LinkKey(alias string) string {
+	return linkNamespace + alias
+}
+
+// UserLinksKey Ключ индекса ссылок пользователя
+func UserLinksKey(username string) string {
+	return userNamespace + username + ":links"
+}
+
+// UserKeepKey Ключ списка ссылок, сохраняемых после окончания подписки
+func UserKeepKey(username string) string {
+	return userNamespace + username + ":keep"
+}
+
+// UserRevokedKey Ключ момента отзыва токенов доступа пользователя
+func UserRevokedKey(username string) string {
+	return userNamespace + username + ":revoked"
+}
+
+// UserJobsKey Ключ множества номеров задач пользователя
+func UserJobsKey(username string) string {
+	return userNamespace + username + ":jobs"
+}
+
+// JobKey Ключ хеша задачи планировщика
+func JobKey(id int64) string {
+	return jobNamespace + strconv.FormatInt(id, 10)
+}
+
+// SubKey Ключ подписки пользователя (значение - план, срок - TTL)
+func SubKey(username string) string {
+	return subNamespace + username
+}
+
+// SubStartKey Ключ даты начала подписки
+func SubStartKey(username string) string {
+	return subNamespace + username + ":start"
+}
+
+// OIDCStateKey Ключ параметров начатого входа через провайдера
+func OIDCStateKey(state string) string {
+	return oidcNamespace + state
+}
+
+// usernameFromLinksKey Извлекает имя пользователя из ключа индекса ссылок
+func usernameFromLinksKey(key string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(key, userNamespace), ":links")
+}
+
+// isWrongType Ключ прежней схемы оказался другого типа (например, множество ссылок вместо подписки)
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// legacySub Читает подписку прежней схемы. Ключ с именем пользователя считается подпиской,
+// только если это строка и под тем же именем нет короткой ссылки
+func legacySub(ctx context.Context, db *redis.Client, username string) (string, time.Duration, bool, error) {
+	var (
+		plan	*redis.StringCmd
+		ttl		*redis.DurationCmd
+		link	*redis.IntCmd
+	)
+	_, err := db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		plan = pipe.Get(ctx, username)
+		ttl = pipe.TTL(ctx, username)
+		link = pipe.Exists(ctx, legacyLinkPrefix+username)
+		return nil
+	})
+	if err != nil && err != redis.Nil && !isWrongType(err) {
+		return "", -1, false, err
+	}
+	if plan.Err() != nil || link.Val() > 0 {
+		return "", -1, false, nil
+	}
+
+	return plan.Val(), ttl.Val(), true, nil
+}
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"short_url/internal/models"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v9"
@@ -11,13 +13,16 @@ import (
 // RedisLinkRepositoryConfig Конфигурация для RedisLinkRepository
 type RedisLinkRepositoryConfig struct {
 	DB			*redis.Client
-	Pipe		redis.Pipeliner
+	Legacy		bool	// Читать ссылки прежней схемы ключей (на время перехода)
 }
 
-// RedisLinkRepository Слой для управления запросами к хранилищу ссылок
+// RedisLinkRepository Слой для управления запросами к хранилищу ссылок.
+// Ссылка хранится в одном хеше, который истекает вместе со ссылкой. Индекс ссылок пользователя -
+// сортированное множество с временем истечения в качестве веса: чтение пропускает истекшие ссылки,
+// а из индекса их убирает TrimExpired. Ключи - по схеме v1 (см. redis_keys.go)
 type RedisLinkRepository struct {
 	db			*redis.Client
-	pipe		redis.Pipeliner
+	legacy		bool
 }
 
 // Структура для записи в таблицу ссылок
@@ -27,43 +32,145 @@ const (
 	p	=	"perm"
 	c	=	"custom"
 	u	=	"url"
+	o	=	"owner"
+	d	=	"disabled"
+	dr	=	"disabled_reason"
 )
 
+// Сколько ключей индексов просматривается за один шаг SCAN
+const sweepBatch = 100
+
+// createLinkScript Создает ссылку, если такой еще нет (в том числе действующей ссылки прежней схемы) и не превышен лимит.
+// Действующие ссылки считаются по весам индекса: бессрочные имеют вес +inf. Возвращает 0, если ссылка занята, -1 - если превышен лимит.
+// KEYS[1] - хеш ссылки, KEYS[2] - индекс ссылок пользователя, KEYS[3] - хеш прежней схемы, KEYS[4] - таймер прежней схемы;
+// ARGV: ссылка, бессрочная, кастомная, полный адрес, владелец, срок (мс, 0 - бессрочная), текущее время (мс), читать прежнюю схему,
+// лимит всех ссылок, лимит бессрочных ссылок (-1 - без лимита)
+var createLinkScript = redis.NewScript(`
+local all = tonumber(ARGV[9])
+if all >= 0 and redis.call('ZCOUNT', KEYS[2], '(' .. ARGV[7], '+inf') >= all then
+	return -1
+end
+
+local perm = tonumber(ARGV[10])
+if ARGV[2] == '1' and perm >= 0 and redis.call('ZCOUNT', KEYS[2], '+inf', '+inf') >= perm then
+	return -1
+end
+
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+
+if ARGV[8] == '1' and redis.call('EXISTS', KEYS[3]) == 1 then
+	local perm = redis.call('HGET', KEYS[3], 'perm')
+	if perm == '1' or redis.call('PTTL', KEYS[3]) > 0 or redis.call('TYPE', KEYS[4]).ok == 'string' then
+		return 0
+	end
+end
+
+redis.call('HSET', KEYS[1], 'perm', ARGV[2], 'custom', ARGV[3], 'url', ARGV[4], 'owner', ARGV[5])
+
+local exp = tonumber(ARGV[6])
+local score = '+inf'
+if exp > 0 then
+	redis.call('PEXPIRE', KEYS[1], exp)
+	score = tonumber(ARGV[7]) + exp
+end
+redis.call('ZADD', KEYS[2], score, ARGV[1])
+
+return 1
+`)
+
+// disableLinkScript Помечает ссылку заблокированной, не воскрешая истекшую.
+// KEYS[1] - хеш ссылки, ARGV: причина
+var disableLinkScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+
+redis.call('HSET', KEYS[1], 'disabled', '1', 'disabled_reason', ARGV[1])
+
+return 1
+`)
+
+// deleteLinkScript Удаляет ссылку и убирает ее из индекса пользователя, в том числе в прежней схеме.
+// Таймер и множество прежней схемы трогаются, только если у ключей ожидаемый тип:
+// под тем же именем может оказаться подписка или ссылки другого пользователя.
+// KEYS[1] - хеш ссылки, KEYS[2] - индекс ссылок пользователя, KEYS[3] - хеш прежней схемы, KEYS[4] - таймер прежней схемы,
+// KEYS[5] - индекс прежней схемы, KEYS[6] - множество ссылок прежней схемы; ARGV: ссылка, читать прежнюю схему
+var deleteLinkScript = redis.NewScript(`
+local deleted = redis.call('DEL', KEYS[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+
+if ARGV[2] == '1' then
+	if redis.call('DEL', KEYS[3]) == 1 then
+		deleted = 1
+		if redis.call('TYPE', KEYS[4]).ok == 'string' then
+			redis.call('DEL', KEYS[4])
+		end
+	end
+	redis.call('ZREM', KEYS[5], ARGV[1])
+	if redis.call('TYPE', KEYS[6]).ok == 'set' then
+		redis.call('SREM', KEYS[6], ARGV[1])
+	end
+end
+
+return deleted
+`)
+
 // NewRedisLinkRepository Конструктор для RedisLinkRepository
 func NewRedisLinkRepository(c *RedisLinkRepositoryConfig) *RedisLinkRepository {
 	return &RedisLinkRepository{
 		db:			c.DB,
-		pipe: 		c.Pipe,
+		legacy:		c.Legacy,
+	}
+}
+
+// flag Переводит признак в значение поля хеша
+func flag(v bool) string {
+	if v {
+		return "1"
 	}
+
+	return "0"
 }
 
-// CreateLinkTimed Создает ссылку в таблицах
-func (r *RedisLinkRepository) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool) (models.LinkDataDB, error) {
-	
+// CreateLink Создает ссылку в таблицах. Занятая ссылка не перезаписывается.
+// Лимиты всех и бессрочных ссылок (quota, nil - без лимитов) проверяются в том же скрипте, что и запись,
+// поэтому параллельные запросы не превышают их. Лимит кастомных ссылок не проверяется: для подсчета нужны
+// хеши ссылок, и он остается на предварительной проверке сервиса. Ссылки прежней схемы в индексе не учитываются
+func (r *RedisLinkRepository) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error) {
+
 	// Определяем, с таймером ли ссылка
 	var perm bool
 	if exp == 0 {
 		perm = true
 	}
 
-	// Вставляем ссылку в таблицу пользователя
-	_, err := r.pipe.SAdd(ctx, username, link).Result()
-	if err != nil {
-		return models.LinkDataDB{}, err
+	// Срок короче миллисекунды округляем вверх, чтобы ссылка не стала бессрочной
+	ms := exp.Milliseconds()
+	if exp > 0 && ms == 0 {
+		ms = 1
 	}
 
-	// Вставляем метаданные в таблицу ссылок
-	metaLink := "meta-" + link
-	_, err = r.pipe.HSet(ctx, metaLink, RedisNote{p:perm, c:custom, u:fullUrl}).Result()
-	if err != nil {
-		return models.LinkDataDB{}, err
+	// Без тарифа лимитов нет
+	all, permAll := -1, -1
+	if quota != nil {
+		all, permAll = quota.All, quota.Perm
 	}
 
-	// Вставляем ссылку в таблицу таймера
-	_, err = r.pipe.Set(ctx, link, 1, exp).Result()
+	// Проверяем лимиты и записываем хеш ссылки и индекс пользователя одним скриптом
+	keys := []string{LinkKey(link), UserLinksKey(username), legacyLinkPrefix + link, link}
+	created, err := createLinkScript.Run(ctx, r.db, keys,
+		link, flag(perm), flag(custom), fullUrl, username, ms, time.Now().UnixMilli(), flag(r.legacy), all, permAll).Int()
 	if err != nil {
 		return models.LinkDataDB{}, err
 	}
+	switch created {
+	case 0:
+		return models.LinkDataDB{}, errors.New("link already exists")
+	case -1:
+		return models.LinkDataDB{}, errors.New("limit exceeded")
+	}
 
 	// Маппим данные в результат
 	return models.LinkDataDB{
@@ -72,79 +179,144 @@ func (r *RedisLinkRepository) CreateLink(ctx context.Context, link, username, fu
 		ExpTime:	exp,
 		Perm:		perm,
 		Custom: 	custom,
+		Owner:		username,
 	}, nil
 }
 
-// DeleteExpLink Метод для удаления просроченных ссылок
-func (r *RedisLinkRepository) DeleteExpLink(ctx context.Context, link, username string) error {
-
-	metaLink := "meta-" + link
-
-	// Удаляем метаданные из таблицы ссылок
-	_, err := r.pipe.Del(ctx, metaLink).Result()
+// DeleteLink Удаляет ссылку и убирает ее из индекса пользователя. Если ссылки нет, возвращает redis.Nil
+func (r *RedisLinkRepository) DeleteLink(ctx context.Context, link, username string) error {
+	keys := []string{LinkKey(link), UserLinksKey(username),
+		legacyLinkPrefix + link, link, legacyUserLinksPrefix + username, username}
+	deleted, err := deleteLinkScript.Run(ctx, r.db, keys, link, flag(r.legacy)).Int()
 	if err != nil {
 		return err
 	}
-
-	// Удаляем ссылку из таблицы пользователя
-	_, err = r.pipe.SRem(ctx, username, link).Result()
-	if err != nil {
-		return err
+	if deleted == 0 {
+		return redis.Nil
 	}
 
 	return nil
 }
 
-// DeleteLink Удаляет записи из хранилища
-func (r *RedisLinkRepository) DeleteLink(ctx context.Context, link, username string) error {
+// linkData Заполняет данные ссылки из полей хеша (perm, custom, url, owner, disabled, disabled_reason)
+func linkData(link string, cell []any, ttl time.Duration) models.LinkDataDB {
+	result := models.LinkDataDB{Link: link, ExpTime: ttl}
+
+	result.Perm = cell[0] == "1"
+	result.Custom = cell[1] == "1"
+	result.FullURL, _ = cell[2].(string)
+	result.Owner, _ = cell[3].(string)
+	result.Disabled = cell[4] == "1"
+	result.DisabledReason, _ = cell[5].(string)
 
-	metaLink := "meta-" + link
+	return result
+}
+
+// FindLink Находит ссылку и метаданные о ней. Если ссылки нет, возвращает redis.Nil
+func (r *RedisLinkRepository) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
 
-	// Удаляем метаданные из таблицы ссылок
-	_, err := r.pipe.Del(ctx, metaLink).Result()
+	// Читаем поля и срок в одной транзакции
+	var (
+		meta	*redis.SliceCmd
+		ttl		*redis.DurationCmd
+	)
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		meta = pipe.HMGet(ctx, LinkKey(link), p, c, u, o, d, dr)
+		ttl = pipe.TTL(ctx, LinkKey(link))
+		return nil
+	})
 	if err != nil {
-		return err
+		return models.LinkDataDB{Link: link}, err
 	}
 
-	// Удаляем ссылку из таблицы пользователя
-	_, err = r.pipe.SRem(ctx, username, link).Result()
+	// Без адреса ссылки нет (возможно, она еще в прежней схеме)
+	cell := meta.Val()
+	if cell[2] == nil {
+		if r.legacy {
+			return r.findLegacyLink(ctx, link)
+		}
+		return models.LinkDataDB{Link: link}, redis.Nil
+	}
+
+	return linkData(link, cell, ttl.Val()), nil
+}
+
+// findLegacyLink Находит ссылку прежней схемы: срок берется у хеша, а если его нет - у ключа таймера.
+// Срочная ссылка без таймера уже истекла
+func (r *RedisLinkRepository) findLegacyLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	var (
+		meta		*redis.SliceCmd
+		metaTTL		*redis.DurationCmd
+		timer		*redis.StatusCmd
+		timerTTL	*redis.DurationCmd
+	)
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		meta = pipe.HMGet(ctx, legacyLinkPrefix+link, p, c, u, o, d, dr)
+		metaTTL = pipe.TTL(ctx, legacyLinkPrefix+link)
+		timer = pipe.Type(ctx, link)
+		timerTTL = pipe.TTL(ctx, link)
+		return nil
+	})
 	if err != nil {
-		return err
+		return models.LinkDataDB{Link: link}, err
 	}
 
-	// Удаляем ссылку из таблицы таймера
-	_, err = r.pipe.Del(ctx, link).Result()
+	cell := meta.Val()
+	if cell[2] == nil {
+		return models.LinkDataDB{Link: link}, redis.Nil
+	}
+
+	result := linkData(link, cell, metaTTL.Val())
+	if metaTTL.Val() < 0 && !result.Perm {
+		if timer.Val() != "string" || timerTTL.Val() < 0 {
+			return models.LinkDataDB{Link: link}, redis.Nil
+		}
+		result.ExpTime = timerTTL.Val()
+	}
+
+	return result, nil
+}
+
+// DisableLink Блокирует ссылку с указанием причины (данные ссылки сохраняются)
+func (r *RedisLinkRepository) DisableLink(ctx context.Context, link, reason string) error {
+	n, err := disableLinkScript.Run(ctx, r.db, []string{LinkKey(link)}, reason).Int()
+	if err == nil && n == 0 && r.legacy {
+		n, err = disableLinkScript.Run(ctx, r.db, []string{legacyLinkPrefix + link}, reason).Int()
+	}
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return redis.Nil
+	}
 
 	return nil
 }
 
-// FindLink Находит ссылку и метаданные о ней
-func (r *RedisLinkRepository) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
-
-	metaLink := "meta-" + link
-
-	// Инициализируем переменные
-	result := models.LinkDataDB{}
-	var err error
+// EnableLink Снимает блокировку со ссылки
+func (r *RedisLinkRepository) EnableLink(ctx context.Context, link string) error {
 
-	// Записываем значения и обрабатываем ошибки
-	result.Link = link
-	data, err := r.pipe.HMGet(ctx, metaLink, p, c, u).Result()
-	if err != nil {
-		return result, err
+	// Удаляем отметку о блокировке
+	n, err := r.db.HDel(ctx, LinkKey(link), d, dr).Result()
+	if err == nil && n == 0 && r.legacy {
+		n, err = r.db.HDel(ctx, legacyLinkPrefix+link, d, dr).Result()
 	}
-	result.Perm = data[0].(bool)
-	result.Custom = data[1].(bool)
-	result.FullURL = data[2].(string)
-	result.ExpTime, err = r.pipe.TTL(ctx, link).Result()
 	if err != nil {
-		return result, err
+		return err
+	}
+	if n == 0 {
+		return redis.Nil
 	}
 
-	return result, nil
+	return nil
+}
+
+// activeLinks Возвращает ссылки пользователя, срок которых еще не истек
+func (r *RedisLinkRepository) activeLinks(ctx context.Context, username string) ([]string, error) {
+	return r.db.ZRangeByScore(ctx, UserLinksKey(username), &redis.ZRangeBy{
+		Min:	"(" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max:	"+inf",
+	}).Result()
 }
 
 // CountLinks Считает кол-во ссылок на аккаунте пользователя
@@ -153,72 +325,225 @@ func (r *RedisLinkRepository) CountLinks(ctx context.Context, username string) (
 	// Инициализируем структуру ответа
 	result := models.LinksAmount{}
 
-	// Получаем все ссылки из таблицы пользователя
-	data, err := r.db.SMembers(ctx, username).Result()
+	// Получаем действующие ссылки пользователя
+	links, err := r.GetAllLinks(ctx, username)
 	if err != nil {
 		return result, err
 	}
 
-	// Инициализируем счетчики особых ссылок
-	var perm, custom int
+	// Считаем особые ссылки
+	for _, link := range links {
+		if link.Perm {
+			result.Perm += 1
+		}
+		if link.Custom {
+			result.Custom += 1
+		}
+	}
+	result.All = len(links)
 
-	// Итерируемся по таблице метаданных, записывая кол-во особых ссылок пользователя
-	for _, k := range data {
+	return result, nil
+}
 
-		metaLink := "meta-" + k
+// GetAllLinks Получает все ссылки пользователя из таблицы ссылок
+func (r *RedisLinkRepository) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
 
-		cell, err := r.db.HMGet(ctx, metaLink, p, c).Result()
-		if err != nil {
-			return result, err
-		}
+	// Получаем действующие ссылки из индекса пользователя
+	data, err := r.activeLinks(ctx, username)
+	if err != nil {
+		return []models.LinkDataDB{}, err
+	}
 
-		if cell[0] == true {
-			perm += 1
+	// Читаем поля и сроки всех ссылок за один запрос
+	metas := make([]*redis.SliceCmd, len(data))
+	ttls := make([]*redis.DurationCmd, len(data))
+	_, err = r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for k, link := range data {
+			metas[k] = pipe.HMGet(ctx, LinkKey(link), p, c, u, o, d, dr)
+			ttls[k] = pipe.TTL(ctx, LinkKey(link))
 		}
-		if cell[1] == true {
-			custom += 1
+		return nil
+	})
+	if err != nil {
+		return []models.LinkDataDB{}, err
+	}
+
+	// Ссылки без хеша (истекшие или удаленные между запросами) пропускаем
+	result := make([]models.LinkDataDB, 0, len(data))
+	for k, link := range data {
+		cell := metas[k].Val()
+		if cell[2] == nil {
+			continue
 		}
+
+		item := linkData(link, cell, ttls[k].Val())
+		item.Owner = username
+		result = append(result, item)
 	}
 
-	// Маппим значения в ответ
-	result.All = len(data)
-	result.Custom = custom
-	result.Perm = perm
+	if !r.legacy {
+		return result, nil
+	}
 
-	return result, nil
+	// Добавляем еще не перенесенные ссылки прежней схемы
+	legacy, err := r.legacyLinks(ctx, username, result)
+	if err != nil {
+		return result, err
+	}
+
+	return append(result, legacy...), nil
 }
 
-// GetAllLinks Получает все ссылки пользователя из таблицы ссылок
-func (r *RedisLinkRepository) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+// legacyLinks Находит ссылки пользователя в индексах прежней схемы, которых нет среди найденных
+func (r *RedisLinkRepository) legacyLinks(ctx context.Context, username string, found []models.LinkDataDB) ([]models.LinkDataDB, error) {
+	seen := make(map[string]bool, len(found))
+	for _, link := range found {
+		seen[link.Link] = true
+	}
 
-	// Получаем все ссылки из таблицы пользователя
-	data, err := r.db.SMembers(ctx, username).Result()
+	// Ссылки пользователя до v1 хранились в сортированном множестве или во множестве с именем пользователя
+	members, err := r.db.ZRange(ctx, legacyUserLinksPrefix+username, 0, -1).Result()
 	if err != nil {
-		return []models.LinkDataDB{}, err
+		return nil, err
+	}
+	set, err := r.db.SMembers(ctx, username).Result()
+	if err != nil && !isWrongType(err) {
+		return nil, err
 	}
+	members = append(members, set...)
 
-	// Инициализируем структуру ответа
-	result := []models.LinkDataDB{}
+	result := make([]models.LinkDataDB, 0)
+	for _, link := range members {
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		data, err := r.findLegacyLink(ctx, link)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data.Owner != "" && data.Owner != username {
+			continue
+		}
+
+		data.Owner = username
+		result = append(result, data)
+	}
+
+	return result, nil
+}
 
-	// Итерируемся по таблицам метаданных и ссылок, записывая данные в ответ
-	for q, k := range data {
+// TrimExpired Убирает истекшие ссылки из индексов всех пользователей.
+// Возвращает убранные ссылки по владельцам
+func (r *RedisLinkRepository) TrimExpired(ctx context.Context, now time.Time) (map[string][]string, error) {
+	result := make(map[string][]string)
 
-		metaLink := "meta-" + k
+	iter := r.db.Scan(ctx, 0, UserLinksKey("*"), sweepBatch).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		username := usernameFromLinksKey(key)
 
-		cell, err := r.db.HMGet(ctx, metaLink, p, c, u).Result()
+		removed, err := r.trimExpired(ctx, key, username, now)
 		if err != nil {
 			return result, err
 		}
+		if len(removed) > 0 {
+			result[username] = append(result[username], removed...)
+		}
+	}
+
+	return result, iter.Err()
+}
+
+// trimExpired Убирает из индекса пользователя ссылки, срок которых истек.
+// Ссылка остается в индексе, пока жив ее хеш с тем же владельцем (время экземпляров может расходиться).
+// Если индекс изменился во время проверки, ссылки уберет следующий проход
+func (r *RedisLinkRepository) trimExpired(ctx context.Context, key, username string, now time.Time) ([]string, error) {
+	var removed []string
+
+	err := r.db.Watch(ctx, func(tx *redis.Tx) error {
+		links, err := tx.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min:	"-inf",
+			Max:	strconv.FormatInt(now.UnixMilli(), 10),
+		}).Result()
+		if err != nil || len(links) == 0 {
+			return err
+		}
+
+		// Владельцев читаем одним запросом
+		owners := make([]*redis.StringCmd, len(links))
+		_, err = tx.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for k, link := range links {
+				owners[k] = pipe.HGet(ctx, LinkKey(link), o)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		members := make([]any, 0, len(links))
+		for k, link := range links {
+			if owners[k].Val() != username {
+				members = append(members, link)
+			}
+		}
+		if len(members) == 0 {
+			return nil
+		}
 
-		result[q].Link = k
-		result[q].FullURL = cell[2].(string)
-		result[q].Perm = cell[0].(bool)
-		result[q].Custom = cell[1].(bool)
-		result[q].ExpTime, err = r.pipe.TTL(ctx, k).Result()
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRem(ctx, key, members...)
+			return nil
+		})
 		if err != nil {
-			return result, err
+			return err
+		}
+
+		for _, member := range members {
+			removed = append(removed, member.(string))
 		}
+		return nil
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, nil
 	}
 
-	return result, nil
+	return removed, err
+}
+
+// KeepLinks Получает ссылки, которые пользователь выбрал для сохранения после окончания подписки
+func (r *RedisLinkRepository) KeepLinks(ctx context.Context, username string) ([]string, error) {
+	links, err := r.db.SMembers(ctx, UserKeepKey(username)).Result()
+	if err != nil || len(links) > 0 || !r.legacy {
+		return links, err
+	}
+
+	return r.db.SMembers(ctx, legacyKeepPrefix+username).Result()
+}
+
+// SetKeepLinks Заменяет список ссылок, сохраняемых после окончания подписки
+func (r *RedisLinkRepository) SetKeepLinks(ctx context.Context, username string, links []string) error {
+	key := UserKeepKey(username)
+
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if r.legacy {
+			pipe.Del(ctx, legacyKeepPrefix+username)
+		}
+		if len(links) > 0 {
+			members := make([]any, len(links))
+			for k, link := range links {
+				members[k] = link
+			}
+			pipe.SAdd(ctx, key, members...)
+		}
+		return nil
+	})
+
+	return err
 }
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+func newTestLinkRepository(t *testing.T) (*RedisLinkRepository, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+
+	return NewRedisLinkRepository(&RedisLinkRepositoryConfig{
+		DB:	redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+	}), mr
+}
+
+func TestRedisLinkRepositoryCreateFind(t *testing.T) {
+	r, mr := newTestLinkRepository(t)
+	ctx := context.Background()
+
+	if _, err := r.CreateLink(ctx, "abc", "alice", "https://example.com", time.Hour, true, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := r.CreateLink(ctx, "perm", "alice", "https://example.org", 0, false, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	link, err := r.FindLink(ctx, "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if link.FullURL != "https://example.com" || link.Owner != "alice" || !link.Custom || link.Perm || link.ExpTime != time.Hour {
+		t.Fatalf("unexpected link: %+v", link)
+	}
+	if mr.TTL("v1:link:abc") != time.Hour || mr.TTL("v1:link:perm") != 0 {
+		t.Fatalf("link must expire with its data, got ttl %s and %s", mr.TTL("v1:link:abc"), mr.TTL("v1:link:perm"))
+	}
+
+	// Занятая ссылка не перезаписывается, даже другим пользователем
+	if _, err = r.CreateLink(ctx, "abc", "bob", "https://evil.example", time.Hour, true, nil); err == nil || err.Error() != "link already exists" {
+		t.Fatalf("expected link already exists, got %v", err)
+	}
+	if got := mr.HGet("v1:link:abc", "owner"); got != "alice" {
+		t.Fatalf("link owner overwritten: %q", got)
+	}
+	if mr.Exists("v1:user:bob:links") {
+		t.Fatal("rejected link must not be added to user set")
+	}
+
+	if _, err = r.FindLink(ctx, "missing"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil for missing link, got %v", err)
+	}
+
+	amount, err := r.CountLinks(ctx, "alice")
+	if err != nil || amount.All != 2 || amount.Perm != 1 || amount.Custom != 1 {
+		t.Fatalf("unexpected amount %+v (%v)", amount, err)
+	}
+
+	links, err := r.GetAllLinks(ctx, "alice")
+	if err != nil || len(links) != 2 {
+		t.Fatalf("expected 2 links, got %+v (%v)", links, err)
+	}
+}
+
+func TestRedisLinkRepositoryDelete(t *testing.T) {
+	r, mr := newTestLinkRepository(t)
+	ctx := context.Background()
+
+	r.CreateLink(ctx, "abc", "alice", "https://example.com", time.Hour, false, nil)
+	r.CreateLink(ctx, "def", "alice", "https://example.com", time.Hour, false, nil)
+
+	if err := r.DeleteLink(ctx, "abc", "alice"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if mr.Exists("v1:link:abc") {
+		t.Fatal("link must be deleted")
+	}
+	if members, _ := mr.ZMembers("v1:user:alice:links"); len(members) != 1 || members[0] != "def" {
+		t.Fatal("link must be removed from user index")
+	}
+	if err := r.DeleteLink(ctx, "abc", "alice"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil for deleted link, got %v", err)
+	}
+}
+
+func TestRedisLinkRepositoryExpiry(t *testing.T) {
+	r, mr := newTestLinkRepository(t)
+	ctx := context.Background()
+
+	r.CreateLink(ctx, "abc", "alice", "https://example.com", time.Hour, false, nil)
+	r.CreateLink(ctx, "perm", "alice", "https://example.com", 0, false, nil)
+	r.CreateLink(ctx, "reused", "alice", "https://example.com", time.Hour, true, nil)
+
+	// Данные ссылки истекают сами, чтение пропускает ссылки без данных
+	mr.FastForward(2 * time.Hour)
+	if _, err := r.FindLink(ctx, "abc"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected expired link to be gone, got %v", err)
+	}
+	if links, _ := r.GetAllLinks(ctx, "alice"); len(links) != 1 || links[0].Link != "perm" {
+		t.Fatalf("expected only permanent link, got %+v", links)
+	}
+	if amount, _ := r.CountLinks(ctx, "alice"); amount.All != 1 || amount.Perm != 1 {
+		t.Fatalf("expired links must not count, got %+v", amount)
+	}
+
+	// Освободившуюся ссылку занял другой пользователь
+	if _, err := r.CreateLink(ctx, "reused", "bob", "https://example.org", 0, true, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Из индексов убираются только истекшие ссылки прежнего владельца
+	expired, err := r.TrimExpired(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(expired) != 1 || len(expired["alice"]) != 2 {
+		t.Fatalf("unexpected expired links: %v", expired)
+	}
+	if members, _ := mr.ZMembers("v1:user:alice:links"); len(members) != 1 || members[0] != "perm" {
+		t.Fatalf("expected only permanent link in index, got %v", members)
+	}
+	if members, _ := mr.ZMembers("v1:user:bob:links"); len(members) != 1 || members[0] != "reused" {
+		t.Fatal("link of the new owner must stay in index")
+	}
+
+	// Повторная чистка ничего не находит
+	if expired, _ = r.TrimExpired(ctx, time.Now().Add(2*time.Hour)); len(expired) != 0 {
+		t.Fatalf("expected nothing to trim, got %v", expired)
+	}
+}
+
+func TestRedisLinkRepositoryConcurrentCreate(t *testing.T) {
+	r, mr := newTestLinkRepository(t)
+	ctx := context.Background()
+
+	// Из одновременных запросов на одну кастомную ссылку проходит ровно один
+	var (
+		wg		sync.WaitGroup
+		mux		sync.Mutex
+		created	int
+	)
+	for _, user := range []string{"alice", "bob", "carol", "dave", "eve", "frank", "grace", "heidi"} {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			if _, err := r.CreateLink(ctx, "promo", user, "https://"+user+".example", 0, true, nil); err == nil {
+				mux.Lock()
+				created++
+				mux.Unlock()
+			}
+		}(user)
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Fatalf("expected single created link, got %d", created)
+	}
+
+	owner := mr.HGet("v1:link:promo", "owner")
+	if members, _ := mr.ZMembers(UserLinksKey(owner)); len(members) != 1 {
+		t.Fatalf("link must belong to its owner %q", owner)
+	}
+}
+
+func TestRedisLinkRepositoryLegacy(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := NewRedisLinkRepository(&RedisLinkRepositoryConfig{
+		DB:		redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		Legacy:	true,
+	})
+	ctx := context.Background()
+
+	// Ссылки прежней схемы: таймер с именем ссылки и множество пользователя
+	mr.HSet("meta-abc", "perm", "0", "custom", "1", "url", "https://example.com")
+	mr.Set("abc", "1")
+	mr.SetTTL("abc", time.Hour)
+	mr.HSet("meta-gone", "perm", "0", "custom", "0", "url", "https://expired.example")
+	mr.SAdd("alice", "abc", "gone")
+	r.CreateLink(ctx, "new", "alice", "https://example.org", 0, false, nil)
+
+	link, err := r.FindLink(ctx, "abc")
+	if err != nil || link.ExpTime != time.Hour || !link.Custom {
+		t.Fatalf("expected legacy link, got %+v (%v)", link, err)
+	}
+	if _, err = r.FindLink(ctx, "gone"); !errors.Is(err, redis.Nil) {
+		t.Fatalf("legacy link without timer must be expired, got %v", err)
+	}
+	if amount, _ := r.CountLinks(ctx, "alice"); amount.All != 2 || amount.Custom != 1 || amount.Perm != 1 {
+		t.Fatalf("unexpected amount %+v", amount)
+	}
+
+	// Живую ссылку прежней схемы занять нельзя, истекшую - можно
+	if _, err = r.CreateLink(ctx, "abc", "bob", "https://evil.example", 0, true, nil); err == nil {
+		t.Fatal("legacy link must not be overwritten")
+	}
+	if _, err = r.CreateLink(ctx, "gone", "bob", "https://example.net", 0, true, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Удаление убирает ключи обеих схем
+	if err = r.DeleteLink(ctx, "abc", "alice"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if mr.Exists("meta-abc") || mr.Exists("abc") {
+		t.Fatal("legacy link keys must be deleted")
+	}
+	if members, _ := mr.Members("alice"); len(members) != 1 || members[0] != "gone" {
+		t.Fatalf("link must be removed from legacy set, got %v", members)
+	}
+}
@@ -0,0 +1,314 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// KeyMigrationReport Итоги переноса ключей прежней схемы в схему v1
+type KeyMigrationReport struct {
+	Links		int			// Перенесено ссылок
+	Expired		int			// Удалено истекших ссылок
+	Orphans		[]string	// Ссылки без владельца: перенесены, но не попали ни в один индекс
+	Keep		int			// Перенесено списков сохраняемых ссылок
+	Subs		int			// Перенесено подписок
+	Starts		int			// Перенесено дат начала подписок
+	Skipped		int			// Ключи, которые уже есть в схеме v1 (прежние удаляются)
+	Indexes		int			// Удалено индексов ссылок прежней схемы
+}
+
+// keyMigration Состояние одного прогона миграции
+type keyMigration struct {
+	db			*redis.Client
+	dryRun		bool
+	report		*KeyMigrationReport
+}
+
+// MigrateKeys Переносит данные прежней схемы ключей (meta-<ссылка>, таймер <ссылка>, множество <пользователь>,
+// links-<пользователь>, keep-<пользователь>, подписка <пользователь>, sub-start-<пользователь>) в схему v1.
+// Повторный запуск безопасен: уже перенесенные ключи не перезаписываются. При dryRun только считает
+func MigrateKeys(ctx context.Context, db *redis.Client, dryRun bool) (KeyMigrationReport, error) {
+	report := KeyMigrationReport{}
+	m := &keyMigration{db: db, dryRun: dryRun, report: &report}
+
+	// Владельцев ссылок берем из индексов прежней схемы: в хешах до v1 владельца не было
+	indexes, owners, err := m.legacyIndexes(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	// Ссылки переносим до подписок: после них ключами с именем пользователя останутся только подписки
+	if err = m.links(ctx, owners); err != nil {
+		return report, err
+	}
+	if err = m.rename(ctx, legacyKeepPrefix+"*", "set", UserKeepKey, &report.Keep); err != nil {
+		return report, err
+	}
+	if err = m.rename(ctx, legacySubStartPrefix+"*", "string", SubStartKey, &report.Starts); err != nil {
+		return report, err
+	}
+	if err = m.subs(ctx); err != nil {
+		return report, err
+	}
+
+	// Индексы прежней схемы больше не нужны
+	report.Indexes = len(indexes)
+	if !dryRun && len(indexes) > 0 {
+		if err = db.Del(ctx, indexes...).Err(); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// scanKeys Собирает ключи заданного типа по шаблону
+func (m *keyMigration) scanKeys(ctx context.Context, match, keyType string) ([]string, error) {
+	keys := make([]string, 0)
+
+	iter := m.db.ScanType(ctx, 0, match, sweepBatch, keyType).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	return keys, iter.Err()
+}
+
+// isLegacyName Ключ с именем пользователя прежней схемы: без пространства имен и служебных префиксов
+func isLegacyName(key string) bool {
+	if strings.Contains(key, ":") {
+		return false
+	}
+	for _, prefix := range []string{legacyLinkPrefix, legacyUserLinksPrefix, legacyKeepPrefix, legacySubStartPrefix} {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// legacyIndexes Находит индексы ссылок прежней схемы и владельцев ссылок в них
+func (m *keyMigration) legacyIndexes(ctx context.Context) ([]string, map[string]string, error) {
+	owners := make(map[string]string)
+
+	// Сортированные множества links-<пользователь> новее множеств <пользователь>, поэтому читаются первыми
+	zsets, err := m.scanKeys(ctx, legacyUserLinksPrefix+"*", "zset")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range zsets {
+		links, err := m.db.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, link := range links {
+			owners[link] = strings.TrimPrefix(key, legacyUserLinksPrefix)
+		}
+	}
+
+	all, err := m.scanKeys(ctx, "*", "set")
+	if err != nil {
+		return nil, nil, err
+	}
+	sets := make([]string, 0, len(all))
+	for _, key := range all {
+		if !isLegacyName(key) {
+			continue
+		}
+		sets = append(sets, key)
+
+		links, err := m.db.SMembers(ctx, key).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, link := range links {
+			if _, ok := owners[link]; !ok {
+				owners[link] = key
+			}
+		}
+	}
+
+	return append(zsets, sets...), owners, nil
+}
+
+// links Переносит хеши ссылок в v1:link:<ссылка> и добавляет ссылки в индексы владельцев.
+// Срок берется у хеша, а если его нет - у таймера; истекшие ссылки удаляются
+func (m *keyMigration) links(ctx context.Context, owners map[string]string) error {
+	keys, err := m.scanKeys(ctx, legacyLinkPrefix+"*", "hash")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		link := strings.TrimPrefix(key, legacyLinkPrefix)
+
+		var (
+			fields		*redis.MapStringStringCmd
+			metaTTL		*redis.DurationCmd
+			timer		*redis.StatusCmd
+			timerTTL	*redis.DurationCmd
+			exists		*redis.IntCmd
+		)
+		_, err = m.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			fields = pipe.HGetAll(ctx, key)
+			metaTTL = pipe.PTTL(ctx, key)
+			timer = pipe.Type(ctx, link)
+			timerTTL = pipe.PTTL(ctx, link)
+			exists = pipe.Exists(ctx, LinkKey(link))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Прежние ключи ссылки: хеш и таймер, если он есть
+		legacy := []string{key}
+		if timer.Val() == "string" {
+			legacy = append(legacy, link)
+		}
+
+		data := fields.Val()
+		var exp time.Duration
+		switch {
+		case data[u] == "":
+			exp = -1
+		case metaTTL.Val() > 0:
+			exp = metaTTL.Val()
+		case data[p] == "1":
+			exp = 0
+		case timer.Val() == "string" && timerTTL.Val() > 0:
+			exp = timerTTL.Val()
+		default:
+			exp = -1
+		}
+
+		// Истекшую ссылку и ссылку, уже перенесенную в v1, только удаляем
+		if exp < 0 || exists.Val() > 0 {
+			if exp < 0 {
+				m.report.Expired++
+			} else {
+				m.report.Skipped++
+			}
+			if !m.dryRun {
+				if err = m.db.Del(ctx, legacy...).Err(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		owner := data[o]
+		if owner == "" {
+			owner = owners[link]
+		}
+		if owner == "" {
+			m.report.Orphans = append(m.report.Orphans, link)
+		}
+		m.report.Links++
+		if m.dryRun {
+			continue
+		}
+
+		values := make(map[string]any, len(data)+1)
+		for field, value := range data {
+			values[field] = value
+		}
+		if owner != "" {
+			values[o] = owner
+		}
+
+		score := math.Inf(1)
+		if exp > 0 {
+			score = float64(time.Now().Add(exp).UnixMilli())
+		}
+
+		_, err = m.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, LinkKey(link), values)
+			if exp > 0 {
+				pipe.PExpire(ctx, LinkKey(link), exp)
+			}
+			if owner != "" {
+				pipe.ZAdd(ctx, UserLinksKey(owner), redis.Z{Score: score, Member: link})
+			}
+			pipe.Del(ctx, legacy...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rename Переносит ключи с префиксом прежней схемы под новые имена (срок ключа сохраняется)
+func (m *keyMigration) rename(ctx context.Context, match, keyType string, target func(string) string, count *int) error {
+	keys, err := m.scanKeys(ctx, match, keyType)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimSuffix(match, "*")
+	for _, key := range keys {
+		if err = m.move(ctx, key, target(strings.TrimPrefix(key, prefix)), count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// move Переименовывает ключ, если в схеме v1 его еще нет, иначе удаляет прежний
+func (m *keyMigration) move(ctx context.Context, key, target string, count *int) error {
+	exists, err := m.db.Exists(ctx, target).Result()
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		m.report.Skipped++
+		if m.dryRun {
+			return nil
+		}
+		return m.db.Del(ctx, key).Err()
+	}
+
+	*count += 1
+	if m.dryRun {
+		return nil
+	}
+
+	return m.db.RenameNX(ctx, key, target).Err()
+}
+
+// subs Переносит подписки: строки с именем пользователя, у которых есть срок и нет ссылки с тем же именем
+func (m *keyMigration) subs(ctx context.Context) error {
+	keys, err := m.scanKeys(ctx, "*", "string")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !isLegacyName(key) {
+			continue
+		}
+
+		_, exp, found, err := legacySub(ctx, m.db, key)
+		if err != nil {
+			return err
+		}
+		if !found || exp <= 0 {
+			continue
+		}
+
+		if err = m.move(ctx, key, SubKey(key), &m.report.Subs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
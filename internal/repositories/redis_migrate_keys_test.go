@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+// seedLegacyKeys Заполняет Redis данными прежней схемы: ссылки с таймерами и множеством пользователя,
+// ссылка с истекающим хешем и индексом links-, подписка под именем пользователя
+func seedLegacyKeys(mr *miniredis.Miniredis) {
+	// Ссылки с таймером и множеством <пользователь>
+	mr.HSet("meta-abc", "perm", "0", "custom", "1", "url", "https://example.com")
+	mr.Set("abc", "1")
+	mr.SetTTL("abc", time.Hour)
+	mr.HSet("meta-perm", "perm", "1", "custom", "0", "url", "https://example.org")
+	mr.Set("perm", "1")
+	mr.SAdd("alice", "abc", "perm", "gone")
+	mr.HSet("meta-gone", "perm", "0", "custom", "0", "url", "https://expired.example")
+
+	// Ссылка с истекающим хешем и индексом links-<пользователь>
+	mr.HSet("meta-def", "perm", "0", "custom", "0", "url", "https://example.net", "owner", "bob")
+	mr.SetTTL("meta-def", time.Hour)
+	mr.ZAdd("links-bob", float64(time.Now().Add(time.Hour).UnixMilli()), "def")
+
+	// Подписка с датой начала и сохраняемые ссылки
+	mr.Set("bob", "pro")
+	mr.SetTTL("bob", 24*time.Hour)
+	mr.Set("sub-start-bob", "1700000000")
+	mr.SetTTL("sub-start-bob", 24*time.Hour)
+	mr.SAdd("keep-bob", "def")
+
+	// Ключи других сервисов не трогаются
+	mr.Set("leader:workers", "instance")
+	mr.SetTTL("leader:workers", time.Minute)
+}
+
+func TestMigrateKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+	seedLegacyKeys(mr)
+
+	// Пробный прогон ничего не меняет
+	report, err := MigrateKeys(ctx, db, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if report.Links != 3 || report.Expired != 1 || report.Subs != 1 || report.Starts != 1 || report.Keep != 1 || report.Indexes != 2 {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	if mr.Exists(LinkKey("abc")) || !mr.Exists("meta-abc") {
+		t.Fatal("dry run must not change keys")
+	}
+
+	if _, err = MigrateKeys(ctx, db, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// В Redis остались только ключи схемы v1 и чужие ключи
+	for _, key := range mr.Keys() {
+		if key[:3] != KeyVersion+":" && key != "leader:workers" {
+			t.Fatalf("legacy key %q left after migration", key)
+		}
+	}
+	if mr.Exists(LinkKey("gone")) {
+		t.Fatal("expired link must not be migrated")
+	}
+
+	// Данные читаются репозиториями без прежней схемы
+	links := NewRedisLinkRepository(&RedisLinkRepositoryConfig{DB: db})
+	link, err := links.FindLink(ctx, "abc")
+	if err != nil || link.Owner != "alice" || !link.Custom || link.ExpTime <= 0 || link.ExpTime > time.Hour {
+		t.Fatalf("unexpected migrated link %+v (%v)", link, err)
+	}
+	if amount, _ := links.CountLinks(ctx, "alice"); amount.All != 2 || amount.Perm != 1 {
+		t.Fatalf("unexpected alice links %+v", amount)
+	}
+	if amount, _ := links.CountLinks(ctx, "bob"); amount.All != 1 {
+		t.Fatalf("unexpected bob links %+v", amount)
+	}
+	if keep, _ := links.KeepLinks(ctx, "bob"); len(keep) != 1 || keep[0] != "def" {
+		t.Fatalf("unexpected keep links %v", keep)
+	}
+
+	subs := NewRedisSubRepository(&RedisSubRepositoryConfig{DB: db})
+	sub, ok := subs.FindSubscription(ctx, "bob")
+	if !ok || sub.Plan != "pro" || sub.Exp != 24*time.Hour || sub.Start.Unix() != 1700000000 {
+		t.Fatalf("unexpected migrated subscription %+v", sub)
+	}
+	if _, ok = subs.FindSubscribe(ctx, "alice"); ok {
+		t.Fatal("link set must not become a subscription")
+	}
+
+	// Повторный запуск ничего не переносит
+	report, err = MigrateKeys(ctx, db, false)
+	if err != nil || report.Links != 0 || report.Subs != 0 || report.Indexes != 0 {
+		t.Fatalf("expected nothing to migrate, got %+v (%v)", report, err)
+	}
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"short_url/internal/models"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// RedisOIDCStateRepositoryConfig Конфигурация для RedisOIDCStateRepository
+type RedisOIDCStateRepositoryConfig struct {
+	DB	*redis.Client
+}
+
+// RedisOIDCStateRepository Слой для хранения начатых входов через провайдера, общий для всех экземпляров
+type RedisOIDCStateRepository struct {
+	db	*redis.Client
+}
+
+// NewRedisOIDCStateRepository Конструктор для RedisOIDCStateRepository
+func NewRedisOIDCStateRepository(c *RedisOIDCStateRepositoryConfig) *RedisOIDCStateRepository {
+	return &RedisOIDCStateRepository{
+		db:	c.DB,
+	}
+}
+
+// SaveState Сохраняет параметры входа по state на время ttl
+func (r *RedisOIDCStateRepository) SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Set(ctx, OIDCStateKey(state), data, ttl).Result()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TakeState Достает и сразу удаляет параметры входа (GETDEL), поэтому state используется один раз.
+// Если state не найден или истек, возвращает redis.Nil
+func (r *RedisOIDCStateRepository) TakeState(ctx context.Context, state string) (models.OIDCState, error) {
+	var st models.OIDCState
+
+	data, err := r.db.GetDel(ctx, OIDCStateKey(state)).Bytes()
+	if err != nil {
+		return st, err
+	}
+
+	err = json.Unmarshal(data, &st)
+
+	return st, err
+}
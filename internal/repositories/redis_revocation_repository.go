@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// RedisRevocationRepositoryConfig Конфигурация для RedisRevocationRepository
+type RedisRevocationRepositoryConfig struct {
+	DB	*redis.Client
+}
+
+// RedisRevocationRepository Слой для хранения момента отзыва токенов доступа пользователя
+type RedisRevocationRepository struct {
+	db	*redis.Client
+}
+
+// NewRedisRevocationRepository Конструктор для RedisRevocationRepository
+func NewRedisRevocationRepository(c *RedisRevocationRepositoryConfig) *RedisRevocationRepository {
+	return &RedisRevocationRepository{
+		db:	c.DB,
+	}
+}
+
+// Revoke Запоминает момент отзыва токенов пользователя на время ttl (не меньше срока жизни токена)
+func (r *RedisRevocationRepository) Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error {
+	_, err := r.db.Set(ctx, UserRevokedKey(username), at.Unix(), ttl).Result()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokedAt Возвращает момент отзыва токенов пользователя (нулевое время - токены не отзывались)
+func (r *RedisRevocationRepository) RevokedAt(ctx context.Context, username string) (time.Time, error) {
+	val, err := r.db.Get(ctx, UserRevokedKey(username)).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	sec, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(sec, 0), nil
+}
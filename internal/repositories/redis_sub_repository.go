@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"short_url/internal/models"
 	"time"
 
 	"github.com/go-redis/redis/v9"
@@ -10,43 +12,162 @@ import (
 // RedisSubRepositoryConfig Конфигурация для RedisLinkRepository
 type RedisSubRepositoryConfig struct {
 	DB		*redis.Client
-	Pipe	redis.Pipeliner
+	Legacy	bool	// Читать подписки прежней схемы ключей (на время перехода)
 }
 
 // RedisSubRepository Слой для управления запросами к хранилищу ссылок
 type RedisSubRepository struct {
 	db		*redis.Client
-	pipe 	redis.Pipeliner
+	legacy	bool
 }
 
 // NewRedisSubRepository Конструктор для RedisSubRepository
 func NewRedisSubRepository(c *RedisSubRepositoryConfig) *RedisSubRepository {
 	return &RedisSubRepository{
 		db:		c.DB,
-		pipe:	c.Pipe,
+		legacy:	c.Legacy,
 	}
 }
 
+// findSub Находит план и срок подписки. Если в схеме v1 подписки нет, читает прежнюю схему
+func (r *RedisSubRepository) findSub(ctx context.Context, username string) (string, time.Duration, bool, error) {
+	var (
+		plan	*redis.StringCmd
+		ttl		*redis.DurationCmd
+	)
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		plan = pipe.Get(ctx, SubKey(username))
+		ttl = pipe.TTL(ctx, SubKey(username))
+		return nil
+	})
+	if err == nil {
+		return plan.Val(), ttl.Val(), true, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", -1, false, err
+	}
+
+	if r.legacy {
+		return legacySub(ctx, r.db, username)
+	}
+
+	return "", -1, false, nil
+}
+
 // FindByUsername Находит подписку и ее срок по имени пользователя
 func (r *RedisSubRepository) FindSubscribe(ctx context.Context, username string) (time.Duration, bool) {
-	// Ищем в базе информацию о подписке
-	_, err := r.db.Get(ctx, username).Result()
-	if err != nil {
+	_, exp, ok, err := r.findSub(ctx, username)
+	if err != nil || !ok {
 		return -1, false
 	}
 
-	exp, err := r.db.TTL(ctx, username).Result()
+	return exp, true
+}
+
+// FindSubPlan Находит подписку, ее тарифный план и срок по имени пользователя.
+// У подписок, оформленных до появления планов, вместо имени плана записано "1"
+func (r *RedisSubRepository) FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool) {
+	plan, exp, ok, err := r.findSub(ctx, username)
+	if err != nil || !ok {
+		return "", -1, false
+	}
+
+	return plan, exp, true
+}
+
+// SubState Находит план действующей подписки. В отличие от FindSubPlan отличает отсутствие подписки от ошибки Redis
+func (r *RedisSubRepository) SubState(ctx context.Context, username string) (string, bool, error) {
+	plan, _, ok, err := r.findSub(ctx, username)
 	if err != nil {
-		return -1, false
+		return "", false, err
 	}
 
-	return exp, true
+	return plan, ok, nil
 }
 
-// AddSubRedis Добавляет пользователю подписку на ограниченное время
-func (r *RedisSubRepository) AddSubRedis(ctx context.Context, username string, exp time.Duration) error {
-	// Загружаем информацию о подписке в базу
-	_, err := r.db.Set(ctx, username, 1, exp).Result()
+// legacyStart Возвращает дату начала подписки прежней схемы (0 - нет)
+func (r *RedisSubRepository) legacyStart(ctx context.Context, username string) int64 {
+	if !r.legacy {
+		return 0
+	}
+
+	start, _ := r.db.Get(ctx, legacySubStartPrefix+username).Int64()
+
+	return start
+}
+
+// AddSubRedis Добавляет пользователю подписку по тарифному плану на ограниченное время.
+// Подписка прежней схемы при этом переносится в схему v1
+func (r *RedisSubRepository) AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error {
+	// Дата начала записывается только для новой подписки, при продлении переносится лишь срок
+	start := time.Now().Unix()
+	if s := r.legacyStart(ctx, username); s > 0 {
+		start = s
+	}
+
+	// Ключ с именем пользователя удаляем, только если это подписка прежней схемы
+	var legacy bool
+	if r.legacy {
+		_, _, found, err := legacySub(ctx, r.db, username)
+		if err != nil {
+			return err
+		}
+		legacy = found
+	}
+
+	_, err := r.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, SubKey(username), plan, exp)
+		pipe.SetNX(ctx, SubStartKey(username), start, exp)
+		pipe.Expire(ctx, SubStartKey(username), exp)
+		if legacy {
+			pipe.Del(ctx, username)
+		}
+		if r.legacy {
+			pipe.Del(ctx, legacySubStartPrefix+username)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// FindSubscription Находит действующую подписку пользователя с планом и датой начала
+func (r *RedisSubRepository) FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool) {
+	plan, exp, ok := r.FindSubPlan(ctx, username)
+	if !ok {
+		return models.SubscriptionDB{}, false
+	}
+
+	result := models.SubscriptionDB{Plan: plan, Exp: exp}
+
+	// Дата начала может отсутствовать у подписок, оформленных раньше
+	start, err := r.db.Get(ctx, SubStartKey(username)).Int64()
+	if err != nil {
+		start = r.legacyStart(ctx, username)
+	}
+	if start > 0 {
+		result.Start = time.Unix(start, 0)
+	}
+
+	return result, true
+}
+
+// RemoveSubscribe Удаляет подписку пользователя
+func (r *RedisSubRepository) RemoveSubscribe(ctx context.Context, username string) error {
+	keys := []string{SubKey(username), SubStartKey(username)}
+
+	if r.legacy {
+		_, _, found, err := legacySub(ctx, r.db, username)
+		if err != nil {
+			return err
+		}
+		if found {
+			keys = append(keys, username)
+		}
+		keys = append(keys, legacySubStartPrefix+username)
+	}
+
+	_, err := r.db.Del(ctx, keys...).Result()
 	if err != nil {
 		return err
 	}
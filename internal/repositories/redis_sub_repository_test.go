@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+func TestRedisSubRepositoryLegacy(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+	r := NewRedisSubRepository(&RedisSubRepositoryConfig{DB: db, Legacy: true})
+
+	// Подписка прежней схемы читается до миграции
+	mr.Set("bob", "pro")
+	mr.SetTTL("bob", time.Hour)
+	mr.Set("sub-start-bob", "1700000000")
+	if plan, exp, ok := r.FindSubPlan(ctx, "bob"); !ok || plan != "pro" || exp != time.Hour {
+		t.Fatalf("expected legacy subscription, got %q %s %v", plan, exp, ok)
+	}
+
+	// Множество ссылок и таймер ссылки с именем пользователя - не подписки
+	mr.SAdd("alice", "abc")
+	mr.Set("carol", "1")
+	mr.HSet("meta-carol", "url", "https://example.com")
+	for _, username := range []string{"alice", "carol"} {
+		if _, ok, err := r.SubState(ctx, username); ok || err != nil {
+			t.Fatalf("unexpected subscription for %s (%v)", username, err)
+		}
+	}
+
+	// Продление переносит подписку в v1 с прежней датой начала
+	if err := r.AddSubRedis(ctx, "bob", "pro", 2*time.Hour); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if mr.Exists("bob") || mr.Exists("sub-start-bob") {
+		t.Fatal("legacy keys must be removed after renewal")
+	}
+	sub, ok := r.FindSubscription(ctx, "bob")
+	if !ok || sub.Exp != 2*time.Hour || sub.Start.Unix() != 1700000000 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+
+	// Удаление подписки не трогает ссылки
+	if err := r.RemoveSubscribe(ctx, "alice"); err != nil || !mr.Exists("alice") {
+		t.Fatalf("link set must survive subscription removal (%v)", err)
+	}
+}
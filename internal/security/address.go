@@ -0,0 +1,81 @@
+package security
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ErrForbiddenAddress Адрес относится к локальной или внутренней сети
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// deniedNets Зарезервированные сети, которые не покрываются проверками net.IP
+var deniedNets = parseCIDRs(
+	"0.0.0.0/8",		// Текущая сеть
+	"100.64.0.0/10",	// Разделяемое адресное пространство (CGNAT), часто внутренние адреса облаков
+	"192.0.0.0/24",		// Назначения протоколов IETF
+	"192.0.2.0/24",		// Документация (TEST-NET-1)
+	"198.18.0.0/15",	// Тестирование производительности
+	"198.51.100.0/24",	// Документация (TEST-NET-2)
+	"203.0.113.0/24",	// Документация (TEST-NET-3)
+	"240.0.0.0/4",		// Зарезервировано, включая широковещательный 255.255.255.255
+	"64:ff9b::/96",		// NAT64: адрес IPv4 внутри IPv6
+	"64:ff9b:1::/48",	// Локальный NAT64
+	"2001:db8::/32",	// Документация
+	"2002::/16",		// 6to4: адрес IPv4 внутри IPv6
+	"100::/64",			// Отбрасываемые адреса
+)
+
+// parseCIDRs Разбирает список сетей
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, len(cidrs))
+	for k, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result[k] = n
+	}
+
+	return result
+}
+
+// PublicIP Проверяет, что адрес не петлевой, не частный, не локальный для канала, не групповой, не нулевой
+// и не относится к зарезервированным сетям. Адрес IPv4 в виде IPv6 (::ffff:a.b.c.d) проверяется как IPv4
+func PublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip == nil {
+		return false
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range deniedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DialControl Запрещает соединения с внутренними адресами (для net.Dialer.Control). Адрес проверяется
+// после разрешения имени, поэтому смена DNS-записи после регистрации вебхука не помогает
+func DialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !PublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package security
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestPublicIP(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":		true,
+		"2606:2800:220:1::1":	true,
+		"127.0.0.1":			false,
+		"::1":					false,
+		"10.1.2.3":				false,
+		"172.16.0.1":			false,
+		"192.168.1.1":			false,
+		"fd00::1":				false,
+		"169.254.169.254":		false,
+		"fe80::1":				false,
+		"0.0.0.0":				false,
+		"::":					false,
+		"224.0.0.1":			false,
+		"ff02::1":				false,
+		"::ffff:127.0.0.1":		false,
+		"::ffff:10.0.0.1":		false,
+		"::ffff:169.254.169.254":	false,
+		"::ffff:93.184.216.34":	true,
+		"100.64.0.1":			false,
+		"100.127.255.254":		false,
+		"100.128.0.1":			true,
+		"192.0.0.170":			false,
+		"198.18.0.1":			false,
+		"198.19.255.255":		false,
+		"240.0.0.1":			false,
+		"255.255.255.255":		false,
+		"0.1.2.3":				false,
+		"64:ff9b::a00:1":		false,
+		"64:ff9b::7f00:1":		false,
+		"2002:a00:1::1":		false,
+		"2001:db8::1":			false,
+	} {
+		if got := PublicIP(net.ParseIP(addr)); got != public {
+			t.Errorf("%s: expected %v, got %v", addr, public, got)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	if err := DialControl("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80"} {
+		if err := DialControl("tcp", addr, nil); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s: expected forbidden address, got %v", addr, err)
+		}
+	}
+}
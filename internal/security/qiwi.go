@@ -0,0 +1,25 @@
+package security
+
+import (
+	"crypto/hmac"
+	"strconv"
+	"strings"
+)
+
+// QiwiAmount Форматирует сумму так, как ее подписывает QIWI (ровно два знака после точки)
+func QiwiAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// QiwiSignature Вычисляет подпись уведомления QIWI: HMAC-SHA256 от полей,
+// разделенных "|", в шестнадцатеричном виде
+func QiwiSignature(key string, fields ...string) string {
+	return HMACSHA256(key, []byte(strings.Join(fields, "|")))
+}
+
+// VerifyQiwiSignature Сравнивает подпись из заголовка с ожидаемой за постоянное время
+func VerifyQiwiSignature(key, sign string, fields ...string) bool {
+	expected := QiwiSignature(key, fields...)
+
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(sign))))
+}
@@ -0,0 +1,54 @@
+package security
+
+import "testing"
+
+// Подписи посчитаны независимо (python: hmac.new(key, msg, sha256).hexdigest())
+func TestQiwiSignatureGolden(t *testing.T) {
+	vectors := []struct {
+		key		string
+		fields	[]string
+		sign	string
+	}{
+		{"secret", []string{"RUB", "1.00", "eqwptt", "r-1", "PAID"}, "38c85300f5f7515e0fe26c0df54bb2d2ab0a44cd4b9644aa49f828941c28b784"},
+		{"test-key", []string{"RUB", "199.90", "893794793973", "test", "REJECTED"}, "17c52bd32474d8737ad3bb4c303baad3cbe406b72a6f6687a6c2bbfb4ec3c012"},
+		{"", []string{"KZT", "10.50", "b", "s", "EXPIRED"}, "78dc8a82d6547d104ca29d7c07cb6f45dcf28726fe98923dba692f526c77508e"},
+	}
+
+	for _, v := range vectors {
+		if sign := QiwiSignature(v.key, v.fields...); sign != v.sign {
+			t.Fatalf("%v: expected %s, got %s", v.fields, v.sign, sign)
+		}
+		if !VerifyQiwiSignature(v.key, v.sign, v.fields...) {
+			t.Fatalf("%v: valid signature rejected", v.fields)
+		}
+	}
+}
+
+func TestVerifyQiwiSignature(t *testing.T) {
+	fields := []string{"RUB", "1.00", "eqwptt", "r-1", "PAID"}
+	sign := "38c85300f5f7515e0fe26c0df54bb2d2ab0a44cd4b9644aa49f828941c28b784"
+
+	// Регистр шестнадцатеричной записи не важен
+	if !VerifyQiwiSignature("secret", " 38C85300F5F7515E0FE26C0DF54BB2D2AB0A44CD4B9644AA49F828941C28B784", fields...) {
+		t.Fatal("uppercase signature rejected")
+	}
+
+	for _, bad := range []string{"", sign[:62], "secret"} {
+		if VerifyQiwiSignature("secret", bad, fields...) {
+			t.Errorf("signature %q must be rejected", bad)
+		}
+	}
+
+	// Сумма "1" вместо "1.00" дает другую подпись
+	if VerifyQiwiSignature("secret", sign, "RUB", "1", "eqwptt", "r-1", "PAID") {
+		t.Fatal("amount must be signed in exact format")
+	}
+}
+
+func TestQiwiAmount(t *testing.T) {
+	for value, s := range map[float64]string{1: "1.00", 199.9: "199.90", 10.5: "10.50", 0.1 + 0.2: "0.30"} {
+		if got := QiwiAmount(value); got != s {
+			t.Errorf("%v: expected %s, got %s", value, s, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateSecret генерирует случайный токен для ссылок в письмах
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// HashSecret хеширует токен для хранения в базе данных.
+// Токен случайный и длинный, поэтому соль и медленный хеш не нужны
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// HMACSHA256 Подписывает данные ключом (HMAC-SHA256 в шестнадцатеричном виде)
+func HMACSHA256(key string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,131 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod	= 30	// Длительность шага TOTP в секундах (RFC 6238)
+	totpDigits	= 6		// Кол-во цифр в коде
+	totpSkew	= 1		// Сколько соседних шагов принимается из-за расхождения часов
+	totpSecret	= 20	// Длина секрета в байтах (160 бит для HMAC-SHA1)
+)
+
+// base32 без выравнивания, как принято в приложениях-аутентификаторах
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret генерирует секрет TOTP в формате base32
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecret)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// hotp вычисляет одноразовый код по счетчику (RFC 4226)
+func hotp(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for k := 0; k < digits; k++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// decodeTOTPSecret декодирует секрет, допуская пробелы и нижний регистр
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+
+	return totpEncoding.DecodeString(secret)
+}
+
+// TOTPCode вычисляет код TOTP для момента времени
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret")
+	}
+
+	return hotp(key, uint64(t.Unix()/totpPeriod), totpDigits), nil
+}
+
+// ValidateTOTP проверяет код TOTP с учетом соседних шагов и возвращает шаг, которому код соответствует.
+// Код принимается только на шаге новее last (RFC 6238, 5.2): использованный код нельзя предъявить повторно,
+// поэтому вызывающий должен сохранить возвращенный шаг
+func ValidateTOTP(secret, code string, t time.Time, last int64) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := t.Unix() / totpPeriod
+	for k := -totpSkew; k <= totpSkew; k++ {
+		expected := hotp(key, uint64(step+int64(k)), totpDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 && step+int64(k) > last {
+			return step + int64(k), true
+		}
+	}
+
+	return 0, false
+}
+
+// TOTPURI формирует otpauth-ссылку для QR-кода приложения-аутентификатора
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
+
+// GenerateRecoveryCodes генерирует резервные коды вида xxxx-xxxx-xxxx-xxxx (64 бита каждый)
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	result := make([]string, n)
+	for k := 0; k < n; k++ {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		h := hex.EncodeToString(b)
+		result[k] = fmt.Sprintf("%s-%s-%s-%s", h[0:4], h[4:8], h[8:12], h[12:16])
+	}
+
+	return result, nil
+}
+
+// NormalizeRecoveryCode приводит введенный резервный код к формату хранения
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,85 @@
+package security
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Тестовые значения из приложения B RFC 6238 (SHA1, последние 6 цифр 8-значных кодов)
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, v := range vectors {
+		code, err := TOTPCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if code != v.code {
+			t.Fatalf("time %d: expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	prev, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	old, _ := TOTPCode(secret, now.Add(-90*time.Second))
+
+	if step, ok := ValidateTOTP(secret, prev, now, 0); !ok || step != now.Unix()/30-1 {
+		t.Fatalf("code of the previous step must be accepted, got step %d", step)
+	}
+	if _, ok := ValidateTOTP(secret, old, now, 0); ok && old != prev {
+		t.Fatal("code older than one step must be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Fatal("code of wrong length must be rejected")
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	code, _ := TOTPCode(secret, now)
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok {
+		t.Fatal("fresh code must be accepted")
+	}
+
+	// Тот же код в пределах окна повторно не принимается
+	if _, ok = ValidateTOTP(secret, code, now.Add(20*time.Second), step); ok {
+		t.Fatal("replayed code must be rejected")
+	}
+
+	// Код предыдущего шага после использования более нового тоже недействителен
+	prev, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	if _, ok = ValidateTOTP(secret, prev, now, step); ok && prev != code {
+		t.Fatal("code of an earlier step must be rejected")
+	}
+
+	next, _ := TOTPCode(secret, now.Add(30*time.Second))
+	if _, ok = ValidateTOTP(secret, next, now.Add(30*time.Second), step); !ok {
+		t.Fatal("code of the next step must be accepted")
+	}
+}
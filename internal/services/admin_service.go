@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+// AdminServiceConfig Конфигурация для AdminService
+type AdminServiceConfig struct {
+	AuthRepo	authRepository
+	LinkRepo	linkRepository
+	SubRepo		subRepository
+	AuditRepo	auditRepository
+	Subscriber	subscriber
+	Manager		manager
+	Logger		*log.Log
+}
+
+// AdminService Операции оператора сервиса над пользователями, ссылками и подписками
+type AdminService struct {
+	authRepo	authRepository
+	linkRepo	linkRepository
+	subRepo		subRepository
+	auditRepo	auditRepository
+	subscriber	subscriber
+	manager		manager
+	logger		*log.Log
+}
+
+const (
+	SearchLimit		= 50				// Максимальное кол-во пользователей в результатах поиска
+	RevokeDelay		= time.Minute		// Через сколько выполняется чистка ссылок после отзыва подписки
+)
+
+// NewAdminService Конструктор для AdminService
+func NewAdminService(c *AdminServiceConfig) *AdminService {
+	return &AdminService{
+		authRepo:	c.AuthRepo,
+		linkRepo:	c.LinkRepo,
+		subRepo:	c.SubRepo,
+		auditRepo:	c.AuditRepo,
+		subscriber:	c.Subscriber,
+		manager:	c.Manager,
+		logger:		c.Logger,
+	}
+}
+
+// findUser Проверяет, что пользователь зарегистрирован
+func (s *AdminService) findUser(ctx context.Context, username string) error {
+	_, err := s.authRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("user not found")
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to find user. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// SearchUsers Ищет пользователей по логину, имени или фамилии
+func (s *AdminService) SearchUsers(ctx context.Context, search string) ([]models.UserDB, error) {
+	ctx = log.ContextWithSpan(ctx, "SearchUsers")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("SearchUsers() started")
+	defer l.Debug("SearchUsers() done")
+
+	users, err := s.authRepo.SearchUsers(ctx, search, SearchLimit)
+	if err != nil {
+		l.Errorf("Unable to search users. Error: %s", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetUserLinks Возвращает все ссылки любого пользователя
+func (s *AdminService) GetUserLinks(ctx context.Context, username string) ([]models.LinkDataDTO, error) {
+	ctx = log.ContextWithSpan(ctx, "GetUserLinks")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("GetUserLinks() started")
+	defer l.Debug("GetUserLinks() done")
+
+	// Проверяем, что пользователь существует
+	if err := s.findUser(ctx, username); err != nil {
+		return nil, err
+	}
+
+	// Получаем все ссылки пользователя из БД
+	data, err := s.linkRepo.GetAllLinks(ctx, username)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		l.Errorf("Unable to get all links from Redis. Error: %s", err)
+		return nil, err
+	}
+
+	// Маппим данные в ответ
+	result := make([]models.LinkDataDTO, len(data))
+	for k, d := range data {
+		result[k].Link = d.Link
+		result[k].FullURL = d.FullURL
+		result[k].ExpTime = int(d.ExpTime)
+		result[k].Disabled = d.Disabled
+		result[k].DisabledReason = d.DisabledReason
+	}
+
+	return result, nil
+}
+
+// DisableLink Блокирует ссылку за нарушения правил сервиса
+func (s *AdminService) DisableLink(ctx context.Context, admin models.JWTUserInfo, link, reason string) error {
+	ctx = log.ContextWithSpan(ctx, "DisableLink")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("DisableLink() started")
+	defer l.Debug("DisableLink() done")
+
+	// Находим ссылку в БД
+	data, err := s.linkRepo.FindLink(ctx, link)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return errors.New("link not found")
+		}
+
+		l.Errorf("Unable to find link in Redis. Error: %s", err)
+		return err
+	}
+
+	// Проверяем права на блокировку
+	if err = authorizeLink(admin, data, actionDisable); err != nil {
+		return err
+	}
+
+	// Блокируем ссылку
+	if err = s.linkRepo.DisableLink(ctx, link, reason); err != nil {
+		l.Errorf("Unable to disable link in Redis. Error: %s", err)
+		return err
+	}
+
+	l.Infof("admin %s disabled link %s of user %s. Reason: %s", admin.Username, link, data.Owner, reason)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		admin.Username,
+		Action:		models.AuditLinkDisable,
+		Target:		link,
+		Owner:		data.Owner,
+		Details:	reason,
+	})
+
+	return nil
+}
+
+// EnableLink Снимает блокировку со ссылки
+func (s *AdminService) EnableLink(ctx context.Context, admin models.JWTUserInfo, link string) error {
+	ctx = log.ContextWithSpan(ctx, "EnableLink")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("EnableLink() started")
+	defer l.Debug("EnableLink() done")
+
+	// Находим ссылку в БД
+	data, err := s.linkRepo.FindLink(ctx, link)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return errors.New("link not found")
+		}
+
+		l.Errorf("Unable to find link in Redis. Error: %s", err)
+		return err
+	}
+
+	// Проверяем права на снятие блокировки
+	if err = authorizeLink(admin, data, actionDisable); err != nil {
+		return err
+	}
+
+	// Снимаем блокировку
+	if err = s.linkRepo.EnableLink(ctx, link); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return errors.New("link not disabled")
+		}
+
+		l.Errorf("Unable to enable link in Redis. Error: %s", err)
+		return err
+	}
+
+	l.Infof("admin %s enabled link %s of user %s", admin.Username, link, data.Owner)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	admin.Username,
+		Action:	models.AuditLinkEnable,
+		Target:	link,
+		Owner:	data.Owner,
+	})
+
+	return nil
+}
+
+// GrantSubscribe Выдает пользователю подписку вручную, минуя оплату через QIWI
+func (s *AdminService) GrantSubscribe(ctx context.Context, admin models.JWTUserInfo, username string, days int, note string) error {
+	ctx = log.ContextWithSpan(ctx, "GrantSubscribe")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("GrantSubscribe() started")
+	defer l.Debug("GrantSubscribe() done")
+
+	// Проверяем, что пользователь существует
+	if err := s.findUser(ctx, username); err != nil {
+		return err
+	}
+
+	// Оформляем подписку так же, как при оплате счета
+	err := s.subscriber.AddSubscribe(ctx, models.SubInfo{
+		Username:	username,
+		Exp:		time.Duration(days) * time.Hour * 24,
+	})
+	if err != nil {
+		l.Errorf("Unable to add subscribe to user. Error: %s", err)
+		return err
+	}
+
+	l.Infof("admin %s granted %d days of subscribe to user %s. Note: %s", admin.Username, days, username, note)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		admin.Username,
+		Action:		models.AuditSubGrant,
+		Target:		username,
+		Owner:		username,
+		Details:	fmt.Sprintf("days: %d, note: %s", days, note),
+	})
+
+	return nil
+}
+
+// RevokeSubscribe Отзывает подписку пользователя и планирует чистку ссылок сверх лимитов
+func (s *AdminService) RevokeSubscribe(ctx context.Context, admin models.JWTUserInfo, username, note string) error {
+	ctx = log.ContextWithSpan(ctx, "RevokeSubscribe")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("RevokeSubscribe() started")
+	defer l.Debug("RevokeSubscribe() done")
+
+	// Проверяем, что подписка есть
+	if _, ok := s.subRepo.FindSubscribe(ctx, username); !ok {
+		return errors.New("subscribe not found")
+	}
+
+	// Удаляем подписку
+	if err := s.subRepo.RemoveSubscribe(ctx, username); err != nil {
+		l.Errorf("Unable to remove subscribe. Error: %s", err)
+		return err
+	}
+
+	// Переносим окончание подписки на ближайшее время (чистка ссылок - по истечении льготного периода)
+	s.manager.RemoveCleanSchedule(ctx, username)
+	err := s.manager.CleanUnsubscribeSchedule(ctx, models.CurrentSub{Exp: RevokeDelay}, username)
+	if err != nil {
+		l.Errorf("Unable to schedule data cleaning. Error: %s", err)
+		return err
+	}
+
+	l.Infof("admin %s revoked subscribe of user %s. Note: %s", admin.Username, username, note)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		admin.Username,
+		Action:		models.AuditSubRevoke,
+		Target:		username,
+		Owner:		username,
+		Details:	note,
+	})
+
+	return nil
+}
+
+// PendingBills Возвращает счета, ожидающие оплаты
+func (s *AdminService) PendingBills(ctx context.Context) []models.BillInfo {
+	return s.subscriber.PendingBills(ctx)
+}
+
+// Jobs Возвращает запланированные задачи очистки
+func (s *AdminService) Jobs(ctx context.Context) []models.SchedJob {
+	return s.manager.Jobs(ctx)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"short_url/internal/events"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+)
+
+// AuditServiceConfig Конфигурация для AuditService
+type AuditServiceConfig struct {
+	AuditRepo	auditRepository
+	Logger		*log.Log
+}
+
+// AuditService Отдает записи журнала аудита
+type AuditService struct {
+	auditRepo	auditRepository
+	logger		*log.Log
+}
+
+const (
+	AuditLimit	= 100	// Кол-во записей журнала аудита по умолчанию
+	AuditMax	= 1000	// Максимальное кол-во записей журнала аудита в одной выдаче
+)
+
+// NewAuditService Конструктор для AuditService
+func NewAuditService(c *AuditServiceConfig) *AuditService {
+	return &AuditService{
+		auditRepo:	c.AuditRepo,
+		logger:		c.Logger,
+	}
+}
+
+// recordAudit Записывает действие в журнал аудита вместе с trace_id вызова.
+// Ошибка записи только логируется и не прерывает основную операцию
+func recordAudit(ctx context.Context, repo auditRepository, l *log.Log, entry models.AuditEntry) {
+	if repo == nil {
+		return
+	}
+
+	entry.TraceID = log.TraceFromContext(ctx)
+
+	if err := repo.AppendEntry(ctx, entry); err != nil {
+		l.Errorf("Unable to write audit entry %s. Error: %s", entry.Action, err)
+	}
+}
+
+// Причины удаления ссылки и соответствующие действия журнала аудита
+var deleteActions = map[string]string{
+	events.ReasonUser:		models.AuditLinkDelete,
+	events.ReasonExpired:	models.AuditLinkExpire,
+	events.ReasonCleanup:	models.AuditLinkCleanup,
+}
+
+// Subscribe Подписывает журнал аудита на события шины
+func (s *AuditService) Subscribe(b *events.Bus) {
+	events.On(b, func(ctx context.Context, ev events.LinkCreated) error {
+		recordAudit(ctx, s.auditRepo, s.logger.WithContext(ctx), models.AuditEntry{
+			Actor:		ev.Owner,
+			Action:		models.AuditLinkCreate,
+			Target:		ev.Link,
+			Owner:		ev.Owner,
+			Details:	ev.FullURL,
+		})
+		return nil
+	})
+	events.On(b, func(ctx context.Context, ev events.LinkDeleted) error {
+		recordAudit(ctx, s.auditRepo, s.logger.WithContext(ctx), models.AuditEntry{
+			Actor:	ev.Actor,
+			Action:	deleteActions[ev.Reason],
+			Target:	ev.Link,
+			Owner:	ev.Owner,
+		})
+		return nil
+	})
+	events.On(b, func(ctx context.Context, ev events.SubscriptionActivated) error {
+		recordAudit(ctx, s.auditRepo, s.logger.WithContext(ctx), models.AuditEntry{
+			Actor:		models.ActorSystem,
+			Action:		models.AuditSubAdd,
+			Target:		ev.Username,
+			Owner:		ev.Username,
+			Details:	fmt.Sprintf("plan %s, added %s, expires in %s", ev.Plan, ev.Added, ev.Exp),
+		})
+		return nil
+	})
+}
+
+// History Возвращает записи журнала, затрагивающие аккаунт пользователя
+func (s *AuditService) History(ctx context.Context, username string) ([]models.AuditEntry, error) {
+	return s.Search(ctx, models.AuditFilter{Owner: username, Limit: AuditLimit})
+}
+
+// Search Возвращает записи журнала по фильтру (для администратора)
+func (s *AuditService) Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx = log.ContextWithSpan(ctx, "AuditSearch")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("AuditSearch() started")
+	defer l.Debug("AuditSearch() done")
+
+	// Ограничиваем размер выдачи
+	if filter.Limit <= 0 {
+		filter.Limit = AuditLimit
+	}
+	if filter.Limit > AuditMax {
+		filter.Limit = AuditMax
+	}
+
+	entries, err := s.auditRepo.FindEntries(ctx, filter)
+	if err != nil {
+		l.Errorf("Unable to read audit log. Error: %s", err)
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/events"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeAuditRepo Журнал аудита в памяти для тестов сервисов
+type fakeAuditRepo struct {
+	entries []models.AuditEntry
+}
+
+func (r *fakeAuditRepo) AppendEntry(ctx context.Context, entry models.AuditEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *fakeAuditRepo) RedactUser(ctx context.Context, username, pseudonym string) error {
+	for k, e := range r.entries {
+		if e.Target == username && e.Owner == username {
+			e.Target = pseudonym
+		}
+		if e.Actor == username {
+			e.Actor, e.Details = pseudonym, ""
+		}
+		if e.Owner == username {
+			e.Owner = pseudonym
+		}
+		r.entries[k] = e
+	}
+	return nil
+}
+
+func (r *fakeAuditRepo) FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	result := make([]models.AuditEntry, 0)
+	for _, e := range r.entries {
+		if filter.Owner != "" && e.Owner != filter.Owner {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func TestLinkServiceDeleteLinkAudit(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "abc", FullURL: "https://example.com", Owner: "alice"})
+	audit := &fakeAuditRepo{}
+	bus := events.NewBus()
+	NewAuditService(&AuditServiceConfig{
+		AuditRepo:	audit,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	}).Subscribe(bus)
+	s := NewLinkService(&LinkServiceConfig{
+		LinkRepo:	repo,
+		Events:		bus,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := log.ContextWithTrace(context.Background(), "trace-1")
+
+	if err := s.DeleteLink(ctx, bob, "abc"); err == nil {
+		t.Fatal("expected access denied for foreign link")
+	}
+	if len(audit.entries) != 0 {
+		t.Fatalf("denied action must not be audited, got %v", audit.entries)
+	}
+
+	if err := s.DeleteLink(ctx, alice, "abc"); err != nil {
+		t.Fatalf("owner must delete own link, got error: %s", err)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %v", audit.entries)
+	}
+
+	e := audit.entries[0]
+	if e.Actor != "alice" || e.Action != models.AuditLinkDelete || e.Target != "abc" || e.Owner != "alice" {
+		t.Fatalf("unexpected audit entry %+v", e)
+	}
+	if e.TraceID != "trace-1" {
+		t.Fatalf("expected trace id of the request, got %q", e.TraceID)
+	}
+}
+
+func TestAuditServiceHistory(t *testing.T) {
+	audit := &fakeAuditRepo{entries: []models.AuditEntry{
+		{Actor: "alice", Action: models.AuditLinkCreate, Target: "abc", Owner: "alice"},
+		{Actor: "root", Action: models.AuditLinkDisable, Target: "xyz", Owner: "bob"},
+	}}
+	s := NewAuditService(&AuditServiceConfig{
+		AuditRepo:	audit,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+
+	entries, err := s.History(context.Background(), "bob")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 1 || entries[0].Target != "xyz" {
+		t.Fatalf("history must contain only entries of the account, got %v", entries)
+	}
+}
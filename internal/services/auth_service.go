@@ -3,16 +3,40 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v9"
 	"github.com/jackc/pgx/v5"
 	"short_url/internal/models"
 	"short_url/internal/security"
 	log "short_url/pkg/logger"
+	"regexp"
+	"strings"
+	"time"
 )
 
+// validUsername Допустимые символы имени пользователя. Двоеточие исключено: оно разделяет части ключей Redis
+// и занято псевдонимами удаленных пользователей (см. deletedUser)
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 // AuthServiceConfig Конфигурация к AuthService
 type AuthServiceConfig struct {
 	AuthRepo	authRepository
 	SubRepo		subRepository
+	LinkRepo	linkRepository
+	TokenRepo	tokenRepository
+	RecoveryRepo	recoveryRepository
+	IdentityRepo	identityRepository
+	WebhookRepo	webhookRemover
+	AuditRepo	auditRepository
+	BillRepo	userRedactor
+	PromoRepo	userRedactor
+	ReportRepo	resolverRedactor
+	Tokens		tokenRevoker
+	Subscriber	subscriber
+	Manager		manager
+	Mailer		mailSender
+	Guard		*LoginGuard
+	LinkURL		string
 	Logger		*log.Log
 }
 
@@ -20,14 +44,52 @@ type AuthServiceConfig struct {
 type AuthService struct {
 	authRepo	authRepository
 	subRepo		subRepository
+	linkRepo	linkRepository
+	tokenRepo	tokenRepository
+	recoveryRepo	recoveryRepository
+	identityRepo	identityRepository
+	webhookRepo	webhookRemover
+	auditRepo	auditRepository
+	billRepo	userRedactor
+	promoRepo	userRedactor
+	reportRepo	resolverRedactor
+	tokens		tokenRevoker
+	subscriber	subscriber
+	manager		manager
+	mailer		mailSender
+	guard		*LoginGuard
+	linkURL		string
 	logger		*log.Log
 }
 
+const (
+	VerifyTokenTTL	= time.Hour * 24	// Срок действия токена подтверждения почты
+	ResetTokenTTL	= time.Hour			// Срок действия токена сброса пароля
+)
+
+// dummyPassword Хеш, с которым сверяется пароль неизвестного пользователя (нулевая соль и ключ)
+const dummyPassword = "0000000000000000000000000000000000000000000000000000000000000000.0000000000000000000000000000000000000000000000000000000000000000"
+
 // Конструктор для AuthService
 func NewAuthService(c *AuthServiceConfig) *AuthService {
 	return &AuthService{
 		authRepo:	c.AuthRepo,
 		subRepo:	c.SubRepo,
+		linkRepo:	c.LinkRepo,
+		tokenRepo:	c.TokenRepo,
+		recoveryRepo:	c.RecoveryRepo,
+		identityRepo:	c.IdentityRepo,
+		webhookRepo:	c.WebhookRepo,
+		auditRepo:	c.AuditRepo,
+		billRepo:	c.BillRepo,
+		promoRepo:	c.PromoRepo,
+		reportRepo:	c.ReportRepo,
+		tokens:		c.Tokens,
+		subscriber:	c.Subscriber,
+		manager:	c.Manager,
+		mailer:		c.Mailer,
+		guard:		c.Guard,
+		linkURL:	c.LinkURL,
 		logger:		c.Logger,
 	}
 }
@@ -40,29 +102,49 @@ func (s *AuthService) SignInUserByName(ctx context.Context, dto models.SignInUse
 	l.Debug("SignInUserByName() started")
 	defer l.Debug("SignInUserByName() done")
 
+	// Проверяем, не заблокирован ли вход после серии неудачных попыток
+	keys := signInKeys(dto.Username, dto.IP)
+	if err := s.guard.check(ctx, keys); err != nil {
+		return dto, err
+	}
+
 	// Ищем зарегистрированного пользователя
 	u, err := s.authRepo.FindByUsername(ctx, dto.Username)
-	if err != nil {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		l.Errorf("Unable to find user. Error: %e", err)
 
 		return dto, err
 	}
+	known := err == nil
+
+	// Для неизвестного пользователя сверяем пароль с фиктивным хешем,
+	// чтобы время ответа не выдавало, зарегистрировано ли имя
+	stored := dummyPassword
+	if known {
+		stored = u.Password
+	}
 
 	// Сравниваем пароли пользователя
-	ok, err := security.ComparePasswords(u.Password, dto.Password)
+	ok, err := security.ComparePasswords(stored, dto.Password)
 	if err != nil {
 		l.Errorf("Unable to compare password. Error: %e", err)
 
 		return dto, err
 	}
-	if !ok {
-		return dto, errors.New("invalid password")
+	if !ok || !known {
+		s.guard.fail(ctx, keys)
+
+		return dto, errors.New("invalid credentials")
 	}
 
+	// Счетчик по IP не сбрасываем, иначе перебор чужих паролей можно было бы прерывать входом в свой аккаунт
+	s.guard.reset(ctx, signInKeys(dto.Username, ""))
+
 	// Проверяем, есть ли у пользователя подписка
-	_, ok = s.subRepo.FindSubscribe(ctx, dto.Username)
+	plan, _, ok := s.subRepo.FindSubPlan(ctx, dto.Username)
 	if ok {
 		dto.Subscribe	= 1
+		dto.Plan		= plan
 	} else {
 		dto.Subscribe	= 2
 	}
@@ -70,6 +152,8 @@ func (s *AuthService) SignInUserByName(ctx context.Context, dto models.SignInUse
 	// Мапим данные из db в dto структуру
 	dto.FirstName	= u.FirstName
 	dto.LastName	= u.LastName
+	dto.Role		= u.Role
+	dto.MFA			= u.TOTPEnabled
 	
 
 	return dto, nil
@@ -83,6 +167,10 @@ func (s *AuthService) SignUpUser(ctx context.Context, dto models.SignUpUserDTO)
 	l.Debug("SignUpUser() started")
 	defer l.Debug("SignUpUser() done")
 
+	if !validUsername.MatchString(dto.Username) {
+		return errors.New("invalid username")
+	}
+
 	// Проверяем, зарегистрирован ли пользователь
 	_, err := s.authRepo.FindByUsername(ctx, dto.Username)
 	if err != nil {
@@ -96,6 +184,13 @@ func (s *AuthService) SignUpUser(ctx context.Context, dto models.SignUpUserDTO)
 		return errors.New("user exist")
 	}
 
+	// Почта необязательна, но должна быть уникальной
+	if dto.Email != "" {
+		if err = s.checkEmail(ctx, dto.Email); err != nil {
+			return err
+		}
+	}
+
 	// Хешируем пароль
 	hashPassword, err := security.HashPassword(dto.Password)
 	if err != nil {
@@ -109,11 +204,596 @@ func (s *AuthService) SignUpUser(ctx context.Context, dto models.SignUpUserDTO)
 		FirstName:	dto.FirstName,
 		LastName:	dto.LastName,
 		Password:	hashPassword,
+		Email:		dto.Email,
 	})
 	if err != nil {
 		l.Errorf("Unable to create user. Error: %e", err)
 		return err
 	}
 
+	// Письмо с подтверждением не должно мешать регистрации
+	if dto.Email != "" {
+		if err = s.sendVerification(ctx, dto.Username, dto.Email); err != nil {
+			l.Errorf("Unable to send verification mail. Error: %s", err)
+		}
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	dto.Username,
+		Action:	models.AuditSignUp,
+		Target:	dto.Username,
+		Owner:	dto.Username,
+	})
+
+	return nil
+}
+
+// findUser Находит пользователя по имени
+func (s *AuthService) findUser(ctx context.Context, username string) (models.UserDB, error) {
+	u, err := s.authRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, errors.New("user not found")
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to find user. Error: %s", err)
+		return u, err
+	}
+
+	return u, nil
+}
+
+// GetProfile Возвращает профиль пользователя
+func (s *AuthService) GetProfile(ctx context.Context, username string) (models.UserDB, error) {
+	ctx = log.ContextWithSpan(ctx, "GetProfile")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("GetProfile() started")
+	defer l.Debug("GetProfile() done")
+
+	return s.findUser(ctx, username)
+}
+
+// UpdateProfile Меняет имя и фамилию пользователя
+func (s *AuthService) UpdateProfile(ctx context.Context, username string, dto models.UpdateUserDTO) (models.UserDB, error) {
+	ctx = log.ContextWithSpan(ctx, "UpdateProfile")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("UpdateProfile() started")
+	defer l.Debug("UpdateProfile() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return u, err
+	}
+
+	// Меняем только переданные поля
+	if dto.FirstName != "" {
+		u.FirstName = dto.FirstName
+	}
+	if dto.LastName != "" {
+		u.LastName = dto.LastName
+	}
+
+	// Новую почту нужно подтвердить заново
+	emailChanged := dto.Email != "" && dto.Email != u.Email
+	if emailChanged {
+		if err = s.checkEmail(ctx, dto.Email); err != nil {
+			return u, err
+		}
+		u.Email = dto.Email
+		u.EmailVerified = false
+	}
+
+	if err = s.authRepo.UpdateUser(ctx, u); err != nil {
+		l.Errorf("Unable to update user. Error: %s", err)
+		return u, err
+	}
+
+	// Ссылки для сброса пароля, отправленные на прежнюю почту, больше не нужны.
+	// Даже если отозвать их не удалось, ResetPassword не примет токен для прежней почты
+	if emailChanged && s.tokenRepo != nil {
+		if err = s.tokenRepo.RevokeTokens(ctx, username, models.TokenReset); err != nil {
+			l.Errorf("Unable to revoke reset tokens. Error: %s", err)
+		}
+	}
+
+	if emailChanged {
+		if err = s.sendVerification(ctx, username, u.Email); err != nil {
+			l.Errorf("Unable to send verification mail. Error: %s", err)
+		}
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	username,
+		Action:	models.AuditUserUpdate,
+		Target:	username,
+		Owner:	username,
+	})
+
+	return u, nil
+}
+
+// ChangePassword Меняет пароль пользователя после проверки старого
+func (s *AuthService) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) error {
+	ctx = log.ContextWithSpan(ctx, "ChangePassword")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ChangePassword() started")
+	defer l.Debug("ChangePassword() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	// Старый пароль подбирается с тем же ограничением попыток, что и при входе
+	keys := signInKeys(username, "")
+	if err = s.guard.check(ctx, keys); err != nil {
+		return err
+	}
+
+	// Сравниваем старый пароль с тем, что лежит в базе
+	ok, err := security.ComparePasswords(u.Password, oldPassword)
+	if err != nil {
+		l.Errorf("Unable to compare password. Error: %s", err)
+		return err
+	}
+	if !ok {
+		s.guard.fail(ctx, keys)
+
+		return errors.New("invalid password")
+	}
+	s.guard.reset(ctx, keys)
+
+	// Хешируем новый пароль
+	hashPassword, err := security.HashPassword(newPassword)
+	if err != nil {
+		l.Errorf("Unable to hash password. Error: %s", err)
+		return err
+	}
+
+	if err = s.authRepo.UpdatePassword(ctx, username, hashPassword); err != nil {
+		l.Errorf("Unable to update password. Error: %s", err)
+		return err
+	}
+
+	// Выданные токены доступа и ссылки для сброса пароля перестают действовать: если пароль меняют
+	// из-за утечки, старые сессии не должны пережить смену
+	if s.tokens != nil {
+		if err = s.tokens.RevokeUserTokens(ctx, username); err != nil {
+			l.Errorf("Unable to revoke access tokens. Error: %s", err)
+			return err
+		}
+	}
+	if s.tokenRepo != nil {
+		if err = s.tokenRepo.RevokeTokens(ctx, username, models.TokenReset); err != nil {
+			l.Errorf("Unable to revoke reset tokens. Error: %s", err)
+			return err
+		}
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	username,
+		Action:	models.AuditUserPassword,
+		Target:	username,
+		Owner:	username,
+	})
+
+	return nil
+}
+
+// reauthenticate Повторно проверяет пароль или, если включен второй фактор, код из приложения.
+// Нужна перед необратимыми действиями, которых не должно быть достаточно одного токена доступа
+func (s *AuthService) reauthenticate(ctx context.Context, u models.UserDB, password, code string) error {
+	l := s.logger.WithContext(ctx)
+
+	if code != "" && u.TOTPEnabled {
+		keys := mfaKeys(u.Username)
+		if err := s.guard.check(ctx, keys); err != nil {
+			return err
+		}
+
+		if err := useTOTP(ctx, s.authRepo, s.logger, u, code, time.Now()); err != nil {
+			if err.Error() == "invalid code" {
+				s.guard.fail(ctx, keys)
+			}
+
+			return err
+		}
+		s.guard.reset(ctx, keys)
+
+		return nil
+	}
+
+	if password == "" {
+		return errors.New("reauthentication required")
+	}
+
+	keys := signInKeys(u.Username, "")
+	if err := s.guard.check(ctx, keys); err != nil {
+		return err
+	}
+
+	ok, err := security.ComparePasswords(u.Password, password)
+	if err != nil {
+		l.Errorf("Unable to compare password. Error: %s", err)
+		return err
+	}
+	if !ok {
+		s.guard.fail(ctx, keys)
+
+		return errors.New("invalid password")
+	}
+	s.guard.reset(ctx, keys)
+
+	return nil
+}
+
+// DeleteAccount Удаляет пользователя вместе со всеми его ссылками, подпиской, счетами и задачами планировщика.
+// Требует пароль или код второго фактора. Выданные токены доступа отзываются, а записи, которые хранятся
+// дольше аккаунта (журнал аудита, счета, использования промокодов, решения по жалобам), обезличиваются
+func (s *AuthService) DeleteAccount(ctx context.Context, username, password, code string) error {
+	ctx = log.ContextWithSpan(ctx, "DeleteAccount")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("DeleteAccount() started")
+	defer l.Debug("DeleteAccount() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err = s.reauthenticate(ctx, u, password, code); err != nil {
+		return err
+	}
+
+	// Отзываем токены доступа сразу, чтобы параллельные запросы с ними перестали проходить
+	if s.tokens != nil {
+		if err = s.tokens.RevokeUserTokens(ctx, username); err != nil {
+			return err
+		}
+	}
+
+	// Отменяем неоплаченные счета и задачи планировщика, чтобы они не сработали после удаления
+	s.subscriber.CancelBills(ctx, username)
+	s.manager.RemoveUserJobs(ctx, username)
+
+	// Удаляем все ссылки пользователя
+	links, err := s.linkRepo.GetAllLinks(ctx, username)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		l.Errorf("Unable to get all links from Redis. Error: %s", err)
+		return err
+	}
+	for _, link := range links {
+		if err = s.linkRepo.DeleteLink(ctx, link.Link, username); err != nil {
+			l.Errorf("Unable to delete link %s from Redis. Error: %s", link.Link, err)
+			return err
+		}
+	}
+
+	// Удаляем список ссылок, сохраняемых после окончания подписки
+	if err = s.linkRepo.SetKeepLinks(ctx, username, nil); err != nil {
+		l.Errorf("Unable to delete keep links. Error: %s", err)
+		return err
+	}
+
+	// Удаляем подписку
+	if err = s.subRepo.RemoveSubscribe(ctx, username); err != nil {
+		l.Errorf("Unable to remove subscribe. Error: %s", err)
+		return err
+	}
+
+	// Удаляем резервные коды двухфакторной аутентификации
+	if s.recoveryRepo != nil {
+		if err = s.recoveryRepo.DeleteCodes(ctx, username); err != nil {
+			l.Errorf("Unable to delete recovery codes. Error: %s", err)
+			return err
+		}
+	}
+
+	// Удаляем привязки к внешним провайдерам, иначе они перейдут к новому пользователю с тем же именем
+	if s.identityRepo != nil {
+		if err = s.identityRepo.DeleteIdentities(ctx, username); err != nil {
+			l.Errorf("Unable to delete identities. Error: %s", err)
+			return err
+		}
+	}
+
+	// Удаляем вебхуки, чтобы события нового пользователя с тем же именем не ушли на чужие адреса
+	if s.webhookRepo != nil {
+		if err = s.webhookRepo.DeleteWebhooks(ctx, username); err != nil {
+			l.Errorf("Unable to delete webhooks. Error: %s", err)
+			return err
+		}
+	}
+
+	// Отзываем неиспользованные ссылки из писем: подтверждение почты и сброс пароля
+	if s.tokenRepo != nil {
+		for _, kind := range []string{models.TokenVerify, models.TokenReset} {
+			if err = s.tokenRepo.RevokeTokens(ctx, username, kind); err != nil {
+				l.Errorf("Unable to revoke %s tokens. Error: %s", kind, err)
+				return err
+			}
+		}
+	}
+
+	// Журнал аудита, счета, использования промокодов и решения по жалобам сохраняются,
+	// но имя пользователя в них заменяется псевдонимом по номеру аккаунта
+	pseudonym := deletedUser(u.ID)
+	if s.auditRepo != nil {
+		if err = s.auditRepo.RedactUser(ctx, username, pseudonym); err != nil {
+			l.Errorf("Unable to redact audit log. Error: %s", err)
+			return err
+		}
+	}
+	if s.billRepo != nil {
+		if err = s.billRepo.RedactUser(ctx, username, pseudonym); err != nil {
+			l.Errorf("Unable to redact bills. Error: %s", err)
+			return err
+		}
+	}
+	if s.promoRepo != nil {
+		if err = s.promoRepo.RedactUser(ctx, username, pseudonym); err != nil {
+			l.Errorf("Unable to redact promo redemptions. Error: %s", err)
+			return err
+		}
+	}
+	if s.reportRepo != nil {
+		if err = s.reportRepo.RedactResolver(ctx, username, pseudonym); err != nil {
+			l.Errorf("Unable to redact reports. Error: %s", err)
+			return err
+		}
+	}
+
+	// Удаляем пользователя последним, чтобы при сбое удаление можно было повторить
+	if err = s.authRepo.DeleteUser(ctx, username); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("user not found")
+		}
+
+		l.Errorf("Unable to delete user. Error: %s", err)
+		return err
+	}
+
+	l.Infof("user %s deleted account with %d links", username, len(links))
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		pseudonym,
+		Action:		models.AuditUserDelete,
+		Target:		pseudonym,
+		Owner:		pseudonym,
+		Details:	fmt.Sprintf("links: %d", len(links)),
+	})
+
+	return nil
+}
+
+// deletedUser Псевдоним удаленного пользователя в журнале аудита. Содержит двоеточие,
+// поэтому не совпадает ни с одним именем, которое можно зарегистрировать
+func deletedUser(id string) string {
+	return "deleted:" + id
+}
+
+// checkEmail Проверяет, что почта не занята другим пользователем
+func (s *AuthService) checkEmail(ctx context.Context, email string) error {
+	_, err := s.authRepo.FindByEmail(ctx, email)
+	if err == nil {
+		return errors.New("email exist")
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		s.logger.WithContext(ctx).Errorf("Unable to find user by email. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// issueToken Выпускает одноразовый токен, отменяя предыдущие токены того же вида
+func (s *AuthService) issueToken(ctx context.Context, username, email, kind string, ttl time.Duration) (string, error) {
+	if err := s.tokenRepo.RevokeTokens(ctx, username, kind); err != nil {
+		return "", err
+	}
+
+	secret, err := security.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokenRepo.CreateToken(ctx, models.UserTokenDB{
+		Username:	username,
+		Kind:		kind,
+		Hash:		security.HashSecret(secret),
+		Email:		email,
+		ExpiresAt:	time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// useToken Погашает одноразовый токен
+func (s *AuthService) useToken(ctx context.Context, kind, secret string) (models.UserTokenDB, error) {
+	token, err := s.tokenRepo.UseToken(ctx, kind, security.HashSecret(secret))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return token, errors.New("invalid token")
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to use token. Error: %s", err)
+		return token, err
+	}
+
+	return token, nil
+}
+
+// sendVerification Отправляет письмо со ссылкой для подтверждения почты
+func (s *AuthService) sendVerification(ctx context.Context, username, email string) error {
+	secret, err := s.issueToken(ctx, username, email, models.TokenVerify, VerifyTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, models.Mail{
+		To:			email,
+		Subject:	"Подтверждение почты",
+		Body:		fmt.Sprintf("Чтобы подтвердить почту для аккаунта %s, перейдите по ссылке:\n%s/v1/email/verify?token=%s\n\nСсылка действует %v.\n",
+			username, s.linkURL, secret, VerifyTokenTTL),
+	})
+}
+
+// ResendVerification Повторно отправляет письмо для подтверждения почты
+func (s *AuthService) ResendVerification(ctx context.Context, username string) error {
+	ctx = log.ContextWithSpan(ctx, "ResendVerification")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ResendVerification() started")
+	defer l.Debug("ResendVerification() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if u.Email == "" {
+		return errors.New("email not set")
+	}
+	if u.EmailVerified {
+		return errors.New("email already verified")
+	}
+
+	if err = s.sendVerification(ctx, username, u.Email); err != nil {
+		l.Errorf("Unable to send verification mail. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// VerifyEmail Подтверждает почту по токену из письма
+func (s *AuthService) VerifyEmail(ctx context.Context, secret string) error {
+	ctx = log.ContextWithSpan(ctx, "VerifyEmail")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("VerifyEmail() started")
+	defer l.Debug("VerifyEmail() done")
+
+	token, err := s.useToken(ctx, models.TokenVerify, secret)
+	if err != nil {
+		return err
+	}
+
+	// Почта могла смениться после отправки письма, тогда токен недействителен
+	if err = s.authRepo.VerifyEmail(ctx, token.Username, token.Email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("invalid token")
+		}
+
+		l.Errorf("Unable to verify email. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ForgotPassword Отправляет письмо со ссылкой для сброса пароля.
+// Чтобы не раскрывать, зарегистрирована ли почта, ошибки только записываются в лог, а вызывающему всегда возвращается nil
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	ctx = log.ContextWithSpan(ctx, "ForgotPassword")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ForgotPassword() started")
+	defer l.Debug("ForgotPassword() done")
+
+	u, err := s.authRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			l.Info("password reset requested for unknown email")
+			return nil
+		}
+
+		l.Errorf("Unable to find user by email. Error: %s", err)
+		return nil
+	}
+
+	// Сбрасывать пароль можно только через подтвержденную почту
+	if !u.EmailVerified {
+		l.Infof("password reset requested for unverified email of user %s", u.Username)
+		return nil
+	}
+
+	secret, err := s.issueToken(ctx, u.Username, u.Email, models.TokenReset, ResetTokenTTL)
+	if err != nil {
+		l.Errorf("Unable to issue reset token. Error: %s", err)
+		return nil
+	}
+
+	err = s.mailer.Send(ctx, models.Mail{
+		To:			u.Email,
+		Subject:	"Сброс пароля",
+		Body:		fmt.Sprintf("Для аккаунта %s запрошен сброс пароля. Код для сброса:\n%s\n\nКод действует %v. Если вы не запрашивали сброс, проигнорируйте письмо.\n",
+			u.Username, secret, ResetTokenTTL),
+	})
+	if err != nil {
+		l.Errorf("Unable to send reset mail. Error: %s", err)
+		return nil
+	}
+
+	return nil
+}
+
+// ResetPassword Устанавливает новый пароль по токену из письма
+func (s *AuthService) ResetPassword(ctx context.Context, secret, newPassword string) error {
+	ctx = log.ContextWithSpan(ctx, "ResetPassword")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ResetPassword() started")
+	defer l.Debug("ResetPassword() done")
+
+	token, err := s.useToken(ctx, models.TokenReset, secret)
+	if err != nil {
+		return err
+	}
+
+	// Письмо могло уйти на прежнюю почту: после смены почты токен недействителен
+	u, err := s.authRepo.FindByUsername(ctx, token.Username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("invalid token")
+		}
+
+		l.Errorf("Unable to find user. Error: %s", err)
+		return err
+	}
+	if !u.EmailVerified || !strings.EqualFold(u.Email, token.Email) {
+		return errors.New("invalid token")
+	}
+
+	// Хешируем новый пароль
+	hashPassword, err := security.HashPassword(newPassword)
+	if err != nil {
+		l.Errorf("Unable to hash password. Error: %s", err)
+		return err
+	}
+
+	if err = s.authRepo.UpdatePassword(ctx, token.Username, hashPassword); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("invalid token")
+		}
+
+		l.Errorf("Unable to update password. Error: %s", err)
+		return err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		token.Username,
+		Action:		models.AuditUserPassword,
+		Target:		token.Username,
+		Owner:		token.Username,
+		Details:	"reset by email",
+	})
+
 	return nil
 }
@@ -1 +1,515 @@
 package services
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	"short_url/internal/mailer"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeAuthRepo Хранилище пользователей в памяти для тестов сервиса
+type fakeAuthRepo struct {
+	users map[string]models.UserDB
+}
+
+func newFakeAuthRepo(users ...models.UserDB) *fakeAuthRepo {
+	r := &fakeAuthRepo{users: make(map[string]models.UserDB)}
+	for _, u := range users {
+		r.users[u.Username] = u
+	}
+	return r
+}
+
+func (r *fakeAuthRepo) FindByUsername(ctx context.Context, username string) (models.UserDB, error) {
+	u, ok := r.users[username]
+	if !ok {
+		return u, pgx.ErrNoRows
+	}
+	return u, nil
+}
+
+func (r *fakeAuthRepo) FindByEmail(ctx context.Context, email string) (models.UserDB, error) {
+	for _, u := range r.users {
+		if u.Email != "" && strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return models.UserDB{}, pgx.ErrNoRows
+}
+
+func (r *fakeAuthRepo) VerifyEmail(ctx context.Context, username, email string) error {
+	u, ok := r.users[username]
+	if !ok || u.Email != email {
+		return pgx.ErrNoRows
+	}
+	u.EmailVerified = true
+	r.users[username] = u
+	return nil
+}
+
+func (r *fakeAuthRepo) SetTOTP(ctx context.Context, username, secret string, enabled bool) error {
+	u, ok := r.users[username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	u.TOTPSecret = secret
+	u.TOTPEnabled = enabled
+	r.users[username] = u
+	return nil
+}
+
+func (r *fakeAuthRepo) UseTOTPStep(ctx context.Context, username string, step int64) error {
+	u, ok := r.users[username]
+	if !ok || u.TOTPStep >= step {
+		return pgx.ErrNoRows
+	}
+	u.TOTPStep = step
+	r.users[username] = u
+	return nil
+}
+
+func (r *fakeAuthRepo) CreateUser(ctx context.Context, user models.UserDB) error {
+	r.users[user.Username] = user
+	return nil
+}
+
+func (r *fakeAuthRepo) SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error) {
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) UpdateUser(ctx context.Context, user models.UserDB) error {
+	if _, ok := r.users[user.Username]; !ok {
+		return pgx.ErrNoRows
+	}
+	r.users[user.Username] = user
+	return nil
+}
+
+func (r *fakeAuthRepo) UpdatePassword(ctx context.Context, username, password string) error {
+	u, ok := r.users[username]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	u.Password = password
+	r.users[username] = u
+	return nil
+}
+
+func (r *fakeAuthRepo) DeleteUser(ctx context.Context, username string) error {
+	if _, ok := r.users[username]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.users, username)
+	return nil
+}
+
+// fakeTokenRepo Хранилище одноразовых токенов в памяти для тестов сервиса
+type fakeTokenRepo struct {
+	tokens	map[string]models.UserTokenDB
+	used	map[string]bool
+}
+
+func newFakeTokenRepo() *fakeTokenRepo {
+	return &fakeTokenRepo{tokens: make(map[string]models.UserTokenDB), used: make(map[string]bool)}
+}
+
+func (r *fakeTokenRepo) CreateToken(ctx context.Context, token models.UserTokenDB) error {
+	r.tokens[token.Hash] = token
+	return nil
+}
+
+func (r *fakeTokenRepo) UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error) {
+	t, ok := r.tokens[hash]
+	if !ok || t.Kind != kind || r.used[hash] || time.Now().After(t.ExpiresAt) {
+		return models.UserTokenDB{}, pgx.ErrNoRows
+	}
+	r.used[hash] = true
+	return t, nil
+}
+
+func (r *fakeTokenRepo) RevokeTokens(ctx context.Context, username, kind string) error {
+	for hash, t := range r.tokens {
+		if t.Username == username && t.Kind == kind {
+			r.used[hash] = true
+		}
+	}
+	return nil
+}
+
+// fakeSubRepo Хранилище подписок в памяти для тестов сервиса
+type fakeSubRepo struct {
+	subs	map[string]time.Duration
+	plans	map[string]string
+}
+
+func (r *fakeSubRepo) FindSubscribe(ctx context.Context, username string) (time.Duration, bool) {
+	exp, ok := r.subs[username]
+	return exp, ok
+}
+
+func (r *fakeSubRepo) FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool) {
+	exp, ok := r.subs[username]
+	return r.plans[username], exp, ok
+}
+
+func (r *fakeSubRepo) AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error {
+	if r.plans == nil {
+		r.plans = make(map[string]string)
+	}
+	r.subs[username] = exp
+	r.plans[username] = plan
+	return nil
+}
+
+func (r *fakeSubRepo) RemoveSubscribe(ctx context.Context, username string) error {
+	delete(r.subs, username)
+	delete(r.plans, username)
+	return nil
+}
+
+// fakeManager Планировщик, запоминающий вызовы, для тестов сервиса
+type fakeManager struct {
+	removed		[]string
+	jobs		[]models.SchedJob
+	overQuota	map[string][]string
+}
+
+func (m *fakeManager) CleanUnsubscribeSchedule(ctx context.Context, sub models.CurrentSub, username string) error {
+	return nil
+}
+
+func (m *fakeManager) RemoveCleanSchedule(ctx context.Context, username string) {}
+
+func (m *fakeManager) RemoveUserJobs(ctx context.Context, username string) {
+	m.removed = append(m.removed, username)
+}
+
+func (m *fakeManager) Jobs(ctx context.Context) []models.SchedJob {
+	return m.jobs
+}
+
+func (m *fakeManager) OverQuota(ctx context.Context, username string) ([]string, error) {
+	return m.overQuota[username], nil
+}
+
+// fakeSubscriber Сервис подписок, запоминающий отмененные счета, для тестов
+type fakeSubscriber struct {
+	canceled []string
+}
+
+func (s *fakeSubscriber) AddSubscribe(ctx context.Context, info models.SubInfo) error {
+	return nil
+}
+
+func (s *fakeSubscriber) PendingBills(ctx context.Context) []models.BillInfo {
+	return nil
+}
+
+func (s *fakeSubscriber) CancelBills(ctx context.Context, username string) {
+	s.canceled = append(s.canceled, username)
+}
+
+func TestAuthServiceChangePassword(t *testing.T) {
+	hash, err := security.HashPassword("secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := newFakeAuthRepo(models.UserDB{Username: "alice", Password: hash})
+	tokens := newFakeTokenRepo()
+	tokens.CreateToken(context.Background(), models.UserTokenDB{Hash: "h1", Username: "alice", Kind: models.TokenReset, ExpiresAt: time.Now().Add(time.Hour)})
+	attempts := newFakeAttemptRepo()
+	revoker := &fakeTokenRevoker{}
+	logger := &log.Log{Logger: zap.NewNop()}
+	s := NewAuthService(&AuthServiceConfig{
+		AuthRepo:	users,
+		TokenRepo:	tokens,
+		Tokens:		revoker,
+		Guard:		NewLoginGuard(&LoginGuardConfig{AttemptRepo: attempts, Logger: logger}),
+		Logger:		logger,
+	})
+	ctx := context.Background()
+
+	if err = s.ChangePassword(ctx, "alice", "wrong", "secret2"); err == nil || err.Error() != "invalid password" {
+		t.Fatalf("expected invalid password, got %v", err)
+	}
+	if attempts.fails["user:alice"] != 1 || len(revoker.revoked) != 0 {
+		t.Fatalf("failed attempt must be counted, got %v", attempts.fails)
+	}
+
+	if err = s.ChangePassword(ctx, "alice", "secret1", "secret2"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ok, err := security.ComparePasswords(users.users["alice"].Password, "secret2")
+	if err != nil || !ok {
+		t.Fatalf("new password must be stored, ok=%v err=%v", ok, err)
+	}
+
+	// Смена пароля отзывает токены доступа и ссылки для сброса пароля
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != "alice" {
+		t.Fatalf("access tokens must be revoked, got %v", revoker.revoked)
+	}
+	if _, err = tokens.UseToken(ctx, models.TokenReset, "h1"); err == nil {
+		t.Fatal("reset token must be revoked")
+	}
+
+	// Подбор старого пароля по украденному токену ограничен так же, как вход
+	for i := int64(0); i < userPolicy.delayAfter; i++ {
+		s.ChangePassword(ctx, "alice", "wrong", "secret3")
+	}
+	err = s.ChangePassword(ctx, "alice", "secret2", "secret3")
+	var lock *models.LockoutError
+	if !errors.As(err, &lock) {
+		t.Fatalf("expected lockout, got %v", err)
+	}
+}
+
+// fakeTokenRevoker Запоминает пользователей, чьи токены доступа отозваны
+type fakeTokenRevoker struct {
+	revoked []string
+}
+
+func (r *fakeTokenRevoker) RevokeUserTokens(ctx context.Context, username string) error {
+	r.revoked = append(r.revoked, username)
+	return nil
+}
+
+func TestAuthServiceDeleteAccount(t *testing.T) {
+	password, _ := security.HashPassword("secret1")
+	secret, _ := security.GenerateTOTPSecret()
+	users := newFakeAuthRepo(
+		models.UserDB{ID: "7", Username: "alice", Password: password},
+		models.UserDB{ID: "8", Username: "bob", Password: password, TOTPSecret: secret, TOTPEnabled: true},
+	)
+	links := newFakeLinkRepo(
+		models.LinkDataDB{Link: "a1", Owner: "alice"},
+		models.LinkDataDB{Link: "a2", Owner: "alice"},
+		models.LinkDataDB{Link: "b1", Owner: "bob"},
+	)
+	links.SetKeepLinks(context.Background(), "alice", []string{"a1"})
+	subs := &fakeSubRepo{subs: map[string]time.Duration{"alice": time.Hour, "bob": time.Hour}}
+	m := &fakeManager{}
+	bills := &fakeSubscriber{}
+	audit := &fakeAuditRepo{entries: []models.AuditEntry{
+		{Actor: "alice", Action: models.AuditLinkCreate, Target: "a1", Owner: "alice", Details: "https://example.com"},
+		{Actor: "admin", Action: models.AuditSubGrant, Target: "alice", Owner: "alice", Details: "days: 7, note: promo"},
+	}}
+	tokens := newFakeTokenRepo()
+	tokens.CreateToken(context.Background(), models.UserTokenDB{Hash: "h1", Username: "alice", Kind: models.TokenReset, ExpiresAt: time.Now().Add(time.Hour)})
+	billRepo := &fakeBillRepo{bills: []models.BillDB{{ID: "b1", Username: "alice"}, {ID: "b2", Username: "bob"}}}
+	promos := newFakePromoRepo()
+	promos.uses["SALE/alice"] = true
+	reports := &fakeReportRepo{reports: []models.ReportDB{{ID: 1, Link: "x", ResolvedBy: "alice"}}}
+	revoker := &fakeTokenRevoker{}
+	s := NewAuthService(&AuthServiceConfig{
+		AuthRepo:	users,
+		SubRepo:	subs,
+		LinkRepo:	links,
+		TokenRepo:	tokens,
+		AuditRepo:	audit,
+		BillRepo:	billRepo,
+		PromoRepo:	promos,
+		ReportRepo:	reports,
+		Tokens:		revoker,
+		Subscriber:	bills,
+		Manager:	m,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	// Одного токена доступа для удаления недостаточно
+	if err := s.DeleteAccount(ctx, "alice", "", ""); err == nil || err.Error() != "reauthentication required" {
+		t.Fatalf("expected reauthentication required, got %v", err)
+	}
+	if err := s.DeleteAccount(ctx, "alice", "wrong", ""); err == nil || err.Error() != "invalid password" {
+		t.Fatalf("expected invalid password, got %v", err)
+	}
+	if _, ok := users.users["alice"]; !ok || len(revoker.revoked) != 0 {
+		t.Fatal("user must be kept after failed reauthentication")
+	}
+
+	if err := s.DeleteAccount(ctx, "alice", "secret1", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Журнал сохраняет записи, но имя пользователя в нем заменено псевдонимом
+	if entries, _ := audit.FindEntries(ctx, models.AuditFilter{Owner: "alice"}); len(entries) != 0 {
+		t.Fatalf("audit must not keep the deleted username, got %+v", entries)
+	}
+	entries, _ := audit.FindEntries(ctx, models.AuditFilter{Owner: "deleted:7"})
+	if len(entries) != 3 || entries[0].Details != "" || entries[1].Target != "deleted:7" || entries[1].Details == "" || entries[2].Action != models.AuditUserDelete {
+		t.Fatalf("unexpected redacted audit: %+v", entries)
+	}
+
+	// Счета, использования промокодов и решения по жалобам остаются под псевдонимом
+	if billRepo.bills[0].Username != "deleted:7" || billRepo.bills[1].Username != "bob" {
+		t.Fatalf("unexpected redacted bills: %+v", billRepo.bills)
+	}
+	if promos.uses["SALE/alice"] || !promos.uses["SALE/deleted:7"] {
+		t.Fatalf("unexpected redacted redemptions: %v", promos.uses)
+	}
+	if reports.reports[0].ResolvedBy != "deleted:7" {
+		t.Fatalf("unexpected redacted report: %+v", reports.reports[0])
+	}
+
+	// Токены доступа и ссылки из писем больше не действуют
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != "alice" {
+		t.Fatalf("access tokens must be revoked, got %v", revoker.revoked)
+	}
+	if _, err := tokens.UseToken(ctx, models.TokenReset, "h1"); err == nil {
+		t.Fatal("reset token must be revoked")
+	}
+
+	if _, ok := users.users["alice"]; ok {
+		t.Fatal("user must be deleted")
+	}
+	if _, ok := links.links["a1"]; ok {
+		t.Fatal("user links must be deleted")
+	}
+	if len(links.keep["alice"]) != 0 {
+		t.Fatalf("keep links must be deleted, got %v", links.keep["alice"])
+	}
+	if _, ok := links.links["b1"]; !ok {
+		t.Fatal("links of other users must be kept")
+	}
+	if _, ok := subs.subs["alice"]; ok {
+		t.Fatal("subscribe must be removed")
+	}
+	if len(m.removed) != 1 || len(bills.canceled) != 1 {
+		t.Fatalf("jobs and bills must be canceled, jobs: %v, bills: %v", m.removed, bills.canceled)
+	}
+
+	if err := s.DeleteAccount(ctx, "alice", "secret1", ""); err == nil || err.Error() != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+
+	// При включенном втором факторе подходит код из приложения, но только один раз
+	code, _ := security.TOTPCode(secret, time.Now())
+	users.users["bob"] = models.UserDB{ID: "8", Username: "bob", TOTPSecret: secret, TOTPEnabled: true, TOTPStep: time.Now().Unix() / 30}
+	if err := s.DeleteAccount(ctx, "bob", "", code); err == nil || err.Error() != "invalid code" {
+		t.Fatalf("expected used code to be rejected, got %v", err)
+	}
+	users.users["bob"] = models.UserDB{ID: "8", Username: "bob", TOTPSecret: secret, TOTPEnabled: true}
+	if err := s.DeleteAccount(ctx, "bob", "", code); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := users.users["bob"]; ok {
+		t.Fatal("user must be deleted by totp code")
+	}
+}
+
+// tokenFromMail Достает токен из текста письма
+func tokenFromMail(t *testing.T, mail models.Mail) string {
+	for _, word := range strings.Fields(mail.Body) {
+		if i := strings.Index(word, "token="); i >= 0 {
+			return word[i+len("token="):]
+		}
+		if len(word) == 64 {
+			return word
+		}
+	}
+	t.Fatalf("token not found in mail %q", mail.Body)
+	return ""
+}
+
+func TestAuthServicePasswordReset(t *testing.T) {
+	users := newFakeAuthRepo()
+	tokens := newFakeTokenRepo()
+	mails := mailer.NewMemoryMailer()
+	s := NewAuthService(&AuthServiceConfig{
+		AuthRepo:	users,
+		TokenRepo:	tokens,
+		Mailer:		mails,
+		LinkURL:	"http://short",
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	err := s.SignUpUser(ctx, models.SignUpUserDTO{Username: "alice", Password: "secret1", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err = s.SignUpUser(ctx, models.SignUpUserDTO{Username: "bob", Password: "secret1", Email: "ALICE@example.com"}); err == nil || err.Error() != "email exist" {
+		t.Fatalf("expected email exist, got %v", err)
+	}
+
+	// Псевдонимы удаленных пользователей зарегистрировать нельзя
+	for _, name := range []string{"deleted:42", "bob smith", ""} {
+		if err = s.SignUpUser(ctx, models.SignUpUserDTO{Username: name, Password: "secret1"}); err == nil || err.Error() != "invalid username" {
+			t.Fatalf("%q: expected invalid username, got %v", name, err)
+		}
+	}
+
+	// Пока почта не подтверждена, письмо для сброса не отправляется
+	if err = s.ForgotPassword(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := len(mails.Mails()); n != 1 {
+		t.Fatalf("expected only verification mail, got %d mails", n)
+	}
+
+	if err = s.VerifyEmail(ctx, tokenFromMail(t, mails.Mails()[0])); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !users.users["alice"].EmailVerified {
+		t.Fatal("email must be verified")
+	}
+
+	// Неизвестная почта не раскрывается
+	if err = s.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email must not return error, got %s", err)
+	}
+
+	if err = s.ForgotPassword(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := len(mails.Mails()); n != 2 {
+		t.Fatalf("expected reset mail, got %d mails", n)
+	}
+	secret := tokenFromMail(t, mails.Mails()[1])
+
+	if err = s.ResetPassword(ctx, secret, "secret2"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ok, err := security.ComparePasswords(users.users["alice"].Password, "secret2")
+	if err != nil || !ok {
+		t.Fatalf("new password must be stored, ok=%v err=%v", ok, err)
+	}
+
+	// Токен одноразовый
+	if err = s.ResetPassword(ctx, secret, "secret3"); err == nil || err.Error() != "invalid token" {
+		t.Fatalf("expected invalid token on reuse, got %v", err)
+	}
+
+	// Токен, отправленный на прежнюю почту, после смены почты не действует
+	s.ForgotPassword(ctx, "alice@example.com")
+	secret = tokenFromMail(t, mails.Mails()[len(mails.Mails())-1])
+	alice := users.users["alice"]
+	alice.Email = "new@example.com"
+	users.users["alice"] = alice
+	if err = s.ResetPassword(ctx, secret, "secret3"); err == nil || err.Error() != "invalid token" {
+		t.Fatalf("expected invalid token after email change, got %v", err)
+	}
+
+	// Ошибка отправки письма не раскрывается вызывающему
+	s.mailer = failingMailer{}
+	alice.Email = "alice@example.com"
+	users.users["alice"] = alice
+	if err = s.ForgotPassword(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("mailer error must not be returned, got %s", err)
+	}
+}
+
+// failingMailer Отправитель, который всегда возвращает ошибку
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, mail models.Mail) error {
+	return errors.New("smtp unavailable")
+}
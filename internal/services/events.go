@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/events"
+)
+
+// publish Передает событие в шину, если она подключена
+func publish(ctx context.Context, bus eventBus, ev events.Event) error {
+	if bus == nil {
+		return nil
+	}
+
+	return bus.Publish(ctx, ev)
+}
@@ -3,33 +3,215 @@ package services
 import (
 	"time"
 	"context"
+	"short_url/internal/events"
 	"short_url/internal/models"
 )
 
 // authRepository Интерфейс к репозиторию хранения данных пользователей
 type authRepository interface {
 	FindByUsername(ctx context.Context, username string) (models.UserDB, error)
+	FindByEmail(ctx context.Context, email string) (models.UserDB, error)
 	CreateUser(ctx context.Context, user models.UserDB) error
+	SearchUsers(ctx context.Context, search string, limit int) ([]models.UserDB, error)
+	UpdateUser(ctx context.Context, user models.UserDB) error
+	UpdatePassword(ctx context.Context, username, password string) error
+	VerifyEmail(ctx context.Context, username, email string) error
+	SetTOTP(ctx context.Context, username, secret string, enabled bool) error
+	UseTOTPStep(ctx context.Context, username string, step int64) error
+	DeleteUser(ctx context.Context, username string) error
+}
+
+// tokenRepository Интерфейс к репозиторию одноразовых токенов пользователей
+type tokenRepository interface {
+	CreateToken(ctx context.Context, token models.UserTokenDB) error
+	UseToken(ctx context.Context, kind, hash string) (models.UserTokenDB, error)
+	RevokeTokens(ctx context.Context, username, kind string) error
+}
+
+// revocationRepository Интерфейс к хранилищу моментов отзыва токенов доступа
+type revocationRepository interface {
+	Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error
+	RevokedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+// tokenRevoker Интерфейс к сервису токенов для отзыва уже выданных токенов доступа
+type tokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, username string) error
+}
+
+// recoveryRepository Интерфейс к репозиторию резервных кодов двухфакторной аутентификации
+type recoveryRepository interface {
+	ReplaceCodes(ctx context.Context, username string, hashes []string) error
+	UseCode(ctx context.Context, username, hash string) error
+	DeleteCodes(ctx context.Context, username string) error
+}
+
+// identityRepository Интерфейс к репозиторию привязок к внешним провайдерам
+type identityRepository interface {
+	FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error)
+	CreateIdentity(ctx context.Context, identity models.IdentityDB) error
+	DeleteIdentities(ctx context.Context, username string) error
+}
+
+// oidcStateRepository Интерфейс к хранилищу начатых входов через провайдера
+type oidcStateRepository interface {
+	SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error
+	TakeState(ctx context.Context, state string) (models.OIDCState, error)
+}
+
+// oidcProvider Интерфейс к клиенту провайдера OpenID Connect
+type oidcProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (models.OIDCClaims, error)
+}
+
+// mailSender Интерфейс к отправителю писем
+type mailSender interface {
+	Send(ctx context.Context, mail models.Mail) error
 }
 
 // linkRepository Интерфейс к репозиторию управления ссылками
 type linkRepository interface {
-	CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool) (models.LinkDataDB, error)
+	CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error)
 	DeleteLink(ctx context.Context, link, username string) error
 	FindLink(ctx context.Context, link string) (models.LinkDataDB, error)
 	CountLinks(ctx context.Context, username string) (models.LinksAmount, error)
 	GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error)
+	DisableLink(ctx context.Context, link, reason string) error
+	EnableLink(ctx context.Context, link string) error
+	KeepLinks(ctx context.Context, username string) ([]string, error)
+	SetKeepLinks(ctx context.Context, username string, links []string) error
+}
+
+// attemptRepository Интерфейс к счетчикам неудачных попыток входа
+type attemptRepository interface {
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	AddFail(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, d time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+// planCatalog Интерфейс к каталогу тарифных планов
+type planCatalog interface {
+	All() []models.Plan
+	Find(name string) (models.Plan, bool)
+	Free() models.Plan
+	Subscribed(name string) models.Plan
 }
 
 // subRepository Интерфейс к слою репозитория подписок Redis
 type subRepository interface {
 	FindSubscribe(ctx context.Context, username string) (time.Duration, bool)
-	AddSubRedis(ctx context.Context, username string, exp time.Duration) error
+	FindSubPlan(ctx context.Context, username string) (string, time.Duration, bool)
+	AddSubRedis(ctx context.Context, username, plan string, exp time.Duration) error
+	RemoveSubscribe(ctx context.Context, username string) error
+}
+
+// subscriptionRepository Интерфейс к репозиторию со сведениями о действующей подписке
+type subscriptionRepository interface {
+	FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool)
+	SubState(ctx context.Context, username string) (string, bool, error)
+}
+
+// billRepository Интерфейс к репозиторию истории счетов
+type billRepository interface {
+	CreateBill(ctx context.Context, bill models.BillDB) error
+	UpdateBillStatus(ctx context.Context, id, status string) error
+	FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error)
+	FindBill(ctx context.Context, id string) (models.BillDB, error)
+	FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error)
+}
+
+// qiwiEventRepository Интерфейс к репозиторию обработанных счетов QIWI
+type qiwiEventRepository interface {
+	MarkProcessed(ctx context.Context, billID, status string) (bool, error)
+	UnmarkProcessed(ctx context.Context, billID string) error
+}
+
+// webhookRepository Интерфейс к репозиторию вебхуков и журнала доставок
+type webhookRepository interface {
+	CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error)
+	FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error)
+	FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error)
+	DeleteWebhook(ctx context.Context, id int64, username string) error
+	DeleteWebhooks(ctx context.Context, username string) error
+	CreateDelivery(ctx context.Context, d models.DeliveryDB) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error)
+	UpdateDelivery(ctx context.Context, d models.DeliveryDB) error
+	FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error)
+	Redeliver(ctx context.Context, id int64, username string) error
+}
+
+// webhookRemover Интерфейс к репозиторию вебхуков для удаления аккаунта
+type webhookRemover interface {
+	DeleteWebhooks(ctx context.Context, username string) error
+}
+
+// userRedactor Интерфейс к репозиторию, в котором имя удаленного пользователя заменяется псевдонимом
+type userRedactor interface {
+	RedactUser(ctx context.Context, username, pseudonym string) error
+}
+
+// resolverRedactor Интерфейс к репозиторию жалоб для удаления аккаунта администратора
+type resolverRedactor interface {
+	RedactResolver(ctx context.Context, username, pseudonym string) error
+}
+
+// eventBus Интерфейс к шине событий
+type eventBus interface {
+	Publish(ctx context.Context, ev events.Event) error
+}
+
+// leaderElector Интерфейс к выбору ведущего экземпляра для фоновых задач
+type leaderElector interface {
+	IsLeader() bool
+}
+
+// promoRepository Интерфейс к репозиторию промокодов
+type promoRepository interface {
+	CreatePromo(ctx context.Context, p models.PromoDB) error
+	FindPromo(ctx context.Context, code string) (models.PromoDB, error)
+	FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error)
+	DeletePromo(ctx context.Context, code string) error
+	Redeemed(ctx context.Context, code, username string) (bool, error)
+	UsePromo(ctx context.Context, code, username string) (models.PromoDB, error)
+	ReleasePromo(ctx context.Context, code, username string) error
+}
+
+// promoUser Интерфейс к сервису промокодов для списания использований
+type promoUser interface {
+	UsePromo(ctx context.Context, code, username string, accept func(models.PromoDB) bool) (models.PromoDB, error)
+	ReleasePromo(ctx context.Context, code, username string)
 }
 
 // manager Интерфейс к планировщику задач
 type manager interface {
 	CleanUnsubscribeSchedule(ctx context.Context, sub models.CurrentSub, username string) error
-	CleaningExpLinkSchedule(ctx context.Context, link, username string, exp time.Duration) error
 	RemoveCleanSchedule(ctx context.Context, username string)
+	RemoveUserJobs(ctx context.Context, username string)
+	Jobs(ctx context.Context) []models.SchedJob
+	OverQuota(ctx context.Context, username string) ([]string, error)
+}
+
+// reportRepository Интерфейс к репозиторию жалоб на ссылки
+type reportRepository interface {
+	CreateReport(ctx context.Context, report models.ReportDB) (int64, error)
+	FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error)
+	FindReport(ctx context.Context, id int64) (models.ReportDB, error)
+	ResolveReports(ctx context.Context, link, status, admin string) error
+}
+
+// auditRepository Интерфейс к журналу аудита
+type auditRepository interface {
+	AppendEntry(ctx context.Context, entry models.AuditEntry) error
+	RedactUser(ctx context.Context, username, pseudonym string) error
+	FindEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// subscriber Интерфейс к сервису, оформляющему подписки пользователей
+type subscriber interface {
+	AddSubscribe(ctx context.Context, info models.SubInfo) error
+	PendingBills(ctx context.Context) []models.BillInfo
+	CancelBills(ctx context.Context, username string)
 }
@@ -0,0 +1,6 @@
+package services
+
+// isLeader Выполнять ли фоновые задачи на этом экземпляре (без выбора ведущего - всегда)
+func isLeader(e leaderElector) bool {
+	return e == nil || e.IsLeader()
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"short_url/internal/models"
+)
+
+// linkAction Действие пользователя над ссылкой
+type linkAction int
+
+// Действия, которые проходят проверку владения
+const (
+	actionRead		linkAction = iota + 1	// Просмотр данных ссылки
+	actionDelete							// Удаление ссылки
+	actionQR								// Генерация QR-кода по ссылке
+	actionDisable							// Блокировка ссылки за нарушения
+)
+
+// authorizeLink Проверяет, может ли пользователь выполнить действие над ссылкой.
+// Ссылкой распоряжается ее владелец и администраторы, всем остальным возвращается "access denied"
+func authorizeLink(user models.JWTUserInfo, data models.LinkDataDB, action linkAction) error {
+	// Администратор имеет доступ к любой ссылке
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+
+	// Блокировать ссылки может только администратор
+	if action == actionDisable {
+		return errors.New("access denied")
+	}
+
+	// Ссылки без владельца недоступны никому
+	if data.Owner == "" {
+		return errors.New("access denied")
+	}
+
+	if data.Owner != user.Username {
+		return errors.New("access denied")
+	}
+
+	return nil
+}
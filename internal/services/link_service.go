@@ -6,8 +6,10 @@ import (
 	"errors"
 	"image/png"
 	"math/rand"
+	"short_url/internal/events"
 	"short_url/internal/models"
 	log "short_url/pkg/logger"
+	"sync"
 	"time"
 
 	"github.com/boombuler/barcode"
@@ -18,57 +20,79 @@ import (
 // LinkServiceConfig Конфигурация для LinkService
 type LinkServiceConfig struct {
 	LinkRepo	linkRepository
-	Manager	manager
+	Plans		planCatalog
+	Events		eventBus
 	Logger		*log.Log
 }
 
 // LinkService Управляет взаимодействием с ссылками
 type LinkService struct {
 	linkRepo 	linkRepository
-	manager	manager
+	plans		planCatalog
+	events		eventBus
 	logger   	*log.Log
 }
 
-const (
-	DefaultLifeTime	= 12 * time.Hour	// Время жизни ссылок по умолчанию
-
-	All    			= 50				// Лимит кол-ва всех ссылок для обычного пользователя
-	SubAll 			= 100				// Лимит кол-ва всех ссылок для подписчика
-
-	Custom    		= 15				// Лимит кол-ва кастомных ссылок для обычного пользователя
-	SubCustom 		= 30				// Лимит кол-ва кастомных ссылок для подписчика
-
-	SubPerm			= 10				// Лимит кол-ва ссылок с безграничным сроком действия для подписчика
-)
+const DefaultLifeTime = 12 * time.Hour	// Время жизни ссылок по умолчанию
 
 // NewLinkService Конструктор для ManageService
 func NewLinkService(c *LinkServiceConfig) *LinkService {
 	return &LinkService{
 		linkRepo:	c.LinkRepo,
-		manager:	c.Manager,
+		plans:		c.Plans,
+		events:		c.Events,
 		logger:		c.Logger,
 	}
 }
 
-// CreateQR Создает QR-код по существующей короткой ссылке и возвращает его в виде байтов
-func (s *LinkService) CreateQR(ctx context.Context, url, link string) (*bytes.Buffer, error) {
-	ctx = log.ContextWithSpan(ctx, "CreateQR")
-	l := s.logger.WithContext(ctx)
+// userPlan Возвращает тарифный план, по которому действуют лимиты пользователя.
+// Подписка в user актуальна на момент запроса (ее подставляет middleware AuthUser)
+func userPlan(plans planCatalog, user models.JWTUserInfo) models.Plan {
+	if user.Subscribe == models.Sub {
+		return plans.Subscribed(user.Plan)
+	}
 
-	l.Debug("CreateQR() started")
-	defer l.Debug("CreateQR() done")
+	return plans.Free()
+}
+
+// findOwnedLink Находит ссылку и проверяет, что пользователю разрешено действие над ней
+func (s *LinkService) findOwnedLink(ctx context.Context, user models.JWTUserInfo, link string, action linkAction) (models.LinkDataDB, error) {
+	l := s.logger.WithContext(ctx)
 
 	// Находим ссылку в БД
-	_, err := s.linkRepo.FindLink(ctx, link)
+	data, err := s.linkRepo.FindLink(ctx, link)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return nil, errors.New("link not found")
+			return models.LinkDataDB{}, errors.New("link not found")
 		} else {
 			l.Errorf("Unable to find link in Redis. Error: %s", err)
-			return nil, err
+			return models.LinkDataDB{}, err
 		}
 	}
 
+	// Проверяем права пользователя на ссылку
+	if err = authorizeLink(user, data, action); err != nil {
+		l.Infof("user %s has no access to link %s", user.Username, link)
+		return models.LinkDataDB{}, err
+	}
+
+	return data, nil
+}
+
+// CreateQR Создает QR-код по короткой ссылке пользователя и возвращает его в виде байтов
+func (s *LinkService) CreateQR(ctx context.Context, user models.JWTUserInfo, url, link string) (*bytes.Buffer, error) {
+	ctx = log.ContextWithSpan(ctx, "CreateQR")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("CreateQR() started")
+	defer l.Debug("CreateQR() done")
+
+	// Находим ссылку пользователя
+	_, err := s.findOwnedLink(ctx, user, link, actionQR)
+	if err != nil {
+		return nil, err
+	}
+
 	// Создаем QR-код на основе короткой ссылки
 	qrCode, err := qr.Encode(url, qr.M, qr.Auto)
 	if err != nil {
@@ -90,7 +114,8 @@ func (s *LinkService) CreateQR(ctx context.Context, url, link string) (*bytes.Bu
 	return result, nil
 }
 
-// FindLink Находит ссылку и доп. информацию о ней
+// FindLink Находит ссылку и доп. информацию о ней без проверки владельца (для переадресации).
+// Заблокированные ссылки возвращаются с отметкой Disabled, решение о переадресации принимает вызывающий
 func (s *LinkService) FindLink(ctx context.Context, link string) (models.LinkDataDTO, error) {
 	ctx = log.ContextWithSpan(ctx, "FindLink")
 	l := s.logger.WithContext(ctx)
@@ -111,9 +136,37 @@ func (s *LinkService) FindLink(ctx context.Context, link string) (models.LinkDat
 
 	// Маппим данные в ответ
 	result := models.LinkDataDTO{
-		Link:    data.Link,
-		FullURL: data.FullURL,
-		ExpTime: int(data.ExpTime),
+		Link:           data.Link,
+		FullURL:        data.FullURL,
+		ExpTime:        int(data.ExpTime),
+		Disabled:       data.Disabled,
+		DisabledReason: data.DisabledReason,
+	}
+
+	return result, nil
+}
+
+// GetLink Находит ссылку пользователя и доп. информацию о ней
+func (s *LinkService) GetLink(ctx context.Context, user models.JWTUserInfo, link string) (models.LinkDataDTO, error) {
+	ctx = log.ContextWithSpan(ctx, "GetLink")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("GetLink() started")
+	defer l.Debug("GetLink() done")
+
+	// Находим ссылку пользователя
+	data, err := s.findOwnedLink(ctx, user, link, actionRead)
+	if err != nil {
+		return models.LinkDataDTO{}, err
+	}
+
+	// Маппим данные в ответ
+	result := models.LinkDataDTO{
+		Link:           data.Link,
+		FullURL:        data.FullURL,
+		ExpTime:        int(data.ExpTime),
+		Disabled:       data.Disabled,
+		DisabledReason: data.DisabledReason,
 	}
 
 	return result, nil
@@ -144,21 +197,29 @@ func (s *LinkService) GetAllLinks(ctx context.Context, username string) ([]model
 		result[k].Link = d.Link
 		result[k].FullURL = d.FullURL
 		result[k].ExpTime = int(d.ExpTime)
+		result[k].Disabled = d.Disabled
+		result[k].DisabledReason = d.DisabledReason
 	}
 
 	return result, nil
 }
 
-// DeleteLink Удаляет ссылку
-func (s *LinkService) DeleteLink(ctx context.Context, username, link string) error {
+// DeleteLink Удаляет ссылку пользователя
+func (s *LinkService) DeleteLink(ctx context.Context, user models.JWTUserInfo, link string) error {
 	ctx = log.ContextWithSpan(ctx, "DeleteLink")
 	l := s.logger.WithContext(ctx)
 
 	l.Debug("DeleteLink() started")
 	defer l.Debug("DeleteLink() done")
 
+	// Находим ссылку пользователя
+	data, err := s.findOwnedLink(ctx, user, link, actionDelete)
+	if err != nil {
+		return err
+	}
+
 	// Удаляем ссылку из БД
-	err := s.linkRepo.DeleteLink(ctx, link, username)
+	err = s.linkRepo.DeleteLink(ctx, data.Link, data.Owner)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return errors.New("link not found")
@@ -168,10 +229,22 @@ func (s *LinkService) DeleteLink(ctx context.Context, username, link string) err
 		}
 	}
 
+	// Ссылка уже удалена, ошибка подписчика только записывается в лог
+	err = publish(ctx, s.events, events.LinkDeleted{
+		Link:	data.Link,
+		Owner:	data.Owner,
+		Actor:	user.Username,
+		Reason:	events.ReasonUser,
+	})
+	if err != nil {
+		l.Errorf("Unable to handle link deletion. Error: %s", err)
+	}
+
 	return nil
 }
 
-// CreateLink Проверяет выполнение условий сервиса и создает ссылку
+// CreateLink Проверяет выполнение условий сервиса и создает ссылку. Лимиты всех и бессрочных ссылок
+// репозиторий проверяет еще раз вместе с записью, лимит кастомных ссылок при параллельных запросах может быть превышен
 func (s *LinkService) CreateLink(ctx context.Context, fullUrl, custom string, exp int, user models.JWTUserInfo) (models.LinkDataDTO, error) {
 	ctx = log.ContextWithSpan(ctx, "CreateLink")
 	l := s.logger.WithContext(ctx)
@@ -188,34 +261,23 @@ func (s *LinkService) CreateLink(ctx context.Context, fullUrl, custom string, ex
 		}
 	}
 
-	// Вводим ограничения сервиса
-	if user.Subscribe == 2 {
-		if exp == 0 {
+	// Вводим ограничения тарифного плана (после смены плана ссылок может быть больше лимита).
+	// Проверка до записи отличает отсутствие подписки и учитывает кастомные ссылки и ссылки прежней схемы
+	quota := userPlan(s.plans, user).Quota
+	if exp == 0 {
+		if quota.Perm == 0 {
 			return models.LinkDataDTO{}, errors.New("need subscribe")
 		}
-		if custom != "" {
-			if amo.Custom == Custom {
-				return models.LinkDataDTO{}, errors.New("limit exceeded")
-			}
-		}
-		if amo.All == All {
-			return models.LinkDataDTO{}, errors.New("limit exceeded")
-		}
-	} else {
-		if exp == 0 {
-			if amo.Perm == SubPerm {
-				return models.LinkDataDTO{}, errors.New("limit exceeded")
-			}
-		}
-		if custom != "" {
-			if amo.Custom == SubCustom {
-				return models.LinkDataDTO{}, errors.New("limit exceeded")
-			}
-		}
-		if amo.All == SubAll {
+		if amo.Perm >= quota.Perm {
 			return models.LinkDataDTO{}, errors.New("limit exceeded")
 		}
 	}
+	if custom != "" && amo.Custom >= quota.Custom {
+		return models.LinkDataDTO{}, errors.New("limit exceeded")
+	}
+	if amo.All >= quota.All {
+		return models.LinkDataDTO{}, errors.New("limit exceeded")
+	}
 
 	// Определяем, кастомная ли ссылка
 	var link string
@@ -228,19 +290,30 @@ func (s *LinkService) CreateLink(ctx context.Context, fullUrl, custom string, ex
 		isCustom = false
 	}
 
-	// Добавляем ссылку в БД
-	data, err := s.linkRepo.CreateLink(ctx, link, user.Username, fullUrl, time.Duration(exp), isCustom)
+	// Добавляем ссылку в БД (занятую случайную ссылку генерируем заново, кастомную - отклоняем)
+	data, err := s.linkRepo.CreateLink(ctx, link, user.Username, fullUrl, time.Duration(exp), isCustom, &quota)
+	for k := 1; !isCustom && err != nil && err.Error() == "link already exists" && k < randLinkAttempts; k++ {
+		data, err = s.linkRepo.CreateLink(ctx, randLink(), user.Username, fullUrl, time.Duration(exp), isCustom, &quota)
+	}
 	if err != nil {
+		if err.Error() == "link already exists" || err.Error() == "limit exceeded" {
+			return models.LinkDataDTO{}, err
+		}
 		l.Errorf("Unable to create link data in Redis. Error: %s", err)
 		return models.LinkDataDTO{}, err
 	}
 
-	// Если параметр срока действия ссылки обозначен, планируем задачу на удаление по истечению срока
-	if exp != 0 {
-		if err = s.manager.CleaningExpLinkSchedule(ctx, link, user.Username, time.Duration(exp)); err != nil {
-			l.Errorf("Unable to schedule cleaning. Error: %s", err)
-			return models.LinkDataDTO{}, err
-		}
+	// Подписчики планируют удаление по истечении срока, пишут аудит и события вебхуков
+	err = publish(ctx, s.events, events.LinkCreated{
+		Link:		data.Link,
+		Owner:		user.Username,
+		FullURL:	data.FullURL,
+		Exp:		time.Duration(exp),
+		Custom:		isCustom,
+	})
+	if err != nil {
+		l.Errorf("Unable to handle link creation. Error: %s", err)
+		return models.LinkDataDTO{}, err
 	}
 
 	// Маппим данные в ответ
@@ -253,23 +326,30 @@ func (s *LinkService) CreateLink(ctx context.Context, fullUrl, custom string, ex
 	return result, nil
 }
 
-// randLink Генератор рандомной ссылки
-func randLink() string {
+// Сколько раз генерировать случайную ссылку, если она уже занята
+const randLinkAttempts = 3
 
-	rand.Seed(time.Now().UnixNano())
+// Алфавит и длина случайной ссылки
+const (
+	linkAlphabet	= "ABCDEFGHIJKLMNOPQRSTUVWXYZ" + "0123456789" + "abcdefghijklmnopqrstuvwxyz"
+	linkLength		= 8
+)
 
-	alphabete := "ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
-		"0123456789" + "abcdefghijklmnopqrstuvwxyz"
-	length := 8
+// linkRand Источник случайных чисел для ссылок: инициализируется один раз, rand.Rand не потокобезопасен
+var (
+	linkRand	= rand.New(rand.NewSource(time.Now().UnixNano()))
+	linkRandMux	sync.Mutex
+)
 
-	buf := make([]byte, length)
-	for i := 2; i < length; i++ {
-		buf[i] = alphabete[rand.Intn(len(alphabete))]
-	}
+// randLink Генератор рандомной ссылки
+func randLink() string {
+	buf := make([]byte, linkLength)
 
-	rand.Shuffle(len(buf), func(i, j int) {
-		buf[i], buf[j] = buf[j], buf[i]
-	})
+	linkRandMux.Lock()
+	for i := range buf {
+		buf[i] = linkAlphabet[linkRand.Intn(len(linkAlphabet))]
+	}
+	linkRandMux.Unlock()
 
 	return string(buf)
 }
@@ -1 +1,344 @@
 package services
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	"short_url/internal/plans"
+	log "short_url/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"go.uber.org/zap"
+)
+
+// fakeLinkRepo Хранилище ссылок в памяти для тестов сервиса
+type fakeLinkRepo struct {
+	links	map[string]models.LinkDataDB
+	keep	map[string][]string
+	deleted	[]string
+}
+
+func newFakeLinkRepo(links ...models.LinkDataDB) *fakeLinkRepo {
+	r := &fakeLinkRepo{links: make(map[string]models.LinkDataDB)}
+	for _, l := range links {
+		r.links[l.Link] = l
+	}
+	return r
+}
+
+func (r *fakeLinkRepo) CreateLink(ctx context.Context, link, username, fullUrl string, exp time.Duration, custom bool, quota *models.LinkQuota) (models.LinkDataDB, error) {
+	if _, ok := r.links[link]; ok {
+		return models.LinkDataDB{}, errors.New("link already exists")
+	}
+	data := models.LinkDataDB{Link: link, FullURL: fullUrl, ExpTime: exp, Perm: exp == 0, Custom: custom, Owner: username}
+	r.links[link] = data
+	return data, nil
+}
+
+func (r *fakeLinkRepo) DeleteLink(ctx context.Context, link, username string) error {
+	if _, ok := r.links[link]; !ok {
+		return redis.Nil
+	}
+	delete(r.links, link)
+	r.deleted = append(r.deleted, link)
+	return nil
+}
+
+func (r *fakeLinkRepo) FindLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	data, ok := r.links[link]
+	if !ok {
+		return models.LinkDataDB{}, redis.Nil
+	}
+	return data, nil
+}
+
+func (r *fakeLinkRepo) CountLinks(ctx context.Context, username string) (models.LinksAmount, error) {
+	amo := models.LinksAmount{}
+	for _, l := range r.links {
+		if l.Owner != username {
+			continue
+		}
+		amo.All++
+		if l.Perm {
+			amo.Perm++
+		}
+		if l.Custom {
+			amo.Custom++
+		}
+	}
+	return amo, nil
+}
+
+func (r *fakeLinkRepo) GetAllLinks(ctx context.Context, username string) ([]models.LinkDataDB, error) {
+	result := make([]models.LinkDataDB, 0)
+	for _, l := range r.links {
+		if l.Owner == username {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeLinkRepo) DisableLink(ctx context.Context, link, reason string) error {
+	data, ok := r.links[link]
+	if !ok {
+		return redis.Nil
+	}
+	data.Disabled = true
+	data.DisabledReason = reason
+	r.links[link] = data
+	return nil
+}
+
+func (r *fakeLinkRepo) EnableLink(ctx context.Context, link string) error {
+	data, ok := r.links[link]
+	if !ok || !data.Disabled {
+		return redis.Nil
+	}
+	data.Disabled = false
+	data.DisabledReason = ""
+	r.links[link] = data
+	return nil
+}
+
+func (r *fakeLinkRepo) KeepLinks(ctx context.Context, username string) ([]string, error) {
+	return append([]string{}, r.keep[username]...), nil
+}
+
+func (r *fakeLinkRepo) SetKeepLinks(ctx context.Context, username string, links []string) error {
+	if r.keep == nil {
+		r.keep = make(map[string][]string)
+	}
+	r.keep[username] = links
+	return nil
+}
+
+// testCatalog Каталог планов с небольшими лимитами для тестов
+func testCatalog(t *testing.T) *plans.Catalog {
+	c, err := plans.NewCatalog([]models.Plan{
+		{Name: plans.Free, Quota: models.LinkQuota{All: 2, Custom: 1}},
+		{Name: "pro", Price: 100, Days: 30, Quota: models.LinkQuota{All: 3, Custom: 2, Perm: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newTestLinkService(repo linkRepository) *LinkService {
+	return NewLinkService(&LinkServiceConfig{
+		LinkRepo:	repo,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+}
+
+var (
+	alice	= models.JWTUserInfo{Username: "alice", Subscribe: models.Default}
+	bob		= models.JWTUserInfo{Username: "bob", Subscribe: models.Default}
+	admin	= models.JWTUserInfo{Username: "root", Subscribe: models.Default, Role: models.RoleAdmin}
+)
+
+func TestLinkServiceGetLinkOwnership(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "abc", FullURL: "https://example.com", Owner: "alice"})
+	s := newTestLinkService(repo)
+	ctx := context.Background()
+
+	data, err := s.GetLink(ctx, alice, "abc")
+	if err != nil {
+		t.Fatalf("owner must read own link, got error: %s", err)
+	}
+	if data.FullURL != "https://example.com" {
+		t.Fatalf("unexpected full url %q", data.FullURL)
+	}
+
+	if _, err = s.GetLink(ctx, bob, "abc"); err == nil || err.Error() != "access denied" {
+		t.Fatalf("expected access denied for foreign link, got %v", err)
+	}
+
+	if _, err = s.GetLink(ctx, alice, "missing"); err == nil || err.Error() != "link not found" {
+		t.Fatalf("expected link not found, got %v", err)
+	}
+}
+
+func TestLinkServiceDeleteLinkOwnership(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "abc", FullURL: "https://example.com", Owner: "alice"})
+	s := newTestLinkService(repo)
+	ctx := context.Background()
+
+	if err := s.DeleteLink(ctx, bob, "abc"); err == nil || err.Error() != "access denied" {
+		t.Fatalf("expected access denied for foreign link, got %v", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Fatalf("foreign link must not be deleted, deleted: %v", repo.deleted)
+	}
+
+	if err := s.DeleteLink(ctx, alice, "abc"); err != nil {
+		t.Fatalf("owner must delete own link, got error: %s", err)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != "abc" {
+		t.Fatalf("expected link to be deleted, deleted: %v", repo.deleted)
+	}
+}
+
+func TestLinkServiceCreateQROwnership(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "abc", FullURL: "https://example.com", Owner: "alice"})
+	s := newTestLinkService(repo)
+	ctx := context.Background()
+
+	if _, err := s.CreateQR(ctx, bob, "http://short/abc", "abc"); err == nil || err.Error() != "access denied" {
+		t.Fatalf("expected access denied for foreign link, got %v", err)
+	}
+
+	buf, err := s.CreateQR(ctx, alice, "http://short/abc", "abc")
+	if err != nil {
+		t.Fatalf("owner must create QR for own link, got error: %s", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("expected non-empty QR image")
+	}
+}
+
+func TestLinkServiceLinkWithoutOwner(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "legacy", FullURL: "https://example.com"})
+	s := newTestLinkService(repo)
+
+	if _, err := s.GetLink(context.Background(), alice, "legacy"); err == nil || err.Error() != "access denied" {
+		t.Fatalf("expected access denied for link without owner, got %v", err)
+	}
+}
+
+func TestLinkServiceAdminAccess(t *testing.T) {
+	repo := newFakeLinkRepo(models.LinkDataDB{Link: "abc", FullURL: "https://example.com", Owner: "alice"})
+	s := newTestLinkService(repo)
+
+	if _, err := s.GetLink(context.Background(), admin, "abc"); err != nil {
+		t.Fatalf("admin must read any link, got error: %s", err)
+	}
+}
+
+func TestLinkServiceCreateLinkQuota(t *testing.T) {
+	repo := newFakeLinkRepo()
+	s := NewLinkService(&LinkServiceConfig{
+		LinkRepo:	repo,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+	exp := int(time.Hour)
+
+	// Бесплатный план: бессрочные ссылки недоступны, кастомная - одна
+	if _, err := s.CreateLink(ctx, "https://example.com", "", 0, alice); err == nil || err.Error() != "need subscribe" {
+		t.Fatalf("expected need subscribe, got %v", err)
+	}
+	if _, err := s.CreateLink(ctx, "https://example.com", "c1", exp, alice); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.CreateLink(ctx, "https://example.com", "c2", exp, alice); err == nil || err.Error() != "limit exceeded" {
+		t.Fatalf("expected custom limit, got %v", err)
+	}
+	if _, err := s.CreateLink(ctx, "https://example.com", "", exp, alice); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.CreateLink(ctx, "https://example.com", "", exp, alice); err == nil || err.Error() != "limit exceeded" {
+		t.Fatalf("expected total limit, got %v", err)
+	}
+
+	// Подписчик получает лимиты своего плана
+	pro := models.JWTUserInfo{Username: "alice", Subscribe: models.Sub, Plan: "pro"}
+	if _, err := s.CreateLink(ctx, "https://example.com", "", 0, pro); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// После смены плана ссылок больше лимита - новые не создаются
+	repo.links["extra"] = models.LinkDataDB{Link: "extra", Owner: "alice"}
+	if _, err := s.CreateLink(ctx, "https://example.com", "", exp, pro); err == nil || err.Error() != "limit exceeded" {
+		t.Fatalf("expected limit over quota, got %v", err)
+	}
+}
+
+func TestLinkServiceCreateLinkTaken(t *testing.T) {
+	repo := newFakeLinkRepo()
+	repo.links["taken"] = models.LinkDataDB{Link: "taken", Owner: "bob"}
+	s := NewLinkService(&LinkServiceConfig{
+		LinkRepo:	repo,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	// Чужая кастомная ссылка не перезаписывается
+	if _, err := s.CreateLink(ctx, "https://example.com", "taken", int(time.Hour), alice); err == nil || err.Error() != "link already exists" {
+		t.Fatalf("expected link already exists, got %v", err)
+	}
+	if repo.links["taken"].Owner != "bob" {
+		t.Fatalf("link owner overwritten: %+v", repo.links["taken"])
+	}
+}
+
+func TestRandLink(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		link := randLink()
+		if len(link) != linkLength || strings.Trim(link, linkAlphabet) != "" {
+			t.Fatalf("unexpected link %q", link)
+		}
+		seen[link] = true
+	}
+
+	// Повторы среди 62^8 вариантов почти невозможны
+	if len(seen) < 999 {
+		t.Fatalf("expected distinct links, got %d of 1000", len(seen))
+	}
+}
+
+// benchLinks Набор ссылок для бенчмарков горячего пути переадресации
+func benchLinks(n int) (*fakeLinkRepo, []string) {
+	repo := newFakeLinkRepo()
+	names := make([]string, n)
+	for i := range names {
+		names[i] = randLink()
+		repo.links[names[i]] = models.LinkDataDB{Link: names[i], FullURL: "https://example.com/" + names[i], ExpTime: time.Hour, Owner: "alice"}
+	}
+	return repo, names
+}
+
+func BenchmarkLinkServiceFindLink(b *testing.B) {
+	repo, names := benchLinks(1000)
+	s := newTestLinkService(repo)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := s.FindLink(ctx, names[i%len(names)]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkLinkServiceFindLinkMissing(b *testing.B) {
+	s := newTestLinkService(newFakeLinkRepo())
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.FindLink(ctx, "missing"); err == nil {
+			b.Fatal("expected link not found")
+		}
+	}
+}
+
+func BenchmarkRandLink(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		randLink()
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoginGuardConfig Конфигурация для LoginGuard
+type LoginGuardConfig struct {
+	AttemptRepo	attemptRepository
+	Logger		*log.Log
+}
+
+// LoginGuard Защищает вход от перебора: считает неудачные попытки и временно блокирует
+// вход по имени пользователя и по IP-адресу
+type LoginGuard struct {
+	attemptRepo	attemptRepository
+	logger		*log.Log
+	Lockouts	*prometheus.CounterVec
+}
+
+// attemptPolicy Правила блокировки для одного вида ключа
+type attemptPolicy struct {
+	scope		string
+	delayAfter	int64	// С какой неудачи начинается прогрессивная задержка
+	lockAfter	int64	// С какой неудачи вход блокируется на LockoutDuration
+}
+
+// attemptKey Ключ счетчика неудачных попыток
+type attemptKey struct {
+	policy	attemptPolicy
+	value	string
+}
+
+const (
+	AttemptWindow	= time.Minute * 15	// Окно, в котором считаются неудачные попытки
+	LockoutDuration	= time.Minute * 15	// Длительность блокировки после LockAfter неудач
+	MaxDelay		= time.Minute		// Максимальная прогрессивная задержка
+)
+
+var (
+	userPolicy	= attemptPolicy{scope: "user", delayAfter: 3, lockAfter: 10}
+	ipPolicy	= attemptPolicy{scope: "ip", delayAfter: 20, lockAfter: 100}
+	mfaPolicy	= attemptPolicy{scope: "mfa", delayAfter: 3, lockAfter: 10}
+)
+
+// NewLoginGuard Конструктор для LoginGuard
+func NewLoginGuard(c *LoginGuardConfig) *LoginGuard {
+	// Создаем метрику
+	lockouts := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "signin_lockout_total",
+			Help: "Кол-во временных блокировок входа после серии неудачных попыток",
+		},
+		[]string{"scope"},
+	)
+
+	return &LoginGuard{
+		attemptRepo:	c.AttemptRepo,
+		logger:			c.Logger,
+		Lockouts:		lockouts,
+	}
+}
+
+// signInKeys Ключи счетчиков для входа по паролю
+func signInKeys(username, ip string) []attemptKey {
+	keys := []attemptKey{{policy: userPolicy, value: strings.ToLower(username)}}
+	if ip != "" {
+		keys = append(keys, attemptKey{policy: ipPolicy, value: ip})
+	}
+
+	return keys
+}
+
+// mfaKeys Ключи счетчиков для проверки кода второго фактора
+func mfaKeys(username string) []attemptKey {
+	return []attemptKey{{policy: mfaPolicy, value: strings.ToLower(username)}}
+}
+
+// name Полное имя ключа в хранилище
+func (k attemptKey) name() string {
+	return k.policy.scope + ":" + k.value
+}
+
+// delay Возвращает задержку после n неудачных попыток: 1с, 2с, 4с ... до MaxDelay,
+// а начиная с lockAfter - полную блокировку
+func (p attemptPolicy) delay(n int64) (time.Duration, bool) {
+	if n >= p.lockAfter {
+		return LockoutDuration, true
+	}
+	if n < p.delayAfter {
+		return 0, false
+	}
+
+	d := time.Second << uint(n-p.delayAfter)
+	if d > MaxDelay {
+		d = MaxDelay
+	}
+
+	return d, false
+}
+
+// check Возвращает *models.LockoutError, если вход по любому из ключей заблокирован.
+// При недоступности хранилища вход не блокируется
+func (g *LoginGuard) check(ctx context.Context, keys []attemptKey) error {
+	if g == nil {
+		return nil
+	}
+
+	var wait time.Duration
+	for _, k := range keys {
+		d, err := g.attemptRepo.LockedFor(ctx, k.name())
+		if err != nil {
+			g.logger.WithContext(ctx).Errorf("Unable to check sign in lock. Error: %s", err)
+			continue
+		}
+		if d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return &models.LockoutError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// fail Учитывает неудачную попытку и при необходимости блокирует вход
+func (g *LoginGuard) fail(ctx context.Context, keys []attemptKey) {
+	if g == nil {
+		return
+	}
+	l := g.logger.WithContext(ctx)
+
+	for _, k := range keys {
+		n, err := g.attemptRepo.AddFail(ctx, k.name(), AttemptWindow)
+		if err != nil {
+			l.Errorf("Unable to count failed sign in. Error: %s", err)
+			continue
+		}
+
+		d, lockout := k.policy.delay(n)
+		if d == 0 {
+			continue
+		}
+		if err = g.attemptRepo.Lock(ctx, k.name(), d); err != nil {
+			l.Errorf("Unable to lock sign in. Error: %s", err)
+			continue
+		}
+
+		if lockout {
+			l.Warnf("sign in locked for %s after %d failed attempts (%s)", k.name(), n, d)
+			g.Lockouts.WithLabelValues(k.policy.scope).Inc()
+		}
+	}
+}
+
+// reset Сбрасывает счетчики после успешного входа
+func (g *LoginGuard) reset(ctx context.Context, keys []attemptKey) {
+	if g == nil {
+		return
+	}
+
+	for _, k := range keys {
+		if err := g.attemptRepo.Reset(ctx, k.name()); err != nil {
+			g.logger.WithContext(ctx).Errorf("Unable to reset failed sign in. Error: %s", err)
+		}
+	}
+}
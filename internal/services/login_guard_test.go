@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeAttemptRepo Счетчики неудачных попыток в памяти для тестов сервиса
+type fakeAttemptRepo struct {
+	fails	map[string]int64
+	locks	map[string]time.Duration
+}
+
+func newFakeAttemptRepo() *fakeAttemptRepo {
+	return &fakeAttemptRepo{fails: make(map[string]int64), locks: make(map[string]time.Duration)}
+}
+
+func (r *fakeAttemptRepo) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	return r.locks[key], nil
+}
+
+func (r *fakeAttemptRepo) AddFail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	r.fails[key]++
+	return r.fails[key], nil
+}
+
+func (r *fakeAttemptRepo) Lock(ctx context.Context, key string, d time.Duration) error {
+	r.locks[key] = d
+	return nil
+}
+
+func (r *fakeAttemptRepo) Reset(ctx context.Context, key string) error {
+	delete(r.fails, key)
+	delete(r.locks, key)
+	return nil
+}
+
+func TestAttemptPolicyDelay(t *testing.T) {
+	cases := []struct {
+		n		int64
+		delay	time.Duration
+		lock	bool
+	}{
+		{n: 1},
+		{n: 2},
+		{n: 3, delay: time.Second},
+		{n: 4, delay: 2 * time.Second},
+		{n: 9, delay: MaxDelay},
+		{n: 10, delay: LockoutDuration, lock: true},
+	}
+
+	for _, c := range cases {
+		d, lock := userPolicy.delay(c.n)
+		if d != c.delay || lock != c.lock {
+			t.Errorf("delay(%d) = %s, %v; want %s, %v", c.n, d, lock, c.delay, c.lock)
+		}
+	}
+}
+
+func TestAuthServiceSignInLockout(t *testing.T) {
+	hash, err := security.HashPassword("secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attempts := newFakeAttemptRepo()
+	logger := &log.Log{Logger: zap.NewNop()}
+	s := NewAuthService(&AuthServiceConfig{
+		AuthRepo:	newFakeAuthRepo(models.UserDB{Username: "alice", Password: hash}),
+		SubRepo:	&fakeSubRepo{subs: map[string]time.Duration{}},
+		Guard:		NewLoginGuard(&LoginGuardConfig{AttemptRepo: attempts, Logger: logger}),
+		Logger:		logger,
+	})
+	ctx := context.Background()
+
+	// Неизвестное имя и неверный пароль дают одинаковую ошибку
+	_, errUnknown := s.SignInUserByName(ctx, models.SignInUserDTO{Username: "bob", Password: "secret1", IP: "10.0.0.1"})
+	_, errWrong := s.SignInUserByName(ctx, models.SignInUserDTO{Username: "alice", Password: "wrong", IP: "10.0.0.1"})
+	if errUnknown == nil || errWrong == nil || errUnknown.Error() != "invalid credentials" || errWrong.Error() != errUnknown.Error() {
+		t.Fatalf("expected uniform invalid credentials, got %v and %v", errUnknown, errWrong)
+	}
+
+	// Успешный вход сбрасывает счетчик пользователя, но не IP
+	if _, err = s.SignInUserByName(ctx, models.SignInUserDTO{Username: "alice", Password: "secret1", IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attempts.fails["user:alice"] != 0 || attempts.fails["ip:10.0.0.1"] != 2 {
+		t.Fatalf("unexpected counters: %v", attempts.fails)
+	}
+
+	// После серии неудач вход блокируется даже с верным паролем
+	for i := int64(0); i < userPolicy.delayAfter; i++ {
+		s.SignInUserByName(ctx, models.SignInUserDTO{Username: "Alice", Password: "wrong"})
+	}
+	_, err = s.SignInUserByName(ctx, models.SignInUserDTO{Username: "alice", Password: "secret1"})
+	var lock *models.LockoutError
+	if !errors.As(err, &lock) || lock.RetryAfter != time.Second {
+		t.Fatalf("expected lockout for 1s, got %v", err)
+	}
+}
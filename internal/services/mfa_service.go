@@ -0,0 +1,288 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jackc/pgx/v5"
+)
+
+// MFAServiceConfig Конфигурация для MFAService
+type MFAServiceConfig struct {
+	AuthRepo		authRepository
+	RecoveryRepo	recoveryRepository
+	AuditRepo		auditRepository
+	Guard			*LoginGuard
+	Issuer			string
+	Logger			*log.Log
+}
+
+// MFAService Управляет двухфакторной аутентификацией по TOTP (RFC 6238) и резервными кодами
+type MFAService struct {
+	authRepo		authRepository
+	recoveryRepo	recoveryRepository
+	auditRepo		auditRepository
+	guard			*LoginGuard
+	issuer			string
+	now				func() time.Time
+	logger			*log.Log
+}
+
+// Кол-во резервных кодов, выдаваемых при включении второго фактора
+const RecoveryCodes = 10
+
+// NewMFAService Конструктор для MFAService
+func NewMFAService(c *MFAServiceConfig) *MFAService {
+	return &MFAService{
+		authRepo:		c.AuthRepo,
+		recoveryRepo:	c.RecoveryRepo,
+		auditRepo:		c.AuditRepo,
+		guard:			c.Guard,
+		issuer:			c.Issuer,
+		now:			time.Now,
+		logger:			c.Logger,
+	}
+}
+
+// findUser Находит пользователя по имени
+func (s *MFAService) findUser(ctx context.Context, username string) (models.UserDB, error) {
+	u, err := s.authRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return u, errors.New("user not found")
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to find user. Error: %s", err)
+		return u, err
+	}
+
+	return u, nil
+}
+
+// useTOTP Проверяет код из приложения по часам сервиса
+func (s *MFAService) useTOTP(ctx context.Context, u models.UserDB, code string) error {
+	return useTOTP(ctx, s.authRepo, s.logger, u, code, s.now())
+}
+
+// useTOTP Проверяет код из приложения и запоминает его шаг, чтобы тот же код нельзя было предъявить повторно.
+// Повторно предъявленный код считается неверным
+func useTOTP(ctx context.Context, repo authRepository, logger *log.Log, u models.UserDB, code string, now time.Time) error {
+	step, ok := security.ValidateTOTP(u.TOTPSecret, code, now, u.TOTPStep)
+	if !ok {
+		return errors.New("invalid code")
+	}
+
+	// Шаг запоминается атомарно: из двух одновременных запросов с одним кодом пройдет только один
+	if err := repo.UseTOTPStep(ctx, u.Username, step); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("invalid code")
+		}
+
+		logger.WithContext(ctx).Errorf("Unable to save totp step. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// EnrollTOTP Генерирует новый секрет TOTP. Второй фактор включается только после ConfirmTOTP
+func (s *MFAService) EnrollTOTP(ctx context.Context, username string) (models.TOTPEnrollment, error) {
+	ctx = log.ContextWithSpan(ctx, "EnrollTOTP")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("EnrollTOTP() started")
+	defer l.Debug("EnrollTOTP() done")
+
+	result := models.TOTPEnrollment{}
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return result, err
+	}
+	if u.TOTPEnabled {
+		return result, errors.New("mfa already enabled")
+	}
+
+	// Генерируем секрет и сохраняем его до подтверждения
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		l.Errorf("Unable to generate totp secret. Error: %s", err)
+		return result, err
+	}
+	if err = s.authRepo.SetTOTP(ctx, username, secret, false); err != nil {
+		l.Errorf("Unable to save totp secret. Error: %s", err)
+		return result, err
+	}
+
+	result.Secret = secret
+	result.URI = security.TOTPURI(s.issuer, username, secret)
+
+	// Создаем QR-код для приложения-аутентификатора
+	qrCode, err := qr.Encode(result.URI, qr.M, qr.Auto)
+	if err != nil {
+		l.Errorf("Unable to encode totp uri to QR. Error: %s", err)
+		return result, err
+	}
+
+	qrCode, err = barcode.Scale(qrCode, 256, 256)
+	if err != nil {
+		l.Errorf("Unable to format QR. Error: %s", err)
+		return result, err
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if err = png.Encode(buf, qrCode); err != nil {
+		l.Errorf("Unable to encode QR to png. Error: %s", err)
+		return result, err
+	}
+	result.QR = buf.Bytes()
+
+	return result, nil
+}
+
+// ConfirmTOTP Включает второй фактор после проверки первого кода и выдает резервные коды
+func (s *MFAService) ConfirmTOTP(ctx context.Context, username, code string) ([]string, error) {
+	ctx = log.ContextWithSpan(ctx, "ConfirmTOTP")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ConfirmTOTP() started")
+	defer l.Debug("ConfirmTOTP() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+	if u.TOTPSecret == "" {
+		return nil, errors.New("mfa not enrolled")
+	}
+
+	// Проверяем, что приложение настроено верно
+	if err = s.useTOTP(ctx, u, code); err != nil {
+		return nil, err
+	}
+
+	// Генерируем резервные коды, в базе храним только хеши
+	codes, err := security.GenerateRecoveryCodes(RecoveryCodes)
+	if err != nil {
+		l.Errorf("Unable to generate recovery codes. Error: %s", err)
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for k, c := range codes {
+		hashes[k] = security.HashSecret(security.NormalizeRecoveryCode(c))
+	}
+	if err = s.recoveryRepo.ReplaceCodes(ctx, username, hashes); err != nil {
+		l.Errorf("Unable to save recovery codes. Error: %s", err)
+		return nil, err
+	}
+
+	if err = s.authRepo.SetTOTP(ctx, username, u.TOTPSecret, true); err != nil {
+		l.Errorf("Unable to enable totp. Error: %s", err)
+		return nil, err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	username,
+		Action:	models.AuditMFAEnable,
+		Target:	username,
+		Owner:	username,
+	})
+
+	return codes, nil
+}
+
+// VerifyCode Проверяет код из приложения или резервный код (резервный код после этого недействителен)
+func (s *MFAService) VerifyCode(ctx context.Context, username, code string) error {
+	ctx = log.ContextWithSpan(ctx, "VerifyCode")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("VerifyCode() started")
+	defer l.Debug("VerifyCode() done")
+
+	u, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return errors.New("mfa not enabled")
+	}
+
+	// Шестизначный код перебирается быстро, поэтому число попыток ограничено
+	keys := mfaKeys(username)
+	if err = s.guard.check(ctx, keys); err != nil {
+		return err
+	}
+
+	err = s.useTOTP(ctx, u, code)
+	if err == nil {
+		s.guard.reset(ctx, keys)
+		return nil
+	}
+	if err.Error() != "invalid code" {
+		return err
+	}
+
+	// Код не подошел как TOTP, пробуем резервный
+	hash := security.HashSecret(security.NormalizeRecoveryCode(code))
+	if err = s.recoveryRepo.UseCode(ctx, username, hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.guard.fail(ctx, keys)
+			return errors.New("invalid code")
+		}
+
+		l.Errorf("Unable to use recovery code. Error: %s", err)
+		return err
+	}
+	s.guard.reset(ctx, keys)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	username,
+		Action:	models.AuditMFARecovery,
+		Target:	username,
+		Owner:	username,
+	})
+
+	return nil
+}
+
+// DisableTOTP Отключает второй фактор после проверки кода
+func (s *MFAService) DisableTOTP(ctx context.Context, username, code string) error {
+	ctx = log.ContextWithSpan(ctx, "DisableTOTP")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("DisableTOTP() started")
+	defer l.Debug("DisableTOTP() done")
+
+	if err := s.VerifyCode(ctx, username, code); err != nil {
+		return err
+	}
+
+	if err := s.authRepo.SetTOTP(ctx, username, "", false); err != nil {
+		l.Errorf("Unable to disable totp. Error: %s", err)
+		return err
+	}
+	if err := s.recoveryRepo.DeleteCodes(ctx, username); err != nil {
+		l.Errorf("Unable to delete recovery codes. Error: %s", err)
+		return err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	username,
+		Action:	models.AuditMFADisable,
+		Target:	username,
+		Owner:	username,
+	})
+
+	return nil
+}
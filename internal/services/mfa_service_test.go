@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeRecoveryRepo Хранилище резервных кодов в памяти для тестов сервиса
+type fakeRecoveryRepo struct {
+	codes map[string]map[string]bool
+}
+
+func (r *fakeRecoveryRepo) ReplaceCodes(ctx context.Context, username string, hashes []string) error {
+	r.codes[username] = make(map[string]bool)
+	for _, h := range hashes {
+		r.codes[username][h] = false
+	}
+	return nil
+}
+
+func (r *fakeRecoveryRepo) UseCode(ctx context.Context, username, hash string) error {
+	used, ok := r.codes[username][hash]
+	if !ok || used {
+		return pgx.ErrNoRows
+	}
+	r.codes[username][hash] = true
+	return nil
+}
+
+func (r *fakeRecoveryRepo) DeleteCodes(ctx context.Context, username string) error {
+	delete(r.codes, username)
+	return nil
+}
+
+func TestMFAServiceFlow(t *testing.T) {
+	users := newFakeAuthRepo(models.UserDB{Username: "alice"})
+	recovery := &fakeRecoveryRepo{codes: make(map[string]map[string]bool)}
+	s := NewMFAService(&MFAServiceConfig{
+		AuthRepo:		users,
+		RecoveryRepo:	recovery,
+		Issuer:			"short_url",
+		Logger:			&log.Log{Logger: zap.NewNop()},
+	})
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	enrollment, err := s.EnrollTOTP(ctx, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") || len(enrollment.QR) == 0 {
+		t.Fatalf("unexpected enrollment %+v", enrollment)
+	}
+	if users.users["alice"].TOTPEnabled {
+		t.Fatal("totp must not be enabled before confirmation")
+	}
+
+	if _, err = s.ConfirmTOTP(ctx, "alice", "000000"); err == nil || err.Error() != "invalid code" {
+		t.Fatalf("expected invalid code, got %v", err)
+	}
+
+	code, _ := security.TOTPCode(enrollment.Secret, now)
+	codes, err := s.ConfirmTOTP(ctx, "alice", code)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(codes) != RecoveryCodes || !users.users["alice"].TOTPEnabled {
+		t.Fatalf("totp must be enabled with %d recovery codes, got %v", RecoveryCodes, codes)
+	}
+
+	// Код, которым подтверждено подключение, повторно не принимается
+	if err = s.VerifyCode(ctx, "alice", code); err == nil || err.Error() != "invalid code" {
+		t.Fatalf("replayed totp code must be rejected, got %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	code, _ = security.TOTPCode(enrollment.Secret, now)
+	if err = s.VerifyCode(ctx, "alice", code); err != nil {
+		t.Fatalf("current totp code must be accepted, got %v", err)
+	}
+	if err = s.VerifyCode(ctx, "alice", code); err == nil || err.Error() != "invalid code" {
+		t.Fatalf("replayed totp code must be rejected, got %v", err)
+	}
+
+	// Резервный код принимается без учета регистра, но только один раз
+	if err = s.VerifyCode(ctx, "alice", strings.ToUpper(codes[0])); err != nil {
+		t.Fatalf("recovery code must be accepted, got %v", err)
+	}
+	if err = s.VerifyCode(ctx, "alice", codes[0]); err == nil || err.Error() != "invalid code" {
+		t.Fatalf("recovery code must be single-use, got %v", err)
+	}
+
+	if err = s.DisableTOTP(ctx, "alice", codes[1]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if u := users.users["alice"]; u.TOTPEnabled || u.TOTPSecret != "" {
+		t.Fatal("totp must be disabled")
+	}
+	if _, ok := recovery.codes["alice"]; ok {
+		t.Fatal("recovery codes must be deleted")
+	}
+}
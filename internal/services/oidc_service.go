@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+// OIDCServiceConfig Конфигурация для OIDCService
+type OIDCServiceConfig struct {
+	Provider		oidcProvider
+	AuthRepo		authRepository
+	IdentityRepo	identityRepository
+	StateRepo		oidcStateRepository
+	SubRepo			subRepository
+	AuditRepo		auditRepository
+	Logger			*log.Log
+}
+
+// OIDCService Вход через внешнего провайдера OpenID Connect (authorization code + PKCE)
+type OIDCService struct {
+	provider		oidcProvider
+	authRepo		authRepository
+	identityRepo	identityRepository
+	stateRepo		oidcStateRepository
+	subRepo			subRepository
+	auditRepo		auditRepository
+	logger			*log.Log
+}
+
+// Сколько ждем возврата пользователя от провайдера
+const OIDCStateTTL = time.Minute * 10
+
+// Допустимые символы имени пользователя, создаваемого по данным провайдера
+var usernameChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// NewOIDCService Конструктор для OIDCService
+func NewOIDCService(c *OIDCServiceConfig) *OIDCService {
+	return &OIDCService{
+		provider:		c.Provider,
+		authRepo:		c.AuthRepo,
+		identityRepo:	c.IdentityRepo,
+		stateRepo:		c.StateRepo,
+		subRepo:		c.SubRepo,
+		auditRepo:		c.AuditRepo,
+		logger:			c.Logger,
+	}
+}
+
+// BeginLogin Начинает вход: сохраняет state, nonce и code_verifier и возвращает адрес страницы провайдера.
+// Параметры хранятся в общем хранилище, поэтому пользователь может вернуться на любой экземпляр
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, error) {
+	ctx = log.ContextWithSpan(ctx, "BeginLogin")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("BeginLogin() started")
+	defer l.Debug("BeginLogin() done")
+
+	// Генерируем случайные значения для защиты от CSRF, подмены токена и перехвата кода
+	var values [3]string
+	for k := range values {
+		v, err := security.GenerateSecret()
+		if err != nil {
+			l.Errorf("Unable to generate secret. Error: %s", err)
+			return "", err
+		}
+		values[k] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	url, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		l.Errorf("Unable to build provider url. Error: %s", err)
+		return "", err
+	}
+
+	err = s.stateRepo.SaveState(ctx, state, models.OIDCState{Nonce: nonce, Verifier: verifier}, OIDCStateTTL)
+	if err != nil {
+		l.Errorf("Unable to save login state. Error: %s", err)
+		return "", err
+	}
+
+	return url, nil
+}
+
+// CompleteLogin Завершает вход по коду от провайдера: находит, привязывает или создает пользователя
+func (s *OIDCService) CompleteLogin(ctx context.Context, state, code string) (models.SignInUserDTO, error) {
+	ctx = log.ContextWithSpan(ctx, "CompleteLogin")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("CompleteLogin() started")
+	defer l.Debug("CompleteLogin() done")
+
+	dto := models.SignInUserDTO{}
+
+	// Каждый state используется один раз
+	if state == "" {
+		return dto, errors.New("invalid state")
+	}
+	st, err := s.stateRepo.TakeState(ctx, state)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return dto, errors.New("invalid state")
+		}
+
+		l.Errorf("Unable to take login state. Error: %s", err)
+		return dto, err
+	}
+
+	// Получаем и проверяем ID token
+	claims, err := s.provider.Exchange(ctx, code, st.Verifier, st.Nonce)
+	if err != nil {
+		l.Errorf("Unable to exchange code. Error: %s", err)
+		return dto, errors.New("provider error")
+	}
+
+	username, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return dto, err
+	}
+
+	u, err := s.authRepo.FindByUsername(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to find user. Error: %s", err)
+		return dto, err
+	}
+
+	// Проверяем, есть ли у пользователя подписка
+	if plan, _, ok := s.subRepo.FindSubPlan(ctx, username); ok {
+		dto.Subscribe = models.Sub
+		dto.Plan = plan
+	} else {
+		dto.Subscribe = models.Default
+	}
+
+	dto.Username	= u.Username
+	dto.FirstName	= u.FirstName
+	dto.LastName	= u.LastName
+	dto.Role		= u.Role
+	dto.MFA			= u.TOTPEnabled
+
+	return dto, nil
+}
+
+// resolveUser Возвращает имя пользователя, привязанного к аккаунту провайдера.
+// Без привязки аккаунт связывается с пользователем по подтвержденной почте или создается новый пользователь
+func (s *OIDCService) resolveUser(ctx context.Context, claims models.OIDCClaims) (string, error) {
+	l := s.logger.WithContext(ctx)
+	issuer := s.provider.Issuer()
+
+	identity, err := s.identityRepo.FindIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		return identity.Username, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		l.Errorf("Unable to find identity. Error: %s", err)
+		return "", err
+	}
+
+	var username string
+
+	// Связываем по почте, только если обе стороны ее подтвердили
+	email := ""
+	if claims.Email != "" && claims.EmailVerified {
+		u, err := s.authRepo.FindByEmail(ctx, claims.Email)
+		switch {
+		case err == nil && u.EmailVerified:
+			username = u.Username
+		case err == nil:
+			// Почта занята, но не подтверждена - создаем отдельного пользователя без почты
+		case errors.Is(err, pgx.ErrNoRows):
+			email = claims.Email
+		default:
+			l.Errorf("Unable to find user by email. Error: %s", err)
+			return "", err
+		}
+	}
+
+	if username == "" {
+		username, err = s.createUser(ctx, claims, email)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = s.identityRepo.CreateIdentity(ctx, models.IdentityDB{
+		Username:	username,
+		Issuer:		issuer,
+		Subject:	claims.Subject,
+		Email:		claims.Email,
+	})
+	if err != nil {
+		l.Errorf("Unable to save identity. Error: %s", err)
+		return "", err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		username,
+		Action:		models.AuditIdentityLink,
+		Target:		username,
+		Owner:		username,
+		Details:	issuer,
+	})
+
+	return username, nil
+}
+
+// createUser Создает пользователя по данным провайдера со случайным паролем
+func (s *OIDCService) createUser(ctx context.Context, claims models.OIDCClaims, email string) (string, error) {
+	l := s.logger.WithContext(ctx)
+
+	// Подбираем свободное имя пользователя
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.Split(claims.Email, "@")[0]
+	}
+	base = strings.Trim(usernameChars.ReplaceAllString(strings.ToLower(base), ""), ".-")
+	if base == "" {
+		base = "user"
+	}
+
+	username := ""
+	for k := 0; k < 5; k++ {
+		candidate := base
+		if k > 0 {
+			suffix, err := security.GenerateSecret()
+			if err != nil {
+				return "", err
+			}
+			candidate = base + "-" + suffix[:6]
+		}
+
+		_, err := s.authRepo.FindByUsername(ctx, candidate)
+		if errors.Is(err, pgx.ErrNoRows) {
+			username = candidate
+			break
+		}
+		if err != nil {
+			l.Errorf("Unable to find user. Error: %s", err)
+			return "", err
+		}
+	}
+	if username == "" {
+		return "", errors.New("unable to pick username")
+	}
+
+	// Пароль случайный: пользователь входит через провайдера или сбрасывает пароль по почте
+	secret, err := security.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	hashPassword, err := security.HashPassword(secret)
+	if err != nil {
+		l.Errorf("Unable to hash password. Error: %e", err)
+		return "", err
+	}
+
+	err = s.authRepo.CreateUser(ctx, models.UserDB{
+		Username:	username,
+		FirstName:	claims.GivenName,
+		LastName:	claims.FamilyName,
+		Password:	hashPassword,
+		Email:		email,
+	})
+	if err != nil {
+		l.Errorf("Unable to create user. Error: %s", err)
+		return "", err
+	}
+
+	// Почта подтверждена провайдером
+	if email != "" {
+		if err = s.authRepo.VerifyEmail(ctx, username, email); err != nil {
+			l.Errorf("Unable to verify email. Error: %s", err)
+		}
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		username,
+		Action:		models.AuditSignUp,
+		Target:		username,
+		Owner:		username,
+		Details:	"oidc",
+	})
+
+	return username, nil
+}
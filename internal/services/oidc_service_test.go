@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeOIDCProvider Провайдер, возвращающий заданные утверждения, для тестов сервиса
+type fakeOIDCProvider struct {
+	claims	models.OIDCClaims
+	nonce	string
+}
+
+func (p *fakeOIDCProvider) Issuer() string {
+	return "https://sso.example.com"
+}
+
+func (p *fakeOIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	p.nonce = nonce
+	return "https://sso.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *fakeOIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (models.OIDCClaims, error) {
+	return p.claims, nil
+}
+
+// fakeIdentityRepo Хранилище привязок в памяти для тестов сервиса
+type fakeIdentityRepo struct {
+	identities map[string]models.IdentityDB
+}
+
+func (r *fakeIdentityRepo) FindIdentity(ctx context.Context, issuer, subject string) (models.IdentityDB, error) {
+	i, ok := r.identities[issuer+"|"+subject]
+	if !ok {
+		return i, pgx.ErrNoRows
+	}
+	return i, nil
+}
+
+func (r *fakeIdentityRepo) CreateIdentity(ctx context.Context, identity models.IdentityDB) error {
+	r.identities[identity.Issuer+"|"+identity.Subject] = identity
+	return nil
+}
+
+func (r *fakeIdentityRepo) DeleteIdentities(ctx context.Context, username string) error {
+	for k, i := range r.identities {
+		if i.Username == username {
+			delete(r.identities, k)
+		}
+	}
+	return nil
+}
+
+// fakeOIDCStateRepo Начатые входы в памяти для тестов сервиса (общие для нескольких экземпляров)
+type fakeOIDCStateRepo struct {
+	states map[string]models.OIDCState
+}
+
+func (r *fakeOIDCStateRepo) SaveState(ctx context.Context, state string, st models.OIDCState, ttl time.Duration) error {
+	r.states[state] = st
+	return nil
+}
+
+func (r *fakeOIDCStateRepo) TakeState(ctx context.Context, state string) (models.OIDCState, error) {
+	st, ok := r.states[state]
+	if !ok {
+		return st, redis.Nil
+	}
+	delete(r.states, state)
+	return st, nil
+}
+
+// beginState Начинает вход и возвращает state из адреса провайдера
+func beginState(t *testing.T, s *OIDCService) string {
+	authURL, err := s.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	u, _ := url.Parse(authURL)
+	return u.Query().Get("state")
+}
+
+func TestOIDCServiceLogin(t *testing.T) {
+	users := newFakeAuthRepo(models.UserDB{Username: "alice", Email: "alice@example.com", EmailVerified: true})
+	identities := &fakeIdentityRepo{identities: make(map[string]models.IdentityDB)}
+	provider := &fakeOIDCProvider{}
+	states := &fakeOIDCStateRepo{states: make(map[string]models.OIDCState)}
+	newService := func() *OIDCService {
+		return NewOIDCService(&OIDCServiceConfig{
+			Provider:		provider,
+			AuthRepo:		users,
+			IdentityRepo:	identities,
+			StateRepo:		states,
+			SubRepo:		&fakeSubRepo{subs: map[string]time.Duration{}},
+			Logger:			&log.Log{Logger: zap.NewNop()},
+		})
+	}
+	s := newService()
+	ctx := context.Background()
+
+	if _, err := s.CompleteLogin(ctx, "unknown", "code"); err == nil || err.Error() != "invalid state" {
+		t.Fatalf("expected invalid state, got %v", err)
+	}
+
+	// Подтвержденная почта связывает аккаунт с существующим пользователем
+	provider.claims = models.OIDCClaims{Subject: "1", Email: "alice@example.com", EmailVerified: true}
+	state := beginState(t, s)
+	dto, err := s.CompleteLogin(ctx, state, "code")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dto.Username != "alice" {
+		t.Fatalf("expected link to alice, got %s", dto.Username)
+	}
+
+	// state одноразовый
+	if _, err = s.CompleteLogin(ctx, state, "code"); err == nil {
+		t.Fatal("state must be single-use")
+	}
+
+	// Вход, начатый на одном экземпляре, завершается на другом
+	if _, err = newService().CompleteLogin(ctx, beginState(t, s), "code"); err != nil {
+		t.Fatalf("unexpected error on other instance: %s", err)
+	}
+
+	// Неподтвержденная почта не связывает аккаунты, создается новый пользователь
+	provider.claims = models.OIDCClaims{Subject: "2", Email: "alice@example.com", PreferredUsername: "Alice"}
+	dto, err = s.CompleteLogin(ctx, beginState(t, s), "code")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dto.Username == "alice" || dto.Username == "" {
+		t.Fatalf("expected new user, got %q", dto.Username)
+	}
+	created := dto.Username
+
+	// Повторный вход находит привязку
+	dto, err = s.CompleteLogin(ctx, beginState(t, s), "code")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dto.Username != created {
+		t.Fatalf("expected %s on second login, got %s", created, dto.Username)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PromoServiceConfig Конфигурация для PromoService
+type PromoServiceConfig struct {
+	PromoRepo	promoRepository
+	AuditRepo	auditRepository
+	Plans		planCatalog
+	Logger		*log.Log
+}
+
+// PromoService Управление промокодами и списание их использований
+type PromoService struct {
+	promoRepo	promoRepository
+	auditRepo	auditRepository
+	plans		planCatalog
+	logger		*log.Log
+}
+
+// Максимальное кол-во промокодов в выдаче администратору
+const PromoLimit = 1000
+
+// NewPromoService Конструктор для PromoService
+func NewPromoService(c *PromoServiceConfig) *PromoService {
+	return &PromoService{
+		promoRepo:	c.PromoRepo,
+		auditRepo:	c.AuditRepo,
+		plans:		c.Plans,
+		logger:		c.Logger,
+	}
+}
+
+// normalizeCode Приводит промокод к единому виду (коды не зависят от регистра)
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validatePromo Проверяет параметры промокода
+func (s *PromoService) validatePromo(p models.PromoDB) error {
+	if p.Code == "" || p.MaxUses < 0 {
+		return errors.New("invalid promo")
+	}
+
+	if p.Plan != "" {
+		plan, ok := s.plans.Find(p.Plan)
+		if !ok || plan.Price <= 0 {
+			return errors.New("invalid promo")
+		}
+	}
+
+	switch p.Kind {
+	case models.PromoPercent:
+		if p.Value <= 0 || p.Value >= 100 || p.Days != 0 {
+			return errors.New("invalid promo")
+		}
+	case models.PromoFixed:
+		if p.Value <= 0 || p.Days != 0 {
+			return errors.New("invalid promo")
+		}
+	case models.PromoTrial:
+		if p.Days <= 0 || p.Value != 0 {
+			return errors.New("invalid promo")
+		}
+	default:
+		return errors.New("invalid promo")
+	}
+
+	return nil
+}
+
+// CreatePromo Создает промокод
+func (s *PromoService) CreatePromo(ctx context.Context, admin models.JWTUserInfo, p models.PromoDB) (models.PromoDB, error) {
+	ctx = log.ContextWithSpan(ctx, "CreatePromo")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("CreatePromo() started")
+	defer l.Debug("CreatePromo() done")
+
+	p.Code = normalizeCode(p.Code)
+	p.CreatedBy = admin.Username
+	if err := s.validatePromo(p); err != nil {
+		return models.PromoDB{}, err
+	}
+
+	// Проверяем, что кода еще нет
+	_, err := s.promoRepo.FindPromo(ctx, p.Code)
+	if err == nil {
+		return models.PromoDB{}, errors.New("promo already exists")
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		l.Errorf("Unable to find promo. Error: %s", err)
+		return models.PromoDB{}, err
+	}
+
+	err = s.promoRepo.CreatePromo(ctx, p)
+	if err != nil {
+		l.Errorf("Unable to create promo. Error: %s", err)
+		return models.PromoDB{}, err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		admin.Username,
+		Action:		models.AuditPromoCreate,
+		Target:		p.Code,
+		Details:	fmt.Sprintf("kind %s, value %v, days %d, plan %q, max uses %d", p.Kind, p.Value, p.Days, p.Plan, p.MaxUses),
+	})
+
+	return s.promoRepo.FindPromo(ctx, p.Code)
+}
+
+// Promos Возвращает промокоды
+func (s *PromoService) Promos(ctx context.Context) ([]models.PromoDB, error) {
+	return s.promoRepo.FindPromos(ctx, PromoLimit)
+}
+
+// DeletePromo Удаляет промокод
+func (s *PromoService) DeletePromo(ctx context.Context, admin models.JWTUserInfo, code string) error {
+	ctx = log.ContextWithSpan(ctx, "DeletePromo")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("DeletePromo() started")
+	defer l.Debug("DeletePromo() done")
+
+	code = normalizeCode(code)
+
+	err := s.promoRepo.DeletePromo(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("promo not found")
+		}
+
+		l.Errorf("Unable to delete promo. Error: %s", err)
+		return err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:	admin.Username,
+		Action:	models.AuditPromoDelete,
+		Target:	code,
+	})
+
+	return nil
+}
+
+// UsePromo Проверяет промокод и списывает его использование пользователем.
+// accept решает, подходит ли код для операции (скидка на план или пробная подписка)
+func (s *PromoService) UsePromo(ctx context.Context, code, username string, accept func(models.PromoDB) bool) (models.PromoDB, error) {
+	ctx = log.ContextWithSpan(ctx, "UsePromo")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("UsePromo() started")
+	defer l.Debug("UsePromo() done")
+
+	code = normalizeCode(code)
+
+	// Проверяем промокод, чтобы сообщить пользователю причину отказа
+	p, err := s.promoRepo.FindPromo(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PromoDB{}, errors.New("promo not found")
+		}
+
+		l.Errorf("Unable to find promo. Error: %s", err)
+		return models.PromoDB{}, err
+	}
+	if !accept(p) {
+		return models.PromoDB{}, errors.New("promo not applicable")
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now()) {
+		return models.PromoDB{}, errors.New("promo expired")
+	}
+
+	used, err := s.promoRepo.Redeemed(ctx, code, username)
+	if err != nil {
+		l.Errorf("Unable to check promo redemption. Error: %s", err)
+		return models.PromoDB{}, err
+	}
+	if used {
+		return models.PromoDB{}, errors.New("promo already used")
+	}
+
+	// Списываем использование (лимит проверяется атомарно в репозитории)
+	p, err = s.promoRepo.UsePromo(ctx, code, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PromoDB{}, errors.New("promo exhausted")
+		}
+
+		l.Errorf("Unable to use promo. Error: %s", err)
+		return models.PromoDB{}, err
+	}
+
+	return p, nil
+}
+
+// ReleasePromo Возвращает использование промокода, если операция с ним не состоялась
+func (s *PromoService) ReleasePromo(ctx context.Context, code, username string) {
+	err := s.promoRepo.ReleasePromo(ctx, normalizeCode(code), username)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Unable to release promo %s of user %s. Error: %s", code, username, err)
+	}
+}
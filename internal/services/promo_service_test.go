@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakePromoRepo Промокоды в памяти для тестов сервисов
+type fakePromoRepo struct {
+	promos	map[string]models.PromoDB
+	uses	map[string]bool
+}
+
+func newFakePromoRepo(promos ...models.PromoDB) *fakePromoRepo {
+	r := &fakePromoRepo{promos: make(map[string]models.PromoDB), uses: make(map[string]bool)}
+	for _, p := range promos {
+		r.promos[p.Code] = p
+	}
+	return r
+}
+
+func (r *fakePromoRepo) CreatePromo(ctx context.Context, p models.PromoDB) error {
+	r.promos[p.Code] = p
+	return nil
+}
+
+func (r *fakePromoRepo) FindPromo(ctx context.Context, code string) (models.PromoDB, error) {
+	p, ok := r.promos[code]
+	if !ok {
+		return models.PromoDB{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (r *fakePromoRepo) FindPromos(ctx context.Context, limit int) ([]models.PromoDB, error) {
+	result := make([]models.PromoDB, 0)
+	for _, p := range r.promos {
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (r *fakePromoRepo) DeletePromo(ctx context.Context, code string) error {
+	if _, ok := r.promos[code]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.promos, code)
+	return nil
+}
+
+func (r *fakePromoRepo) Redeemed(ctx context.Context, code, username string) (bool, error) {
+	return r.uses[code+"/"+username], nil
+}
+
+func (r *fakePromoRepo) UsePromo(ctx context.Context, code, username string) (models.PromoDB, error) {
+	p, ok := r.promos[code]
+	if !ok || (p.MaxUses > 0 && p.Uses >= p.MaxUses) || r.uses[code+"/"+username] {
+		return models.PromoDB{}, pgx.ErrNoRows
+	}
+	p.Uses++
+	r.promos[code] = p
+	r.uses[code+"/"+username] = true
+	return p, nil
+}
+
+func (r *fakePromoRepo) ReleasePromo(ctx context.Context, code, username string) error {
+	if r.uses[code+"/"+username] {
+		delete(r.uses, code+"/"+username)
+		p := r.promos[code]
+		p.Uses--
+		r.promos[code] = p
+	}
+	return nil
+}
+
+func (r *fakePromoRepo) RedactUser(ctx context.Context, username, pseudonym string) error {
+	for use := range r.uses {
+		code, user, _ := strings.Cut(use, "/")
+		if user == username {
+			delete(r.uses, use)
+			r.uses[code+"/"+pseudonym] = true
+		}
+	}
+	return nil
+}
+
+func TestPromoServiceCreatePromo(t *testing.T) {
+	s := NewPromoService(&PromoServiceConfig{
+		PromoRepo:	newFakePromoRepo(),
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	p, err := s.CreatePromo(ctx, admin, models.PromoDB{Code: "spring", Kind: models.PromoPercent, Value: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.Code != "SPRING" || p.CreatedBy != admin.Username {
+		t.Fatalf("unexpected promo: %+v", p)
+	}
+	if _, err = s.CreatePromo(ctx, admin, models.PromoDB{Code: "Spring", Kind: models.PromoTrial, Days: 7}); err == nil || err.Error() != "promo already exists" {
+		t.Fatalf("expected promo already exists, got %v", err)
+	}
+
+	invalid := []models.PromoDB{
+		{Code: "A1", Kind: models.PromoPercent, Value: 100},
+		{Code: "A2", Kind: models.PromoFixed},
+		{Code: "A3", Kind: models.PromoTrial},
+		{Code: "A4", Kind: models.PromoTrial, Days: 7, Plan: "free"},
+		{Code: "A5", Kind: "gift", Value: 1},
+	}
+	for _, p := range invalid {
+		if _, err = s.CreatePromo(ctx, admin, p); err == nil || err.Error() != "invalid promo" {
+			t.Errorf("expected invalid promo for %+v, got %v", p, err)
+		}
+	}
+}
+
+func TestQiwiServiceRedeem(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	promos := NewPromoService(&PromoServiceConfig{
+		PromoRepo:	newFakePromoRepo(
+			models.PromoDB{Code: "TRIAL", Kind: models.PromoTrial, Days: 7, MaxUses: 1},
+			models.PromoDB{Code: "OLD", Kind: models.PromoTrial, Days: 7, ExpiresAt: &past},
+			models.PromoDB{Code: "SALE", Kind: models.PromoPercent, Value: 50},
+		),
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	s := NewQiwiService(&QiwiServiceConfig{
+		SubRepo:	subs,
+		Promos:		promos,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	if _, err := s.Redeem(ctx, "alice", "trial"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if subs.subs["alice"] != 7*24*time.Hour || subs.plans["alice"] != "pro" {
+		t.Fatalf("expected 7 days of pro, got %v %q", subs.subs["alice"], subs.plans["alice"])
+	}
+
+	cases := map[string]string{
+		"TRIAL":	"promo already used",
+		"OLD":		"promo expired",
+		"SALE":		"promo not applicable",
+		"NOPE":		"promo not found",
+	}
+	for code, want := range cases {
+		if _, err := s.Redeem(ctx, "alice", code); err == nil || err.Error() != want {
+			t.Errorf("%s: expected %q, got %v", code, want, err)
+		}
+	}
+
+	// Лимит использований общий для всех пользователей
+	if _, err := s.Redeem(ctx, "bob", "TRIAL"); err == nil || err.Error() != "promo exhausted" {
+		t.Fatalf("expected promo exhausted, got %v", err)
+	}
+}
+
+func TestPromoApply(t *testing.T) {
+	cases := []struct {
+		promo	models.PromoDB
+		want	float64
+	}{
+		{models.PromoDB{Kind: models.PromoPercent, Value: 15}, 170},
+		{models.PromoDB{Kind: models.PromoPercent, Value: 33.3}, 133.4},
+		{models.PromoDB{Kind: models.PromoFixed, Value: 50}, 150},
+		{models.PromoDB{Kind: models.PromoFixed, Value: 500}, models.MinBillAmount},
+	}
+	for _, c := range cases {
+		if got := c.promo.Apply(200); got != c.want {
+			t.Errorf("%+v: expected %v, got %v", c.promo, c.want, got)
+		}
+	}
+}
@@ -5,23 +5,35 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"short_url/internal/events"
 	"short_url/internal/models"
+	"short_url/internal/security"
 	log "short_url/pkg/logger"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// Кол-во ожидающих оплаты счетов из истории, проверяемых за один проход
+const WaitingBillsLimit = 500
+
 // QiwiServiceConfig Конфиг для QiwiService
 type QiwiServiceConfig struct {
 	Key			string
 	SubRepo		subRepository
 	AuthRepo	authRepository
-	Manager		manager
-	Prices		models.ConfigPrice
+	AuditRepo	auditRepository
+	BillRepo	billRepository
+	EventRepo	qiwiEventRepository
+	Promos		promoUser
+	Plans		planCatalog
+	Events		eventBus
+	Leader		leaderElector
 	Logger		*log.Log
 }
 
@@ -30,8 +42,13 @@ type QiwiService struct {
 	key				string
 	subRepo			subRepository
 	authRepo		authRepository
-	manager			manager
-	prices			models.ConfigPrice
+	auditRepo		auditRepository
+	billRepo		billRepository
+	eventRepo		qiwiEventRepository
+	promos			promoUser
+	plans			planCatalog
+	events			eventBus
+	leader			leaderElector
 	billIds			map[string]models.SubInfo
 	subs			map[string]models.CurrentSub
 	client			*http.Client
@@ -43,9 +60,15 @@ type QiwiService struct {
 func NewQiwiService(c *QiwiServiceConfig) *QiwiService {
 	return &QiwiService{
 		key:			c.Key,
-		prices:			c.Prices,
+		plans:			c.Plans,
 		subRepo:		c.SubRepo,
 		authRepo:		c.AuthRepo,
+		auditRepo:		c.AuditRepo,
+		billRepo:		c.BillRepo,
+		eventRepo:		c.EventRepo,
+		promos:			c.Promos,
+		events:			c.Events,
+		leader:			c.Leader,
 		billIds:		make(map[string]models.SubInfo),
 		subs:			make(map[string]models.CurrentSub),
 		client:			&http.Client{},
@@ -81,7 +104,7 @@ func (s *QiwiService) makeBillRequest(ctx context.Context, amount float64) (int,
 	BReq := billReq{
 		Amount: map[string]any{
 			"currency":"RUB",
-			"value":amount,
+			"value":security.QiwiAmount(amount),
 		},
 		Comment: "Спасибо что пользуетесь нашим сервисом",
 		Exp: t.Format("2006-01-01T00:00:00+01:00"),
@@ -125,55 +148,94 @@ func (s *QiwiService) makeBillRequest(ctx context.Context, amount float64) (int,
 	return resp.StatusCode, uid, body, err
 }
 
-// CalculateSub Рассчитывает срок подписки для пользователя исходя из стоимости
-func (s *QiwiService) calculateSub(amount float64, username string) (models.SubInfo, error) {
-	// Инициализируем переменные
-	result := models.SubInfo{Username: username}
-	day := time.Hour * 24
-	var err error
-
-	// Выбираем срок подписки
-	switch amount {
-	case s.prices.Weekly:
-		result.Exp = day * 7
-	case s.prices.Monthly:
-		result.Exp = day * 31
-	case s.prices.Yearly:
-		result.Exp = day * 365
-	default:
-		err = errors.New("unknown subscribe duration")
-	}
-
-	return result, err
+// findPaidPlan Находит платный план по имени
+func (s *QiwiService) findPaidPlan(name string) (models.Plan, error) {
+	plan, ok := s.plans.Find(name)
+	if !ok || plan.Price <= 0 {
+		return plan, errors.New("plan not found")
+	}
+
+	return plan, nil
+}
+
+// Plans Возвращает каталог тарифных планов
+func (s *QiwiService) Plans(ctx context.Context) []models.Plan {
+	return s.plans.All()
 }
 
-// BillRequest Создает счет на оплату в QIWI и парсит ссылку на оплату из тела ответа
-func (s *QiwiService) BillRequest(ctx context.Context, amo float64, username string) (string, error) {
+// usePromo Списывает использование промокода (nil - промокоды не поддерживаются)
+func (s *QiwiService) usePromo(ctx context.Context, code, username string, accept func(models.PromoDB) bool) (models.PromoDB, error) {
+	if s.promos == nil {
+		return models.PromoDB{}, errors.New("promo not found")
+	}
+
+	return s.promos.UsePromo(ctx, code, username, accept)
+}
+
+// releasePromo Возвращает использование промокода неоплаченного счета
+func (s *QiwiService) releasePromo(ctx context.Context, info models.SubInfo) {
+	if s.promos == nil || info.Promo == "" {
+		return
+	}
+
+	s.promos.ReleasePromo(ctx, info.Promo, info.Username)
+}
+
+// BillRequest Создает счет на оплату плана в QIWI (со скидкой по промокоду, если он указан)
+// и парсит ссылку на оплату из тела ответа
+func (s *QiwiService) BillRequest(ctx context.Context, planName, username, promo string) (url string, err error) {
 	ctx = log.ContextWithSpan(ctx, "BillRequest")
 	l := s.logger.WithContext(ctx)
 
 	l.Debug("BillRequest() started")
 	defer l.Debug("BillRequest() done")
 
-	// Отправляем запрос
-	code, uid, jsonResp, err := s.makeBillRequest(ctx, amo)
+	// Находим план и его стоимость
+	plan, err := s.findPaidPlan(planName)
 	if err != nil {
-		l.Errorf("Unable to make bill request. Error: %s", err)
 		return "", err
 	}
 
-	// Если код не успешный, сбрасываем операцию
-	if code != http.StatusOK {
-		return "", l.RErrorf("Error: HTTP Response code (%s) not equal 200", code)
+	// Срок подписки берем из плана
+	info := models.SubInfo{
+		Username:	username,
+		Plan:		plan.Name,
+		Exp:		plan.Duration(),
+	}
+
+	// Применяем скидку по промокоду
+	amount := plan.Price
+	if promo != "" {
+		p, err := s.usePromo(ctx, promo, username, func(p models.PromoDB) bool {
+			return (p.Kind == models.PromoPercent || p.Kind == models.PromoFixed) && (p.Plan == "" || p.Plan == plan.Name)
+		})
+		if err != nil {
+			return "", err
+		}
+
+		info.Promo = p.Code
+		amount = p.Apply(plan.Price)
+
+		// Если счет не удалось выставить, промокод остается доступен
+		defer func() {
+			if err != nil {
+				s.releasePromo(ctx, info)
+			}
+		}()
 	}
 
-	// Считаем срок подписки
-	info, err := s.calculateSub(amo, username)
+	// Отправляем запрос
+	code, uid, jsonResp, err := s.makeBillRequest(ctx, amount)
 	if err != nil {
-		l.Errorf("Unable to calculate subscribe duration. Error: %s", err)
+		l.Errorf("Unable to make bill request. Error: %s", err)
 		return "", err
 	}
 
+	// Если код не успешный, сбрасываем операцию
+	if code != http.StatusOK {
+		return "", l.RErrorf("Error: HTTP Response code (%d) not equal 200", code)
+	}
+
 	// Сохраняем номер счета для мониторинга его статуса
 	err = s.saveBillId(uid, info)
 	if err != nil {
@@ -181,6 +243,21 @@ func (s *QiwiService) BillRequest(ctx context.Context, amo float64, username str
 		return "", err
 	}
 
+	// Записываем счет в историю (ошибка не мешает оплате)
+	if s.billRepo != nil {
+		err = s.billRepo.CreateBill(ctx, models.BillDB{
+			ID:			uid,
+			Username:	username,
+			Plan:		plan.Name,
+			Amount:		amount,
+			Promo:		info.Promo,
+			Status:		models.BillWaiting,
+		})
+		if err != nil {
+			l.Errorf("Unable to save bill %s to history. Error: %s", uid, err)
+		}
+	}
+
 	// Парсим ответ в структуру
 	resp := make(map[string]any)
 	err = json.Unmarshal(jsonResp, &resp)
@@ -209,7 +286,7 @@ func (s *QiwiService) makeCheckRequest(ctx context.Context, bill string) ([]byte
 	// Создаем запрос и прикрепляем заголовки
 	req, err := http.NewRequest("GET", "https://api.qiwi.com/partner/bill/v1/bills/"+bill, nil)
 	if err != nil {
-		l.Errorf("Unable to build GET request to QIWI", err)
+		l.Errorf("Unable to build GET request to QIWI. Error: %s", err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
@@ -218,7 +295,7 @@ func (s *QiwiService) makeCheckRequest(ctx context.Context, bill string) ([]byte
 	// Отправляем запрос и получаем ответ
 	resp, err := s.client.Do(req)
 	if err != nil {
-		l.Errorf("Unable to push GET request to QIWI", err)
+		l.Errorf("Unable to push GET request to QIWI. Error: %s", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -241,39 +318,83 @@ func (s *QiwiService) AddSubscribe(ctx context.Context, info models.SubInfo) err
 	l.Debug("AddSubscribe() started")
 	defer l.Debug("AddSubscribe() done")
 
-	// Модель текущей подписки
-	sub := models.CurrentSub{}
-
-	//
-	exp, ok := s.subRepo.FindSubscribe(ctx, info.Username)
+	// Находим действующую подписку и увеличиваем ее срок
+	current, exp, ok := s.subRepo.FindSubPlan(ctx, info.Username)
+	total := info.Exp
 	if ok {
-		// Отменяем назначенные операции по чистке (подписка продлена)
-		s.manager.RemoveCleanSchedule(ctx, info.Username)
-
-		// Увеличиваем срок подписки
-		sub.Exp = info.Exp + exp
-	} else {
-		// Задаем срок подписки
-		sub.Exp = info.Exp
+		total += exp
 	}
 
-	// Планируем чистку по окончанию подписки
-	err := s.manager.CleanUnsubscribeSchedule(ctx, sub, info.Username)
-	if err != nil {
-		l.Errorf("Unable to schedule data cleaning. Error: %s", err)
-		return err
+	// Без явного плана (например, при выдаче администратором) сохраняется план текущей подписки
+	plan := info.Plan
+	if plan == "" {
+		plan = s.plans.Subscribed(current).Name
 	}
 
 	// Меняем пользователю статус подписки во временном хранилище подписчиков
-	err = s.subRepo.AddSubRedis(ctx, info.Username, info.Exp)
+	err := s.subRepo.AddSubRedis(ctx, info.Username, plan, total)
 	if err != nil {
 		l.Errorf("Unable to subscribe user. Error: %s", err)
 		return err
 	}
 
+	// Подписчики переносят чистку ссылок на новое окончание подписки, пишут аудит и события вебхуков.
+	// Подписка уже оформлена, поэтому ошибка подписчика только записывается в лог
+	err = publish(ctx, s.events, events.SubscriptionActivated{
+		Username:	info.Username,
+		Plan:		plan,
+		Added:		info.Exp,
+		Exp:		total,
+		Renewal:	ok,
+	})
+	if err != nil {
+		l.Errorf("Unable to handle subscription activation. Error: %s", err)
+	}
+
 	return nil
 }
 
+// Redeem Оформляет пробную подписку по промокоду, минуя оплату
+func (s *QiwiService) Redeem(ctx context.Context, username, code string) (models.PromoDB, error) {
+	ctx = log.ContextWithSpan(ctx, "Redeem")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Redeem() started")
+	defer l.Debug("Redeem() done")
+
+	p, err := s.usePromo(ctx, code, username, func(p models.PromoDB) bool {
+		return p.Kind == models.PromoTrial
+	})
+	if err != nil {
+		return models.PromoDB{}, err
+	}
+
+	// Без плана в промокоде сохраняется план текущей подписки
+	info := models.SubInfo{
+		Username:	username,
+		Plan:		p.Plan,
+		Exp:		time.Duration(p.Days) * 24 * time.Hour,
+		Promo:		p.Code,
+	}
+
+	err = s.AddSubscribe(ctx, info)
+	if err != nil {
+		l.Errorf("Unable to add subscribe to user. Error: %s", err)
+		s.releasePromo(ctx, info)
+		return models.PromoDB{}, err
+	}
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		username,
+		Action:		models.AuditPromoRedeem,
+		Target:		p.Code,
+		Owner:		username,
+		Details:	fmt.Sprintf("%d days", p.Days),
+	})
+
+	return p, nil
+}
+
 // NotifyFromQiwi Обрабатывает уведомление от сервера Qiwi о счете
 func (s *QiwiService) NotifyFromQiwi(ctx context.Context, status, bill string) error {
 	ctx = log.ContextWithSpan(ctx, "NotifyFromQiwi")
@@ -282,32 +403,128 @@ func (s *QiwiService) NotifyFromQiwi(ctx context.Context, status, bill string) e
 	l.Debug("NotifyFromQiwi() started")
 	defer l.Debug("NotifyFromQiwi() done")
 
-	// Если счет оплачен, добавляем пользователю подписку
-	if status == "PAID" {
-		// Получаем информацию о клиенте по счету
-		info, ok := s.getSubInfo(bill)
-		if !ok {
-			return l.RError("Unable to get information about subscribe")
+	// Счет еще ожидает оплаты
+	if status == models.BillWaiting {
+		return nil
+	}
+
+	return s.processBill(ctx, bill, status)
+}
+
+// processBill Обрабатывает итоговый статус счета ровно один раз: оформляет подписку по оплаченному
+// счету или возвращает промокод неоплаченного. Повторное уведомление или гонка с опросом статуса
+// не продлевают подписку дважды
+func (s *QiwiService) processBill(ctx context.Context, bill, status string) error {
+	l := s.logger.WithContext(ctx)
+
+	// Отмечаем счет обработанным до оформления подписки
+	claimed, err := s.markProcessed(ctx, bill, status)
+	if err != nil {
+		l.Errorf("Unable to mark bill %s processed. Error: %s", bill, err)
+		return err
+	}
+	if !claimed {
+		l.Infof("Bill %s already processed", bill)
+		s.takeBillId(bill)
+		return nil
+	}
+
+	// Забираем счет из кэша, чтобы его не обработал параллельный вызов.
+	// Счет, выставленный другим экземпляром, восстанавливаем по истории
+	info, ok := s.takeBillId(bill)
+	if !ok {
+		info, ok = s.billFromHistory(ctx, bill)
+	}
+	if !ok {
+		// Неоплаченный счет достаточно отметить в истории
+		if status != models.BillPaid {
+			s.updateBillStatus(ctx, bill, status)
+			return nil
 		}
 
-		// Оформляем подписку
-		err := s.AddSubscribe(ctx, info)
+		s.unmarkProcessed(ctx, bill)
+		return l.RError("Unable to get information about subscribe")
+	}
+
+	if status == models.BillPaid {
+		// Оформляем подписку (при ошибке счет возвращается в кэш до следующей проверки)
+		err = s.AddSubscribe(ctx, info)
 		if err != nil {
 			l.Errorf("Unable to add subscribe to user. Error: %s", err)
+			s.restoreBillId(bill, info)
+			s.unmarkProcessed(ctx, bill)
 			return err
 		}
+
+		err = publish(ctx, s.events, events.BillPaid{
+			BillID:		bill,
+			Username:	info.Username,
+			Plan:		info.Plan,
+			Promo:		info.Promo,
+		})
+		if err != nil {
+			l.Errorf("Unable to handle bill payment. Error: %s", err)
+		}
+	} else {
+		// Счет не оплачен - промокод можно использовать снова
+		s.releasePromo(ctx, info)
+	}
+
+	// Обновляем статус в истории счетов
+	s.updateBillStatus(ctx, bill, status)
+
+	return nil
+}
+
+// markProcessed Отмечает счет обработанным (без репозитория исключительность дает только кэш счетов)
+func (s *QiwiService) markProcessed(ctx context.Context, bill, status string) (bool, error) {
+	if s.eventRepo == nil {
+		return true, nil
 	}
 
-	// Удаляем счет, так как он больше не WAITING (либо оплачен, либо просрочен, либо отменен)
-	err := s.deleteBillId(bill)
+	return s.eventRepo.MarkProcessed(ctx, bill, status)
+}
+
+// unmarkProcessed Снимает отметку об обработке, чтобы счет можно было обработать повторно
+func (s *QiwiService) unmarkProcessed(ctx context.Context, bill string) {
+	if s.eventRepo == nil {
+		return
+	}
+
+	err := s.eventRepo.UnmarkProcessed(ctx, bill)
 	if err != nil {
-		l.Errorf("Unable to delete billId from cache. Error: %s", err)
+		s.logger.WithContext(ctx).Errorf("Unable to unmark bill %s. Error: %s", bill, err)
 	}
+}
 
-	return nil
+// billFromHistory Восстанавливает сведения о подписке по счету из истории счетов
+func (s *QiwiService) billFromHistory(ctx context.Context, bill string) (models.SubInfo, bool) {
+	if s.billRepo == nil {
+		return models.SubInfo{}, false
+	}
+
+	b, err := s.billRepo.FindBill(ctx, bill)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.WithContext(ctx).Errorf("Unable to find bill %s in history. Error: %s", bill, err)
+		}
+		return models.SubInfo{}, false
+	}
+
+	plan, ok := s.plans.Find(b.Plan)
+	if !ok {
+		return models.SubInfo{}, false
+	}
+
+	return models.SubInfo{
+		Username:	b.Username,
+		Plan:		plan.Name,
+		Exp:		plan.Duration(),
+		Promo:		b.Promo,
+	}, true
 }
 
-// QiwiCheck Проходит по счетам в кэше и выполняет операции по ним
+// QiwiCheck Проходит по счетам в кэше и в истории счетов, ожидающим оплаты, и выполняет операции по ним
 func (s *QiwiService) QiwiCheck(ctx context.Context) error {
 	ctx = log.ContextWithSpan(ctx, "QiwiCheck")
 	l := s.logger.WithContext(ctx)
@@ -315,11 +532,28 @@ func (s *QiwiService) QiwiCheck(ctx context.Context) error {
 	l.Debug("QiwiCheck() started")
 	defer l.Debug("QiwiCheck() done")
 
-	// Слайс с номерами счетов для удаления из кэша (в статусе != WAITING)
-	billToDelete := make([]string, 0)
-
+	// Копируем счета, чтобы не держать блокировку во время запросов к QIWI
 	s.mux.RLock()
-	for bill, subInfo := range s.billIds {
+	bills := make([]string, 0, len(s.billIds))
+	for bill := range s.billIds {
+		bills = append(bills, bill)
+	}
+	s.mux.RUnlock()
+
+	// Добавляем счета, выставленные другими экземплярами
+	if s.billRepo != nil {
+		waiting, err := s.billRepo.FindWaitingBills(ctx, WaitingBillsLimit)
+		if err != nil {
+			l.Errorf("Unable to find waiting bills. Error: %s", err)
+		}
+		for _, b := range waiting {
+			if _, ok := s.getSubInfo(b.ID); !ok {
+				bills = append(bills, b.ID)
+			}
+		}
+	}
+
+	for _, bill := range bills {
 		// Определяем структуру для хранения ответа
 		resp := make(map[string]any)
 
@@ -327,48 +561,32 @@ func (s *QiwiService) QiwiCheck(ctx context.Context) error {
 		jsonResp, err := s.makeCheckRequest(ctx, bill)
 		if err != nil {
 			l.Errorf("Unable to make GET request to QIWI. Error: %s", err)
-			return err // log
+			continue
 		}
 
 		// Парсим ответ в структуру
 		err = json.Unmarshal(jsonResp, &resp)
 		if err != nil {
 			l.Errorf("Unable to unmarshal response body. Error: %s", err)
-			return err
+			continue
 		}
 
 		// Проверяем поле статуса счета в структуре ответа
-		status, ok := resp["status"].(map[string]string)
+		status, ok := resp["status"].(map[string]any)
 		if !ok {
-			return l.RError("Unable to get bill status from response body")
+			l.Errorf("Unable to get bill %s status from response body", bill)
+			continue
 		}
+		value, _ := status["value"].(string)
 
-		// Если статус счета уже не в ожидании, можно удалять его из кэша
-		if status["value"] != "WAITING" {
-			
-			// Добавляем счет на удаление
-			billToDelete = append(billToDelete, bill)
-
-			// Если статус платежа оплачен, присваиваем пользователю подписку
-			if status["value"] == "PAID" {
-				// Оформляем подписку
-				err := s.AddSubscribe(ctx, subInfo)
-				if err != nil {
-					l.Errorf("Unable to add subscribe to user. Error: %s", err)
-					return err
-				}
+		// Если статус счета уже не в ожидании, обрабатываем его (счет удаляется из кэша)
+		if value != models.BillWaiting {
+			err = s.processBill(ctx, bill, value)
+			if err != nil {
+				l.Errorf("Unable to process bill %s. Error: %s", bill, err)
 			}
 		}
 	}
-	s.mux.RUnlock()
-
-	// Удаляем из кэша каждый счет, помеченый на удаление
-	for k := 0; k < len(billToDelete); k++ {
-		err := s.deleteBillId(billToDelete[k])
-		if err != nil {
-			l.Errorf("Unable to delete useless billId. Error: %s", err)
-		}
-	}
 
 	return nil
 }
@@ -390,8 +608,10 @@ func (s *QiwiService) QiwiCheckCycle(ctx context.Context) chan struct{} {
 		for {
 			select {
 			case <-ticker.C:
-				// Итерируемся по Id счетов
-				s.QiwiCheck(ctx)
+				// Статусы счетов опрашивает только ведущий экземпляр
+				if isLeader(s.leader) {
+					s.QiwiCheck(ctx)
+				}
 				
 			case <-doneChannel:
 				// Останавливаем тикер
@@ -407,6 +627,18 @@ func (s *QiwiService) QiwiCheckCycle(ctx context.Context) chan struct{} {
 	return doneChannel
 }
 
+// updateBillStatus Обновляет статус счета в истории счетов
+func (s *QiwiService) updateBillStatus(ctx context.Context, bill, status string) {
+	if s.billRepo == nil {
+		return
+	}
+
+	err := s.billRepo.UpdateBillStatus(ctx, bill, status)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Unable to update bill %s status. Error: %s", bill, err)
+	}
+}
+
 // saveBillId Добавляет Id счета оплаты в кэш
 func (s *QiwiService) saveBillId(billId string, info models.SubInfo) error {
 	_, ok := s.getSubInfo(billId)
@@ -419,16 +651,24 @@ func (s *QiwiService) saveBillId(billId string, info models.SubInfo) error {
 	return nil
 }
 
-// deleteBillId Удаляет Id счета оплаты из кэша
-func (s *QiwiService) deleteBillId(billId string) error {
-	_, ok := s.getSubInfo(billId)
-	if !ok {
-		return errors.New("billId not found")
+// takeBillId Атомарно забирает счет оплаты из кэша
+func (s *QiwiService) takeBillId(billId string) (models.SubInfo, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	info, ok := s.billIds[billId]
+	if ok {
+		delete(s.billIds, billId)
 	}
+
+	return info, ok
+}
+
+// restoreBillId Возвращает счет в кэш, если обработать его не удалось
+func (s *QiwiService) restoreBillId(billId string, info models.SubInfo) {
 	s.mux.Lock()
-	delete(s.billIds, billId)
+	s.billIds[billId] = info
 	s.mux.Unlock()
-	return nil
 }
 
 // getSubInfo Получает информацию о пользователе и сроке подписки по номеру счета из кэша
@@ -438,3 +678,90 @@ func (s *QiwiService) getSubInfo(billId string) (models.SubInfo, bool) {
 	s.mux.RUnlock()
 	return info, ok
 }
+
+// CancelBills Отменяет неоплаченные счета пользователя (при удалении аккаунта). Счет отмечается обработанным
+// и отклоненным, поэтому оплата, о которой станет известно позже, подписку не оформит
+func (s *QiwiService) CancelBills(ctx context.Context, username string) {
+	l := s.logger.WithContext(ctx)
+
+	bills := make([]string, 0)
+	s.mux.Lock()
+	for bill, info := range s.billIds {
+		if info.Username == username {
+			bills = append(bills, bill)
+			delete(s.billIds, bill)
+		}
+	}
+	s.mux.Unlock()
+
+	// Счета, выставленные другими экземплярами, есть только в истории
+	if s.billRepo != nil {
+		history, err := s.billRepo.FindBills(ctx, username, BillsLimit)
+		if err != nil {
+			l.Errorf("Unable to find bills of user %s. Error: %s", username, err)
+		}
+		for _, b := range history {
+			if b.Status == models.BillWaiting {
+				bills = append(bills, b.ID)
+			}
+		}
+	}
+
+	for _, bill := range bills {
+		claimed, err := s.markProcessed(ctx, bill, models.BillRejected)
+		if err != nil {
+			l.Errorf("Unable to cancel bill %s. Error: %s", bill, err)
+			continue
+		}
+		if claimed {
+			s.updateBillStatus(ctx, bill, models.BillRejected)
+		}
+	}
+}
+
+// PendingBills Возвращает счета, ожидающие оплаты, из истории счетов (общей для всех экземпляров).
+// Если истории нет или она недоступна, возвращает счета из кэша этого экземпляра
+func (s *QiwiService) PendingBills(ctx context.Context) []models.BillInfo {
+	if s.billRepo != nil {
+		waiting, err := s.billRepo.FindWaitingBills(ctx, WaitingBillsLimit)
+		if err == nil {
+			result := make([]models.BillInfo, 0, len(waiting))
+			for _, b := range waiting {
+				// Срок подписки берем из кэша, если счет выставлен этим экземпляром, иначе - по плану
+				info, ok := s.getSubInfo(b.ID)
+				if !ok {
+					info = models.SubInfo{Username: b.Username, Plan: b.Plan}
+					if plan, found := s.plans.Find(b.Plan); found {
+						info.Exp = plan.Duration()
+					}
+				}
+
+				result = append(result, models.BillInfo{
+					BillID:		b.ID,
+					Username:	info.Username,
+					Plan:		info.Plan,
+					Exp:		info.Exp,
+				})
+			}
+
+			return result
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to find waiting bills. Error: %s", err)
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	result := make([]models.BillInfo, 0, len(s.billIds))
+	for bill, info := range s.billIds {
+		result = append(result, models.BillInfo{
+			BillID:		bill,
+			Username:	info.Username,
+			Plan:		info.Plan,
+			Exp:		info.Exp,
+		})
+	}
+
+	return result
+}
@@ -1 +1,179 @@
 package services
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeEventRepo Учет обработанных счетов в памяти для тестов
+type fakeEventRepo struct {
+	processed map[string]string
+}
+
+func (r *fakeEventRepo) MarkProcessed(ctx context.Context, billID, status string) (bool, error) {
+	if _, ok := r.processed[billID]; ok {
+		return false, nil
+	}
+	r.processed[billID] = status
+	return true, nil
+}
+
+func (r *fakeEventRepo) UnmarkProcessed(ctx context.Context, billID string) error {
+	delete(r.processed, billID)
+	return nil
+}
+
+func newTestQiwiService(t *testing.T, subs *fakeSubRepo, events *fakeEventRepo, bills *fakeBillRepo) *QiwiService {
+	return NewQiwiService(&QiwiServiceConfig{
+		SubRepo:	subs,
+		BillRepo:	bills,
+		EventRepo:	events,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+}
+
+func TestQiwiServiceNotifyIdempotent(t *testing.T) {
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	events := &fakeEventRepo{processed: make(map[string]string)}
+	bills := &fakeBillRepo{bills: []models.BillDB{{ID: "b1", Username: "alice", Status: models.BillWaiting}}}
+	s := newTestQiwiService(t, subs, events, bills)
+	ctx := context.Background()
+
+	s.saveBillId("b1", models.SubInfo{Username: "alice", Plan: "pro", Exp: time.Hour})
+
+	// Повторное уведомление не продлевает подписку второй раз
+	for k := 0; k < 2; k++ {
+		if err := s.NotifyFromQiwi(ctx, models.BillPaid, "b1"); err != nil {
+			t.Fatalf("notification %d: unexpected error: %s", k, err)
+		}
+	}
+	if subs.subs["alice"] != time.Hour {
+		t.Fatalf("expected single hour of subscription, got %s", subs.subs["alice"])
+	}
+	if bills.bills[0].Status != models.BillPaid || events.processed["b1"] != models.BillPaid {
+		t.Fatalf("expected bill marked paid, got %q / %q", bills.bills[0].Status, events.processed["b1"])
+	}
+	if len(s.PendingBills(ctx)) != 0 {
+		t.Fatal("processed bill must leave the cache")
+	}
+
+	// После перезапуска кэш счетов пуст, но отметка об обработке сохранилась
+	restarted := newTestQiwiService(t, subs, events, bills)
+	if err := restarted.NotifyFromQiwi(ctx, models.BillPaid, "b1"); err != nil {
+		t.Fatalf("unexpected error after restart: %s", err)
+	}
+	if subs.subs["alice"] != time.Hour {
+		t.Fatalf("subscription extended after restart: %s", subs.subs["alice"])
+	}
+}
+
+func TestQiwiServiceNotifyUnknownBill(t *testing.T) {
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	events := &fakeEventRepo{processed: make(map[string]string)}
+	s := newTestQiwiService(t, subs, events, &fakeBillRepo{})
+	ctx := context.Background()
+
+	// Оплаченный счет без данных о подписке остается необработанным
+	if err := s.NotifyFromQiwi(ctx, models.BillPaid, "lost"); err == nil {
+		t.Fatal("expected error for unknown paid bill")
+	}
+	if _, ok := events.processed["lost"]; ok {
+		t.Fatal("unknown paid bill must not be marked processed")
+	}
+
+	// Неоплаченный счет достаточно отметить
+	if err := s.NotifyFromQiwi(ctx, "EXPIRED", "gone"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if events.processed["gone"] != "EXPIRED" {
+		t.Fatal("expected expired bill marked processed")
+	}
+}
+
+func TestQiwiServiceNotifyFromHistory(t *testing.T) {
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	events := &fakeEventRepo{processed: make(map[string]string)}
+	bills := &fakeBillRepo{bills: []models.BillDB{{ID: "b1", Username: "alice", Plan: "pro", Status: models.BillWaiting}}}
+	s := newTestQiwiService(t, subs, events, bills)
+	ctx := context.Background()
+
+	// Счет выставлен другим экземпляром: в кэше его нет, сведения берутся из истории
+	if err := s.NotifyFromQiwi(ctx, models.BillPaid, "b1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pro, _ := testCatalog(t).Find("pro")
+	if subs.subs["alice"] != pro.Duration() || subs.plans["alice"] != "pro" {
+		t.Fatalf("expected pro subscription for %s, got %q for %s", pro.Duration(), subs.plans["alice"], subs.subs["alice"])
+	}
+	if bills.bills[0].Status != models.BillPaid {
+		t.Fatalf("expected bill marked paid, got %q", bills.bills[0].Status)
+	}
+}
+
+func TestQiwiServiceCancelBills(t *testing.T) {
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	events := &fakeEventRepo{processed: make(map[string]string)}
+	bills := &fakeBillRepo{bills: []models.BillDB{
+		{ID: "b1", Username: "alice", Plan: "pro", Status: models.BillWaiting},
+		{ID: "b2", Username: "alice", Plan: "pro", Status: models.BillPaid},
+		{ID: "b3", Username: "bob", Plan: "pro", Status: models.BillWaiting},
+	}}
+	s := newTestQiwiService(t, subs, events, bills)
+	ctx := context.Background()
+
+	// Счет выставлен другим экземпляром: в кэше его нет, только в истории
+	s.saveBillId("b4", models.SubInfo{Username: "alice", Plan: "pro", Exp: time.Hour})
+	s.CancelBills(ctx, "alice")
+
+	if bills.bills[0].Status != models.BillRejected || bills.bills[1].Status != models.BillPaid || bills.bills[2].Status != models.BillWaiting {
+		t.Fatalf("unexpected bill statuses: %+v", bills.bills)
+	}
+	if _, ok := s.getSubInfo("b4"); ok {
+		t.Fatal("cancelled bill must leave the cache")
+	}
+	if pending := s.PendingBills(ctx); len(pending) != 1 || pending[0].BillID != "b3" {
+		t.Fatalf("only bills of other users must stay pending, got %+v", pending)
+	}
+
+	// Оплата отмененного счета, о которой стало известно позже, подписку не оформляет
+	for _, bill := range []string{"b1", "b4"} {
+		if err := s.NotifyFromQiwi(ctx, models.BillPaid, bill); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if _, ok := subs.subs["alice"]; ok {
+		t.Fatalf("cancelled bill must not subscribe, got %s", subs.subs["alice"])
+	}
+}
+
+func TestQiwiServicePendingBills(t *testing.T) {
+	bills := &fakeBillRepo{bills: []models.BillDB{
+		{ID: "b1", Username: "alice", Plan: "pro", Status: models.BillWaiting},
+		{ID: "b2", Username: "bob", Plan: "pro", Status: models.BillPaid},
+	}}
+	ctx := context.Background()
+
+	// Счет, выставленный другим экземпляром или до перезапуска, виден по истории счетов
+	s := newTestQiwiService(t, &fakeSubRepo{subs: make(map[string]time.Duration)}, &fakeEventRepo{processed: make(map[string]string)}, bills)
+	pending := s.PendingBills(ctx)
+	if len(pending) != 1 || pending[0].BillID != "b1" || pending[0].Username != "alice" || pending[0].Exp == 0 {
+		t.Fatalf("unexpected pending bills: %+v", pending)
+	}
+
+	// Без истории счетов используется кэш экземпляра
+	s = NewQiwiService(&QiwiServiceConfig{
+		SubRepo:	&fakeSubRepo{subs: make(map[string]time.Duration)},
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	s.saveBillId("b3", models.SubInfo{Username: "carol", Plan: "pro", Exp: time.Hour})
+	if pending = s.PendingBills(ctx); len(pending) != 1 || pending[0].BillID != "b3" {
+		t.Fatalf("unexpected cached bills: %+v", pending)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReportServiceConfig Конфигурация для ReportService
+type ReportServiceConfig struct {
+	ReportRepo	reportRepository
+	LinkRepo	linkRepository
+	AuditRepo	auditRepository
+	Logger		*log.Log
+}
+
+// ReportService Принимает жалобы на ссылки и ведет очередь модерации
+type ReportService struct {
+	reportRepo	reportRepository
+	linkRepo	linkRepository
+	auditRepo	auditRepository
+	logger		*log.Log
+}
+
+const (
+	QueueLimit	= 100	// Максимальное кол-во жалоб в выдаче очереди модерации
+
+	ResolveDisable	= "disable"	// Решение по жалобе: заблокировать ссылку
+	ResolveDismiss	= "dismiss"	// Решение по жалобе: отклонить жалобу
+)
+
+// NewReportService Конструктор для ReportService
+func NewReportService(c *ReportServiceConfig) *ReportService {
+	return &ReportService{
+		reportRepo:	c.ReportRepo,
+		linkRepo:	c.LinkRepo,
+		auditRepo:	c.AuditRepo,
+		logger:		c.Logger,
+	}
+}
+
+// findLink Находит ссылку в БД
+func (s *ReportService) findLink(ctx context.Context, link string) (models.LinkDataDB, error) {
+	data, err := s.linkRepo.FindLink(ctx, link)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return data, errors.New("link not found")
+		}
+
+		s.logger.WithContext(ctx).Errorf("Unable to find link in Redis. Error: %s", err)
+		return data, err
+	}
+
+	return data, nil
+}
+
+// ReportLink Сохраняет жалобу на ссылку от любого посетителя
+func (s *ReportService) ReportLink(ctx context.Context, link, reason, reporter string) (int64, error) {
+	ctx = log.ContextWithSpan(ctx, "ReportLink")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ReportLink() started")
+	defer l.Debug("ReportLink() done")
+
+	// Жаловаться можно только на существующие ссылки
+	if _, err := s.findLink(ctx, link); err != nil {
+		return 0, err
+	}
+
+	// Сохраняем жалобу
+	id, err := s.reportRepo.CreateReport(ctx, models.ReportDB{
+		Link:		link,
+		Reason:		reason,
+		Reporter:	reporter,
+	})
+	if err != nil {
+		l.Errorf("Unable to save report. Error: %s", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Reports Возвращает очередь модерации (открытые жалобы)
+func (s *ReportService) Reports(ctx context.Context) ([]models.ReportDB, error) {
+	ctx = log.ContextWithSpan(ctx, "Reports")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Reports() started")
+	defer l.Debug("Reports() done")
+
+	reports, err := s.reportRepo.FindReports(ctx, models.ReportOpen, QueueLimit)
+	if err != nil {
+		l.Errorf("Unable to get reports. Error: %s", err)
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// ResolveReport Принимает решение по жалобе: блокирует ссылку или отклоняет жалобу.
+// Решение распространяется на все открытые жалобы на эту ссылку. Причину блокировки, которую видят
+// посетители и владелец ссылки, указывает администратор: текст жалобы остается внутренним
+func (s *ReportService) ResolveReport(ctx context.Context, admin models.JWTUserInfo, id int64, action, reason string) error {
+	ctx = log.ContextWithSpan(ctx, "ResolveReport")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("ResolveReport() started")
+	defer l.Debug("ResolveReport() done")
+
+	// Находим жалобу
+	report, err := s.reportRepo.FindReport(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("report not found")
+		}
+
+		l.Errorf("Unable to find report. Error: %s", err)
+		return err
+	}
+	if report.Status != models.ReportOpen {
+		return errors.New("report already resolved")
+	}
+
+	var status string
+	switch action {
+	case ResolveDisable:
+		status = models.ReportDisabled
+		if reason == "" {
+			return errors.New("reason required")
+		}
+
+		// Находим ссылку и проверяем права на блокировку
+		data, err := s.findLink(ctx, report.Link)
+		if err != nil {
+			return err
+		}
+		if err = authorizeLink(admin, data, actionDisable); err != nil {
+			return err
+		}
+
+		// Блокируем ссылку с причиной от администратора
+		if err = s.linkRepo.DisableLink(ctx, report.Link, reason); err != nil {
+			l.Errorf("Unable to disable link in Redis. Error: %s", err)
+			return err
+		}
+	case ResolveDismiss:
+		status = models.ReportDismissed
+	default:
+		return errors.New("unknown action")
+	}
+
+	// Закрываем жалобы на ссылку
+	if err = s.reportRepo.ResolveReports(ctx, report.Link, status, admin.Username); err != nil {
+		l.Errorf("Unable to resolve reports. Error: %s", err)
+		return err
+	}
+
+	l.Infof("admin %s resolved reports on link %s with status %s", admin.Username, report.Link, status)
+
+	recordAudit(ctx, s.auditRepo, l, models.AuditEntry{
+		Actor:		admin.Username,
+		Action:		models.AuditReportResolve,
+		Target:		report.Link,
+		Details:	fmt.Sprintf("report: %d, status: %s", report.ID, status),
+	})
+
+	return nil
+}
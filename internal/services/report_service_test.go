@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeReportRepo Хранилище жалоб в памяти для тестов сервиса
+type fakeReportRepo struct {
+	reports	[]models.ReportDB
+}
+
+func (r *fakeReportRepo) CreateReport(ctx context.Context, report models.ReportDB) (int64, error) {
+	report.ID = int64(len(r.reports) + 1)
+	report.Status = models.ReportOpen
+	r.reports = append(r.reports, report)
+	return report.ID, nil
+}
+
+func (r *fakeReportRepo) FindReports(ctx context.Context, status string, limit int) ([]models.ReportDB, error) {
+	result := make([]models.ReportDB, 0)
+	for _, report := range r.reports {
+		if report.Status == status {
+			result = append(result, report)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeReportRepo) FindReport(ctx context.Context, id int64) (models.ReportDB, error) {
+	if id < 1 || int(id) > len(r.reports) {
+		return models.ReportDB{}, pgx.ErrNoRows
+	}
+	return r.reports[id-1], nil
+}
+
+func (r *fakeReportRepo) ResolveReports(ctx context.Context, link, status, admin string) error {
+	for k := range r.reports {
+		if r.reports[k].Link == link && r.reports[k].Status == models.ReportOpen {
+			r.reports[k].Status = status
+			r.reports[k].ResolvedBy = admin
+		}
+	}
+	return nil
+}
+
+func (r *fakeReportRepo) RedactResolver(ctx context.Context, username, pseudonym string) error {
+	for k := range r.reports {
+		if r.reports[k].ResolvedBy == username {
+			r.reports[k].ResolvedBy = pseudonym
+		}
+	}
+	return nil
+}
+
+func TestReportServiceDisableFlow(t *testing.T) {
+	links := newFakeLinkRepo(models.LinkDataDB{Link: "phish", FullURL: "https://evil.example", Owner: "alice"})
+	reports := &fakeReportRepo{}
+	logger := &log.Log{Logger: zap.NewNop()}
+	s := NewReportService(&ReportServiceConfig{ReportRepo: reports, LinkRepo: links, Logger: logger})
+	ctx := context.Background()
+
+	if _, err := s.ReportLink(ctx, "missing", "spam", "127.0.0.1"); err == nil || err.Error() != "link not found" {
+		t.Fatalf("expected link not found, got %v", err)
+	}
+
+	id, err := s.ReportLink(ctx, "phish", "phishing page", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("unable to report link: %s", err)
+	}
+
+	// Обычный пользователь не может выносить решения по жалобам
+	if err = s.ResolveReport(ctx, bob, id, ResolveDisable, "phishing"); err == nil || err.Error() != "access denied" {
+		t.Fatalf("expected access denied, got %v", err)
+	}
+
+	// Без причины от администратора ссылка не блокируется
+	if err = s.ResolveReport(ctx, admin, id, ResolveDisable, ""); err == nil || err.Error() != "reason required" {
+		t.Fatalf("expected reason required, got %v", err)
+	}
+
+	if err = s.ResolveReport(ctx, admin, id, ResolveDisable, "phishing"); err != nil {
+		t.Fatalf("unable to resolve report: %s", err)
+	}
+
+	// Ссылка сохраняет данные, но помечена заблокированной
+	data, err := newTestLinkService(links).FindLink(ctx, "phish")
+	if err != nil {
+		t.Fatalf("disabled link must keep its data, got error: %s", err)
+	}
+	if !data.Disabled || data.DisabledReason != "phishing" || data.FullURL != "https://evil.example" {
+		t.Fatalf("unexpected link state: %+v", data)
+	}
+
+	// Владелец видит блокировку и ее причину
+	own, err := newTestLinkService(links).GetLink(ctx, alice, "phish")
+	if err != nil || !own.Disabled || own.DisabledReason != "phishing" {
+		t.Fatalf("owner must see disabled state, got %+v, %v", own, err)
+	}
+
+	if err = s.ResolveReport(ctx, admin, id, ResolveDismiss, ""); err == nil || err.Error() != "report already resolved" {
+		t.Fatalf("expected report already resolved, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"sort"
+	"time"
+)
+
+// SubscriptionServiceConfig Конфигурация для SubscriptionService
+type SubscriptionServiceConfig struct {
+	SubRepo		subscriptionRepository
+	BillRepo	billRepository
+	LinkRepo	linkRepository
+	Manager		manager
+	Plans		planCatalog
+	Grace		time.Duration		// Отсрочка чистки ссылок после окончания подписки
+	Logger		*log.Log
+}
+
+// SubscriptionService Отдает пользователю состояние подписки и историю счетов
+type SubscriptionService struct {
+	subRepo		subscriptionRepository
+	billRepo	billRepository
+	linkRepo	linkRepository
+	manager		manager
+	plans		planCatalog
+	grace		time.Duration
+	logger		*log.Log
+}
+
+// Кол-во последних счетов в истории
+const BillsLimit = 100
+
+// NewSubscriptionService Конструктор для SubscriptionService
+func NewSubscriptionService(c *SubscriptionServiceConfig) *SubscriptionService {
+	return &SubscriptionService{
+		subRepo:	c.SubRepo,
+		billRepo:	c.BillRepo,
+		linkRepo:	c.LinkRepo,
+		manager:	c.Manager,
+		plans:		c.Plans,
+		grace:		c.Grace,
+		logger:		c.Logger,
+	}
+}
+
+// State Возвращает актуальное состояние подписки пользователя (для проверки прав на каждый запрос)
+func (s *SubscriptionService) State(ctx context.Context, username string) (models.Subscribe, string, error) {
+	plan, ok, err := s.subRepo.SubState(ctx, username)
+	if err != nil {
+		return models.Default, "", err
+	}
+	if !ok {
+		return models.Default, "", nil
+	}
+
+	return models.Sub, plan, nil
+}
+
+// Subscription Возвращает план, сроки подписки и ссылки, которые будут удалены по ее окончании
+func (s *SubscriptionService) Subscription(ctx context.Context, username string) (models.SubscriptionDTO, error) {
+	ctx = log.ContextWithSpan(ctx, "Subscription")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Subscription() started")
+	defer l.Debug("Subscription() done")
+
+	free := s.plans.Free()
+
+	// Без подписки действует бесплатный план
+	sub, ok := s.subRepo.FindSubscription(ctx, username)
+	if !ok {
+		return models.SubscriptionDTO{Plan: free}, nil
+	}
+
+	expiresAt := time.Now().Add(sub.Exp)
+	result := models.SubscriptionDTO{
+		Active:		true,
+		Plan:		s.plans.Subscribed(sub.Plan),
+		ExpiresAt:	&expiresAt,
+	}
+	if !sub.Start.IsZero() {
+		result.Start = &sub.Start
+	}
+
+	// Ссылки, которые будут удалены при переходе на бесплатный план
+	remove, err := s.manager.OverQuota(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to get links over quota. Error: %s", err)
+		return models.SubscriptionDTO{}, err
+	}
+
+	keep, err := s.linkRepo.KeepLinks(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to get links to keep. Error: %s", err)
+		return models.SubscriptionDTO{}, err
+	}
+	sort.Strings(keep)
+
+	result.Downgrade = &models.DowngradeDTO{
+		At:				expiresAt.Add(s.grace),
+		Plan:			free.Name,
+		KeepLinks:		keep,
+		DeleteLinks:	remove,
+	}
+
+	return result, nil
+}
+
+// SetKeepLinks Сохраняет ссылки, которые пользователь хочет оставить после окончания подписки.
+// Выбранные ссылки должны укладываться в лимиты бесплатного плана
+func (s *SubscriptionService) SetKeepLinks(ctx context.Context, username string, links []string) error {
+	ctx = log.ContextWithSpan(ctx, "SetKeepLinks")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("SetKeepLinks() started")
+	defer l.Debug("SetKeepLinks() done")
+
+	all, err := s.linkRepo.GetAllLinks(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to get all user links. Error: %s", err)
+		return err
+	}
+	owned := make(map[string]models.LinkDataDB, len(all))
+	for _, data := range all {
+		owned[data.Link] = data
+	}
+
+	// Проверяем владельца и считаем выбранные ссылки по категориям
+	amo := models.LinksAmount{}
+	unique := make([]string, 0, len(links))
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		data, ok := owned[link]
+		if !ok {
+			return errors.New("link not found")
+		}
+
+		amo.All++
+		if data.Perm {
+			amo.Perm++
+		} else if data.Custom {
+			amo.Custom++
+		}
+		unique = append(unique, link)
+	}
+
+	quota := s.plans.Free().Quota
+	if amo.All > quota.All || amo.Perm > quota.Perm || amo.Custom > quota.Custom {
+		return errors.New("limit exceeded")
+	}
+
+	err = s.linkRepo.SetKeepLinks(ctx, username, unique)
+	if err != nil {
+		l.Errorf("Unable to save links to keep. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Bills Возвращает последние счета пользователя
+func (s *SubscriptionService) Bills(ctx context.Context, username string) ([]models.BillDB, error) {
+	ctx = log.ContextWithSpan(ctx, "Bills")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Bills() started")
+	defer l.Debug("Bills() done")
+
+	bills, err := s.billRepo.FindBills(ctx, username, BillsLimit)
+	if err != nil {
+		l.Errorf("Unable to find bills of user %s. Error: %s", username, err)
+		return nil, err
+	}
+
+	return bills, nil
+}
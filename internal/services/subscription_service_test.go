@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeSubscriptionRepo Действующие подписки в памяти для тестов сервиса
+type fakeSubscriptionRepo struct {
+	subs	map[string]models.SubscriptionDB
+	err		error
+}
+
+func (r *fakeSubscriptionRepo) FindSubscription(ctx context.Context, username string) (models.SubscriptionDB, bool) {
+	sub, ok := r.subs[username]
+	return sub, ok
+}
+
+func (r *fakeSubscriptionRepo) SubState(ctx context.Context, username string) (string, bool, error) {
+	if r.err != nil {
+		return "", false, r.err
+	}
+	sub, ok := r.subs[username]
+	return sub.Plan, ok, nil
+}
+
+// fakeBillRepo История счетов в памяти для тестов сервисов
+type fakeBillRepo struct {
+	bills []models.BillDB
+}
+
+func (r *fakeBillRepo) CreateBill(ctx context.Context, bill models.BillDB) error {
+	r.bills = append(r.bills, bill)
+	return nil
+}
+
+func (r *fakeBillRepo) UpdateBillStatus(ctx context.Context, id, status string) error {
+	for k := range r.bills {
+		if r.bills[k].ID == id {
+			r.bills[k].Status = status
+		}
+	}
+	return nil
+}
+
+func (r *fakeBillRepo) FindBill(ctx context.Context, id string) (models.BillDB, error) {
+	for _, b := range r.bills {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return models.BillDB{}, pgx.ErrNoRows
+}
+
+func (r *fakeBillRepo) FindWaitingBills(ctx context.Context, limit int) ([]models.BillDB, error) {
+	result := make([]models.BillDB, 0)
+	for _, b := range r.bills {
+		if b.Status == models.BillWaiting && len(result) < limit {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeBillRepo) FindBills(ctx context.Context, username string, limit int) ([]models.BillDB, error) {
+	result := make([]models.BillDB, 0)
+	for k := len(r.bills) - 1; k >= 0 && len(result) < limit; k-- {
+		if r.bills[k].Username == username {
+			result = append(result, r.bills[k])
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeBillRepo) RedactUser(ctx context.Context, username, pseudonym string) error {
+	for k := range r.bills {
+		if r.bills[k].Username == username {
+			r.bills[k].Username = pseudonym
+		}
+	}
+	return nil
+}
+
+func TestSubscriptionServiceSubscription(t *testing.T) {
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	s := NewSubscriptionService(&SubscriptionServiceConfig{
+		SubRepo:	&fakeSubscriptionRepo{subs: map[string]models.SubscriptionDB{
+			"alice": {Plan: "pro", Start: start, Exp: time.Hour},
+		}},
+		BillRepo:	&fakeBillRepo{},
+		LinkRepo:	&fakeLinkRepo{keep: map[string][]string{"alice": {"c"}}},
+		Manager:	&fakeManager{overQuota: map[string][]string{"alice": {"a", "b"}}},
+		Plans:		testCatalog(t),
+		Grace:		72 * time.Hour,
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	sub, err := s.Subscription(ctx, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !sub.Active || sub.Plan.Name != "pro" || sub.Start == nil || !sub.Start.Equal(start) || sub.ExpiresAt == nil {
+		t.Fatalf("unexpected subscription: %+v", sub)
+	}
+	if sub.Downgrade == nil || sub.Downgrade.Plan != "free" || !sub.Downgrade.At.Equal(sub.ExpiresAt.Add(72*time.Hour)) {
+		t.Fatalf("unexpected downgrade: %+v", sub.Downgrade)
+	}
+	if keep := sub.Downgrade.KeepLinks; len(keep) != 1 || keep[0] != "c" {
+		t.Fatalf("expected link c to be kept, got %v", keep)
+	}
+	if links := sub.Downgrade.DeleteLinks; len(links) != 2 || links[0] != "a" || links[1] != "b" {
+		t.Fatalf("expected links a, b to be deleted, got %v", links)
+	}
+
+	// Без подписки действует бесплатный план
+	sub, err = s.Subscription(ctx, "bob")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sub.Active || sub.Plan.Name != "free" || sub.ExpiresAt != nil || sub.Downgrade != nil {
+		t.Fatalf("unexpected subscription: %+v", sub)
+	}
+}
+
+func TestQiwiServiceBillHistory(t *testing.T) {
+	bills := &fakeBillRepo{}
+	subs := &fakeSubRepo{subs: make(map[string]time.Duration)}
+	s := NewQiwiService(&QiwiServiceConfig{
+		SubRepo:	subs,
+		BillRepo:	bills,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	bills.CreateBill(ctx, models.BillDB{ID: "b1", Username: "alice", Plan: "pro", Amount: 100, Status: models.BillWaiting})
+	s.saveBillId("b1", models.SubInfo{Username: "alice", Plan: "pro", Exp: time.Hour})
+
+	if err := s.NotifyFromQiwi(ctx, models.BillPaid, "b1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	history, _ := bills.FindBills(ctx, "alice", BillsLimit)
+	if len(history) != 1 || history[0].Status != models.BillPaid {
+		t.Fatalf("expected paid bill in history, got %+v", history)
+	}
+	if subs.plans["alice"] != "pro" {
+		t.Fatalf("expected pro subscription, got %q", subs.plans["alice"])
+	}
+}
+
+func TestSubscriptionServiceState(t *testing.T) {
+	repo := &fakeSubscriptionRepo{subs: map[string]models.SubscriptionDB{"alice": {Plan: "pro", Exp: time.Hour}}}
+	s := NewSubscriptionService(&SubscriptionServiceConfig{
+		SubRepo:	repo,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	if sub, plan, err := s.State(ctx, "alice"); err != nil || sub != models.Sub || plan != "pro" {
+		t.Fatalf("expected pro subscription, got %v %q %v", sub, plan, err)
+	}
+
+	// Подписка закончилась - права подписчика пропадают сразу, без нового входа
+	delete(repo.subs, "alice")
+	if sub, _, err := s.State(ctx, "alice"); err != nil || sub != models.Default {
+		t.Fatalf("expected no subscription, got %v %v", sub, err)
+	}
+
+	// Ошибка хранилища не выдается за отсутствие подписки
+	repo.err = errors.New("connection refused")
+	if _, _, err := s.State(ctx, "alice"); err == nil {
+		t.Fatal("expected storage error")
+	}
+}
+
+func TestSubscriptionServiceSetKeepLinks(t *testing.T) {
+	repo := newFakeLinkRepo(
+		models.LinkDataDB{Link: "c1", Custom: true, Owner: "alice"},
+		models.LinkDataDB{Link: "c2", Custom: true, Owner: "alice"},
+		models.LinkDataDB{Link: "d1", Owner: "alice"},
+		models.LinkDataDB{Link: "p1", Perm: true, Owner: "alice"},
+		models.LinkDataDB{Link: "x1", Owner: "bob"},
+	)
+	s := NewSubscriptionService(&SubscriptionServiceConfig{
+		LinkRepo:	repo,
+		Plans:		testCatalog(t),
+		Logger:		&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	// Бесплатный план: две ссылки, из них одна кастомная, без бессрочных
+	if err := s.SetKeepLinks(ctx, "alice", []string{"c1", "d1", "d1"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if keep := repo.keep["alice"]; len(keep) != 2 {
+		t.Fatalf("expected deduplicated keep list, got %v", keep)
+	}
+
+	for _, links := range [][]string{{"c1", "c2"}, {"p1"}, {"c1", "d1", "c2"}} {
+		if err := s.SetKeepLinks(ctx, "alice", links); err == nil || err.Error() != "limit exceeded" {
+			t.Errorf("expected limit exceeded for %v, got %v", links, err)
+		}
+	}
+	if err := s.SetKeepLinks(ctx, "alice", []string{"x1"}); err == nil || err.Error() != "link not found" {
+		t.Fatalf("expected link not found for foreign link, got %v", err)
+	}
+}
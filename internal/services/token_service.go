@@ -3,16 +3,22 @@ package services
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"short_url/internal/models"
 	"short_url/internal/security"
 	log "short_url/pkg/logger"
+	"time"
 )
 
+// Время жизни токена, выданного до проверки второго фактора (в секундах)
+const MFATokenExpirationSec = 300
+
 // TSConfig конфигурация для TokenService
 type TSConfig struct {
 	PrivateKey			*rsa.PrivateKey
 	PublicKey			*rsa.PublicKey
 	TokenExpirationSec	int64
+	RevocationRepo		revocationRepository	// Отзыв токенов (nil - токены действуют до истечения срока)
 	Logger				*log.Log
 }
 
@@ -21,6 +27,7 @@ type TokenService struct {
 	privateKey			*rsa.PrivateKey
 	publicKey			*rsa.PublicKey
 	tokenExpirationSec	int64
+	revocationRepo		revocationRepository
 	logger				*log.Log
 }
 
@@ -30,6 +37,7 @@ func NewTokenService(c *TSConfig) *TokenService {
 		privateKey:         c.PrivateKey,
 		publicKey:          c.PublicKey,
 		tokenExpirationSec: c.TokenExpirationSec,
+		revocationRepo:		c.RevocationRepo,
 		logger:				c.Logger,
 	}
 }
@@ -51,8 +59,24 @@ func (s *TokenService) ValidateToken(ctx context.Context, token string) (models.
 		return jwtUser, err
 	}
 
+	// Токены, выданные до отзыва (например, до удаления аккаунта), больше не принимаются.
+	// Если хранилище недоступно, токен отклоняется
+	if s.revocationRepo != nil {
+		revokedAt, err := s.revocationRepo.RevokedAt(ctx, claims.User.Username)
+		if err != nil {
+			l.Errorf("Unable to check token revocation. Error: %s", err)
+			return jwtUser, err
+		}
+		if !revokedAt.IsZero() && claims.IssuedAt <= revokedAt.Unix() {
+			return jwtUser, errors.New("access token is revoked")
+		}
+	}
+
 	jwtUser.Username = claims.User.Username
 	jwtUser.Subscribe = claims.User.Subscribe
+	jwtUser.Role = claims.User.Role
+	jwtUser.Plan = claims.User.Plan
+	jwtUser.MFAPending = claims.User.MFAPending
 
 	return jwtUser, nil
 }
@@ -65,7 +89,13 @@ func (s *TokenService) CreateToken(ctx context.Context, dto models.CreateTokenDT
 	l.Debug("CreateToken() started")
 	defer l.Debug("CreateToken() done")
 
-	token, err := security.GenerateAccessToken(models.JWTUserInfo{Username: dto.Username, Subscribe: dto.Subscribe}, s.privateKey, s.tokenExpirationSec)
+	// Токен до проверки второго фактора живет недолго
+	exp := s.tokenExpirationSec
+	if dto.MFAPending {
+		exp = MFATokenExpirationSec
+	}
+
+	token, err := security.GenerateAccessToken(models.JWTUserInfo{Username: dto.Username, Subscribe: dto.Subscribe, Role: dto.Role, Plan: dto.Plan, MFAPending: dto.MFAPending}, s.privateKey, exp)
 
 	if err != nil {
 		l.Errorf("Unable to create access token. Error: %s", err)
@@ -74,3 +104,29 @@ func (s *TokenService) CreateToken(ctx context.Context, dto models.CreateTokenDT
 
 	return token, nil
 }
+
+// RevokeUserTokens Отзывает все выданные пользователю токены доступа. Отметка хранится,
+// пока не истечет срок самого долгоживущего токена
+func (s *TokenService) RevokeUserTokens(ctx context.Context, username string) error {
+	ctx = log.ContextWithSpan(ctx, "RevokeUserTokens")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("RevokeUserTokens() started")
+	defer l.Debug("RevokeUserTokens() done")
+
+	if s.revocationRepo == nil {
+		return nil
+	}
+
+	exp := s.tokenExpirationSec
+	if exp < MFATokenExpirationSec {
+		exp = MFATokenExpirationSec
+	}
+
+	if err := s.revocationRepo.Revoke(ctx, username, time.Now(), time.Duration(exp)*time.Second); err != nil {
+		l.Errorf("Unable to revoke access tokens. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
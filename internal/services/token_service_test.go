@@ -1 +1,75 @@
 package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"short_url/internal/models"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeRevocationRepo Моменты отзыва токенов в памяти для тестов сервиса
+type fakeRevocationRepo struct {
+	revoked	map[string]time.Time
+	err		error
+}
+
+func (r *fakeRevocationRepo) Revoke(ctx context.Context, username string, at time.Time, ttl time.Duration) error {
+	r.revoked[username] = at
+	return nil
+}
+
+func (r *fakeRevocationRepo) RevokedAt(ctx context.Context, username string) (time.Time, error) {
+	return r.revoked[username], r.err
+}
+
+func TestTokenServiceRevoke(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	repo := &fakeRevocationRepo{revoked: make(map[string]time.Time)}
+	s := NewTokenService(&TSConfig{
+		PrivateKey:			key,
+		PublicKey:			&key.PublicKey,
+		TokenExpirationSec:	3600,
+		RevocationRepo:		repo,
+		Logger:				&log.Log{Logger: zap.NewNop()},
+	})
+	ctx := context.Background()
+
+	alice, _ := s.CreateToken(ctx, models.CreateTokenDTO{Username: "alice"})
+	bob, _ := s.CreateToken(ctx, models.CreateTokenDTO{Username: "bob"})
+	if info, err := s.ValidateToken(ctx, alice); err != nil || info.Username != "alice" {
+		t.Fatalf("unexpected token info: %+v (%v)", info, err)
+	}
+
+	// После отзыва ранее выданный токен не принимается, токены других пользователей действуют
+	if err = s.RevokeUserTokens(ctx, "alice"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err = s.ValidateToken(ctx, alice); err == nil {
+		t.Fatal("revoked token must be rejected")
+	}
+	if _, err = s.ValidateToken(ctx, bob); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Токен, выданный позже отзыва, действует
+	repo.revoked["alice"] = time.Now().Add(-2 * time.Second)
+	fresh, _ := s.CreateToken(ctx, models.CreateTokenDTO{Username: "alice"})
+	if _, err = s.ValidateToken(ctx, fresh); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Если хранилище недоступно, токен отклоняется
+	repo.err = errors.New("connection refused")
+	if _, err = s.ValidateToken(ctx, bob); err == nil {
+		t.Fatal("token must be rejected when revocation is unknown")
+	}
+}
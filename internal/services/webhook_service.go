@@ -0,0 +1,479 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"short_url/internal/events"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Ограничения вебхуков
+const (
+	WebhookLimit	= 10				// Максимальное кол-во вебхуков у пользователя
+	DeliveryLimit	= 100				// Максимальное кол-во доставок в выдаче пользователю
+	deliveryBatch	= 100				// Сколько доставок отправляется за один проход
+	maxBackoff		= 6 * time.Hour		// Максимальная задержка между повторами
+)
+
+// Заголовки запроса доставки события
+const (
+	HeaderWebhookEvent		= "X-Webhook-Event"
+	HeaderWebhookDelivery	= "X-Webhook-Delivery"
+	HeaderWebhookSignature	= "X-Webhook-Signature"		// sha256=<HMAC-SHA256 тела запроса в hex>
+)
+
+// WebhookServiceConfig Конфигурация для WebhookService
+type WebhookServiceConfig struct {
+	WebhookRepo	webhookRepository
+	Conf		*models.ConfigWebhook
+	Leader		leaderElector
+	Logger		*log.Log
+}
+
+// WebhookService Управляет вебхуками пользователей и доставляет на них события
+type WebhookService struct {
+	webhookRepo	webhookRepository
+	conf		*models.ConfigWebhook
+	leader		leaderElector
+	client		*http.Client
+	resolve		func(ctx context.Context, host string) ([]net.IP, error)
+	logger		*log.Log
+}
+
+// NewWebhookService Конструктор для WebhookService
+func NewWebhookService(c *WebhookServiceConfig) *WebhookService {
+	return &WebhookService{
+		webhookRepo:	c.WebhookRepo,
+		conf:			c.Conf,
+		leader:			c.Leader,
+		client:			newWebhookClient(c.Conf.Timeout, security.DialControl),
+		resolve:		func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+		logger:			c.Logger,
+	}
+}
+
+// newWebhookClient Создает клиент доставки: адрес соединения проверяется control после разрешения имени,
+// прокси из окружения не используется, редиректы не выполняются (ответ 3xx считается неудачей)
+func newWebhookClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:	timeout,
+		Control:	control,
+	}
+
+	return &http.Client{
+		Timeout:	timeout,
+		Transport:	&http.Transport{
+			DialContext:			dialer.DialContext,
+			TLSHandshakeTimeout:	timeout,
+			MaxIdleConns:			100,
+			IdleConnTimeout:		90 * time.Second,
+		},
+		CheckRedirect:	func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicHost Проверяет, что все адреса хоста вебхука внешние
+func (s *WebhookService) publicHost(ctx context.Context, host string) bool {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = s.resolve(ctx, host); err != nil || len(ips) == 0 {
+			return false
+		}
+	}
+
+	for _, ip := range ips {
+		if !security.PublicIP(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deliveryError Возвращает описание неудачной доставки для журнала пользователя (без подробностей сети)
+func deliveryError(code int, err error) string {
+	var netErr net.Error
+	switch {
+	case code != 0:
+		return fmt.Sprintf("unexpected status %d", code)
+	case errors.Is(err, security.ErrForbiddenAddress):
+		return "address not allowed"
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "connection failed"
+	}
+}
+
+// validateEvents Проверяет виды событий и убирает повторы
+func validateEvents(events []string) ([]string, error) {
+	known := make(map[string]bool, len(models.EventKinds))
+	for _, kind := range models.EventKinds {
+		known[kind] = true
+	}
+
+	result := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, kind := range events {
+		if !known[kind] {
+			return nil, errors.New("invalid event")
+		}
+		if seen[kind] {
+			continue
+		}
+
+		seen[kind] = true
+		result = append(result, kind)
+	}
+
+	return result, nil
+}
+
+// CreateWebhook Регистрирует адрес для событий пользователя и создает ключ подписи
+func (s *WebhookService) CreateWebhook(ctx context.Context, username, rawURL string, events []string) (models.WebhookDB, error) {
+	ctx = log.ContextWithSpan(ctx, "CreateWebhook")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("CreateWebhook() started")
+	defer l.Debug("CreateWebhook() done")
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return models.WebhookDB{}, errors.New("invalid url")
+	}
+
+	// Адреса во внутренней сети не принимаются (при доставке адрес проверяется еще раз)
+	if !s.publicHost(ctx, u.Hostname()) {
+		return models.WebhookDB{}, errors.New("invalid url")
+	}
+
+	events, err = validateEvents(events)
+	if err != nil {
+		return models.WebhookDB{}, err
+	}
+
+	// Проверяем лимит вебхуков
+	hooks, err := s.webhookRepo.FindWebhooks(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to find webhooks. Error: %s", err)
+		return models.WebhookDB{}, err
+	}
+	if len(hooks) >= WebhookLimit {
+		return models.WebhookDB{}, errors.New("limit exceeded")
+	}
+
+	secret, err := security.GenerateSecret()
+	if err != nil {
+		l.Errorf("Unable to generate webhook secret. Error: %s", err)
+		return models.WebhookDB{}, err
+	}
+
+	hook, err := s.webhookRepo.CreateWebhook(ctx, models.WebhookDB{
+		Username:	username,
+		URL:		u.String(),
+		Secret:		secret,
+		Events:		events,
+	})
+	if err != nil {
+		l.Errorf("Unable to create webhook. Error: %s", err)
+		return models.WebhookDB{}, err
+	}
+
+	return hook, nil
+}
+
+// Webhooks Возвращает вебхуки пользователя (без ключей подписи)
+func (s *WebhookService) Webhooks(ctx context.Context, username string) ([]models.WebhookDB, error) {
+	ctx = log.ContextWithSpan(ctx, "Webhooks")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Webhooks() started")
+	defer l.Debug("Webhooks() done")
+
+	hooks, err := s.webhookRepo.FindWebhooks(ctx, username)
+	if err != nil {
+		l.Errorf("Unable to find webhooks. Error: %s", err)
+		return nil, err
+	}
+
+	for k := range hooks {
+		hooks[k].Secret = ""
+	}
+
+	return hooks, nil
+}
+
+// DeleteWebhook Удаляет вебхук пользователя
+func (s *WebhookService) DeleteWebhook(ctx context.Context, username string, id int64) error {
+	ctx = log.ContextWithSpan(ctx, "DeleteWebhook")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("DeleteWebhook() started")
+	defer l.Debug("DeleteWebhook() done")
+
+	err := s.webhookRepo.DeleteWebhook(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("webhook not found")
+		}
+
+		l.Errorf("Unable to delete webhook. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Deliveries Возвращает журнал доставок событий пользователя
+func (s *WebhookService) Deliveries(ctx context.Context, username string) ([]models.DeliveryDB, error) {
+	ctx = log.ContextWithSpan(ctx, "Deliveries")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Deliveries() started")
+	defer l.Debug("Deliveries() done")
+
+	deliveries, err := s.webhookRepo.FindDeliveries(ctx, username, DeliveryLimit)
+	if err != nil {
+		l.Errorf("Unable to find deliveries. Error: %s", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver Ставит доставку события в очередь повторно
+func (s *WebhookService) Redeliver(ctx context.Context, username string, id int64) error {
+	ctx = log.ContextWithSpan(ctx, "Redeliver")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Redeliver() started")
+	defer l.Debug("Redeliver() done")
+
+	err := s.webhookRepo.Redeliver(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("delivery not found")
+		}
+
+		l.Errorf("Unable to redeliver. Error: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Enqueue Ставит событие в очередь доставки на вебхуки пользователя.
+// Ошибки только записываются в лог: событие не должно мешать операции, которая его вызвала
+func (s *WebhookService) Enqueue(ctx context.Context, kind, username string, data map[string]any) {
+	l := s.logger.WithContext(ctx)
+
+	hooks, err := s.webhookRepo.FindSubscribed(ctx, username, kind)
+	if err != nil {
+		l.Errorf("Unable to find webhooks for event %s. Error: %s", kind, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	event := models.Event{
+		ID:			uuid.NewString(),
+		Kind:		kind,
+		Username:	username,
+		Data:		data,
+		CreatedAt:	time.Now().UTC(),
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		l.Errorf("Unable to marshal event %s. Error: %s", kind, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		err = s.webhookRepo.CreateDelivery(ctx, models.DeliveryDB{
+			WebhookID:	hook.ID,
+			Username:	username,
+			EventID:	event.ID,
+			Kind:		kind,
+			Payload:	string(payload),
+		})
+		if err != nil {
+			l.Errorf("Unable to queue event %s for webhook %d. Error: %s", kind, hook.ID, err)
+		}
+	}
+}
+
+// Subscribe Подписывает очередь доставки на события шины
+func (s *WebhookService) Subscribe(b *events.Bus) {
+	events.On(b, func(ctx context.Context, ev events.LinkCreated) error {
+		s.Enqueue(ctx, models.EventLinkCreated, ev.Owner, map[string]any{
+			"link":		ev.Link,
+			"url":		ev.FullURL,
+			"exp":		int64(ev.Exp),
+			"custom":	ev.Custom,
+		})
+		return nil
+	})
+	events.On(b, func(ctx context.Context, ev events.LinkDeleted) error {
+		kind := models.EventLinkDeleted
+		if ev.Reason == events.ReasonExpired {
+			kind = models.EventLinkExpired
+		}
+		s.Enqueue(ctx, kind, ev.Owner, map[string]any{
+			"link":		ev.Link,
+			"reason":	ev.Reason,
+		})
+		return nil
+	})
+	events.On(b, func(ctx context.Context, ev events.SubscriptionActivated) error {
+		// Продление действующей подписки новым событием не считается
+		if ev.Renewal {
+			return nil
+		}
+		s.Enqueue(ctx, models.EventSubStarted, ev.Username, map[string]any{
+			"plan":			ev.Plan,
+			"expires_at":	time.Now().Add(ev.Exp).UTC(),
+		})
+		return nil
+	})
+	events.On(b, func(ctx context.Context, ev events.SubscriptionEnded) error {
+		s.Enqueue(ctx, models.EventSubEnded, ev.Username, map[string]any{
+			"expired_at":	ev.ExpiresAt.UTC(),
+			"cleanup_at":	ev.CleanupAt.UTC(),
+		})
+		return nil
+	})
+}
+
+// backoff Возвращает задержку перед следующей попыткой (удваивается с каждой неудачей)
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.conf.Backoff
+	for k := 1; k < attempts && delay < maxBackoff; k++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
+
+// send Отправляет событие на адрес вебхука, подписывая тело ключом вебхука
+func (s *WebhookService) send(ctx context.Context, d models.DeliveryDB) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, d.Kind)
+	req.Header.Set(HeaderWebhookDelivery, d.EventID)
+	req.Header.Set(HeaderWebhookSignature, "sha256="+security.HMACSHA256(d.Secret, []byte(d.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Deliver Отправляет события, время доставки которых наступило. Неудачные попытки
+// повторяются с экспоненциальной задержкой, пока не исчерпан лимит попыток
+func (s *WebhookService) Deliver(ctx context.Context) error {
+	ctx = log.ContextWithSpan(ctx, "Deliver")
+	l := s.logger.WithContext(ctx)
+
+	l.Debug("Deliver() started")
+	defer l.Debug("Deliver() done")
+
+	deliveries, err := s.webhookRepo.DueDeliveries(ctx, time.Now(), deliveryBatch)
+	if err != nil {
+		l.Errorf("Unable to get due deliveries. Error: %s", err)
+		return err
+	}
+
+	for _, d := range deliveries {
+		d.Attempts++
+		d.ResponseCode, err = s.send(ctx, d)
+		if err == nil {
+			d.Status = models.DeliverySuccess
+			d.LastError = ""
+		} else {
+			d.LastError = deliveryError(d.ResponseCode, err)
+			if d.Attempts >= s.conf.MaxAttempts {
+				d.Status = models.DeliveryFailed
+			} else {
+				d.NextAttempt = time.Now().Add(s.backoff(d.Attempts))
+			}
+
+			l.Warnf("Delivery %d of event %s failed (attempt %d). Error: %s", d.ID, d.Kind, d.Attempts, err)
+		}
+
+		if err = s.webhookRepo.UpdateDelivery(ctx, d); err != nil {
+			l.Errorf("Unable to update delivery %d. Error: %s", d.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// DeliveryCycle Доставляет события в цикле
+func (s *WebhookService) DeliveryCycle(ctx context.Context) chan struct{} {
+	ctx = log.ContextWithSpan(ctx, "DeliveryCycle")
+	l := s.logger.WithContext(ctx)
+
+	// Канал сигнала остановки
+	doneChannel := make(chan struct{}, 1)
+
+	// Тикер (интервал)
+	ticker := time.NewTicker(s.conf.Interval)
+
+	go func(doneChannel chan struct{}, ticker *time.Ticker) {
+		l.Info("start webhook delivery")
+		for {
+			select {
+			case <-ticker.C:
+				// Доставляет только ведущий экземпляр, иначе событие уйдет несколько раз
+				if isLeader(s.leader) {
+					s.Deliver(ctx)
+				}
+
+			case <-doneChannel:
+				// Останавливаем тикер
+				ticker.Stop()
+
+				l.Info("end webhook delivery")
+
+				return
+			}
+		}
+	}(doneChannel, ticker)
+
+	return doneChannel
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"short_url/internal/models"
+	"short_url/internal/security"
+	log "short_url/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// fakeWebhookRepo Вебхуки и доставки в памяти для тестов
+type fakeWebhookRepo struct {
+	hooks		[]models.WebhookDB
+	deliveries	[]models.DeliveryDB
+}
+
+func (r *fakeWebhookRepo) CreateWebhook(ctx context.Context, w models.WebhookDB) (models.WebhookDB, error) {
+	w.ID = int64(len(r.hooks) + 1)
+	w.CreatedAt = time.Now()
+	r.hooks = append(r.hooks, w)
+	return w, nil
+}
+
+func (r *fakeWebhookRepo) FindWebhooks(ctx context.Context, username string) ([]models.WebhookDB, error) {
+	result := make([]models.WebhookDB, 0)
+	for _, w := range r.hooks {
+		if w.Username == username {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) FindSubscribed(ctx context.Context, username, kind string) ([]models.WebhookDB, error) {
+	result := make([]models.WebhookDB, 0)
+	for _, w := range r.hooks {
+		if w.Username != username {
+			continue
+		}
+		subscribed := len(w.Events) == 0
+		for _, e := range w.Events {
+			subscribed = subscribed || e == kind
+		}
+		if subscribed {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) DeleteWebhook(ctx context.Context, id int64, username string) error {
+	for k, w := range r.hooks {
+		if w.ID == id && w.Username == username {
+			r.hooks = append(r.hooks[:k], r.hooks[k+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (r *fakeWebhookRepo) DeleteWebhooks(ctx context.Context, username string) error {
+	hooks := r.hooks[:0]
+	for _, w := range r.hooks {
+		if w.Username != username {
+			hooks = append(hooks, w)
+		}
+	}
+	r.hooks = hooks
+	return nil
+}
+
+func (r *fakeWebhookRepo) CreateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	d.ID = int64(len(r.deliveries) + 1)
+	d.Status = models.DeliveryPending
+	d.NextAttempt = time.Now()
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+func (r *fakeWebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DeliveryDB, error) {
+	result := make([]models.DeliveryDB, 0)
+	for _, d := range r.deliveries {
+		if d.Status != models.DeliveryPending || d.NextAttempt.After(now) {
+			continue
+		}
+		for _, w := range r.hooks {
+			if w.ID == d.WebhookID {
+				d.URL, d.Secret = w.URL, w.Secret
+			}
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(ctx context.Context, d models.DeliveryDB) error {
+	for k := range r.deliveries {
+		if r.deliveries[k].ID == d.ID {
+			r.deliveries[k] = d
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepo) FindDeliveries(ctx context.Context, username string, limit int) ([]models.DeliveryDB, error) {
+	return r.deliveries, nil
+}
+
+func (r *fakeWebhookRepo) Redeliver(ctx context.Context, id int64, username string) error {
+	for k := range r.deliveries {
+		if r.deliveries[k].ID == id && r.deliveries[k].Username == username {
+			r.deliveries[k].Status = models.DeliveryPending
+			r.deliveries[k].Attempts = 0
+			r.deliveries[k].NextAttempt = time.Now()
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func newTestWebhookService(repo *fakeWebhookRepo) *WebhookService {
+	s := NewWebhookService(&WebhookServiceConfig{
+		WebhookRepo:	repo,
+		Conf:			&models.ConfigWebhook{Timeout: time.Second, MaxAttempts: 2, Backoff: time.Minute},
+		Logger:			&log.Log{Logger: zap.NewNop()},
+	})
+
+	// DNS в тестах не используется
+	s.resolve = func(ctx context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.5")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	return s
+}
+
+// addTestWebhook Регистрирует вебхук в обход проверки адреса (тестовый сервер слушает петлевой адрес)
+func addTestWebhook(repo *fakeWebhookRepo, username, url string, events []string) models.WebhookDB {
+	hook, _ := repo.CreateWebhook(context.Background(), models.WebhookDB{
+		Username:	username,
+		URL:		url,
+		Secret:		"secret-" + username,
+		Events:		events,
+	})
+	return hook
+}
+
+func TestWebhookServiceCreateWebhook(t *testing.T) {
+	repo := &fakeWebhookRepo{}
+	s := newTestWebhookService(repo)
+	ctx := context.Background()
+
+	for _, bad := range []struct {
+		url		string
+		events	[]string
+		err		string
+	}{
+		{"ftp://example.com", nil, "invalid url"},
+		{"/relative", nil, "invalid url"},
+		{"http://127.0.0.1:8080/hook", nil, "invalid url"},
+		{"http://[::1]/hook", nil, "invalid url"},
+		{"http://169.254.169.254/latest/meta-data", nil, "invalid url"},
+		{"http://10.0.0.1/hook", nil, "invalid url"},
+		{"http://0.0.0.0/hook", nil, "invalid url"},
+		{"https://internal.example.com/hook", nil, "invalid url"},
+		{"https://unknown.example.com/hook", nil, "invalid url"},
+		{"https://example.com/hook", []string{"link.unknown"}, "invalid event"},
+	} {
+		if _, err := s.CreateWebhook(ctx, "alice", bad.url, bad.events); err == nil || err.Error() != bad.err {
+			t.Errorf("%s %v: expected %q, got %v", bad.url, bad.events, bad.err, err)
+		}
+	}
+
+	hook, err := s.CreateWebhook(ctx, "alice", "https://example.com/hook", []string{models.EventLinkCreated, models.EventLinkCreated})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if hook.Secret == "" || len(hook.Events) != 1 {
+		t.Fatalf("expected secret and deduplicated events, got %+v", hook)
+	}
+
+	// Ключ подписи показывается только при создании
+	hooks, _ := s.Webhooks(ctx, "alice")
+	if len(hooks) != 1 || hooks[0].Secret != "" {
+		t.Fatalf("secret must be hidden in list, got %+v", hooks)
+	}
+
+	if err = s.DeleteWebhook(ctx, "bob", hook.ID); err == nil || err.Error() != "webhook not found" {
+		t.Fatalf("expected webhook not found for other user, got %v", err)
+	}
+}
+
+func TestWebhookServiceDeliver(t *testing.T) {
+	var (
+		gotBody		[]byte
+		gotSign		string
+		gotEvent	string
+		fail		bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSign = r.Header.Get(HeaderWebhookSignature)
+		gotEvent = r.Header.Get(HeaderWebhookEvent)
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	repo := &fakeWebhookRepo{}
+	s := newTestWebhookService(repo)
+	s.client = newWebhookClient(time.Second, nil)
+	ctx := context.Background()
+
+	hook := addTestWebhook(repo, "alice", server.URL, []string{models.EventLinkCreated})
+	addTestWebhook(repo, "bob", server.URL, nil)
+
+	// Событие другого вида и другого пользователя не попадает в очередь
+	s.Enqueue(ctx, models.EventLinkDeleted, "alice", nil)
+	s.Enqueue(ctx, models.EventLinkCreated, "alice", map[string]any{"link": "abc"})
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("expected single delivery, got %d", len(repo.deliveries))
+	}
+
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	d := repo.deliveries[0]
+	if d.Status != models.DeliverySuccess || d.Attempts != 1 || d.ResponseCode != http.StatusOK {
+		t.Fatalf("expected successful delivery, got %+v", d)
+	}
+	if gotEvent != models.EventLinkCreated || gotSign != "sha256="+security.HMACSHA256(hook.Secret, gotBody) {
+		t.Fatalf("unexpected headers: event %q, signature %q", gotEvent, gotSign)
+	}
+
+	var event models.Event
+	if err := json.Unmarshal(gotBody, &event); err != nil || event.Username != "alice" || event.Data["link"] != "abc" {
+		t.Fatalf("unexpected body %s (%v)", gotBody, err)
+	}
+
+	// Неудачная доставка откладывается, после последней попытки помечается failed
+	fail = true
+	if err := s.Redeliver(ctx, "alice", d.ID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s.Deliver(ctx)
+	d = repo.deliveries[0]
+	if d.Status != models.DeliveryPending || d.Attempts != 1 || d.ResponseCode != http.StatusBadGateway || d.LastError != "unexpected status 502" || d.NextAttempt.Before(time.Now().Add(50*time.Second)) {
+		t.Fatalf("expected postponed retry, got %+v", d)
+	}
+
+	repo.deliveries[0].NextAttempt = time.Now()
+	s.Deliver(ctx)
+	if d = repo.deliveries[0]; d.Status != models.DeliveryFailed || d.Attempts != 2 {
+		t.Fatalf("expected failed delivery, got %+v", d)
+	}
+
+	if err := s.Redeliver(ctx, "bob", d.ID); err == nil || err.Error() != "delivery not found" {
+		t.Fatalf("expected delivery not found for other user, got %v", err)
+	}
+}
+
+func TestWebhookServiceDeliverInternal(t *testing.T) {
+	var hits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, r.URL.Path)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	repo := &fakeWebhookRepo{}
+	s := newTestWebhookService(repo)
+	ctx := context.Background()
+
+	// Адрес вебхука стал указывать во внутреннюю сеть после регистрации: соединение не устанавливается
+	addTestWebhook(repo, "alice", server.URL+"/hook", nil)
+	s.Enqueue(ctx, models.EventLinkCreated, "alice", nil)
+	s.Deliver(ctx)
+	if d := repo.deliveries[0]; d.Status != models.DeliveryPending || d.LastError != "address not allowed" || len(hits) != 0 {
+		t.Fatalf("delivery to internal address must be blocked, got %+v (hits %v)", d, hits)
+	}
+
+	// Редирект не выполняется и считается неудачей
+	s.client = newWebhookClient(time.Second, nil)
+	repo.deliveries = nil
+	repo.hooks[0].URL = server.URL + "/redirect"
+	s.Enqueue(ctx, models.EventLinkCreated, "alice", nil)
+	s.Deliver(ctx)
+	if d := repo.deliveries[0]; d.ResponseCode != http.StatusFound || d.LastError != "unexpected status 302" || len(hits) != 1 {
+		t.Fatalf("redirect must not be followed, got %+v (hits %v)", d, hits)
+	}
+}
+
+func TestWebhookServiceBackoff(t *testing.T) {
+	s := newTestWebhookService(&fakeWebhookRepo{})
+
+	for attempts, delay := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 50: maxBackoff} {
+		if got := s.backoff(attempts); got != delay {
+			t.Errorf("attempt %d: expected %s, got %s", attempts, delay, got)
+		}
+	}
+}
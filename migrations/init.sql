@@ -6,5 +6,203 @@ CREATE TABLE IF NOT EXISTS cpuser (
     username varchar     NOT NULL UNIQUE,
     first_name varchar   NOT NULL DEFAULT '',
     last_name varchar    NOT NULL DEFAULT '',
-    password varchar     NOT NULL
+    password varchar     NOT NULL,
+    role varchar         NOT NULL DEFAULT 'user'
 );
+
+/*
+Роль пользователя (user - обычный пользователь, admin - оператор сервиса).
+Администраторы назначаются вручную: UPDATE cpuser SET role = 'admin' WHERE username = '...';
+*/
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS role varchar NOT NULL DEFAULT 'user';
+
+/*
+Необязательная почта пользователя (пустая строка - почта не указана)
+*/
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS email varchar NOT NULL DEFAULT '';
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;
+CREATE UNIQUE INDEX IF NOT EXISTS cpuser_email_idx ON cpuser (lower(email)) WHERE email <> '';
+
+/*
+Двухфакторная аутентификация (TOTP, RFC 6238)
+*/
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS totp_secret varchar NOT NULL DEFAULT '';
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false;
+
+/*
+Последний принятый шаг TOTP: код того же или более раннего шага повторно не принимается (RFC 6238, 5.2)
+*/
+ALTER TABLE cpuser ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0;
+
+/*
+Резервные коды для входа без приложения-аутентификатора (хранится только sha256 от кода)
+*/
+CREATE TABLE IF NOT EXISTS recovery_code (
+    code_id bigserial       NOT NULL PRIMARY KEY,
+    username varchar        NOT NULL,
+    code_hash varchar       NOT NULL,
+    used_at timestamptz     NULL,
+    created_at timestamptz  NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS recovery_code_username_idx ON recovery_code (username);
+
+/*
+Привязка аккаунтов к пользователям внешних провайдеров OpenID Connect
+*/
+CREATE TABLE IF NOT EXISTS user_identity (
+    identity_id bigserial   NOT NULL PRIMARY KEY,
+    username varchar        NOT NULL,
+    issuer varchar          NOT NULL,
+    subject varchar         NOT NULL,
+    email varchar           NOT NULL DEFAULT '',
+    created_at timestamptz  NOT NULL DEFAULT now(),
+    UNIQUE (issuer, subject)
+);
+CREATE INDEX IF NOT EXISTS user_identity_username_idx ON user_identity (username);
+
+/*
+Одноразовые токены подтверждения почты и сброса пароля (хранится только sha256 от токена)
+*/
+CREATE TABLE IF NOT EXISTS user_token (
+    token_id bigserial      NOT NULL PRIMARY KEY,
+    username varchar        NOT NULL,
+    kind varchar            NOT NULL,
+    token_hash varchar      NOT NULL UNIQUE,
+    email varchar           NOT NULL DEFAULT '',
+    expires_at timestamptz  NOT NULL,
+    used_at timestamptz     NULL,
+    created_at timestamptz  NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS user_token_username_idx ON user_token (username, kind);
+
+/*
+Жалобы на ссылки (очередь модерации)
+*/
+CREATE TABLE IF NOT EXISTS link_report (
+    report_id bigserial     NOT NULL PRIMARY KEY,
+    link varchar            NOT NULL,
+    reason varchar          NOT NULL,
+    reporter varchar        NOT NULL DEFAULT '',
+    status varchar          NOT NULL DEFAULT 'open',
+    created_at timestamptz  NOT NULL DEFAULT now(),
+    resolved_by varchar     NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS link_report_status_idx ON link_report (status, created_at);
+
+/*
+Журнал аудита изменений аккаунтов и ссылок (только добавление записей).
+Единственное допустимое изменение - обезличивание удаленного пользователя: в транзакции с
+SET LOCAL audit.redact = 'on' можно заменить actor, owner, target и details, остальные поля неизменны
+*/
+CREATE TABLE IF NOT EXISTS audit_log (
+    audit_id bigserial      NOT NULL PRIMARY KEY,
+    actor varchar           NOT NULL,
+    action varchar          NOT NULL,
+    target varchar          NOT NULL DEFAULT '',
+    owner varchar           NOT NULL DEFAULT '',
+    details varchar         NOT NULL DEFAULT '',
+    trace_id varchar        NOT NULL DEFAULT '',
+    created_at timestamptz  NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS audit_log_owner_idx ON audit_log (owner, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, created_at);
+DROP RULE IF EXISTS audit_log_no_update ON audit_log;
+DROP RULE IF EXISTS audit_log_no_delete ON audit_log;
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND current_setting('audit.redact', true) = 'on' THEN
+        IF NEW.audit_id = OLD.audit_id AND NEW.action = OLD.action
+            AND NEW.trace_id = OLD.trace_id AND NEW.created_at = OLD.created_at THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+
+    -- Как и прежние правила DO INSTEAD NOTHING, остальные изменения молча пропускаются
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+/*
+История счетов на оплату подписок
+*/
+CREATE TABLE IF NOT EXISTS bill (
+    bill_id varchar         NOT NULL PRIMARY KEY,
+    username varchar        NOT NULL,
+    plan varchar            NOT NULL,
+    amount numeric(12, 2)   NOT NULL,
+    status varchar          NOT NULL DEFAULT 'WAITING',
+    created_at timestamptz  NOT NULL DEFAULT now(),
+    updated_at timestamptz  NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS bill_username_idx ON bill (username, created_at);
+
+/*
+Промокоды: скидки на оплату планов и пробные подписки
+*/
+CREATE TABLE IF NOT EXISTS promo_code (
+    code varchar            NOT NULL PRIMARY KEY,
+    kind varchar            NOT NULL,
+    value numeric(12, 2)    NOT NULL DEFAULT 0,
+    days integer            NOT NULL DEFAULT 0,
+    plan varchar            NOT NULL DEFAULT '',
+    max_uses integer        NOT NULL DEFAULT 0,
+    uses integer            NOT NULL DEFAULT 0,
+    expires_at timestamptz,
+    created_by varchar      NOT NULL DEFAULT '',
+    created_at timestamptz  NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS promo_redemption (
+    code varchar            NOT NULL REFERENCES promo_code (code) ON DELETE CASCADE,
+    username varchar        NOT NULL,
+    created_at timestamptz  NOT NULL DEFAULT now(),
+    PRIMARY KEY (code, username)
+);
+ALTER TABLE bill ADD COLUMN IF NOT EXISTS promo varchar NOT NULL DEFAULT '';
+
+/*
+Обработанные уведомления QIWI: счет обрабатывается один раз, даже если уведомление
+пришло повторно или одновременно с опросом статуса
+*/
+CREATE TABLE IF NOT EXISTS qiwi_event (
+    bill_id varchar             NOT NULL PRIMARY KEY,
+    status varchar              NOT NULL,
+    processed_at timestamptz    NOT NULL DEFAULT now()
+);
+
+/*
+Вебхуки пользователей и журнал доставки событий на них
+*/
+CREATE TABLE IF NOT EXISTS webhook (
+    id bigserial                NOT NULL PRIMARY KEY,
+    username varchar            NOT NULL,
+    url varchar                 NOT NULL,
+    secret varchar              NOT NULL,
+    events varchar[]            NOT NULL DEFAULT '{}',
+    created_at timestamptz      NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS webhook_username_idx ON webhook (username);
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id bigserial                NOT NULL PRIMARY KEY,
+    webhook_id bigint           NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+    username varchar            NOT NULL,
+    event_id varchar            NOT NULL,
+    kind varchar                NOT NULL,
+    payload text                NOT NULL,
+    status varchar              NOT NULL DEFAULT 'pending',
+    attempts integer            NOT NULL DEFAULT 0,
+    next_attempt timestamptz    NOT NULL DEFAULT now(),
+    response_code integer       NOT NULL DEFAULT 0,
+    last_error varchar          NOT NULL DEFAULT '',
+    created_at timestamptz      NOT NULL DEFAULT now(),
+    updated_at timestamptz      NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (status, next_attempt);
+CREATE INDEX IF NOT EXISTS webhook_delivery_username_idx ON webhook_delivery (username, created_at);
+
+/*
+Ожидающие оплаты счета опрашивает ведущий экземпляр, в том числе выставленные другими экземплярами
+*/
+CREATE INDEX IF NOT EXISTS bill_status_idx ON bill (status, created_at);
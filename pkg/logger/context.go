@@ -43,6 +43,12 @@ func ContextWithSpan(ctx context.Context, span string) context.Context {
 	return ctx
 }
 
+// TraceFromContext Возвращает сквозной идентификатор вызова из контекста
+func TraceFromContext(ctx context.Context) string {
+	trace, _ := ctx.Value(TraceID{}).(string)
+	return trace
+}
+
 // contextWithParentSpan
 func contextWithParentSpan(ctx context.Context) context.Context {
 	span, ok := ctx.Value(SpanID{}).(string)